@@ -11,17 +11,27 @@ import (
 	"time"
 
 	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/closer"
 	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/dbpool"
+	"github.com/ayubfarah/vehicle-auc/internal/drafts"
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/payments"
 	"github.com/ayubfarah/vehicle-auc/internal/realtime"
+	"github.com/ayubfarah/vehicle-auc/internal/results"
 	"github.com/ayubfarah/vehicle-auc/internal/tracing"
+	"github.com/ayubfarah/vehicle-auc/internal/version"
+	"github.com/ayubfarah/vehicle-auc/internal/webhook"
 	"github.com/getsentry/sentry-go"
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
 )
 
 func main() {
@@ -42,6 +52,7 @@ func main() {
 		logger.Error("invalid config", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
+	httpx.SetPageSizes(cfg.PageSizeDefault, cfg.PageSizeMax)
 
 	// Initialize Sentry
 	if cfg.SentryDSN != "" {
@@ -74,6 +85,7 @@ func main() {
 	dbConfig.MaxConns = int32(cfg.DBMaxConns)
 	dbConfig.MinConns = int32(cfg.DBMinConns)
 	dbConfig.MaxConnLifetime = cfg.DBMaxConnLife
+	dbConfig.ConnConfig.Tracer = dbpool.NewTracer(cfg.DBAcquireTimeout)
 
 	db, err := pgxpool.NewWithConfig(ctx, dbConfig)
 	if err != nil {
@@ -89,77 +101,208 @@ func main() {
 	logger.Info("database_connected")
 
 	// Initialize SSE broker
-	broker := realtime.NewBroker(logger)
+	brokerOpts := []realtime.BrokerOption{
+		realtime.WithViewerCountInterval(cfg.SSEViewerCountInterval),
+		realtime.WithShutdownRetry(cfg.SSEShutdownRetry),
+		realtime.WithMaxConnsPerUser(cfg.SSEMaxConnPerUser),
+		realtime.WithMaxConnsPerIP(cfg.SSEMaxConnPerIP),
+		realtime.WithGlobalInterval(cfg.SSEGlobalInterval),
+		// Checked as each global window is sent, so bounded tighter than DB_QUERY_TIMEOUT
+		realtime.WithGlobalFilter(handler.PublicAuctionFilter(db, time.Second)),
+	}
+	if cfg.SSERelay == "postgres" {
+		brokerOpts = append(brokerOpts, realtime.WithRelay(realtime.NewPGRelay(db, cfg.SSERelayChannel)))
+	}
+	// Read cache for auction pages; the broker expires entries on bids and
+	// other auction events, including ones relayed from other instances
+	var auctionOpts []handler.AuctionHandlerOption
+	if cfg.ReadCacheTTL > 0 {
+		auctionCache := handler.NewAuctionCache(cfg.ReadCacheTTL)
+		brokerOpts = append(brokerOpts, realtime.WithEventHook(auctionCache.Observe))
+		auctionOpts = append(auctionOpts, handler.WithAuctionCache(auctionCache))
+	}
+	// Auction stream ticks share one read per auction per keepalive, expired
+	// the same way
+	tickCache := handler.NewTickCache(cfg.SSEKeepaliveInterval)
+	brokerOpts = append(brokerOpts, realtime.WithEventHook(tickCache.Observe))
+	broker := realtime.NewBroker(logger, brokerOpts...)
 	broker.Start()
-	defer broker.Stop()
+
+	// Outbound webhooks for users' own bid outcomes and auction lifecycle events
+	var webhookEndpoints []webhook.Endpoint
+	for _, url := range cfg.WebhookEndpoints {
+		webhookEndpoints = append(webhookEndpoints, webhook.Endpoint{URL: url, Secret: cfg.WebhookSecret})
+	}
+	webhooks := webhook.NewDispatcher(db, logger,
+		webhook.WithEndpoints(webhookEndpoints...),
+		webhook.WithRetries(cfg.WebhookMaxRetries, cfg.WebhookRetryBackoff),
+		webhook.WithInsecureURLs(cfg.WebhookInsecureURLs),
+	)
+	webhooks.Start()
+	defer webhooks.Stop()
+
+	// Shared bid results, so any instance can answer a status poll
+	var resultStore bidengine.ResultStore
+	if cfg.BidResultStore == "redis" {
+		redisOpts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			logger.Error("failed to parse redis url", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		redisClient := redis.NewClient(redisOpts)
+		defer redisClient.Close()
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			logger.Error("failed to ping redis", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		resultStore = results.NewRedisStore(redisClient, cfg.BidResultTTL)
+		logger.Info("redis_connected")
+	}
 
 	// Initialize bid engine
 	engine := bidengine.NewEngine(
 		db, logger, broker,
+		bidengine.WithNotifier(webhooks),
 		bidengine.WithQueueSize(cfg.BidQueueSize),
 		bidengine.WithMaxRetries(cfg.BidMaxRetries),
 		bidengine.WithRetryBackoff(cfg.BidRetryBackoff),
+		bidengine.WithBidGrace(cfg.BidEndGrace),
+		bidengine.WithClockSkew(cfg.BidClockSkew),
+		bidengine.WithBidTimeout(cfg.BidProcessTimeout),
+		bidengine.WithMaxExtensionTotal(cfg.BidMaxExtensionTotal),
+		bidengine.WithMaxBidMultiple(cfg.BidMaxMultiple),
+		bidengine.WithDurableQueue(cfg.BidDurableQueue),
 		bidengine.WithSyncMode(cfg.SyncBidMode),
+		bidengine.WithResultStore(resultStore),
 	)
 	engine.Start()
 	defer engine.Stop()
 
+	// Charge buyers for new orders in the background, retrying unpaid ones
+	var paymentGateway payments.Gateway
+	if cfg.AuthorizeNetLoginID != "" && cfg.AuthorizeNetTransactionKey != "" {
+		paymentGateway = payments.NewAuthorizeNet(cfg.AuthorizeNetEndpoint, cfg.AuthorizeNetLoginID, cfg.AuthorizeNetTransactionKey)
+	}
+	closerOpts := []closer.Option{
+		closer.WithInterval(cfg.AuctionCloseInterval),
+		closer.WithConcurrency(cfg.AuctionCloseConcurrency),
+		closer.WithGrace(max(cfg.BidEndGrace, cfg.BidClockSkew)),
+		closer.WithBroadcaster(broker),
+		closer.WithPublisher(broker),
+		closer.WithNotifier(webhooks),
+	}
+	if paymentGateway != nil {
+		capturer := payments.NewCapturer(db, logger, paymentGateway,
+			payments.WithInterval(cfg.PaymentCaptureInterval),
+			payments.WithTimeout(cfg.PaymentCaptureTimeout),
+			payments.WithMaxAttempts(cfg.PaymentCaptureMaxAttempts),
+		)
+		capturer.Start()
+		defer capturer.Stop()
+		closerOpts = append(closerOpts, closer.WithPayments(capturer))
+		auctionOpts = append(auctionOpts, handler.WithPaymentQueue(capturer))
+	}
+
+	// Close auctions once they run out of time
+	if cfg.AuctionCloseInterval > 0 {
+		auctionCloser := closer.New(db, logger, closerOpts...)
+		auctionCloser.Start()
+		defer auctionCloser.Stop()
+	}
+
+	// Warn about, then archive, drafts sellers have abandoned
+	if cfg.DraftSweepInterval > 0 {
+		draftSweeper := drafts.New(db, logger,
+			drafts.WithInterval(cfg.DraftSweepInterval),
+			drafts.WithStaleAfter(cfg.DraftStaleAfter),
+			drafts.WithArchiveAfter(cfg.DraftArchiveAfter),
+			drafts.WithPublisher(broker),
+		)
+		draftSweeper.Start()
+		defer draftSweeper.Stop()
+	}
+
 	// Initialize handlers
 	healthHandler := handler.NewHealthHandler(db)
-	vehicleHandler := handler.NewVehicleHandler(db, logger)
-	auctionHandler := handler.NewAuctionHandler(db, logger)
-	bidHandler := handler.NewBidHandler(engine, logger)
-	sseHandler := handler.NewSSEHandler(broker, logger, cfg)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, cfg)
+	auctionHandler := handler.NewAuctionHandler(db, logger, cfg, broker, webhooks, auctionOpts...)
+	bidHandler := handler.NewBidHandler(engine, logger,
+		handler.WithSyncResponse(cfg.SyncBidResponse),
+		handler.WithWaitTimeout(cfg.BidWaitTimeout),
+		handler.WithMaxAmount(decimal.NewFromFloat(cfg.BidMaxAmount)),
+	)
+	sseHandler := handler.NewSSEHandler(db, broker, logger, cfg, handler.WithTickCache(tickCache))
 	debugHandler := handler.NewDebugHandler(engine, broker, db, logger)
 	authHandler := handler.NewAuthHandler(db, logger)
 	imageHandler := handler.NewImageHandler(db, logger, cfg, nil) // S3 client nil for now
-	watchlistHandler := handler.NewWatchlistHandler(db, logger)
+	watchlistHandler := handler.NewWatchlistHandler(db, logger, cfg)
 	notificationHandler := handler.NewNotificationHandler(db, logger)
 	vinHandler := handler.NewVINHandler(logger, nil) // VIN decoder nil for now
+	moderationHandler := handler.NewModerationHandler(db, logger)
+	paymentHandler := handler.NewPaymentHandler(db, logger, paymentGateway)
+	webhookHandler := handler.NewWebhookHandler(db, logger, handler.WithInsecureWebhookURLs(cfg.WebhookInsecureURLs))
+	reviewHandler := handler.NewReviewHandler(db, logger)
 
 	// Initialize auth middleware
 	clerkAuth := middleware.NewClerkAuth(logger, cfg.ClerkJWKSURL, cfg.ClerkSecretKey, db)
 
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.Error("invalid config", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	// Setup router
 	r := chi.NewRouter()
 
 	// Global middleware
 	r.Use(chimw.Recoverer)
 	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP(trustedProxies))
+	r.Use(middleware.SecurityHeaders(cfg.HSTSHeaderMaxAge()))
 	r.Use(middleware.Tracing)
 	r.Use(middleware.Logging(logger))
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORSAllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"X-Request-ID"},
-		AllowCredentials: true,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", "If-Match"},
+		ExposedHeaders:   []string{"X-Request-ID", "ETag"},
+		AllowCredentials: cfg.CORSAllowCredentials,
 		MaxAge:           300,
 	}))
+	if cfg.CompressMinSize > 0 {
+		r.Use(middleware.Compress(cfg.CompressMinSize))
+	}
 
 	// Health endpoints (no auth)
 	r.Get("/health", healthHandler.Health)
 	r.Get("/ready", healthHandler.Ready)
 	r.Get("/live", healthHandler.Live)
+	r.Get("/version", healthHandler.Version)
 
-	// Metrics endpoint
-	r.Handle(cfg.MetricsPath, promhttp.Handler())
+	// Metrics endpoint, behind METRICS_AUTH_TOKEN when set
+	r.With(middleware.RequireToken(cfg.MetricsAuthToken, logger)).Handle(cfg.MetricsPath, promhttp.Handler())
 
 	// API routes
 	r.Route("/api", func(r chi.Router) {
 		// Public endpoints
 		r.Get("/vehicles", vehicleHandler.ListVehicles)
+		r.Get("/vehicles/options", vehicleHandler.GetVehicleOptions)
 		r.Get("/vehicles/{id}", vehicleHandler.GetVehicle)
 		r.Get("/vehicles/{id}/images", vehicleHandler.GetVehicleImages)
 		r.Get("/auctions", auctionHandler.ListAuctions)
-		r.Get("/auctions/{id}", auctionHandler.GetAuction)
-		r.Get("/auctions/{id}/bids", auctionHandler.GetBidHistory)
+		r.Get("/auctions/featured", auctionHandler.ListFeaturedAuctions)
+		r.With(clerkAuth.OptionalAuth).Get("/auctions/{id}", auctionHandler.GetAuction)
+		r.With(clerkAuth.OptionalAuth).Get("/auctions/{id}/bids", auctionHandler.GetBidHistory)
+		r.With(clerkAuth.OptionalAuth).Get("/auctions/{id}/rules", auctionHandler.GetAuctionRules)
+		r.Get("/sellers/{id}/reviews", reviewHandler.GetSellerReviews)
 
 		// SSE endpoint (optional auth)
+		r.With(clerkAuth.OptionalAuth).Get("/auctions/stream", sseHandler.StreamGlobal)
 		r.With(clerkAuth.OptionalAuth).Get("/auctions/{id}/stream", sseHandler.StreamAuction)
 
-		// Auth - Clerk sync (no auth required - creates user)
-		r.Post("/auth/clerk-sync", authHandler.ClerkSync)
+		// Auth - Clerk sync (session token required, local user may not exist yet)
+		r.With(clerkAuth.RequireSession).Post("/auth/clerk-sync", authHandler.ClerkSync)
 
 		// Protected endpoints
 		r.Group(func(r chi.Router) {
@@ -167,13 +310,26 @@ func main() {
 
 			// Auth / User
 			r.Get("/auth/me", authHandler.Me)
+			r.Get("/auth/bid-eligibility", authHandler.BidEligibility)
 			r.Put("/auth/me", authHandler.UpdateProfile)
+			// Without gateway credentials there's nothing to show
+			if paymentGateway != nil {
+				r.Get("/me/payment", paymentHandler.GetPaymentStatus)
+			}
+			r.Post("/orders/{id}/review", reviewHandler.CreateReview)
+			r.Get("/me/webhooks", webhookHandler.ListWebhooks)
+			r.Post("/me/webhooks", webhookHandler.CreateWebhook)
+			r.Delete("/me/webhooks/{id}", webhookHandler.DeleteWebhook)
 
 			// Vehicles
 			r.Post("/vehicles", vehicleHandler.CreateVehicle)
-			r.Put("/vehicles/{id}", vehicleHandler.UpdateVehicle)
+			r.Put("/vehicles/{id}", vehicleHandler.ReplaceVehicle)
+			r.Patch("/vehicles/{id}", vehicleHandler.UpdateVehicle)
 			r.Delete("/vehicles/{id}", vehicleHandler.DeleteVehicle)
 			r.Post("/vehicles/{id}/submit", vehicleHandler.SubmitVehicle)
+			r.Post("/vehicles/{id}/restore", vehicleHandler.RestoreVehicle)
+			r.Post("/vehicles/{id}/transfer", vehicleHandler.TransferVehicle)
+			r.Get("/vehicles/{id}/pricing-insights", vehicleHandler.GetPricingInsights)
 
 			// Vehicle Images
 			r.Post("/vehicles/{id}/upload-url", imageHandler.GetUploadURL)
@@ -185,10 +341,25 @@ func main() {
 
 			// Auctions
 			r.Post("/auctions", auctionHandler.CreateAuction)
+			r.Put("/auctions/{id}", auctionHandler.UpdateDraftAuction)
+			r.Post("/auctions/{id}/publish", auctionHandler.PublishAuction)
+			r.Post("/auctions/{id}/cancel", auctionHandler.CancelAuction)
+			r.Post("/auctions/{id}/relist", auctionHandler.RelistAuction)
+			r.Post("/auctions/{id}/buy-now", auctionHandler.BuyNow)
+			r.Post("/auctions/{id}/invites", auctionHandler.InviteBidder)
+			r.Delete("/auctions/{id}/invites/{userId}", auctionHandler.RevokeInvite)
+			r.With(middleware.RequireRole(db, logger, "admin")).Get("/auctions/{id}/events", auctionHandler.GetAuctionEvents)
+			r.Get("/seller/auctions/{id}/analytics", auctionHandler.GetAuctionAnalytics)
 
 			// Bids (support both /bid and /bids for backwards compatibility)
-			r.Post("/auctions/{id}/bid", bidHandler.PlaceBid)
-			r.Post("/auctions/{id}/bids", bidHandler.PlaceBid)
+			bidRoutes := r.With()
+			if cfg.DebugEndpointsEnabled && cfg.DebugBodyLogLimit > 0 {
+				bidRoutes = r.With(middleware.LogBodies(logger, cfg.DebugBodyLogLimit))
+			}
+			bidRoutes.Post("/auctions/{id}/bid", bidHandler.PlaceBid)
+			bidRoutes.Post("/auctions/{id}/bids", bidHandler.PlaceBid)
+			r.Post("/auctions/{id}/proxy", bidHandler.PlaceProxyBid)
+			r.Post("/auctions/{id}/bids/{bidId}/retract", auctionHandler.RetractBid)
 			r.Get("/bids/{ticketId}/status", bidHandler.GetBidStatus)
 
 			// Watchlist
@@ -196,13 +367,28 @@ func main() {
 			r.Post("/auctions/{id}/watch", watchlistHandler.AddToWatchlist)
 			r.Delete("/auctions/{id}/watch", watchlistHandler.RemoveFromWatchlist)
 			r.Get("/auctions/{id}/watching", watchlistHandler.IsWatching)
+			r.Get("/recommendations", watchlistHandler.GetRecommendations)
 
 			// Notifications
 			r.Get("/notifications", notificationHandler.GetNotifications)
+			r.Get("/notifications/stream", sseHandler.StreamNotifications)
 			r.Get("/notifications/unread-count", notificationHandler.GetUnreadCount)
 			r.Post("/notifications/{id}/read", notificationHandler.MarkRead)
+			r.Post("/notifications/read", notificationHandler.MarkReadBulk)
 			r.Post("/notifications/read-all", notificationHandler.MarkAllRead)
 			r.Delete("/notifications/{id}", notificationHandler.DeleteNotification)
+
+			// Admin
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(middleware.RequireRole(db, logger, "admin"))
+
+				r.Get("/moderation", moderationHandler.ListQueue)
+				r.Post("/moderation/vehicles/{id}", moderationHandler.ReviewVehicle)
+				r.Post("/auctions/{id}/close", auctionHandler.ForceCloseAuction)
+				r.Post("/auctions/{id}/extend", auctionHandler.ExtendAuction)
+				r.Post("/auctions/{id}/featured", auctionHandler.SetFeatured)
+				r.Get("/retractions", auctionHandler.ListRetractions)
+			})
 		})
 	})
 
@@ -210,6 +396,7 @@ func main() {
 	if cfg.DebugEndpointsEnabled {
 		r.Route("/debug", func(r chi.Router) {
 			r.Get("/bidengine", debugHandler.BidEngineStats)
+			r.Get("/slowbids", debugHandler.SlowBids)
 			r.Get("/sse", debugHandler.SSEStats)
 			r.Get("/stats", debugHandler.AllStats)
 			r.Post("/seed", debugHandler.Seed)
@@ -231,6 +418,8 @@ func main() {
 		logger.Info("server_starting",
 			slog.Int("port", cfg.Port),
 			slog.String("environment", cfg.Environment),
+			slog.String("version", version.Version),
+			slog.String("commit", version.Commit),
 		)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("server_error", slog.String("error", err.Error()))
@@ -245,6 +434,10 @@ func main() {
 
 	logger.Info("server_shutting_down")
 
+	// Tell SSE clients to reconnect elsewhere first; open streams would
+	// otherwise hold Shutdown until its timeout
+	broker.Stop()
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
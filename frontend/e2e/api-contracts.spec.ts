@@ -21,11 +21,12 @@ test.describe('Vehicle API Contracts', () => {
     const data = await response.json();
     
     // Validate response shape
-    expect(data).toHaveProperty('vehicles');
+    expect(data).toHaveProperty('items');
     expect(data).toHaveProperty('total');
     expect(data).toHaveProperty('limit');
     expect(data).toHaveProperty('offset');
-    expect(Array.isArray(data.vehicles)).toBeTruthy();
+    expect(data).toHaveProperty('has_more');
+    expect(Array.isArray(data.items)).toBeTruthy();
   });
 
   test('GET /api/vehicles/:id returns expected shape', async ({ request }) => {
@@ -210,9 +211,9 @@ test.describe('Auction API Contracts', () => {
     expect(response.ok()).toBeTruthy();
     const data = await response.json();
     
-    expect(data).toHaveProperty('auctions');
+    expect(data).toHaveProperty('items');
     expect(data).toHaveProperty('total');
-    expect(Array.isArray(data.auctions)).toBeTruthy();
+    expect(Array.isArray(data.items)).toBeTruthy();
   });
 
   test('POST /api/auctions/:id/bid returns expected shape', async ({ request }) => {
@@ -47,8 +47,8 @@ test.describe('Watchlist API', () => {
     
     expect(response.status()).toBe(200);
     const data = await response.json();
-    // API returns watchlist array (may be empty)
-    expect(data).toHaveProperty('watchlist');
+    // API returns the watchlist page (items may be empty)
+    expect(data).toHaveProperty('items');
   });
 });
 
@@ -28,7 +28,7 @@ export function useAuctions(filters?: AuctionFilters) {
 
   // Client-side filtering for instant response
   const auctions = useMemo(() => {
-    const allAuctions: Auction[] = query.data?.auctions || [];
+    const allAuctions: Auction[] = query.data?.items || [];
     return allAuctions.filter((a) => {
       if (filters?.make && a.vehicle?.make) {
         if (!a.vehicle.make.toLowerCase().includes(filters.make.toLowerCase())) {
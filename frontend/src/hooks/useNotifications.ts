@@ -16,7 +16,7 @@ export interface Notification {
 }
 
 interface NotificationsResponse {
-  notifications: Notification[];
+  items: Notification[];
   total: number;
   unread_count: number;
 }
@@ -35,7 +35,7 @@ export function useNotifications() {
   });
 
   return {
-    notifications: query.data?.notifications || [],
+    notifications: query.data?.items || [],
     total: query.data?.total || 0,
     unreadCount: query.data?.unread_count || 0,
     isLoading: query.isLoading,
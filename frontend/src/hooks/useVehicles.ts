@@ -15,7 +15,7 @@ export function useVehicles(filters: VehicleFilters) {
 
   // Client-side filtering for instant response
   const vehicles = useMemo(() => {
-    const allVehicles: Vehicle[] = query.data?.data?.vehicles || [];
+    const allVehicles: Vehicle[] = query.data?.data?.items || [];
     return allVehicles.filter((v) => {
       if (filters.make && !v.make.toLowerCase().includes(filters.make.toLowerCase())) {
         return false;
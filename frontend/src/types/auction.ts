@@ -5,7 +5,8 @@
 export interface Auction {
   id: number;
   vehicle_id: number;
-  status: 'scheduled' | 'active' | 'ended' | 'cancelled';
+  status: 'draft' | 'scheduled' | 'active' | 'ended' | 'cancelled';
+  visibility?: 'public' | 'private';
   starts_at: string;
   ends_at: string;
   current_bid: number;
@@ -14,6 +15,7 @@ export interface Auction {
   version: number;
   winner_id?: number;
   winning_bid?: number;
+  is_final?: boolean;
   created_at: string;
   updated_at: string;
   // Joined vehicle data
@@ -67,10 +69,12 @@ export interface AuctionFilters {
 }
 
 export interface AuctionListResponse {
-  auctions: Auction[];
+  items: Auction[];
   total: number;
   limit: number;
   offset: number;
+  has_more: boolean;
+  server_time: string;
 }
 
 export interface BidListResponse {
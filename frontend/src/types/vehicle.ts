@@ -62,8 +62,9 @@ export interface VehicleFilters {
 }
 
 export interface VehicleListResponse {
-  vehicles: Vehicle[];
+  items: Vehicle[];
   total: number;
-  pages: number;
-  current_page: number;
+  limit: number;
+  offset: number;
+  has_more: boolean;
 }
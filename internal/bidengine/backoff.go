@@ -0,0 +1,34 @@
+package bidengine
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// retryBackoff is the wait before OCC retry attempt+1: full jitter, a uniform
+// draw from [0, base*2^attempt). Spreading the waits keeps bids that lost the
+// same race from colliding again on the next attempt. rnd returns values in
+// [0, 1); nil uses the global source.
+func retryBackoff(base time.Duration, attempt int, rnd func() float64) time.Duration {
+	ceiling := base * time.Duration(1<<attempt)
+	if ceiling <= 0 {
+		return 0
+	}
+	if rnd == nil {
+		rnd = rand.Float64
+	}
+	return time.Duration(rnd() * float64(ceiling))
+}
+
+// seededJitter returns a concurrency-safe [0, 1) source with a fixed seed, so
+// tests can reproduce a run's backoff sequence
+func seededJitter(seed uint64) func() float64 {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(seed, seed))
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return rng.Float64()
+	}
+}
@@ -0,0 +1,46 @@
+package bidengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBackoff_WithinBounds(t *testing.T) {
+	base := 10 * time.Millisecond
+	jitter := seededJitter(42)
+
+	for attempt := 0; attempt < 5; attempt++ {
+		ceiling := base * time.Duration(1<<attempt)
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 100; i++ {
+			d := retryBackoff(base, attempt, jitter)
+			assert.GreaterOrEqual(t, d, time.Duration(0))
+			assert.Less(t, d, ceiling, "attempt %d", attempt)
+			seen[d] = true
+		}
+		// Jitter spreads the waits instead of every retry sleeping the ceiling
+		assert.Greater(t, len(seen), 50, "attempt %d", attempt)
+	}
+
+	// The extremes of the jitter source map to the ends of the range
+	assert.Equal(t, time.Duration(0), retryBackoff(base, 2, func() float64 { return 0 }))
+	assert.Equal(t, 20*time.Millisecond, retryBackoff(base, 2, func() float64 { return 0.5 }))
+	assert.Equal(t, time.Duration(0), retryBackoff(0, 3, nil))
+}
+
+func TestRetryBackoff_SeedIsDeterministic(t *testing.T) {
+	a, b := seededJitter(7), seededJitter(7)
+	other := seededJitter(8)
+
+	differs := false
+	for attempt := 0; attempt < 4; attempt++ {
+		da := retryBackoff(time.Millisecond, attempt, a)
+		assert.Equal(t, da, retryBackoff(time.Millisecond, attempt, b))
+		if da != retryBackoff(time.Millisecond, attempt, other) {
+			differs = true
+		}
+	}
+	assert.True(t, differs, "different seeds should give different schedules")
+}
@@ -3,6 +3,7 @@ package bidengine
 import (
 	"context"
 	"log/slog"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/ayubfarah/vehicle-auc/internal/domain"
 	"github.com/ayubfarah/vehicle-auc/internal/metrics"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
 )
 
 // Engine processes bids using goroutine workers with OCC
@@ -17,9 +19,10 @@ type Engine struct {
 	db            *pgxpool.Pool
 	logger        *slog.Logger
 	broadcaster   Broadcaster
+	notifier      Notifier
 	
 	// Incoming bid queue
-	queue         chan domain.BidRequest
+	queue         chan submission
 	queueSize     int
 	
 	// Worker management
@@ -27,10 +30,22 @@ type Engine struct {
 	workersMu     sync.RWMutex
 	maxRetries    int
 	retryBackoff  time.Duration
+	bidGrace      time.Duration
+	clockSkew     time.Duration
+	bidTimeout    time.Duration
+	maxExtTotal   time.Duration
+	maxBidMult    decimal.Decimal
+	now           func() time.Time
+	jitter        func() float64
 	
 	// Result delivery
 	results       map[string]chan domain.BidResult
 	resultsMu     sync.RWMutex
+	resultStore   ResultStore // Shared with other instances; nil keeps results local
+	storeQueue    chan domain.BidResult // Results waiting for storeWriter
+	storeDone     chan struct{}
+	storeWG       sync.WaitGroup
+	subs          subscribers
 	
 	// Stats
 	totalProcessed atomic.Int64
@@ -43,6 +58,16 @@ type Engine struct {
 	
 	// Testing mode
 	syncMode      bool
+	
+	// Stage submitted bids in bid_queue so a restart replays them
+	durable       bool
+}
+
+// submission is a queued bid together with the context it was submitted
+// under, so cancelling the submitter's context aborts the bid
+type submission struct {
+	ctx context.Context
+	req domain.BidRequest
 }
 
 // Broadcaster interface for SSE integration
@@ -50,6 +75,17 @@ type Broadcaster interface {
 	Broadcast(event domain.BidEvent)
 }
 
+// Notifier receives bid outcomes scoped to a single user (e.g. their webhooks)
+type Notifier interface {
+	NotifyUser(userID int64, event string, data any)
+}
+
+// EventNotifier is implemented by notifiers that also deliver system-wide
+// auction lifecycle events (auction.ended, order.created)
+type EventNotifier interface {
+	NotifyEvent(event string, data any)
+}
+
 // EngineOption configures the engine
 type EngineOption func(*Engine)
 
@@ -74,13 +110,89 @@ func WithMaxRetries(retries int) EngineOption {
 	}
 }
 
-// WithRetryBackoff sets the OCC retry backoff duration
+// WithRetryBackoff sets the base OCC retry backoff. Retry n waits a random
+// time below base*2^n.
 func WithRetryBackoff(d time.Duration) EngineOption {
 	return func(e *Engine) {
 		e.retryBackoff = d
 	}
 }
 
+// WithRetryJitterSeed makes OCC retry backoff jitter deterministic, for tests
+// that need a reproducible retry schedule. By default it is randomly seeded.
+func WithRetryJitterSeed(seed uint64) EngineOption {
+	return func(e *Engine) {
+		e.jitter = seededJitter(seed)
+	}
+}
+
+// WithBidGrace sets how far before the server received a bid a client-supplied
+// submit timestamp may be honored when deciding whether it beat ends_at
+func WithBidGrace(d time.Duration) EngineOption {
+	return func(e *Engine) {
+		e.bidGrace = d
+	}
+}
+
+// WithClockSkew keeps taking bids this long past ends_at, so a bid the bidder
+// saw land just in time isn't lost to drift between our clock and theirs or the
+// database's. It applies to the server's receipt time only; a bid judged by
+// its client submit time gets the bid grace instead, never both. The closer's
+// grace should be at least the larger of the two.
+func WithClockSkew(d time.Duration) EngineOption {
+	return func(e *Engine) {
+		e.clockSkew = d
+	}
+}
+
+// WithBidTimeout bounds how long a single bid may spend in the processor,
+// OCC retries included. A bid that runs out of time is aborted with an error
+// result. Zero disables the deadline.
+func WithBidTimeout(d time.Duration) EngineOption {
+	return func(e *Engine) {
+		e.bidTimeout = d
+	}
+}
+
+// WithMaxExtensionTotal caps the wall-clock time anti-snipe and reserve
+// extensions may add to one auction, on top of the max_extensions count. The
+// extension that reaches the cap is shortened to fit. Zero disables the cap.
+func WithMaxExtensionTotal(d time.Duration) EngineOption {
+	return func(e *Engine) {
+		e.maxExtTotal = d
+	}
+}
+
+// WithMaxBidMultiple caps an auto-bid MaxBid at this multiple of the current
+// bid (or starting price before the first bid). Zero disables the cap.
+func WithMaxBidMultiple(multiple float64) EngineOption {
+	return func(e *Engine) {
+		e.maxBidMult = decimal.NewFromFloat(multiple)
+	}
+}
+
+// WithClock overrides the time source used for deadline and snipe checks
+func WithClock(now func() time.Time) EngineOption {
+	return func(e *Engine) {
+		e.now = now
+	}
+}
+
+// WithNotifier sends accepted/outbid outcomes to the affected bidders
+func WithNotifier(n Notifier) EngineOption {
+	return func(e *Engine) {
+		e.notifier = n
+	}
+}
+
+// WithDurableQueue persists each submitted bid to bid_queue until it has been
+// processed, and replays leftover rows on Start. Ignored in sync mode.
+func WithDurableQueue(durable bool) EngineOption {
+	return func(e *Engine) {
+		e.durable = durable
+	}
+}
+
 // NewEngine creates a new bid processing engine
 func NewEngine(db *pgxpool.Pool, logger *slog.Logger, broadcaster Broadcaster, opts ...EngineOption) *Engine {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -92,6 +204,7 @@ func NewEngine(db *pgxpool.Pool, logger *slog.Logger, broadcaster Broadcaster, o
 		queueSize:    10000,
 		maxRetries:   3,
 		retryBackoff: 10 * time.Millisecond,
+		now:          time.Now,
 		workers:      make(map[int64]*Worker),
 		results:      make(map[string]chan domain.BidResult),
 		ctx:          ctx,
@@ -102,13 +215,22 @@ func NewEngine(db *pgxpool.Pool, logger *slog.Logger, broadcaster Broadcaster, o
 		opt(e)
 	}
 	
-	e.queue = make(chan domain.BidRequest, e.queueSize)
+	e.queue = make(chan submission, e.queueSize)
+	if e.resultStore != nil {
+		e.storeQueue = make(chan domain.BidResult, resultStoreQueueSize)
+		e.storeDone = make(chan struct{})
+	}
 	
 	return e
 }
 
 // Start begins the dispatcher goroutine
 func (e *Engine) Start() {
+	if e.resultStore != nil {
+		e.storeWG.Add(1)
+		go e.storeWriter()
+	}
+	
 	if e.syncMode {
 		e.logger.Info("bid_engine_started", slog.Bool("sync_mode", true))
 		return
@@ -117,9 +239,14 @@ func (e *Engine) Start() {
 	e.wg.Add(1)
 	go e.dispatcher()
 	
+	if e.durable {
+		e.replayStaged()
+	}
+	
 	e.logger.Info("bid_engine_started",
 		slog.Int("queue_size", e.queueSize),
 		slog.Int("max_retries", e.maxRetries),
+		slog.Bool("durable_queue", e.durable),
 	)
 }
 
@@ -138,24 +265,55 @@ func (e *Engine) Stop() {
 	}
 	e.workersMu.Unlock()
 	
+	// Workers are done, so every result is queued; flush them to the store
+	if e.resultStore != nil {
+		close(e.storeDone)
+		e.storeWG.Wait()
+	}
+	
+	e.closeSubscribers()
+	
 	e.logger.Info("bid_engine_stopped",
 		slog.Int64("total_processed", e.totalProcessed.Load()),
 	)
 }
 
+// SyncMode reports whether bids are processed inline by Submit
+func (e *Engine) SyncMode() bool {
+	return e.syncMode
+}
+
 // Submit queues a bid for processing
 // Returns immediately with a ticket ID
 func (e *Engine) Submit(req domain.BidRequest) error {
+	return e.SubmitContext(context.Background(), req)
+}
+
+// SubmitContext is Submit with a context that is carried into processing:
+// cancelling it aborts the bid wherever it is, queued or mid-transaction.
+// Callers that hand back a ticket before the bid runs should pass a context
+// that outlives their request.
+func (e *Engine) SubmitContext(ctx context.Context, req domain.BidRequest) error {
 	// In sync mode, process immediately
 	if e.syncMode {
-		result := e.processBidSync(req)
+		result := e.processBidSync(ctx, req)
 		e.deliverResult(req.TicketID, result)
 		return nil
 	}
 	
+	if e.durable {
+		if err := e.stageBid(req); err != nil {
+			e.logger.Error("bid_stage_failed",
+				slog.String("ticket_id", req.TicketID),
+				slog.String("error", err.Error()),
+			)
+			return ErrStageFailed
+		}
+	}
+	
 	// Non-blocking send to queue
 	select {
-	case e.queue <- req:
+	case e.queue <- submission{ctx: ctx, req: req}:
 		metrics.BidEngineQueueDepth.Set(float64(len(e.queue)))
 		e.logger.Debug("bid_queued",
 			slog.String("ticket_id", req.TicketID),
@@ -163,11 +321,94 @@ func (e *Engine) Submit(req domain.BidRequest) error {
 		)
 		return nil
 	default:
+		// The caller is told to retry, so don't replay this one later
+		if e.durable {
+			e.unstageBid(req.TicketID)
+		}
 		return ErrQueueFull
 	}
 }
 
-// GetResult waits for a bid result with timeout
+// stageBid records a bid in bid_queue before it is handed to a worker
+func (e *Engine) stageBid(req domain.BidRequest) error {
+	ctx, cancel := context.WithTimeout(e.ctx, 2*time.Second)
+	defer cancel()
+	
+	var clientSubmittedAt *time.Time
+	if !req.ClientSubmittedAt.IsZero() {
+		clientSubmittedAt = &req.ClientSubmittedAt
+	}
+	_, err := e.db.Exec(ctx, `
+		INSERT INTO bid_queue (ticket_id, auction_id, user_id, amount, max_bid, client_submitted_at, created_at, proxy_only)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, req.TicketID, req.AuctionID, req.UserID, req.Amount, decimalOrNil(req.MaxBid), clientSubmittedAt, req.CreatedAt, req.ProxyOnly)
+	return err
+}
+
+// unstageBid removes a bid from bid_queue once it has a result
+func (e *Engine) unstageBid(ticketID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	
+	if _, err := e.db.Exec(ctx, `DELETE FROM bid_queue WHERE ticket_id = $1`, ticketID); err != nil {
+		e.logger.Error("bid_unstage_failed",
+			slog.String("ticket_id", ticketID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// replayStaged re-queues bids left in bid_queue by a previous process, oldest
+// first. An accepted bid leaves bid_queue in the transaction that records it,
+// so only bids that never ran, or were turned away without changing anything,
+// are processed again.
+func (e *Engine) replayStaged() {
+	rows, err := e.db.Query(e.ctx, `
+		SELECT ticket_id, auction_id, user_id, amount, max_bid, client_submitted_at, created_at, proxy_only
+		FROM bid_queue
+		ORDER BY created_at, ticket_id
+	`)
+	if err != nil {
+		e.logger.Error("bid_replay_failed", slog.String("error", err.Error()))
+		return
+	}
+	
+	var staged []domain.BidRequest
+	for rows.Next() {
+		var req domain.BidRequest
+		var maxBid decimal.NullDecimal
+		var clientSubmittedAt *time.Time
+		if err := rows.Scan(&req.TicketID, &req.AuctionID, &req.UserID, &req.Amount, &maxBid, &clientSubmittedAt, &req.CreatedAt, &req.ProxyOnly); err != nil {
+			e.logger.Error("bid_replay_scan_failed", slog.String("error", err.Error()))
+			continue
+		}
+		if maxBid.Valid {
+			req.MaxBid = maxBid.Decimal
+		}
+		if clientSubmittedAt != nil {
+			req.ClientSubmittedAt = *clientSubmittedAt
+		}
+		staged = append(staged, req)
+	}
+	rows.Close()
+	
+	for _, req := range staged {
+		select {
+		case e.queue <- submission{ctx: context.Background(), req: req}:
+		case <-e.ctx.Done():
+			return
+		}
+	}
+	
+	if len(staged) > 0 {
+		e.logger.Info("bid_queue_replayed", slog.Int("bids", len(staged)))
+	}
+}
+
+// GetResult waits for a bid result with timeout. With a result store it also
+// polls the store, so it finds bids processed by other instances, and a
+// result stays readable there after the first read. Store lookups run in the
+// background, so a slow store never holds up a result delivered locally.
 func (e *Engine) GetResult(ticketID string, timeout time.Duration) (domain.BidResult, error) {
 	e.resultsMu.Lock()
 	ch, exists := e.results[ticketID]
@@ -176,14 +417,43 @@ func (e *Engine) GetResult(ticketID string, timeout time.Duration) (domain.BidRe
 		e.results[ticketID] = ch
 	}
 	e.resultsMu.Unlock()
+	defer e.cleanupResult(ticketID)
 	
-	select {
-	case result := <-ch:
-		e.cleanupResult(ticketID)
-		return result, nil
-	case <-time.After(timeout):
-		e.cleanupResult(ticketID)
-		return domain.BidResult{}, ErrTimeout
+	var (
+		poll    <-chan time.Time
+		stored  chan storeLookup
+		looking bool
+	)
+	if e.resultStore != nil {
+		// Buffered so a lookup finishing after we return doesn't leak
+		stored = make(chan storeLookup, 1)
+		go e.lookupStored(ticketID, stored)
+		looking = true
+		ticker := time.NewTicker(resultPollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case result := <-ch:
+			return result, nil
+		case lookup := <-stored:
+			if lookup.ok {
+				return lookup.result, nil
+			}
+			looking = false
+		case <-poll:
+			// One lookup at a time; a slow store just means fewer polls
+			if !looking {
+				go e.lookupStored(ticketID, stored)
+				looking = true
+			}
+		case <-deadline.C:
+			return domain.BidResult{}, ErrTimeout
+		}
 	}
 }
 
@@ -193,6 +463,8 @@ func (e *Engine) cleanupResult(ticketID string) {
 	e.resultsMu.Unlock()
 }
 
+// deliverResult hands a result to local waiters and subscribers, then queues
+// it for the store, so the worker never waits on the store
 func (e *Engine) deliverResult(ticketID string, result domain.BidResult) {
 	e.resultsMu.Lock()
 	ch, exists := e.results[ticketID]
@@ -207,6 +479,27 @@ func (e *Engine) deliverResult(ticketID string, result domain.BidResult) {
 	case ch <- result:
 	default:
 	}
+	
+	e.publishResult(result)
+	e.queueStore(ticketID, result)
+}
+
+// completeBid acknowledges a processed bid and hands its result to waiters
+func (e *Engine) completeBid(ticketID string, result domain.BidResult) {
+	if e.durable {
+		e.unstageBid(ticketID)
+	}
+	e.deliverResult(ticketID, result)
+}
+
+// abortBid handles a bid cut short by Stop. A durable bid stays staged so the
+// next process replays it and reports its real result; otherwise waiters
+// learn it was cancelled.
+func (e *Engine) abortBid(ticketID string, result domain.BidResult) {
+	if e.durable {
+		return
+	}
+	e.deliverResult(ticketID, result)
 }
 
 // dispatcher routes bids to per-auction workers
@@ -217,19 +510,21 @@ func (e *Engine) dispatcher() {
 		select {
 		case <-e.ctx.Done():
 			return
-		case req := <-e.queue:
+		case sub := <-e.queue:
 			metrics.BidEngineQueueDepth.Set(float64(len(e.queue)))
-			e.routeToWorker(req)
+			e.routeToWorker(sub)
 		}
 	}
 }
 
-func (e *Engine) routeToWorker(req domain.BidRequest) {
+func (e *Engine) routeToWorker(sub submission) {
+	req := sub.req
 	e.workersMu.Lock()
 	worker, exists := e.workers[req.AuctionID]
 	if !exists {
-		worker = NewWorker(req.AuctionID, e.db, e.logger, e.broadcaster, e.maxRetries, e.retryBackoff)
-		worker.OnResult = e.deliverResult
+		worker = NewWorker(req.AuctionID, e.newProcessor(), e.logger)
+		worker.OnResult = e.completeBid
+		worker.OnAborted = e.abortBid
 		worker.OnComplete = func() {
 			e.totalProcessed.Add(1)
 		}
@@ -242,19 +537,32 @@ func (e *Engine) routeToWorker(req domain.BidRequest) {
 	}
 	e.workersMu.Unlock()
 	
-	worker.Submit(req)
+	worker.SubmitContext(sub.ctx, req)
 }
 
-// processBidSync processes a bid synchronously (for testing)
-func (e *Engine) processBidSync(req domain.BidRequest) domain.BidResult {
-	processor := &BidProcessor{
+// newProcessor builds a BidProcessor carrying the engine's settings
+func (e *Engine) newProcessor() *BidProcessor {
+	return &BidProcessor{
 		db:           e.db,
 		logger:       e.logger,
 		broadcaster:  e.broadcaster,
+		notifier:     e.notifier,
 		maxRetries:   e.maxRetries,
 		retryBackoff: e.retryBackoff,
+		bidGrace:     e.bidGrace,
+		clockSkew:    e.clockSkew,
+		timeout:      e.bidTimeout,
+		maxExtTotal:  e.maxExtTotal,
+		maxBidMult:   e.maxBidMult,
+		durable:      e.durable,
+		now:          e.now,
+		jitter:       e.jitter,
 	}
-	return processor.Process(context.Background(), req)
+}
+
+// processBidSync processes a bid synchronously (for testing)
+func (e *Engine) processBidSync(ctx context.Context, req domain.BidRequest) domain.BidResult {
+	return e.newProcessor().Process(ctx, req)
 }
 
 // Stats returns engine statistics
@@ -276,6 +584,24 @@ func (e *Engine) Stats() EngineStats {
 	}
 }
 
+// SlowBids returns per-auction worker stats ordered by OCC retries, then by
+// worst recent processing time, so the most contended auctions come first
+func (e *Engine) SlowBids(limit int) []WorkerStats {
+	stats := e.Stats().Workers
+	
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Retries != stats[j].Retries {
+			return stats[i].Retries > stats[j].Retries
+		}
+		return stats[i].MaxDurationMs > stats[j].MaxDurationMs
+	})
+	
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats
+}
+
 // EngineStats holds engine statistics for debug endpoints
 type EngineStats struct {
 	QueueDepth     int           `json:"queue_depth"`
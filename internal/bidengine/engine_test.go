@@ -88,14 +88,14 @@ func TestEngine_QueueFull(t *testing.T) {
 	engine := &Engine{
 		logger:      logger,
 		broadcaster: broadcaster,
-		queue:       make(chan domain.BidRequest, 1), // Size 1
+		queue:       make(chan submission, 1), // Size 1
 		results:     make(map[string]chan domain.BidResult),
 		workers:     make(map[int64]*Worker),
 		syncMode:    false,
 	}
 
 	// Fill the queue
-	engine.queue <- domain.BidRequest{TicketID: "1"}
+	engine.queue <- submission{req: domain.BidRequest{TicketID: "1"}}
 
 	// Next submit should fail
 	err := engine.Submit(domain.BidRequest{TicketID: "2"})
@@ -224,3 +224,203 @@ func TestResultTimeout(t *testing.T) {
 	assert.Equal(t, ErrTimeout, err)
 }
 
+// memoryResultStore is an in-process ResultStore that engines in one test
+// share, standing in for Redis
+type memoryResultStore struct {
+	mu      sync.Mutex
+	results map[string]domain.BidResult
+}
+
+func (m *memoryResultStore) Put(ctx context.Context, result domain.BidResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.results == nil {
+		m.results = make(map[string]domain.BidResult)
+	}
+	m.results[result.TicketID] = result
+	return nil
+}
+
+func (m *memoryResultStore) Get(ctx context.Context, ticketID string) (domain.BidResult, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, ok := m.results[ticketID]
+	return result, ok, nil
+}
+
+func TestResultStore_AnyInstanceServesTicket(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	store := &memoryResultStore{}
+	processing := NewEngine(nil, logger, nil, WithSyncMode(true), WithResultStore(store))
+	polled := NewEngine(nil, logger, nil, WithSyncMode(true), WithResultStore(store))
+	processing.Start()
+	defer processing.Stop()
+	polled.Start()
+	defer polled.Stop()
+
+	// Already finished on another instance: found straight away, and again on
+	// a repeat poll
+	ticketID := uuid.New().String()
+	processing.deliverResult(ticketID, domain.BidResult{Status: "accepted", AuctionID: 1, Amount: decimal.NewFromInt(150)})
+	require.Eventually(t, func() bool {
+		_, ok, _ := store.Get(context.Background(), ticketID)
+		return ok
+	}, time.Second, time.Millisecond)
+	for i := 0; i < 2; i++ {
+		result, err := polled.GetResult(ticketID, 10*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, ticketID, result.TicketID)
+		assert.Equal(t, "accepted", result.Status)
+		assert.True(t, decimal.NewFromInt(150).Equal(result.Amount))
+	}
+
+	// Finishing elsewhere while this instance waits
+	ticketID = uuid.New().String()
+	go func() {
+		time.Sleep(2 * resultPollInterval)
+		processing.deliverResult(ticketID, domain.BidResult{Status: "rejected", Reason: "bid_too_low"})
+	}()
+	result, err := polled.GetResult(ticketID, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "bid_too_low", result.Reason)
+
+	// Unknown tickets still time out
+	_, err = polled.GetResult(uuid.New().String(), 2*resultPollInterval)
+	assert.Equal(t, ErrTimeout, err)
+
+	// Without a store, results stay on the instance that processed them
+	ticketID = uuid.New().String()
+	local := NewEngine(nil, logger, nil, WithSyncMode(true))
+	local.deliverResult(ticketID, domain.BidResult{Status: "accepted"})
+	_, err = polled.GetResult(ticketID, 2*resultPollInterval)
+	assert.Equal(t, ErrTimeout, err)
+}
+
+// slowResultStore blocks every call until release is closed
+type slowResultStore struct {
+	memoryResultStore
+	release chan struct{}
+}
+
+func (s *slowResultStore) Put(ctx context.Context, result domain.BidResult) error {
+	<-s.release
+	return s.memoryResultStore.Put(ctx, result)
+}
+
+func (s *slowResultStore) Get(ctx context.Context, ticketID string) (domain.BidResult, bool, error) {
+	<-s.release
+	return s.memoryResultStore.Get(ctx, ticketID)
+}
+
+func TestResultStore_SlowStoreDoesNotDelayDelivery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	store := &slowResultStore{release: make(chan struct{})}
+	engine := NewEngine(nil, logger, nil, WithSyncMode(true), WithResultStore(store))
+	engine.Start()
+
+	// The poll is waiting on a store lookup that won't return, yet the local
+	// result still reaches it, and delivering doesn't wait for the write
+	ticketID := uuid.New().String()
+	go func() {
+		time.Sleep(resultPollInterval)
+		engine.deliverResult(ticketID, domain.BidResult{Status: "accepted"})
+	}()
+	start := time.Now()
+	result, err := engine.GetResult(ticketID, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", result.Status)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// Stop flushes the queued write once the store recovers
+	close(store.release)
+	engine.Stop()
+	stored, ok, err := store.Get(context.Background(), ticketID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "accepted", stored.Status)
+}
+
+func TestEngine_Subscribe(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	engine := NewEngine(nil, logger, &mockBroadcaster{}, WithSyncMode(true))
+
+	first, unsubscribeFirst := engine.Subscribe(4)
+	second, unsubscribeSecond := engine.Subscribe(4)
+	defer unsubscribeSecond()
+
+	engine.deliverResult("t1", domain.BidResult{TicketID: "t1", Status: "accepted", AuctionID: 7})
+	engine.deliverResult("t2", domain.BidResult{TicketID: "t2", Status: "rejected", AuctionID: 7})
+
+	for _, ch := range []<-chan domain.BidResult{first, second} {
+		assert.Equal(t, "t1", (<-ch).TicketID)
+		assert.Equal(t, "t2", (<-ch).TicketID)
+	}
+
+	// The ticket's own waiter still gets its result
+	result, err := engine.GetResult("t1", 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", result.Status)
+
+	// Unsubscribing closes the channel and stops delivery to it only
+	unsubscribeFirst()
+	unsubscribeFirst()
+	engine.deliverResult("t3", domain.BidResult{TicketID: "t3", AuctionID: 7})
+	_, open := <-first
+	assert.False(t, open)
+	assert.Equal(t, "t3", (<-second).TicketID)
+}
+
+func TestEngine_Subscribe_SlowSubscriberDoesNotBlock(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	engine := NewEngine(nil, logger, &mockBroadcaster{}, WithSyncMode(true))
+
+	ch, unsubscribe := engine.Subscribe(1)
+	defer unsubscribe()
+
+	engine.deliverResult("t1", domain.BidResult{TicketID: "t1"})
+	engine.deliverResult("t2", domain.BidResult{TicketID: "t2"})
+
+	assert.Equal(t, "t1", (<-ch).TicketID)
+	select {
+	case r := <-ch:
+		t.Fatalf("expected t2 to be dropped, got %s", r.TicketID)
+	default:
+	}
+}
+
+func TestEngine_Stop_ClosesSubscriptions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	engine := NewEngine(nil, logger, &mockBroadcaster{}, WithSyncMode(true))
+
+	ch, unsubscribe := engine.Subscribe(1)
+	engine.Stop()
+
+	_, open := <-ch
+	assert.False(t, open)
+	unsubscribe() // safe after Stop
+}
+
+func TestEngine_SlowBids_OrdersByContention(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	engine := NewEngine(nil, logger, &mockBroadcaster{}, WithSyncMode(true))
+
+	quiet := NewWorker(1, nil, logger)
+	quiet.recordDuration(2 * time.Millisecond)
+
+	hot := NewWorker(2, nil, logger)
+	hot.retries.Add(5)
+	hot.recordDuration(10 * time.Millisecond)
+	hot.recordDuration(30 * time.Millisecond)
+
+	engine.workers[1] = quiet
+	engine.workers[2] = hot
+
+	slow := engine.SlowBids(10)
+	require.Len(t, slow, 2)
+	assert.Equal(t, int64(2), slow[0].AuctionID)
+	assert.Equal(t, int64(5), slow[0].Retries)
+	assert.InDelta(t, 20.0, slow[0].AvgDurationMs, 0.001)
+	assert.InDelta(t, 30.0, slow[0].MaxDurationMs, 0.001)
+
+	assert.Len(t, engine.SlowBids(1), 1)
+}
@@ -6,6 +6,9 @@ var (
 	// ErrQueueFull is returned when the bid queue is at capacity
 	ErrQueueFull = errors.New("bid queue is full")
 	
+	// ErrStageFailed is returned when a durable queue can't persist the bid
+	ErrStageFailed = errors.New("failed to stage bid")
+	
 	// ErrVersionConflict is returned when OCC detects a concurrent modification
 	ErrVersionConflict = errors.New("version conflict - concurrent modification")
 	
@@ -0,0 +1,33 @@
+package bidengine
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Auction event types recorded in the auction_events audit log
+const (
+	EventBidAccepted = "bid_accepted"
+	EventOutbid      = "outbid"
+	EventExtended    = "extended"
+	EventClosed      = "closed"
+	EventCancelled   = "cancelled"
+	EventRelisted    = "relisted"
+	EventPublished   = "published"
+	EventRetracted   = "bid_retracted"
+)
+
+// RecordAuctionEvent appends an entry to an auction's audit log. Call it
+// inside the transaction that makes the change so the log can never show a
+// state change that was rolled back.
+func RecordAuctionEvent(ctx context.Context, tx pgx.Tx, auctionID int64, eventType string, payload map[string]any) error {
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO auction_events (auction_id, type, payload)
+		VALUES ($1, $2, $3)
+	`, auctionID, eventType, payload)
+	return err
+}
@@ -2,6 +2,7 @@ package bidengine
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
@@ -14,13 +15,26 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// MinBidIncrement is the step a bid must clear the current bid by, unless the
+// auction sets its own min_increment. Proxy bids raise by exactly one step.
+var MinBidIncrement = decimal.New(1, -2)
+
 // BidProcessor handles the actual bid processing with OCC
 type BidProcessor struct {
 	db           *pgxpool.Pool
 	logger       *slog.Logger
 	broadcaster  Broadcaster
+	notifier     Notifier
 	maxRetries   int
 	retryBackoff time.Duration
+	bidGrace     time.Duration
+	clockSkew    time.Duration // Bids are still taken this long past ends_at
+	timeout      time.Duration // Per-bid deadline; 0 disables
+	maxExtTotal  time.Duration // Cap on time extensions add to an auction; 0 disables
+	maxBidMult   decimal.Decimal
+	durable      bool // Bids are staged in bid_queue; an accepted bid unstages itself
+	now          func() time.Time
+	jitter       func() float64 // Backoff jitter in [0, 1); nil uses the global source
 	onRetry      func()
 }
 
@@ -28,6 +42,12 @@ type BidProcessor struct {
 func (p *BidProcessor) Process(ctx context.Context, req domain.BidRequest) domain.BidResult {
 	start := time.Now()
 	
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	
 	// Start tracing span
 	ctx, span := tracing.StartSpan(ctx, "bid.process")
 	defer span.End()
@@ -50,6 +70,10 @@ func (p *BidProcessor) Process(ctx context.Context, req domain.BidRequest) domai
 	var retries int
 	
 	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		if ctx.Err() != nil {
+			result = abortedResult(ctx, req)
+			break
+		}
 		result = p.attemptBid(ctx, req, attempt)
 		
 		if result.Status != "retry" {
@@ -61,9 +85,12 @@ func (p *BidProcessor) Process(ctx context.Context, req domain.BidRequest) domai
 			p.onRetry()
 		}
 		
-		// Exponential backoff
-		backoff := p.retryBackoff * time.Duration(1<<attempt)
-		time.Sleep(backoff)
+		// Exponential backoff with full jitter
+		backoff := retryBackoff(p.retryBackoff, attempt, p.jitter)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
 		
 		p.logger.Debug("bid_occ_retry",
 			slog.String("ticket_id", req.TicketID),
@@ -80,6 +107,7 @@ func (p *BidProcessor) Process(ctx context.Context, req domain.BidRequest) domai
 	
 	result.Retries = retries
 	result.ProcessedAt = time.Now()
+	p.attachAuctionState(ctx, req, &result)
 	
 	// Log final result
 	p.logger.Info("bid_processing_completed",
@@ -98,9 +126,12 @@ func (p *BidProcessor) attemptBid(ctx context.Context, req domain.BidRequest, at
 	span.SetAttributes(attribute.Int("attempt", attempt))
 	
 	// 1. Fetch current auction state
-	auction, err := p.getAuctionState(ctx, req.AuctionID)
+	auction, err := p.getAuctionState(ctx, req.AuctionID, req.UserID)
 	if err != nil {
 		tracing.RecordError(ctx, err)
+		if ctx.Err() != nil {
+			return abortedResult(ctx, req)
+		}
 		return domain.BidResult{
 			TicketID:  req.TicketID,
 			AuctionID: req.AuctionID,
@@ -110,32 +141,40 @@ func (p *BidProcessor) attemptBid(ctx context.Context, req domain.BidRequest, at
 		}
 	}
 	
-	// 2. Validate auction is active
-	if auction.Status != "active" {
+	// 2. Validate the bidder may bid, the auction is open and the bid beat
+	// the deadline
+	if auction.Private && !auction.BidderInvited {
 		return domain.BidResult{
 			TicketID:  req.TicketID,
 			AuctionID: req.AuctionID,
 			Amount:    req.Amount,
 			Status:    "rejected",
-			Reason:    "auction_not_active",
+			Reason:    "not_invited",
 		}
 	}
-	
-	// 3. Validate bid amount
-	if req.Amount.LessThanOrEqual(auction.CurrentBid) {
+	if reason := p.closedReason(auction, req); reason != "" {
 		return domain.BidResult{
-			TicketID:        req.TicketID,
-			AuctionID:       req.AuctionID,
-			Amount:          req.Amount,
-			Status:          "rejected",
-			Reason:          "bid_too_low",
-			PreviousHighBid: auction.CurrentBid,
+			TicketID:  req.TicketID,
+			AuctionID: req.AuctionID,
+			Amount:    req.Amount,
+			Status:    "rejected",
+			Reason:    reason,
 		}
 	}
 	
-	// 4. Attempt OCC update
+	// 3. Validate bid amount. A proxy-only bid is priced here, from the state
+	// this attempt read, so an OCC retry re-prices it.
+	if req.ProxyOnly {
+		req.Amount = minimumNextBid(auction)
+	}
+	if reason := p.amountRejection(req, auction); reason != "" {
+		return amountRejected(req, auction, reason)
+	}
+	
+	// 4. Settle against the leader's proxy, then attempt OCC update
+	placed, defended := resolveProxy(req, auction)
 	previousBid := auction.CurrentBid
-	bidID, extended, err := p.updateAuctionOCC(ctx, req, auction)
+	bidID, ext, err := p.updateAuctionOCC(ctx, placed, auction)
 	
 	if err == ErrVersionConflict {
 		metrics.BidOCCConflictsTotal.Inc()
@@ -144,6 +183,9 @@ func (p *BidProcessor) attemptBid(ctx context.Context, req domain.BidRequest, at
 	
 	if err != nil {
 		tracing.RecordError(ctx, err)
+		if ctx.Err() != nil {
+			return abortedResult(ctx, req)
+		}
 		return domain.BidResult{
 			TicketID:  req.TicketID,
 			AuctionID: req.AuctionID,
@@ -154,22 +196,49 @@ func (p *BidProcessor) attemptBid(ctx context.Context, req domain.BidRequest, at
 	}
 	
 	// 5. Broadcast to SSE subscribers
+	extended := ext.snipe || ext.reserve
 	if p.broadcaster != nil {
+		now := p.clock()
 		event := domain.BidEvent{
 			Type:             "bid_accepted",
 			AuctionID:        req.AuctionID,
-			Amount:           req.Amount,
-			BidderID:         req.UserID,
+			Amount:           placed.Amount,
+			BidderID:         publicBidderID(placed.UserID, req.UserID, auction),
 			BidCount:         auction.BidCount + 1,
-			EndsAt:           auction.EndsAt,
+			EndsAt:           ext.endsAt,
 			ExtensionApplied: extended,
-			Timestamp:        time.Now(),
+			Version:          auction.Version + 1,
+			Timestamp:        now,
 		}
 		p.broadcaster.Broadcast(event)
 		metrics.SSEMessagesSent.WithLabelValues("bid_accepted").Inc()
 		
 		if extended {
-			metrics.AuctionExtensions.Inc()
+			p.broadcaster.Broadcast(domain.BidEvent{
+				Type:             "auction_extended",
+				AuctionID:        req.AuctionID,
+				EndsAt:           ext.endsAt,
+				ExtensionApplied: true,
+				Timestamp:        now,
+			})
+			metrics.SSEMessagesSent.WithLabelValues("auction_extended").Inc()
+		}
+	}
+	if extended {
+		metrics.AuctionExtensions.Inc()
+	}
+	p.notifyOutcome(placed, auction, bidID, ext.endsAt)
+	
+	if defended {
+		return domain.BidResult{
+			TicketID:        req.TicketID,
+			AuctionID:       req.AuctionID,
+			Amount:          req.Amount,
+			Status:          "rejected",
+			Reason:          "outbid_by_proxy",
+			PreviousHighBid: previousBid,
+			NewHighBid:      placed.Amount,
+			NextMinBid:      placed.Amount.Add(bidIncrement(auction)),
 		}
 	}
 	
@@ -177,37 +246,182 @@ func (p *BidProcessor) attemptBid(ctx context.Context, req domain.BidRequest, at
 		TicketID:        req.TicketID,
 		Status:          "accepted",
 		BidID:           bidID,
-		Amount:          req.Amount,
+		Amount:          placed.Amount,
 		PreviousHighBid: previousBid,
-		NewHighBid:      req.Amount,
+		NewHighBid:      placed.Amount,
 		AuctionID:       req.AuctionID,
 	}
 }
 
-func (p *BidProcessor) getAuctionState(ctx context.Context, auctionID int64) (*domain.AuctionState, error) {
+// publicBidderID is the bidder ID a broadcast may carry for a bid placed by
+// placedBy, or 0 when they hide their identity. A proxy defence is placed in
+// the leader's name, so their setting applies rather than the challenger's.
+func publicBidderID(placedBy, requestedBy int64, auction *domain.AuctionState) int64 {
+	hidden := auction.BidderHidden
+	if placedBy != requestedBy {
+		hidden = auction.LeaderHidden
+	}
+	if hidden {
+		return 0
+	}
+	return placedBy
+}
+
+// abortedResult reports a bid given up because its context ended: the
+// submitter went away (bid_cancelled) or the per-bid deadline hit
+// (bid_timeout). Any transaction in flight has been rolled back.
+func abortedResult(ctx context.Context, req domain.BidRequest) domain.BidResult {
+	reason := "bid_cancelled"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = "bid_timeout"
+	}
+	return domain.BidResult{
+		TicketID:  req.TicketID,
+		AuctionID: req.AuctionID,
+		Amount:    req.Amount,
+		Status:    "error",
+		Reason:    reason,
+	}
+}
+
+// amountRejection checks a bid's amount and max against the auction as read,
+// returning the rejection reason or "" when the amount is acceptable
+func (p *BidProcessor) amountRejection(req domain.BidRequest, auction *domain.AuctionState) string {
+	switch {
+	case req.ProxyOnly && req.MaxBid.LessThan(req.Amount):
+		return "max_bid_too_low"
+	case auction.BidCount == 0 && req.Amount.LessThan(auction.StartingPrice):
+		return "below_starting_price"
+	case req.Amount.LessThanOrEqual(auction.CurrentBid):
+		return "bid_too_low"
+	case auction.BidCount > 0 && req.Amount.LessThan(minimumNextBid(auction)):
+		return "below_min_increment"
+	}
+	if limit, ok := p.maxBidLimit(auction); ok && req.MaxBid.GreaterThan(limit) {
+		return "max_bid_too_high"
+	}
+	return ""
+}
+
+// amountRejected builds the result for a bid turned away on its amount. It
+// carries the high bid and the minimum next bid as read, so a client can offer
+// a retry at that price without polling the auction.
+func amountRejected(req domain.BidRequest, auction *domain.AuctionState, reason string) domain.BidResult {
+	amount := req.Amount
+	if reason == "max_bid_too_low" {
+		amount = req.MaxBid
+	}
+	return domain.BidResult{
+		TicketID:        req.TicketID,
+		AuctionID:       req.AuctionID,
+		Amount:          amount,
+		Status:          "rejected",
+		Reason:          reason,
+		PreviousHighBid: auction.CurrentBid,
+		NextMinBid:      minimumNextBid(auction),
+	}
+}
+
+// bidIncrement is the step bids on this auction must clear the current bid by
+func bidIncrement(auction *domain.AuctionState) decimal.Decimal {
+	if auction.MinIncrement.Valid {
+		return auction.MinIncrement.Decimal
+	}
+	return MinBidIncrement
+}
+
+// minimumNextBid is the lowest amount the engine accepts next: the starting
+// price before the first bid, then one increment over the current bid
+func minimumNextBid(auction *domain.AuctionState) decimal.Decimal {
+	if auction.BidCount == 0 {
+		return auction.StartingPrice
+	}
+	return auction.CurrentBid.Add(bidIncrement(auction))
+}
+
+// resolveProxy settles an incoming bid against the current leader's proxy
+// max and returns the bid to actually place. A leader whose max covers the
+// challenger's ceiling defends with an auto-bid one increment above it
+// (capped at their max, so ties go to the earlier proxy) and defended is
+// true. Otherwise the challenger takes the lead at the lowest price that
+// beats the leader's max, never less than what they offered.
+func resolveProxy(req domain.BidRequest, auction *domain.AuctionState) (domain.BidRequest, bool) {
+	leader := auction.CurrentBidUserID
+	if leader == nil || *leader == req.UserID || !auction.LeaderMaxBid.Valid {
+		return req, false
+	}
+	
+	leaderMax := auction.LeaderMaxBid.Decimal
+	step := bidIncrement(auction)
+	ceiling := decimal.Max(req.Amount, req.MaxBid)
+	if leaderMax.GreaterThanOrEqual(ceiling) {
+		return domain.BidRequest{
+			TicketID:  req.TicketID,
+			AuctionID: req.AuctionID,
+			UserID:    *leader,
+			Amount:    decimal.Min(leaderMax, ceiling.Add(step)),
+			MaxBid:    leaderMax,
+			TraceID:   req.TraceID,
+			CreatedAt: req.CreatedAt,
+			AutoBid:   true,
+		}, true
+	}
+	
+	placed := req
+	placed.Amount = decimal.Max(req.Amount, decimal.Min(ceiling, leaderMax.Add(step)))
+	return placed, false
+}
+
+// getAuctionState reads the auction as bidderID sees it, including whether
+// they're invited to it
+func (p *BidProcessor) getAuctionState(ctx context.Context, auctionID, bidderID int64) (*domain.AuctionState, error) {
 	ctx, span := tracing.StartSpan(ctx, "db.auction.read")
 	defer span.End()
 	
 	query := `
-		SELECT id, status::text, current_bid, current_bid_user_id, bid_count, version, 
-		       ends_at, extension_count, max_extensions, snipe_threshold_minutes, extension_minutes
-		FROM auctions WHERE id = $1
+		SELECT a.id, a.status::text, a.current_bid, a.current_bid_user_id, a.bid_count, a.version, 
+		       a.starts_at, a.ends_at, a.extension_count, a.max_extensions, a.snipe_threshold_minutes, a.extension_minutes,
+		       a.extended_seconds, v.starting_price, v.reserve_price, a.extend_on_reserve_met, a.reserve_extension_applied,
+		       a.extend_on_leader_change_only, a.min_increment,
+		       (SELECT b.max_bid FROM bids b
+		        WHERE b.auction_id = a.id AND b.user_id = a.current_bid_user_id AND b.status = 'accepted'
+		        ORDER BY b.id DESC LIMIT 1),
+		       a.visibility = 'private',
+		       EXISTS(SELECT 1 FROM auction_invites i WHERE i.auction_id = a.id AND i.user_id = $2),
+		       COALESCE((SELECT u.hide_bidder_identity FROM users u WHERE u.id = $2), false),
+		       COALESCE((SELECT u.hide_bidder_identity FROM users u WHERE u.id = a.current_bid_user_id), false)
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
 	`
 	
 	var auction domain.AuctionState
 	var status string
-	err := p.db.QueryRow(ctx, query, auctionID).Scan(
+	err := p.db.QueryRow(ctx, query, auctionID, bidderID).Scan(
 		&auction.ID,
 		&status,
 		&auction.CurrentBid,
 		&auction.CurrentBidUserID,
 		&auction.BidCount,
 		&auction.Version,
+		&auction.StartsAt,
 		&auction.EndsAt,
 		&auction.ExtensionCount,
 		&auction.MaxExtensions,
 		&auction.SnipeThresholdMins,
 		&auction.ExtensionMins,
+		&auction.ExtendedSeconds,
+		&auction.StartingPrice,
+		&auction.ReservePrice,
+		&auction.ExtendOnReserveMet,
+		&auction.ReserveExtensionApplied,
+		&auction.ExtendOnLeaderChangeOnly,
+		&auction.MinIncrement,
+		&auction.LeaderMaxBid,
+		&auction.Private,
+		&auction.BidderInvited,
+		&auction.BidderHidden,
+		&auction.LeaderHidden,
 	)
 	
 	if err != nil {
@@ -218,51 +432,64 @@ func (p *BidProcessor) getAuctionState(ctx context.Context, auctionID int64) (*d
 	return &auction, nil
 }
 
-func (p *BidProcessor) updateAuctionOCC(ctx context.Context, req domain.BidRequest, auction *domain.AuctionState) (int64, bool, error) {
+func (p *BidProcessor) updateAuctionOCC(ctx context.Context, req domain.BidRequest, auction *domain.AuctionState) (int64, extensionPlan, error) {
 	ctx, span := tracing.StartSpan(ctx, "db.auction.update.occ")
 	defer span.End()
 	
+	// Check for snipe / reserve-met extension
+	ext := p.planExtension(auction, req)
+	
 	tx, err := p.db.Begin(ctx)
 	if err != nil {
-		return 0, false, err
+		return 0, ext, err
 	}
 	defer tx.Rollback(ctx)
 	
-	// Check for snipe extension
-	extended := false
-	newEndsAt := auction.EndsAt
-	if auction.ExtensionCount < auction.MaxExtensions {
-		snipeThreshold := time.Duration(auction.SnipeThresholdMins) * time.Minute
-		if time.Until(auction.EndsAt) < snipeThreshold {
-			extended = true
-			newEndsAt = auction.EndsAt.Add(time.Duration(auction.ExtensionMins) * time.Minute)
-		}
-	}
-	
-	// OCC update - only succeeds if version matches
+	// OCC update - only succeeds if version matches. Every status change
+	// bumps the version, so setting status only promotes a scheduled auction
+	// that has started.
 	var updateQuery string
 	var args []interface{}
 	
-	if extended {
+	if ext.reserve {
+		// One-time reserve extension doesn't consume the snipe extension budget
+		updateQuery = `
+			UPDATE auctions SET
+				current_bid = $1,
+				current_bid_user_id = $2,
+				bid_count = bid_count + 1,
+				version = version + 1,
+				status = 'active',
+				ends_at = $3,
+				reserve_extension_applied = true,
+				extended_seconds = extended_seconds + $6
+			WHERE id = $4 AND version = $5
+			RETURNING id
+		`
+		args = []interface{}{req.Amount, req.UserID, ext.endsAt, req.AuctionID, auction.Version, int(ext.added.Seconds())}
+	} else if ext.snipe {
 		updateQuery = `
 			UPDATE auctions SET
 				current_bid = $1,
 				current_bid_user_id = $2,
 				bid_count = bid_count + 1,
 				version = version + 1,
+				status = 'active',
 				ends_at = $3,
-				extension_count = extension_count + 1
+				extension_count = extension_count + 1,
+				extended_seconds = extended_seconds + $6
 			WHERE id = $4 AND version = $5
 			RETURNING id
 		`
-		args = []interface{}{req.Amount, req.UserID, newEndsAt, req.AuctionID, auction.Version}
+		args = []interface{}{req.Amount, req.UserID, ext.endsAt, req.AuctionID, auction.Version, int(ext.added.Seconds())}
 	} else {
 		updateQuery = `
 			UPDATE auctions SET
 				current_bid = $1,
 				current_bid_user_id = $2,
 				bid_count = bid_count + 1,
-				version = version + 1
+				version = version + 1,
+				status = 'active'
 			WHERE id = $3 AND version = $4
 			RETURNING id
 		`
@@ -274,10 +501,10 @@ func (p *BidProcessor) updateAuctionOCC(ctx context.Context, req domain.BidReque
 	
 	if err == pgx.ErrNoRows {
 		// Version mismatch - another bid won the race
-		return 0, false, ErrVersionConflict
+		return 0, ext, ErrVersionConflict
 	}
 	if err != nil {
-		return 0, false, err
+		return 0, ext, err
 	}
 	
 	// Record the bid in history
@@ -294,11 +521,11 @@ func (p *BidProcessor) updateAuctionOCC(ctx context.Context, req domain.BidReque
 		req.Amount,
 		auction.CurrentBid,
 		decimalOrNil(req.MaxBid),
-		false,
+		req.AutoBid,
 	).Scan(&bidID)
 	
 	if err != nil {
-		return 0, false, err
+		return 0, ext, err
 	}
 	
 	// Mark previous high bidder's bid as outbid
@@ -308,15 +535,258 @@ func (p *BidProcessor) updateAuctionOCC(ctx context.Context, req domain.BidReque
 			WHERE auction_id = $1 AND user_id = $2 AND status = 'accepted'
 		`, req.AuctionID, *auction.CurrentBidUserID)
 		if err != nil {
-			return 0, false, err
+			return 0, ext, err
+		}
+	}
+	
+	if err := p.recordBidEvents(ctx, tx, req, auction, bidID, ext); err != nil {
+		return 0, ext, err
+	}
+	
+	// Unstage with the bid's own effects, so a crash before the engine
+	// acknowledges it can't replay an accepted bid
+	if p.durable {
+		if _, err := tx.Exec(ctx, `DELETE FROM bid_queue WHERE ticket_id = $1`, req.TicketID); err != nil {
+			return 0, ext, err
 		}
 	}
 	
 	if err := tx.Commit(ctx); err != nil {
-		return 0, false, err
+		return 0, ext, err
+	}
+	
+	return bidID, ext, nil
+}
+
+// recordBidEvents writes the audit trail for an accepted bid: the bid itself,
+// the previous leader losing the lead, and any extension it triggered
+func (p *BidProcessor) recordBidEvents(ctx context.Context, tx pgx.Tx, req domain.BidRequest, auction *domain.AuctionState, bidID int64, ext extensionPlan) error {
+	err := RecordAuctionEvent(ctx, tx, req.AuctionID, EventBidAccepted, map[string]any{
+		"bid_id":            bidID,
+		"user_id":           req.UserID,
+		"amount":            req.Amount,
+		"previous_high_bid": auction.CurrentBid,
+		"version":           auction.Version + 1,
+	})
+	if err != nil {
+		return err
+	}
+	
+	if auction.CurrentBidUserID != nil && *auction.CurrentBidUserID != req.UserID {
+		err = RecordAuctionEvent(ctx, tx, req.AuctionID, EventOutbid, map[string]any{
+			"user_id":     *auction.CurrentBidUserID,
+			"outbid_by":   req.UserID,
+			"your_bid":    auction.CurrentBid,
+			"current_bid": req.Amount,
+		})
+		if err != nil {
+			return err
+		}
 	}
 	
-	return bidID, extended, nil
+	if ext.snipe || ext.reserve {
+		reason := "snipe"
+		if ext.reserve {
+			reason = "reserve_met"
+		}
+		err = RecordAuctionEvent(ctx, tx, req.AuctionID, EventExtended, map[string]any{
+			"bid_id":           bidID,
+			"reason":           reason,
+			"previous_ends_at": auction.EndsAt,
+			"ends_at":          ext.endsAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyOutcome tells the bidder their bid was accepted and the previous
+// leader that they were outbid. Each payload only carries what its recipient
+// is entitled to see.
+func (p *BidProcessor) notifyOutcome(req domain.BidRequest, auction *domain.AuctionState, bidID int64, endsAt time.Time) {
+	if p.notifier == nil {
+		return
+	}
+	
+	p.notifier.NotifyUser(req.UserID, "bid.accepted", map[string]interface{}{
+		"auction_id": req.AuctionID,
+		"bid_id":     bidID,
+		"amount":     req.Amount,
+		"ends_at":    endsAt,
+	})
+	
+	if auction.CurrentBidUserID != nil && *auction.CurrentBidUserID != req.UserID {
+		p.notifier.NotifyUser(*auction.CurrentBidUserID, "bid.outbid", map[string]interface{}{
+			"auction_id":  req.AuctionID,
+			"your_bid":    auction.CurrentBid,
+			"current_bid": req.Amount,
+			"ends_at":     endsAt,
+		})
+	}
+}
+
+// extensionPlan is the outcome of planExtension
+type extensionPlan struct {
+	endsAt  time.Time
+	added   time.Duration // How far endsAt moved, counted against the time cap
+	snipe   bool          // counts against max_extensions
+	reserve bool          // one-time reserve-met extension
+}
+
+// planExtension decides whether a bid landing in the final window extends the
+// auction. A bid that first meets the reserve on an auction opted into
+// extend_on_reserve_met gets a one-time extension even when the snipe budget
+// is spent; otherwise the normal snipe rule applies, skipped for a bid by the
+// current leader when the auction only extends on a change of leader.
+func (p *BidProcessor) planExtension(auction *domain.AuctionState, bid domain.BidRequest) extensionPlan {
+	plan := extensionPlan{endsAt: auction.EndsAt}
+	amount := bid.Amount
+	
+	snipeThreshold := time.Duration(auction.SnipeThresholdMins) * time.Minute
+	if auction.EndsAt.Sub(p.clock()) >= snipeThreshold {
+		return plan
+	}
+	step := time.Duration(auction.ExtensionMins) * time.Minute
+	if p.maxExtTotal > 0 {
+		// The last extension is cut short so the total never exceeds the cap
+		remaining := p.maxExtTotal - time.Duration(auction.ExtendedSeconds)*time.Second
+		if remaining <= 0 {
+			return plan
+		}
+		step = min(step, remaining)
+	}
+	extendTo := auction.EndsAt.Add(step)
+	
+	if auction.ExtendOnReserveMet && !auction.ReserveExtensionApplied && auction.ReservePrice.Valid {
+		reserve := auction.ReservePrice.Decimal
+		if auction.CurrentBid.LessThan(reserve) && amount.GreaterThanOrEqual(reserve) {
+			plan.endsAt = extendTo
+			plan.added = step
+			plan.reserve = true
+			return plan
+		}
+	}
+	
+	sameLeader := auction.CurrentBidUserID != nil && *auction.CurrentBidUserID == bid.UserID
+	if auction.ExtendOnLeaderChangeOnly && sameLeader {
+		return plan
+	}
+	if auction.ExtensionCount < auction.MaxExtensions {
+		plan.endsAt = extendTo
+		plan.added = step
+		plan.snipe = true
+	}
+	return plan
+}
+
+// attachAuctionState snapshots the auction after processing so the result
+// reports the resulting current bid and whether the bidder is now leading
+func (p *BidProcessor) attachAuctionState(ctx context.Context, req domain.BidRequest, result *domain.BidResult) {
+	// An uninvited bidder learns nothing about a private auction
+	if result.Reason == "auction_not_found" || result.Reason == "not_invited" || ctx.Err() != nil {
+		return
+	}
+	
+	auction, err := p.getAuctionState(ctx, req.AuctionID, req.UserID)
+	if err != nil {
+		p.logger.Warn("bid_result_auction_state_failed",
+			slog.String("ticket_id", req.TicketID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	
+	result.Auction = &domain.AuctionSnapshot{
+		Status:     auction.Status,
+		CurrentBid: auction.CurrentBid,
+		BidCount:   auction.BidCount,
+		EndsAt:     auction.EndsAt,
+	}
+	result.IsWinning = auction.CurrentBidUserID != nil && *auction.CurrentBidUserID == req.UserID
+}
+
+// maxBidLimit returns the highest MaxBid allowed on this auction, guarding
+// against typo'd auto-bid ceilings. ok is false when no cap is configured.
+func (p *BidProcessor) maxBidLimit(auction *domain.AuctionState) (decimal.Decimal, bool) {
+	if !p.maxBidMult.IsPositive() {
+		return decimal.Zero, false
+	}
+	reference := auction.CurrentBid
+	if auction.BidCount == 0 {
+		reference = auction.StartingPrice
+	}
+	return reference.Mul(p.maxBidMult), true
+}
+
+// closedReason says why an auction can't take a bid, or "" if it can. The
+// deadline decides when bidding stops, not the status: the closer only marks
+// an auction ended a while after ends_at, and a scheduled auction stays
+// scheduled until its first bid, so a status that hasn't caught up doesn't
+// turn bids away. A bid counts if its honored client submit time is before
+// ends_at, or the server received it before ends_at plus clockSkew.
+func (p *BidProcessor) closedReason(auction *domain.AuctionState, req domain.BidRequest) string {
+	placed := p.effectiveBidTime(req)
+	
+	switch auction.Status {
+	case "active":
+	case "scheduled":
+		if placed.Before(auction.StartsAt) {
+			return "auction_not_active"
+		}
+	case "ended":
+		// Every close pulls ends_at back to the close, so this is final
+		return "auction_ended"
+	default:
+		return "auction_not_active"
+	}
+	
+	// The grace and the skew allowance are alternatives rather than added
+	// together, so a bid arrives at most max(bidGrace, clockSkew) late
+	if !placed.Before(auction.EndsAt) && !p.receivedAt(req).Before(auction.EndsAt.Add(p.clockSkew)) {
+		return "auction_ended"
+	}
+	return ""
+}
+
+// effectiveBidTime is the instant a bid counts as placed for the end-of-auction
+// cutoff. It defaults to when the server received the request. A client submit
+// timestamp is honored only if it is no later than receipt and no more than
+// bidGrace earlier, so a client can't backdate a bid past the grace window.
+func (p *BidProcessor) effectiveBidTime(req domain.BidRequest) time.Time {
+	received := p.receivedAt(req)
+	
+	if p.bidGrace <= 0 || req.ClientSubmittedAt.IsZero() {
+		return received
+	}
+	
+	lag := received.Sub(req.ClientSubmittedAt)
+	if lag < 0 || lag > p.bidGrace {
+		p.logger.Warn("bid_client_timestamp_rejected",
+			slog.String("ticket_id", req.TicketID),
+			slog.Duration("lag", lag),
+			slog.Duration("grace", p.bidGrace),
+		)
+		return received
+	}
+	
+	return req.ClientSubmittedAt
+}
+
+// receivedAt is when the server received the bid
+func (p *BidProcessor) receivedAt(req domain.BidRequest) time.Time {
+	if req.CreatedAt.IsZero() {
+		return p.clock()
+	}
+	return req.CreatedAt
+}
+
+func (p *BidProcessor) clock() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
 }
 
 func decimalOrNil(d decimal.Decimal) interface{} {
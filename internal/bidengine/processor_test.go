@@ -0,0 +1,476 @@
+package bidengine
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidProcessor_EffectiveBidTime(t *testing.T) {
+	endsAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	grace := 2 * time.Second
+
+	tests := []struct {
+		name       string
+		received   time.Time
+		clientSent time.Time
+		grace      time.Duration
+		wantBefore bool
+	}{
+		{
+			name:       "received before end",
+			received:   endsAt.Add(-time.Millisecond),
+			wantBefore: true,
+		},
+		{
+			name:       "received exactly at end",
+			received:   endsAt,
+			wantBefore: false,
+		},
+		{
+			name:       "received after end, sent inside grace",
+			received:   endsAt.Add(500 * time.Millisecond),
+			clientSent: endsAt.Add(-100 * time.Millisecond),
+			grace:      grace,
+			wantBefore: true,
+		},
+		{
+			name:       "received after end, sent right at grace boundary",
+			received:   endsAt.Add(grace - time.Millisecond),
+			clientSent: endsAt.Add(-time.Millisecond),
+			grace:      grace,
+			wantBefore: true,
+		},
+		{
+			name:       "backdated beyond grace falls back to received time",
+			received:   endsAt.Add(500 * time.Millisecond),
+			clientSent: endsAt.Add(-time.Minute),
+			grace:      grace,
+			wantBefore: false,
+		},
+		{
+			name:       "client timestamp after receipt is ignored",
+			received:   endsAt.Add(500 * time.Millisecond),
+			clientSent: endsAt.Add(time.Second),
+			grace:      grace,
+			wantBefore: false,
+		},
+		{
+			name:       "client timestamp ignored when grace disabled",
+			received:   endsAt.Add(500 * time.Millisecond),
+			clientSent: endsAt.Add(-100 * time.Millisecond),
+			grace:      0,
+			wantBefore: false,
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &BidProcessor{
+				logger:   logger,
+				bidGrace: tt.grace,
+				now:      func() time.Time { return tt.received },
+			}
+			req := domain.BidRequest{
+				CreatedAt:         tt.received,
+				ClientSubmittedAt: tt.clientSent,
+			}
+
+			assert.Equal(t, tt.wantBefore, p.effectiveBidTime(req).Before(endsAt))
+		})
+	}
+}
+
+func TestBidProcessor_EffectiveBidTime_DefaultsToClock(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := &BidProcessor{now: func() time.Time { return now }}
+
+	assert.Equal(t, now, p.effectiveBidTime(domain.BidRequest{}))
+}
+
+func TestBidProcessor_PlanExtension_ReserveMetFiresOnce(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := &BidProcessor{now: func() time.Time { return now }}
+
+	auction := &domain.AuctionState{
+		CurrentBid:         decimal.NewFromInt(900),
+		EndsAt:             now.Add(time.Minute),
+		ExtensionCount:     0,
+		MaxExtensions:      0, // snipe budget exhausted
+		SnipeThresholdMins: 2,
+		ExtensionMins:      2,
+		ReservePrice:       decimal.NewNullDecimal(decimal.NewFromInt(1000)),
+		ExtendOnReserveMet: true,
+	}
+
+	// First bid to meet the reserve in the final window extends
+	plan := p.planExtension(auction, domain.BidRequest{Amount: decimal.NewFromInt(1000)})
+	assert.True(t, plan.reserve)
+	assert.False(t, plan.snipe)
+	assert.Equal(t, auction.EndsAt.Add(2*time.Minute), plan.endsAt)
+
+	// After it has been applied, later bids don't extend again
+	auction.ReserveExtensionApplied = true
+	auction.CurrentBid = decimal.NewFromInt(1000)
+	auction.EndsAt = now.Add(30 * time.Second)
+	plan = p.planExtension(auction, domain.BidRequest{Amount: decimal.NewFromInt(1100)})
+	assert.False(t, plan.reserve)
+	assert.False(t, plan.snipe)
+}
+
+func TestBidProcessor_PlanExtension_ReserveRules(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := &BidProcessor{now: func() time.Time { return now }}
+
+	base := func() *domain.AuctionState {
+		return &domain.AuctionState{
+			CurrentBid:         decimal.NewFromInt(500),
+			EndsAt:             now.Add(time.Minute),
+			SnipeThresholdMins: 2,
+			ExtensionMins:      2,
+			ReservePrice:       decimal.NewNullDecimal(decimal.NewFromInt(1000)),
+			ExtendOnReserveMet: true,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(a *domain.AuctionState)
+		amount      int64
+		wantReserve bool
+	}{
+		{"meets reserve in window", func(a *domain.AuctionState) {}, 1000, true},
+		{"below reserve", func(a *domain.AuctionState) {}, 999, false},
+		{"outside final window", func(a *domain.AuctionState) { a.EndsAt = now.Add(time.Hour) }, 1000, false},
+		{"reserve already met", func(a *domain.AuctionState) { a.CurrentBid = decimal.NewFromInt(1000) }, 1100, false},
+		{"not opted in", func(a *domain.AuctionState) { a.ExtendOnReserveMet = false }, 1000, false},
+		{"no reserve", func(a *domain.AuctionState) { a.ReservePrice = decimal.NullDecimal{} }, 1000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auction := base()
+			tt.modify(auction)
+			plan := p.planExtension(auction, domain.BidRequest{Amount: decimal.NewFromInt(tt.amount)})
+			assert.Equal(t, tt.wantReserve, plan.reserve)
+		})
+	}
+}
+
+func TestResolveProxy(t *testing.T) {
+	leaderID := int64(1)
+	auction := &domain.AuctionState{
+		CurrentBid:       decimal.NewFromInt(200),
+		CurrentBidUserID: &leaderID,
+		BidCount:         3,
+		LeaderMaxBid:     decimal.NewNullDecimal(decimal.NewFromInt(500)),
+	}
+	dec := func(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+	tests := []struct {
+		name         string
+		userID       int64
+		amount       string
+		maxBid       string
+		wantUser     int64
+		wantAmount   string
+		wantDefended bool
+	}{
+		{"leader max covers the bid", 2, "300", "0", 1, "300.01", true},
+		{"leader max covers the challenger's max", 2, "300", "450", 1, "450.01", true},
+		{"tie goes to the leader", 2, "300", "500", 1, "500", true},
+		{"challenger beats the max", 2, "600", "0", 2, "600", false},
+		{"challenger max beats the max by the minimum", 2, "300", "800", 2, "500.01", false},
+		{"challenger max one cent over", 2, "300", "500.01", 2, "500.01", false},
+		{"leader raising their own bid", 1, "300", "0", 1, "300", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := domain.BidRequest{UserID: tt.userID, Amount: dec(tt.amount), MaxBid: dec(tt.maxBid)}
+			placed, defended := resolveProxy(req, auction)
+			assert.Equal(t, tt.wantDefended, defended)
+			assert.Equal(t, tt.wantUser, placed.UserID)
+			assert.True(t, dec(tt.wantAmount).Equal(placed.Amount), "amount %s", placed.Amount)
+			assert.Equal(t, defended, placed.AutoBid)
+		})
+	}
+
+	// Without a leader proxy the bid goes through as offered
+	plain := *auction
+	plain.LeaderMaxBid = decimal.NullDecimal{}
+	placed, defended := resolveProxy(domain.BidRequest{UserID: 2, Amount: dec("250")}, &plain)
+	assert.False(t, defended)
+	assert.True(t, dec("250").Equal(placed.Amount))
+}
+
+func TestPublicBidderID(t *testing.T) {
+	auction := &domain.AuctionState{}
+	assert.Equal(t, int64(2), publicBidderID(2, 2, auction))
+
+	// A hidden challenger isn't named when they take the lead
+	auction.BidderHidden = true
+	assert.Equal(t, int64(0), publicBidderID(2, 2, auction))
+
+	// A proxy defence is in the leader's name, so their setting decides
+	assert.Equal(t, int64(1), publicBidderID(1, 2, auction))
+	auction.LeaderHidden = true
+	assert.Equal(t, int64(0), publicBidderID(1, 2, auction))
+}
+
+func TestMinimumNextBid(t *testing.T) {
+	auction := &domain.AuctionState{StartingPrice: decimal.NewFromInt(100), CurrentBid: decimal.Zero}
+	assert.Equal(t, "100.00", minimumNextBid(auction).StringFixed(2))
+
+	auction.BidCount = 1
+	auction.CurrentBid = decimal.NewFromInt(150)
+	assert.Equal(t, "150.01", minimumNextBid(auction).StringFixed(2))
+
+	// A per-auction increment replaces the default step
+	auction.MinIncrement = decimal.NewNullDecimal(decimal.NewFromInt(25))
+	assert.Equal(t, "175.00", minimumNextBid(auction).StringFixed(2))
+}
+
+func TestResolveProxy_AuctionIncrement(t *testing.T) {
+	leaderID := int64(1)
+	auction := &domain.AuctionState{
+		CurrentBid:       decimal.NewFromInt(200),
+		CurrentBidUserID: &leaderID,
+		BidCount:         3,
+		LeaderMaxBid:     decimal.NewNullDecimal(decimal.NewFromInt(500)),
+		MinIncrement:     decimal.NewNullDecimal(decimal.NewFromInt(50)),
+	}
+
+	// The leader's proxy answers one auction increment above the challenger
+	placed, defended := resolveProxy(domain.BidRequest{UserID: 2, Amount: decimal.NewFromInt(300)}, auction)
+	assert.True(t, defended)
+	assert.True(t, decimal.NewFromInt(350).Equal(placed.Amount), "amount %s", placed.Amount)
+
+	// A challenger max above the leader's clears it by one auction increment
+	placed, defended = resolveProxy(domain.BidRequest{UserID: 2, Amount: decimal.NewFromInt(300), MaxBid: decimal.NewFromInt(800)}, auction)
+	assert.False(t, defended)
+	assert.True(t, decimal.NewFromInt(550).Equal(placed.Amount), "amount %s", placed.Amount)
+}
+
+func TestBidProcessor_CancelledContext(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	// No database: a cancelled bid must give up before touching it
+	p := &BidProcessor{logger: logger, maxRetries: 3, retryBackoff: time.Second, now: time.Now}
+	req := domain.BidRequest{TicketID: "cancelled", AuctionID: 1, UserID: 2, Amount: decimal.NewFromInt(150)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	result := p.Process(ctx, req)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, "error", result.Status)
+	assert.Equal(t, "bid_cancelled", result.Reason)
+	assert.Equal(t, "cancelled", result.TicketID)
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	result = p.Process(ctx, req)
+	assert.Equal(t, "error", result.Status)
+	assert.Equal(t, "bid_timeout", result.Reason)
+}
+
+func TestEngine_SubmitContext_CancelledSyncBid(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	engine := NewEngine(nil, logger, nil, WithSyncMode(true))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := domain.BidRequest{TicketID: "sync-cancelled", AuctionID: 1, UserID: 2, Amount: decimal.NewFromInt(150)}
+	require.NoError(t, engine.SubmitContext(ctx, req))
+
+	result, err := engine.GetResult(req.TicketID, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "error", result.Status)
+	assert.Equal(t, "bid_cancelled", result.Reason)
+}
+
+func TestBidProcessor_PlanExtension_LeaderChangeOnly(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := &BidProcessor{now: func() time.Time { return now }}
+
+	leaderID := int64(1)
+	auction := &domain.AuctionState{
+		CurrentBid:         decimal.NewFromInt(500),
+		CurrentBidUserID:   &leaderID,
+		EndsAt:             now.Add(time.Minute),
+		MaxExtensions:      5,
+		SnipeThresholdMins: 2,
+		ExtensionMins:      2,
+	}
+	raise := domain.BidRequest{UserID: leaderID, Amount: decimal.NewFromInt(600)}
+	challenge := domain.BidRequest{UserID: 2, Amount: decimal.NewFromInt(600)}
+
+	// Off by default: any bid in the window extends
+	assert.True(t, p.planExtension(auction, raise).snipe)
+	assert.True(t, p.planExtension(auction, challenge).snipe)
+
+	auction.ExtendOnLeaderChangeOnly = true
+	plan := p.planExtension(auction, raise)
+	assert.False(t, plan.snipe)
+	assert.Equal(t, auction.EndsAt, plan.endsAt)
+
+	plan = p.planExtension(auction, challenge)
+	assert.True(t, plan.snipe)
+	assert.Equal(t, auction.EndsAt.Add(2*time.Minute), plan.endsAt)
+}
+
+func TestBidProcessor_PlanExtension_TotalTimeCap(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := &BidProcessor{now: func() time.Time { return now }, maxExtTotal: 5 * time.Minute}
+
+	auction := &domain.AuctionState{
+		CurrentBid:         decimal.NewFromInt(500),
+		EndsAt:             now.Add(time.Minute),
+		MaxExtensions:      10,
+		SnipeThresholdMins: 2,
+		ExtensionMins:      2,
+	}
+	bid := domain.BidRequest{UserID: 2, Amount: decimal.NewFromInt(600)}
+
+	// Well under the cap: a full extension
+	plan := p.planExtension(auction, bid)
+	assert.True(t, plan.snipe)
+	assert.Equal(t, 2*time.Minute, plan.added)
+	assert.Equal(t, auction.EndsAt.Add(2*time.Minute), plan.endsAt)
+
+	// The extension that reaches the cap is cut to what's left
+	auction.ExtensionCount, auction.ExtendedSeconds = 2, 240
+	plan = p.planExtension(auction, bid)
+	assert.True(t, plan.snipe)
+	assert.Equal(t, time.Minute, plan.added)
+	assert.Equal(t, auction.EndsAt.Add(time.Minute), plan.endsAt)
+
+	// At the cap nothing extends, though the count budget isn't spent
+	auction.ExtensionCount, auction.ExtendedSeconds = 3, 300
+	plan = p.planExtension(auction, bid)
+	assert.False(t, plan.snipe)
+	assert.Equal(t, auction.EndsAt, plan.endsAt)
+
+	// The reserve extension is bound by the same cap
+	auction.ReservePrice = decimal.NewNullDecimal(decimal.NewFromInt(550))
+	auction.ExtendOnReserveMet = true
+	plan = p.planExtension(auction, bid)
+	assert.False(t, plan.reserve)
+
+	// Zero leaves only the count cap
+	p.maxExtTotal = 0
+	plan = p.planExtension(auction, bid)
+	assert.True(t, plan.reserve)
+	assert.Equal(t, 2*time.Minute, plan.added)
+}
+
+func TestBidProcessor_ClosedReason(t *testing.T) {
+	startsAt := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	endsAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status string
+		placed time.Time
+		skew   time.Duration
+		want   string
+	}{
+		{"active, just before end", "active", endsAt.Add(-time.Millisecond), 0, ""},
+		{"active, at end", "active", endsAt, 0, "auction_ended"},
+		{"active, just after end", "active", endsAt.Add(time.Millisecond), 0, "auction_ended"},
+		{"active, inside skew", "active", endsAt.Add(400 * time.Millisecond), 500 * time.Millisecond, ""},
+		{"active, past skew", "active", endsAt.Add(500 * time.Millisecond), 500 * time.Millisecond, "auction_ended"},
+		{"stale scheduled, started", "scheduled", endsAt.Add(-time.Minute), 0, ""},
+		{"scheduled, not started", "scheduled", startsAt.Add(-time.Second), 0, "auction_not_active"},
+		{"stale scheduled, past end", "scheduled", endsAt.Add(time.Second), 0, "auction_ended"},
+		{"ended", "ended", endsAt.Add(-time.Minute), 500 * time.Millisecond, "auction_ended"},
+		{"cancelled", "cancelled", endsAt.Add(-time.Minute), 0, "auction_not_active"},
+		{"draft", "draft", endsAt.Add(-time.Minute), 0, "auction_not_active"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &BidProcessor{clockSkew: tt.skew}
+			auction := &domain.AuctionState{Status: tt.status, StartsAt: startsAt, EndsAt: endsAt}
+
+			assert.Equal(t, tt.want, p.closedReason(auction, domain.BidRequest{CreatedAt: tt.placed}))
+		})
+	}
+}
+
+func TestBidProcessor_ClosedReason_GraceAndSkewDontStack(t *testing.T) {
+	endsAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	tests := []struct {
+		name       string
+		received   time.Time
+		clientSent time.Time
+		want       string
+	}{
+		{"sent before end, received inside grace", endsAt.Add(1900 * time.Millisecond), endsAt.Add(-50 * time.Millisecond), ""},
+		{"sent after end, received inside skew", endsAt.Add(400 * time.Millisecond), endsAt.Add(100 * time.Millisecond), ""},
+		{"sent inside skew, received past skew", endsAt.Add(1900 * time.Millisecond), endsAt.Add(400 * time.Millisecond), "auction_ended"},
+		{"no client time, received past skew", endsAt.Add(600 * time.Millisecond), time.Time{}, "auction_ended"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &BidProcessor{logger: logger, bidGrace: 2 * time.Second, clockSkew: 500 * time.Millisecond}
+			auction := &domain.AuctionState{Status: "active", StartsAt: endsAt.Add(-time.Hour), EndsAt: endsAt}
+			req := domain.BidRequest{CreatedAt: tt.received, ClientSubmittedAt: tt.clientSent}
+
+			assert.Equal(t, tt.want, p.closedReason(auction, req))
+		})
+	}
+}
+
+func TestBidProcessor_AmountRejection_CarriesNextMinBid(t *testing.T) {
+	leading := &domain.AuctionState{
+		StartingPrice: decimal.NewFromInt(100),
+		CurrentBid:    decimal.NewFromInt(200),
+		BidCount:      2,
+		MinIncrement:  decimal.NewNullDecimal(decimal.NewFromInt(25)),
+	}
+	fresh := &domain.AuctionState{StartingPrice: decimal.NewFromInt(100)}
+
+	tests := []struct {
+		name        string
+		auction     *domain.AuctionState
+		req         domain.BidRequest
+		wantReason  string
+		wantHighBid string
+		wantNextMin string
+	}{
+		{"beaten to it", leading, domain.BidRequest{Amount: decimal.NewFromInt(200)}, "bid_too_low", "200.00", "225.00"},
+		{"under the increment", leading, domain.BidRequest{Amount: decimal.NewFromInt(210)}, "below_min_increment", "200.00", "225.00"},
+		{"under the starting price", fresh, domain.BidRequest{Amount: decimal.NewFromInt(90)}, "below_starting_price", "0.00", "100.00"},
+		{"proxy max too low", leading, domain.BidRequest{Amount: decimal.NewFromInt(225), MaxBid: decimal.NewFromInt(220), ProxyOnly: true}, "max_bid_too_low", "200.00", "225.00"},
+		{"enough", leading, domain.BidRequest{Amount: decimal.NewFromInt(225)}, "", "", ""},
+	}
+
+	p := &BidProcessor{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := p.amountRejection(tt.req, tt.auction)
+			require.Equal(t, tt.wantReason, reason)
+			if reason == "" {
+				return
+			}
+
+			result := amountRejected(tt.req, tt.auction, reason)
+			assert.Equal(t, "rejected", result.Status)
+			assert.Equal(t, tt.wantHighBid, result.PreviousHighBid.StringFixed(2))
+			assert.Equal(t, tt.wantNextMin, result.NextMinBid.StringFixed(2))
+		})
+	}
+}
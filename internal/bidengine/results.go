@@ -0,0 +1,111 @@
+package bidengine
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+)
+
+// ResultStore keeps finished bid results where every instance can read them,
+// so a status poll doesn't have to land on the instance that processed the
+// bid. Results only need to live as long as clients might poll for them.
+type ResultStore interface {
+	Put(ctx context.Context, result domain.BidResult) error
+	// Get reports false when the ticket has no stored result (yet)
+	Get(ctx context.Context, ticketID string) (domain.BidResult, bool, error)
+}
+
+// resultPollInterval is how often GetResult checks the store while waiting
+const resultPollInterval = 50 * time.Millisecond
+
+// resultStoreTimeout bounds each store call so a slow store can't stall the
+// store writer or a status poll for long
+const resultStoreTimeout = time.Second
+
+// resultStoreQueueSize bounds the results waiting to be written to the store.
+// Past it results are only served locally until the store catches up.
+const resultStoreQueueSize = 1000
+
+// WithResultStore also writes every result to store and has GetResult read
+// from it, so any instance can answer for any ticket
+func WithResultStore(store ResultStore) EngineOption {
+	return func(e *Engine) {
+		e.resultStore = store
+	}
+}
+
+// queueStore hands a result to storeWriter without blocking
+func (e *Engine) queueStore(ticketID string, result domain.BidResult) {
+	if e.resultStore == nil {
+		return
+	}
+	result.TicketID = ticketID
+	select {
+	case e.storeQueue <- result:
+	default:
+		e.logger.Warn("bid_result_store_queue_full", slog.String("ticket_id", ticketID))
+	}
+}
+
+// storeWriter writes queued results to the store until Stop, then flushes
+// whatever is still queued
+func (e *Engine) storeWriter() {
+	defer e.storeWG.Done()
+	for {
+		select {
+		case result := <-e.storeQueue:
+			e.storeResult(result)
+		case <-e.storeDone:
+			for {
+				select {
+				case result := <-e.storeQueue:
+					e.storeResult(result)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// storeResult writes a finished result to the store
+func (e *Engine) storeResult(result domain.BidResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), resultStoreTimeout)
+	defer cancel()
+	if err := e.resultStore.Put(ctx, result); err != nil {
+		e.logger.Error("bid_result_store_failed",
+			slog.String("ticket_id", result.TicketID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// storeLookup is the outcome of a background store lookup
+type storeLookup struct {
+	result domain.BidResult
+	ok     bool
+}
+
+// lookupStored sends storedResult's answer to out
+func (e *Engine) lookupStored(ticketID string, out chan<- storeLookup) {
+	result, ok := e.storedResult(ticketID)
+	out <- storeLookup{result: result, ok: ok}
+}
+
+// storedResult looks a ticket up in the store; errors count as not found so
+// the caller keeps waiting
+func (e *Engine) storedResult(ticketID string) (domain.BidResult, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), resultStoreTimeout)
+	defer cancel()
+	result, ok, err := e.resultStore.Get(ctx, ticketID)
+	if err != nil {
+		e.logger.Warn("bid_result_lookup_failed",
+			slog.String("ticket_id", ticketID),
+			slog.String("error", err.Error()),
+		)
+		return domain.BidResult{}, false
+	}
+	return result, ok
+}
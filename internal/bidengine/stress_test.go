@@ -0,0 +1,140 @@
+package bidengine
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_ConcurrentBidsStress fires rounds of concurrent bids at a single
+// auction through several async engines sharing one database, the way
+// multiple app instances would. Each engine serializes bids per auction, so
+// contention only comes from the other instances and is settled by OCC.
+func TestEngine_ConcurrentBidsStress(t *testing.T) {
+	if os.Getenv("TEST_DATABASE_URL") == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping integration test")
+	}
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	const (
+		instances = 4
+		bidders   = 8
+		rounds    = 15
+	)
+
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	bidderIDs := make([]int64, bidders)
+	for i := range bidderIDs {
+		bidderIDs[i] = fixtures.BuyerUser(t, db)
+	}
+
+	engines := make([]*Engine, instances)
+	for i := range engines {
+		engines[i] = NewEngine(db, logger, &mockBroadcaster{},
+			WithMaxRetries(bidders*2),
+			WithRetryBackoff(time.Millisecond),
+		)
+		engines[i].Start()
+		defer engines[i].Stop()
+	}
+
+	conflictsBefore := testutil.ToFloat64(metrics.BidOCCConflictsTotal)
+	accepted := 0
+	for round := 0; round < rounds; round++ {
+		// Every bid in a round beats everything from the previous one, so the
+		// round's highest bid must end up leading no matter the arrival order
+		base := decimal.NewFromInt(int64(100 + round*100))
+		results := make([]domain.BidResult, bidders)
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < bidders; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				engine := engines[i%instances]
+				req := domain.BidRequest{
+					TicketID:  uuid.New().String(),
+					AuctionID: auctionID,
+					UserID:    bidderIDs[i],
+					Amount:    base.Add(decimal.NewFromInt(int64(i))),
+					CreatedAt: time.Now(),
+				}
+				<-start
+				if err := engine.Submit(req); err != nil {
+					results[i] = domain.BidResult{Status: "error", Reason: err.Error()}
+					return
+				}
+				result, err := engine.GetResult(req.TicketID, 10*time.Second)
+				if err != nil {
+					result = domain.BidResult{Status: "error", Reason: err.Error()}
+				}
+				results[i] = result
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		roundAccepted := 0
+		for i, result := range results {
+			require.NotEqual(t, "error", result.Status, "round %d bidder %d: %s", round, i, result.Reason)
+			if result.Status == "accepted" {
+				roundAccepted++
+			}
+		}
+		require.GreaterOrEqual(t, roundAccepted, 1, "round %d", round)
+		accepted += roundAccepted
+
+		var currentBid decimal.Decimal
+		var leaderID int64
+		require.NoError(t, db.QueryRow(ctx, `
+			SELECT current_bid, current_bid_user_id FROM auctions WHERE id = $1
+		`, auctionID).Scan(&currentBid, &leaderID))
+		top := base.Add(decimal.NewFromInt(bidders - 1))
+		assert.True(t, top.Equal(currentBid), "round %d: current bid %s, want %s", round, currentBid, top)
+		assert.Equal(t, bidderIDs[bidders-1], leaderID, "round %d", round)
+		assert.True(t, results[bidders-1].Status == "accepted", "round %d: top bid %s", round, results[bidders-1].Reason)
+	}
+
+	// One version bump and one bid row per accepted bid, nothing lost
+	var bidCount, version, bidRows int
+	require.NoError(t, db.QueryRow(ctx, `SELECT bid_count, version FROM auctions WHERE id = $1`, auctionID).Scan(&bidCount, &version))
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM bids WHERE auction_id = $1`, auctionID).Scan(&bidRows))
+	assert.Equal(t, accepted, bidCount)
+	assert.Equal(t, accepted, version)
+	assert.Equal(t, accepted, bidRows)
+
+	// Each accepted bid saw the one before it as the high bid: the history is
+	// a single chain with no update applied on top of a stale read
+	rows, err := db.Query(ctx, `
+		SELECT amount, previous_high_bid FROM bids WHERE auction_id = $1 ORDER BY id
+	`, auctionID)
+	require.NoError(t, err)
+	defer rows.Close()
+	previous := decimal.Zero
+	for rows.Next() {
+		var amount, previousHigh decimal.Decimal
+		require.NoError(t, rows.Scan(&amount, &previousHigh))
+		assert.True(t, previous.Equal(previousHigh), "bid %s recorded previous high %s, want %s", amount, previousHigh, previous)
+		assert.True(t, amount.GreaterThan(previous))
+		previous = amount
+	}
+	require.NoError(t, rows.Err())
+
+	assert.Greater(t, testutil.ToFloat64(metrics.BidOCCConflictsTotal), conflictsBefore, "no OCC conflicts under contention")
+}
@@ -0,0 +1,72 @@
+package bidengine
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+)
+
+// subscribers fans processed bid results out to in-process consumers,
+// independently of the per-ticket results map that GetResult reads
+type subscribers struct {
+	mu     sync.Mutex
+	nextID int
+	chans  map[int]chan domain.BidResult
+}
+
+// Subscribe registers a consumer for the result of every bid the engine
+// processes, accepted or not. The returned channel holds up to buffer results;
+// a subscriber that falls further behind misses results rather than stalling
+// the workers. Call the returned function to unsubscribe, which closes the
+// channel. Stop closes all remaining subscriptions.
+func (e *Engine) Subscribe(buffer int) (<-chan domain.BidResult, func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	ch := make(chan domain.BidResult, buffer)
+
+	e.subs.mu.Lock()
+	if e.subs.chans == nil {
+		e.subs.chans = make(map[int]chan domain.BidResult)
+	}
+	id := e.subs.nextID
+	e.subs.nextID++
+	e.subs.chans[id] = ch
+	e.subs.mu.Unlock()
+
+	return ch, func() {
+		e.subs.mu.Lock()
+		defer e.subs.mu.Unlock()
+		if c, ok := e.subs.chans[id]; ok {
+			delete(e.subs.chans, id)
+			close(c)
+		}
+	}
+}
+
+// publishResult hands a result to every subscriber without blocking
+func (e *Engine) publishResult(result domain.BidResult) {
+	e.subs.mu.Lock()
+	defer e.subs.mu.Unlock()
+	for _, ch := range e.subs.chans {
+		select {
+		case ch <- result:
+		default:
+			e.logger.Warn("bid_result_dropped_subscriber_full",
+				slog.String("ticket_id", result.TicketID),
+				slog.Int64("auction_id", result.AuctionID),
+			)
+		}
+	}
+}
+
+// closeSubscribers ends every subscription; used on shutdown
+func (e *Engine) closeSubscribers() {
+	e.subs.mu.Lock()
+	defer e.subs.mu.Unlock()
+	for id, ch := range e.subs.chans {
+		delete(e.subs.chans, id)
+		close(ch)
+	}
+}
@@ -8,57 +8,64 @@ import (
 	"time"
 
 	"github.com/ayubfarah/vehicle-auc/internal/domain"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Worker processes bids for a single auction
 type Worker struct {
 	auctionID    int64
-	db           *pgxpool.Pool
+	processor    *BidProcessor
 	logger       *slog.Logger
-	broadcaster  Broadcaster
-	maxRetries   int
-	retryBackoff time.Duration
 	
 	// Internal queue
-	queue        chan domain.BidRequest
+	queue        chan submission
 	
 	// Callbacks
 	OnResult     func(ticketID string, result domain.BidResult)
+	OnAborted    func(ticketID string, result domain.BidResult) // Bid cut short by Stop; OnResult when unset
 	OnComplete   func()
 	OnRetry      func()
 	
 	// Stats
 	processed    atomic.Int64
+	retries      atomic.Int64
 	lastBidAt    atomic.Int64 // Unix timestamp
 	
+	// Recent processing durations (ring buffer) for contention debugging
+	durationsMu  sync.Mutex
+	durations    []time.Duration
+	durationsIdx int
+	
 	// Lifecycle
 	ctx          context.Context
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
 }
 
+// recentDurationWindow is how many processing durations each worker keeps
+const recentDurationWindow = 50
+
 // WorkerStats for debug endpoints
 type WorkerStats struct {
-	AuctionID   int64  `json:"auction_id"`
-	QueueDepth  int    `json:"queue_depth"`
-	Processed   int64  `json:"processed"`
-	LastBidAt   string `json:"last_bid_at,omitempty"`
-	IdleFor     string `json:"idle_for,omitempty"`
+	AuctionID     int64   `json:"auction_id"`
+	QueueDepth    int     `json:"queue_depth"`
+	Processed     int64   `json:"processed"`
+	Retries       int64   `json:"retries"`
+	AvgDurationMs float64 `json:"avg_duration_ms"` // Over the recent window
+	MaxDurationMs float64 `json:"max_duration_ms"` // Over the recent window
+	LastBidAt     string  `json:"last_bid_at,omitempty"`
+	IdleFor       string  `json:"idle_for,omitempty"`
 }
 
 // NewWorker creates a new auction worker
-func NewWorker(auctionID int64, db *pgxpool.Pool, logger *slog.Logger, broadcaster Broadcaster, maxRetries int, retryBackoff time.Duration) *Worker {
+func NewWorker(auctionID int64, processor *BidProcessor, logger *slog.Logger) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	return &Worker{
 		auctionID:    auctionID,
-		db:           db,
+		processor:    processor,
 		logger:       logger,
-		broadcaster:  broadcaster,
-		maxRetries:   maxRetries,
-		retryBackoff: retryBackoff,
-		queue:        make(chan domain.BidRequest, 100),
+		queue:        make(chan submission, 100),
+		durations:    make([]time.Duration, 0, recentDurationWindow),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
@@ -78,8 +85,13 @@ func (w *Worker) Stop() {
 
 // Submit sends a bid to this worker
 func (w *Worker) Submit(req domain.BidRequest) {
+	w.SubmitContext(context.Background(), req)
+}
+
+// SubmitContext sends a bid to this worker; cancelling ctx aborts it
+func (w *Worker) SubmitContext(ctx context.Context, req domain.BidRequest) {
 	select {
-	case w.queue <- req:
+	case w.queue <- submission{ctx: ctx, req: req}:
 	case <-w.ctx.Done():
 	}
 }
@@ -92,7 +104,22 @@ func (w *Worker) Stats() WorkerStats {
 		AuctionID:  w.auctionID,
 		QueueDepth: len(w.queue),
 		Processed:  w.processed.Load(),
+		Retries:    w.retries.Load(),
+	}
+	
+	w.durationsMu.Lock()
+	if len(w.durations) > 0 {
+		var total, max time.Duration
+		for _, d := range w.durations {
+			total += d
+			if d > max {
+				max = d
+			}
+		}
+		stats.AvgDurationMs = float64(total) / float64(len(w.durations)) / float64(time.Millisecond)
+		stats.MaxDurationMs = float64(max) / float64(time.Millisecond)
 	}
+	w.durationsMu.Unlock()
 	
 	if !lastBid.IsZero() && lastBid.Unix() > 0 {
 		stats.LastBidAt = lastBid.Format(time.RFC3339)
@@ -105,26 +132,35 @@ func (w *Worker) Stats() WorkerStats {
 func (w *Worker) run() {
 	defer w.wg.Done()
 	
-	processor := &BidProcessor{
-		db:           w.db,
-		logger:       w.logger,
-		broadcaster:  w.broadcaster,
-		maxRetries:   w.maxRetries,
-		retryBackoff: w.retryBackoff,
-		onRetry:      w.OnRetry,
+	processor := w.processor
+	processor.onRetry = func() {
+		w.retries.Add(1)
+		if w.OnRetry != nil {
+			w.OnRetry()
+		}
 	}
 	
 	for {
 		select {
 		case <-w.ctx.Done():
 			return
-		case req := <-w.queue:
-			result := processor.Process(w.ctx, req)
+		case sub := <-w.queue:
+			// select picks at random when both are ready; a stopped worker
+			// leaves the bid unprocessed like the rest of its queue
+			if w.ctx.Err() != nil {
+				return
+			}
+			req := sub.req
+			start := time.Now()
+			result, aborted := w.process(processor, sub)
+			w.recordDuration(time.Since(start))
 			
 			w.processed.Add(1)
 			w.lastBidAt.Store(time.Now().Unix())
 			
-			if w.OnResult != nil {
+			if aborted && w.OnAborted != nil {
+				w.OnAborted(req.TicketID, result)
+			} else if w.OnResult != nil {
 				w.OnResult(req.TicketID, result)
 			}
 			if w.OnComplete != nil {
@@ -134,3 +170,28 @@ func (w *Worker) run() {
 	}
 }
 
+// process runs one bid under its submitter's context, also aborting it if
+// the worker is stopped mid-bid. aborted reports that Stop, not the
+// submitter or the per-bid deadline, is what gave the bid up.
+func (w *Worker) process(processor *BidProcessor, sub submission) (result domain.BidResult, aborted bool) {
+	ctx, cancel := context.WithCancel(sub.ctx)
+	defer cancel()
+	stop := context.AfterFunc(w.ctx, cancel)
+	defer stop()
+	
+	result = processor.Process(ctx, sub.req)
+	aborted = result.Reason == "bid_cancelled" && w.ctx.Err() != nil && sub.ctx.Err() == nil
+	return result, aborted
+}
+
+func (w *Worker) recordDuration(d time.Duration) {
+	w.durationsMu.Lock()
+	defer w.durationsMu.Unlock()
+	
+	if len(w.durations) < recentDurationWindow {
+		w.durations = append(w.durations, d)
+		return
+	}
+	w.durations[w.durationsIdx] = d
+	w.durationsIdx = (w.durationsIdx + 1) % recentDurationWindow
+}
@@ -0,0 +1,111 @@
+// Package cache is a small in-memory read cache for hot detail rows, so
+// traffic spikes on one auction page don't turn into a query per view.
+package cache
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+)
+
+// Cache holds loaded values by key, each tagged with the row version it was
+// read at. Entries expire after the TTL, and Invalidate drops them early once
+// the row moves past that version.
+type Cache[K comparable, V any] struct {
+	name string
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu        sync.Mutex
+	entries   map[K]entry[V]
+	loading   map[K]int // Loads in flight per key
+	stale     map[K]int // Version an in-flight load must have reached to be kept
+	nextSweep time.Time
+}
+
+type entry[V any] struct {
+	value   V
+	version int
+	expires time.Time
+}
+
+// New creates a cache whose entries live for ttl. name labels its hit and
+// miss counts.
+func New[K comparable, V any](name string, ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		name:    name,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[K]entry[V]),
+		loading: make(map[K]int),
+		stale:   make(map[K]int),
+	}
+}
+
+// Get returns the cached value for key, or calls load and caches what it
+// returns along with its version. Errors aren't cached. A value invalidated
+// while it was loading is returned but not kept.
+func (c *Cache[K, V]) Get(key K, load func() (V, int, error)) (V, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expires) {
+		c.mu.Unlock()
+		metrics.ReadCacheRequests.WithLabelValues(c.name, "hit").Inc()
+		return e.value, nil
+	}
+	c.loading[key]++
+	c.mu.Unlock()
+	metrics.ReadCacheRequests.WithLabelValues(c.name, "miss").Inc()
+
+	value, version, err := load()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	minVersion, invalidated := c.stale[key]
+	if c.loading[key]--; c.loading[key] == 0 {
+		delete(c.loading, key)
+		delete(c.stale, key)
+	}
+	if err != nil || (invalidated && version < minVersion) {
+		return value, err
+	}
+	// A concurrent load may already have stored something newer
+	if e, ok := c.entries[key]; ok && e.version > version && c.now().Before(e.expires) {
+		return value, nil
+	}
+	c.store(key, entry[V]{value: value, version: version, expires: c.now().Add(c.ttl)})
+	return value, nil
+}
+
+// Invalidate drops key's entry unless it was already read at version or
+// later. A version of 0 means unknown and always drops it.
+func (c *Cache[K, V]) Invalidate(key K, version int) {
+	if version <= 0 {
+		version = math.MaxInt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && e.version < version {
+		delete(c.entries, key)
+	}
+	if c.loading[key] > 0 && c.stale[key] < version {
+		c.stale[key] = version
+	}
+}
+
+// store saves an entry, sweeping out expired ones at most once per TTL so
+// keys that are never read again don't pile up. Callers hold c.mu.
+func (c *Cache[K, V]) store(key K, e entry[V]) {
+	now := c.now()
+	if now.After(c.nextSweep) {
+		for k, old := range c.entries {
+			if !now.Before(old.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+	c.entries[key] = e
+}
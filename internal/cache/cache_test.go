@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// row is a stand-in for a database row: loads count how often it was read
+type row struct {
+	value   string
+	version int
+	loads   int
+}
+
+func (r *row) load() (string, int, error) {
+	r.loads++
+	return r.value, r.version, nil
+}
+
+func TestCache_HitSkipsLoad(t *testing.T) {
+	c := New[int64, string]("test_hit", time.Minute)
+	r := &row{value: "v1", version: 1}
+
+	got, err := c.Get(1, r.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", got)
+
+	r.value = "changed underneath"
+	got, err = c.Get(1, r.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", got)
+	assert.Equal(t, 1, r.loads, "second read should come from the cache")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ReadCacheRequests.WithLabelValues("test_hit", "hit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ReadCacheRequests.WithLabelValues("test_hit", "miss")))
+}
+
+func TestCache_VersionBumpInvalidates(t *testing.T) {
+	c := New[int64, string]("test_version", time.Minute)
+	r := &row{value: "v3", version: 3}
+	_, err := c.Get(1, r.load)
+	require.NoError(t, err)
+
+	// An event for a version the entry already has changes nothing
+	c.Invalidate(1, 3)
+	_, err = c.Get(1, r.load)
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.loads)
+
+	r.value, r.version = "v4", 4
+	c.Invalidate(1, 4)
+	got, err := c.Get(1, r.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v4", got)
+	assert.Equal(t, 2, r.loads)
+
+	// Version 0 is unknown and always drops the entry
+	c.Invalidate(1, 0)
+	_, err = c.Get(1, r.load)
+	require.NoError(t, err)
+	assert.Equal(t, 3, r.loads)
+}
+
+func TestCache_Expires(t *testing.T) {
+	c := New[int64, string]("test_expiry", time.Second)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	r := &row{value: "v1", version: 1}
+
+	c.Get(1, r.load)
+	now = now.Add(999 * time.Millisecond)
+	c.Get(1, r.load)
+	assert.Equal(t, 1, r.loads)
+
+	now = now.Add(time.Millisecond)
+	c.Get(1, r.load)
+	assert.Equal(t, 2, r.loads)
+}
+
+func TestCache_InvalidatedWhileLoading(t *testing.T) {
+	c := New[int64, string]("test_inflight", time.Minute)
+	r := &row{value: "v1", version: 1}
+
+	// A bid lands between the read and the store: the old row isn't kept
+	got, err := c.Get(1, func() (string, int, error) {
+		c.Invalidate(1, 2)
+		return r.load()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "v1", got)
+
+	r.value, r.version = "v2", 2
+	got, err = c.Get(1, r.load)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", got)
+	assert.Equal(t, 2, r.loads)
+}
+
+func TestCache_ErrorsNotCached(t *testing.T) {
+	c := New[int64, string]("test_errors", time.Minute)
+	loads := 0
+	failing := func() (string, int, error) {
+		loads++
+		return "", 0, errors.New("boom")
+	}
+
+	_, err := c.Get(1, failing)
+	assert.Error(t, err)
+	_, err = c.Get(1, failing)
+	assert.Error(t, err)
+	assert.Equal(t, 2, loads)
+}
@@ -0,0 +1,540 @@
+package closer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+// NotificationPublisher pushes stored notifications to their owners' open streams
+type NotificationPublisher interface {
+	PublishNotification(n domain.Notification)
+}
+
+// PaymentQueue charges for new orders in the background
+type PaymentQueue interface {
+	Enqueue(orderID int64)
+}
+
+// Closer ends auctions whose time has run out: it records the winner, opens
+// the order and charges for it, and tells every bidder how the auction
+// turned out.
+type Closer struct {
+	db          *pgxpool.Pool
+	logger      *slog.Logger
+	interval    time.Duration
+	grace       time.Duration
+	batchSize   int
+	concurrency int
+	now         func() time.Time
+
+	broadcaster bidengine.Broadcaster
+	publisher   NotificationPublisher
+	notifier    bidengine.Notifier
+	payments    PaymentQueue
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Option configures the closer
+type Option func(*Closer)
+
+// WithInterval sets how often due auctions are swept
+func WithInterval(d time.Duration) Option {
+	return func(c *Closer) {
+		c.interval = d
+	}
+}
+
+// WithGrace delays closing past ends_at so bids still in the engine's
+// submit grace window can land first
+func WithGrace(d time.Duration) Option {
+	return func(c *Closer) {
+		c.grace = d
+	}
+}
+
+// WithConcurrency sets how many auctions a sweep closes in parallel, so a
+// burst of simultaneous endings doesn't queue up behind one another
+func WithConcurrency(n int) Option {
+	return func(c *Closer) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithClock sets the time source (tests)
+func WithClock(now func() time.Time) Option {
+	return func(c *Closer) {
+		c.now = now
+	}
+}
+
+// WithBroadcaster sends auction_ended to the auction's SSE subscribers
+func WithBroadcaster(b bidengine.Broadcaster) Option {
+	return func(c *Closer) {
+		c.broadcaster = b
+	}
+}
+
+// WithPublisher pushes auction_won / auction_lost to participants' notification streams
+func WithPublisher(p NotificationPublisher) Option {
+	return func(c *Closer) {
+		c.publisher = p
+	}
+}
+
+// WithNotifier sends auction.won to the winner's webhooks, and auction.ended
+// and order.created to the lifecycle endpoints if n is a bidengine.EventNotifier
+func WithNotifier(n bidengine.Notifier) Option {
+	return func(c *Closer) {
+		c.notifier = n
+	}
+}
+
+// WithPayments hands each order the closer opens to q to charge the winner.
+// Without one, orders stay pending_payment.
+func WithPayments(q PaymentQueue) Option {
+	return func(c *Closer) {
+		c.payments = q
+	}
+}
+
+// New creates an auction closer
+func New(db *pgxpool.Pool, logger *slog.Logger, opts ...Option) *Closer {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Closer{
+		db:          db,
+		logger:      logger,
+		interval:    5 * time.Second,
+		batchSize:   100,
+		concurrency: 1,
+		now:         time.Now,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Start begins sweeping for due auctions every interval
+func (c *Closer) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := c.RunOnce(c.ctx); err != nil && c.ctx.Err() == nil {
+					c.logger.Error("auction_close_sweep_failed", slog.String("error", err.Error()))
+				}
+			}
+		}
+	}()
+
+	c.logger.Info("auction_closer_started",
+		slog.Duration("interval", c.interval),
+		slog.Int("concurrency", c.concurrency),
+	)
+}
+
+// Stop waits for an in-flight sweep to finish
+func (c *Closer) Stop() {
+	c.cancel()
+	c.wg.Wait()
+	c.logger.Info("auction_closer_stopped")
+}
+
+// RunOnce closes up to a batch of active auctions that are past their end
+// time and grace, returning how many were closed. Workers claim due auctions
+// one at a time with SKIP LOCKED, so they never wait on each other or on an
+// auction a bid is updating; that one is simply picked up next sweep.
+func (c *Closer) RunOnce(ctx context.Context) (int, error) {
+	cutoff := c.now().Add(-c.grace)
+
+	var (
+		claimed  atomic.Int64
+		closed   atomic.Int64
+		mu       sync.Mutex
+		failed   = []int64{}
+		sweepErr error
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for claimed.Add(1) <= int64(c.batchSize) && ctx.Err() == nil {
+				mu.Lock()
+				skip := append([]int64(nil), failed...)
+				mu.Unlock()
+
+				auctionID, ok, err := c.closeNext(ctx, cutoff, skip)
+				if errors.Is(err, pgx.ErrNoRows) {
+					return
+				}
+				if err != nil {
+					if auctionID == 0 {
+						// Couldn't even claim one; the database is likely unavailable
+						mu.Lock()
+						sweepErr = err
+						mu.Unlock()
+						return
+					}
+					c.logger.Error("auction_close_failed",
+						slog.Int64("auction_id", auctionID),
+						slog.String("error", err.Error()),
+					)
+					// Rolled back and still due; don't claim it again this sweep
+					mu.Lock()
+					failed = append(failed, auctionID)
+					mu.Unlock()
+					continue
+				}
+				if ok {
+					closed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return int(closed.Load()), sweepErr
+}
+
+// closing is what Close learned while ending an auction, kept for the
+// side effects that run after commit
+type closing struct {
+	auctionID     int64
+	vehicleID     int64
+	sellerID      int64
+	orderID       int64
+	endsAt        time.Time
+	bidCount      int
+	finalPrice    decimal.Decimal
+	winnerID      *int64
+	relisting     *Relisting
+	notifications []domain.Notification
+}
+
+// claimColumns is the auction state Close reads under the row lock
+const claimColumns = `
+	SELECT a.id, a.status::text, a.ends_at, a.current_bid, a.current_bid_user_id, a.bid_count,
+	       a.vehicle_id, v.seller_id, v.year, v.make, v.model, v.reserve_price,
+	       a.auto_relist_price_drops, a.auto_relist_round
+	FROM auctions a
+	JOIN vehicles v ON a.vehicle_id = v.id
+`
+
+// Close ends a single auction if it is still active and due. It reports
+// false when another closer or an admin got there first.
+func (c *Closer) Close(ctx context.Context, auctionID int64) (bool, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, ok, err := c.close(ctx, tx, tx.QueryRow(ctx, claimColumns+`
+		WHERE a.id = $1
+		FOR UPDATE OF a
+	`, auctionID))
+	return ok, err
+}
+
+// closeNext claims the most overdue auction nobody else has locked and closes
+// it. It returns pgx.ErrNoRows when nothing is left to claim, and the claimed
+// auction's ID alongside any error closing it.
+func (c *Closer) closeNext(ctx context.Context, cutoff time.Time, skip []int64) (int64, bool, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	return c.close(ctx, tx, tx.QueryRow(ctx, claimColumns+`
+		WHERE a.status = 'active' AND a.ends_at <= $1 AND a.id <> ALL($2)
+		ORDER BY a.ends_at
+		LIMIT 1
+		FOR UPDATE OF a SKIP LOCKED
+	`, cutoff, skip))
+}
+
+// close ends the auction read by row, which must hold its lock within tx,
+// and commits. It returns the auction's ID once the row has been read.
+func (c *Closer) close(ctx context.Context, tx pgx.Tx, row pgx.Row) (int64, bool, error) {
+	var (
+		status             string
+		leaderID           *int64
+		year               int
+		vehicleMake, model string
+		reservePrice       decimal.NullDecimal
+		priceDrops         []int16
+		relistRound        int16
+	)
+	var cl closing
+	err := row.Scan(
+		&cl.auctionID, &status, &cl.endsAt, &cl.finalPrice, &leaderID, &cl.bidCount,
+		&cl.vehicleID, &cl.sellerID, &year, &vehicleMake, &model, &reservePrice,
+		&priceDrops, &relistRound,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	if status != "active" || cl.endsAt.After(c.now().Add(-c.grace)) {
+		return cl.auctionID, false, nil
+	}
+	auctionID := cl.auctionID
+
+	reserveMet := !reservePrice.Valid || cl.finalPrice.GreaterThanOrEqual(reservePrice.Decimal)
+	if leaderID != nil && cl.bidCount > 0 && reserveMet {
+		cl.winnerID = leaderID
+	}
+
+	var winningBid *decimal.Decimal
+	if cl.winnerID != nil {
+		winningBid = &cl.finalPrice
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE auctions
+		SET status = 'ended', winner_id = $2, winning_bid = $3, version = version + 1, updated_at = NOW()
+		WHERE id = $1
+	`, auctionID, cl.winnerID, winningBid)
+	if err != nil {
+		return auctionID, false, fmt.Errorf("end auction: %w", err)
+	}
+	err = bidengine.RecordAuctionEvent(ctx, tx, auctionID, bidengine.EventClosed, map[string]any{
+		"reason":      "expired",
+		"winner_id":   cl.winnerID,
+		"final_price": cl.finalPrice,
+		"bid_count":   cl.bidCount,
+		"reserve_met": reserveMet,
+	})
+	if err != nil {
+		return auctionID, false, fmt.Errorf("record close event: %w", err)
+	}
+
+	if cl.winnerID != nil {
+		// orders.auction_id is unique, so a close that races another path to
+		// the order finds it already there. That counts as done; orderID stays
+		// 0 so order.created is only sent by whoever created it.
+		err = tx.QueryRow(ctx, `
+			INSERT INTO orders (auction_id, buyer_id, seller_id, vehicle_id, sale_price, total_price)
+			VALUES ($1, $2, $3, $4, $5, $5)
+			ON CONFLICT (auction_id) DO NOTHING
+			RETURNING id
+		`, auctionID, *cl.winnerID, cl.sellerID, cl.vehicleID, cl.finalPrice).Scan(&cl.orderID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return auctionID, false, fmt.Errorf("create order: %w", err)
+		}
+		if _, err = tx.Exec(ctx, `UPDATE vehicles SET status = 'sold' WHERE id = $1`, cl.vehicleID); err != nil {
+			return auctionID, false, fmt.Errorf("mark vehicle sold: %w", err)
+		}
+	}
+
+	vehicle := fmt.Sprintf("%d %s %s", year, vehicleMake, model)
+	cl.notifications, err = c.notifyParticipants(ctx, tx, cl, vehicle, reserveMet)
+	if err != nil {
+		return auctionID, false, fmt.Errorf("notify participants: %w", err)
+	}
+
+	// Opted-in auctions that missed their reserve go straight back up, cheaper
+	if !reserveMet && int(relistRound) < len(priceDrops) {
+		relisting, err := Relist(ctx, tx, auctionID, int(priceDrops[relistRound]), c.now())
+		if err != nil {
+			return auctionID, false, fmt.Errorf("relist: %w", err)
+		}
+		cl.relisting = &relisting
+
+		n, err := c.notifySellerRelisted(ctx, tx, cl, vehicle, len(priceDrops))
+		if err != nil {
+			return auctionID, false, fmt.Errorf("notify seller: %w", err)
+		}
+		cl.notifications = append(cl.notifications, n)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return auctionID, false, err
+	}
+
+	c.publish(cl)
+	if c.payments != nil && cl.orderID != 0 {
+		c.payments.Enqueue(cl.orderID)
+	}
+
+	c.logger.Info("auction_closed",
+		slog.Int64("auction_id", auctionID),
+		slog.Bool("sold", cl.winnerID != nil),
+		slog.String("final_price", cl.finalPrice.StringFixed(2)),
+		slog.Int("participants", len(cl.notifications)),
+	)
+	if cl.relisting != nil {
+		c.logger.Info("auction_auto_relisted",
+			slog.Int64("auction_id", auctionID),
+			slog.Int64("new_auction_id", cl.relisting.AuctionID),
+			slog.Int("round", cl.relisting.Round),
+			slog.String("starting_price", cl.relisting.StartingPrice.StringFixed(2)),
+		)
+	}
+	return auctionID, true, nil
+}
+
+// notifySellerRelisted tells the seller their unsold vehicle went back up and at what prices
+func (c *Closer) notifySellerRelisted(ctx context.Context, tx pgx.Tx, cl closing, vehicle string, rounds int) (domain.Notification, error) {
+	r := cl.relisting
+	data := map[string]any{
+		"auction_id":     cl.auctionID,
+		"new_auction_id": r.AuctionID,
+		"vehicle_id":     cl.vehicleID,
+		"final_price":    cl.finalPrice.StringFixed(2),
+		"starting_price": r.StartingPrice.StringFixed(2),
+		"round":          r.Round,
+		"rounds":         rounds,
+		"ends_at":        r.EndsAt,
+	}
+	if r.ReservePrice.Valid {
+		data["reserve_price"] = r.ReservePrice.Decimal.StringFixed(2)
+	}
+
+	n := domain.Notification{
+		UserID:  cl.sellerID,
+		Type:    "auction_relisted",
+		Title:   "Auction relisted",
+		Message: fmt.Sprintf("Your %s didn't meet its reserve and was relisted starting at $%s.", vehicle, r.StartingPrice.StringFixed(2)),
+		Data:    data,
+	}
+	err := tx.QueryRow(ctx, `
+		INSERT INTO notifications (user_id, type, title, message, data)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, n.UserID, n.Type, n.Title, n.Message, n.Data).Scan(&n.ID, &n.CreatedAt)
+	return n, err
+}
+
+// notifyParticipants stores auction_won for the winner and auction_lost for
+// every other distinct bidder
+func (c *Closer) notifyParticipants(ctx context.Context, tx pgx.Tx, cl closing, vehicle string, reserveMet bool) ([]domain.Notification, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT user_id FROM bids
+		WHERE auction_id = $1 AND status NOT IN ('rejected', 'retracted')
+	`, cl.auctionID)
+	if err != nil {
+		return nil, err
+	}
+	bidders, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+
+	price := cl.finalPrice.StringFixed(2)
+	notifications := make([]domain.Notification, 0, len(bidders))
+	for _, userID := range bidders {
+		n := domain.Notification{
+			UserID: userID,
+			Data: map[string]any{
+				"auction_id":  cl.auctionID,
+				"vehicle_id":  cl.vehicleID,
+				"final_price": price,
+				"reserve_met": reserveMet,
+			},
+		}
+		switch {
+		case cl.winnerID != nil && *cl.winnerID == userID:
+			n.Type = "auction_won"
+			n.Title = "You won the auction"
+			n.Message = fmt.Sprintf("You won the %s for $%s.", vehicle, price)
+		case cl.winnerID != nil:
+			n.Type = "auction_lost"
+			n.Title = "Auction ended"
+			n.Message = fmt.Sprintf("The %s sold to another bidder for $%s.", vehicle, price)
+		default:
+			n.Type = "auction_lost"
+			n.Title = "Auction ended"
+			n.Message = fmt.Sprintf("The %s ended without meeting the reserve.", vehicle)
+		}
+
+		err := tx.QueryRow(ctx, `
+			INSERT INTO notifications (user_id, type, title, message, data)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		`, n.UserID, n.Type, n.Title, n.Message, n.Data).Scan(&n.ID, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+// publish fans out a committed close to SSE subscribers and webhooks
+func (c *Closer) publish(cl closing) {
+	if c.broadcaster != nil {
+		event := domain.BidEvent{
+			Type:      "auction_ended",
+			AuctionID: cl.auctionID,
+			Amount:    cl.finalPrice,
+			BidCount:  cl.bidCount,
+			EndsAt:    cl.endsAt,
+			Timestamp: c.now(),
+		}
+		if cl.winnerID != nil {
+			event.BidderID = *cl.winnerID
+		}
+		c.broadcaster.Broadcast(event)
+	}
+	if c.publisher != nil {
+		for _, n := range cl.notifications {
+			c.publisher.PublishNotification(n)
+		}
+	}
+	if c.notifier != nil && cl.winnerID != nil {
+		c.notifier.NotifyUser(*cl.winnerID, "auction.won", map[string]interface{}{
+			"auction_id": cl.auctionID,
+			"amount":     cl.finalPrice,
+			"ended_at":   cl.endsAt,
+		})
+	}
+	if events, ok := c.notifier.(bidengine.EventNotifier); ok {
+		events.NotifyEvent("auction.ended", map[string]any{
+			"auction_id":  cl.auctionID,
+			"vehicle_id":  cl.vehicleID,
+			"reason":      "expired",
+			"sold":        cl.winnerID != nil,
+			"winner_id":   cl.winnerID,
+			"final_price": cl.finalPrice.StringFixed(2),
+			"bid_count":   cl.bidCount,
+			"ended_at":    cl.endsAt,
+		})
+		if cl.orderID != 0 {
+			events.NotifyEvent("order.created", map[string]any{
+				"order_id":   cl.orderID,
+				"auction_id": cl.auctionID,
+				"vehicle_id": cl.vehicleID,
+				"buyer_id":   *cl.winnerID,
+				"seller_id":  cl.sellerID,
+				"sale_price": cl.finalPrice.StringFixed(2),
+			})
+		}
+	}
+}
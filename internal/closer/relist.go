@@ -0,0 +1,96 @@
+package closer
+
+import (
+	"context"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// Relisting is the auction opened for a vehicle whose previous auction didn't sell
+type Relisting struct {
+	AuctionID     int64
+	Round         int
+	StartingPrice decimal.Decimal
+	ReservePrice  decimal.NullDecimal
+	StartsAt      time.Time
+	EndsAt        time.Time
+}
+
+// Relist opens a new auction for the vehicle of an ended auction, with the same
+// length and bidding rules, after cutting the vehicle's starting and reserve
+// prices by priceDropPct percent. It runs inside the caller's transaction.
+func Relist(ctx context.Context, tx pgx.Tx, auctionID int64, priceDropPct int, now time.Time) (Relisting, error) {
+	r := Relisting{StartsAt: now}
+
+	err := tx.QueryRow(ctx, `
+		UPDATE vehicles v
+		SET starting_price = ROUND(v.starting_price * (100 - $2::numeric) / 100, 0),
+		    reserve_price = ROUND(v.reserve_price * (100 - $2::numeric) / 100, 0),
+		    version = v.version + 1,
+		    updated_at = NOW()
+		FROM auctions a
+		WHERE a.id = $1 AND v.id = a.vehicle_id
+		RETURNING v.starting_price, v.reserve_price
+	`, auctionID, priceDropPct).Scan(&r.StartingPrice, &r.ReservePrice)
+	if err != nil {
+		return r, err
+	}
+
+	return openRelisting(ctx, tx, auctionID, r, "active", nil, 1)
+}
+
+// RelistOnRequest opens a new auction for the vehicle of an unsold auction at
+// the seller's request, at the vehicle's current prices. It is scheduled when
+// startsAt is in the future; a nil endsAt keeps the previous auction's length.
+// Unlike Relist it doesn't use up an automatic relist round. It runs inside the
+// caller's transaction.
+func RelistOnRequest(ctx context.Context, tx pgx.Tx, auctionID int64, startsAt time.Time, endsAt *time.Time, now time.Time) (Relisting, error) {
+	r := Relisting{StartsAt: startsAt}
+
+	err := tx.QueryRow(ctx, `
+		SELECT v.starting_price, v.reserve_price
+		FROM auctions a
+		JOIN vehicles v ON v.id = a.vehicle_id
+		WHERE a.id = $1
+	`, auctionID).Scan(&r.StartingPrice, &r.ReservePrice)
+	if err != nil {
+		return r, err
+	}
+
+	status := "active"
+	if startsAt.After(now) {
+		status = "scheduled"
+	}
+	return openRelisting(ctx, tx, auctionID, r, status, endsAt, 0)
+}
+
+// openRelisting inserts the follow-up auction with the previous one's bidding
+// rules, advancing its automatic relist round by roundStep, and records the
+// hand-off on the previous auction's event log.
+func openRelisting(ctx context.Context, tx pgx.Tx, auctionID int64, r Relisting, status string, endsAt *time.Time, roundStep int) (Relisting, error) {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO auctions (
+			vehicle_id, status, starts_at, ends_at,
+			max_extensions, snipe_threshold_minutes, extension_minutes, extend_on_reserve_met,
+			extend_on_leader_change_only, min_increment, relisted_from, auto_relist_price_drops, auto_relist_round
+		)
+		SELECT vehicle_id, $3::auction_status, $2::timestamptz, COALESCE($4::timestamptz, $2::timestamptz + (ends_at - starts_at)),
+		       max_extensions, snipe_threshold_minutes, extension_minutes, extend_on_reserve_met,
+		       extend_on_leader_change_only, min_increment, id, auto_relist_price_drops, auto_relist_round + $5
+		FROM auctions WHERE id = $1
+		RETURNING id, ends_at, auto_relist_round
+	`, auctionID, r.StartsAt, status, endsAt, roundStep).Scan(&r.AuctionID, &r.EndsAt, &r.Round)
+	if err != nil {
+		return r, err
+	}
+
+	err = bidengine.RecordAuctionEvent(ctx, tx, auctionID, bidengine.EventRelisted, map[string]any{
+		"new_auction_id": r.AuctionID,
+		"round":          r.Round,
+		"starting_price": r.StartingPrice,
+	})
+	return r, err
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func productionConfig() *Config {
+	return &Config{
+		Environment:          "production",
+		ClerkSecretKey:       "sk_live_test",
+		SentryDSN:            "https://sentry.example.com/1",
+		CORSAllowCredentials: true,
+	}
+}
+
+func TestValidate_RejectsWildcardOriginWithCredentialsInProduction(t *testing.T) {
+	cfg := productionConfig()
+	cfg.CORSAllowedOrigins = []string{"https://app.example.com", " * "}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CORS_ALLOWED_ORIGINS")
+}
+
+func TestValidate_AllowsWildcardWithoutCredentials(t *testing.T) {
+	cfg := productionConfig()
+	cfg.CORSAllowCredentials = false
+	cfg.CORSAllowedOrigins = []string{"*"}
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_AllowsWildcardOutsideProduction(t *testing.T) {
+	cfg := &Config{
+		Environment:          "development",
+		CORSAllowCredentials: true,
+		CORSAllowedOrigins:   []string{"*"},
+	}
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_NormalizesMultipleOrigins(t *testing.T) {
+	cfg := productionConfig()
+	cfg.CORSAllowedOrigins = []string{
+		" https://App.Example.com/ ",
+		"https://admin.example.com",
+		"",
+		"https://app.example.com",
+	}
+
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"https://app.example.com", "https://admin.example.com"}, cfg.CORSAllowedOrigins)
+}
+
+func TestValidate_SSERelay(t *testing.T) {
+	for _, relay := range []string{"", "postgres"} {
+		cfg := &Config{Environment: "development", SSERelay: relay}
+		assert.NoError(t, cfg.Validate(), relay)
+	}
+
+	cfg := &Config{Environment: "development", SSERelay: "redis"}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SSE_RELAY")
+}
+
+func TestValidate_BidResultStore(t *testing.T) {
+	for _, store := range []string{"", "redis"} {
+		cfg := &Config{Environment: "development", BidResultStore: store}
+		assert.NoError(t, cfg.Validate(), store)
+	}
+
+	cfg := &Config{Environment: "development", BidResultStore: "memcached"}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BID_RESULT_STORE")
+}
+
+func TestHSTSHeaderMaxAge_OnlyInProduction(t *testing.T) {
+	cfg := productionConfig()
+	cfg.HSTSMaxAge = 24 * time.Hour
+	assert.Equal(t, 24*time.Hour, cfg.HSTSHeaderMaxAge())
+
+	cfg.HSTSMaxAge = 0
+	assert.Zero(t, cfg.HSTSHeaderMaxAge())
+
+	dev := &Config{Environment: "development", HSTSMaxAge: 24 * time.Hour}
+	assert.Zero(t, dev.HSTSHeaderMaxAge())
+}
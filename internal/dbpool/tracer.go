@@ -0,0 +1,90 @@
+// Package dbpool instruments the pgx connection pool: how long queries wait
+// for a connection, and a bound on that wait so a saturated pool fails fast
+// with ErrExhausted instead of holding requests until they time out.
+package dbpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrExhausted is returned by pool queries that gave up waiting for a free
+// connection. It wraps context.DeadlineExceeded, so timeout checks match it
+// too; test for it first to tell overload from a slow query.
+var ErrExhausted = fmt.Errorf("database connection pool exhausted: %w", context.DeadlineExceeded)
+
+// IsExhausted reports whether err came from waiting on a saturated pool
+func IsExhausted(err error) bool {
+	return errors.Is(err, ErrExhausted)
+}
+
+// Tracer is a pgxpool.AcquireTracer. Install it as the pool config's
+// ConnConfig.Tracer.
+type Tracer struct {
+	acquireTimeout time.Duration
+}
+
+// NewTracer creates a tracer that gives up with ErrExhausted after waiting
+// acquireTimeout for a connection on a pool with every connection in use. A
+// zero timeout waits as long as the caller's context allows.
+func NewTracer(acquireTimeout time.Duration) *Tracer {
+	return &Tracer{acquireTimeout: acquireTimeout}
+}
+
+// acquireContext is the context the pool acquires under. Once its own
+// deadline passes while the caller's context is still live, Err reports
+// ErrExhausted, which the pool hands back as the query's error.
+type acquireContext struct {
+	context.Context
+	caller context.Context
+	start  time.Time
+	cancel context.CancelFunc
+}
+
+func (c *acquireContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && c.caller.Err() == nil {
+		return ErrExhausted
+	}
+	return err
+}
+
+// TraceAcquireStart starts timing the wait. Only a pool that is already full
+// gets the acquire timeout, so dialing a new connection is never cut short.
+func (t *Tracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	ac := &acquireContext{Context: ctx, caller: ctx, start: time.Now()}
+	if t.acquireTimeout > 0 {
+		if stat := pool.Stat(); stat.AcquiredConns() >= stat.MaxConns() {
+			ac.Context, ac.cancel = context.WithTimeout(ctx, t.acquireTimeout)
+		}
+	}
+	return ac
+}
+
+// TraceAcquireEnd records the wait and the connections now in use
+func (t *Tracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, _ pgxpool.TraceAcquireEndData) {
+	ac, ok := ctx.(*acquireContext)
+	if !ok {
+		return
+	}
+	if ac.cancel != nil {
+		ac.cancel()
+	}
+	metrics.DBPoolWait.Observe(time.Since(ac.start).Seconds())
+	metrics.DBConnectionsActive.Set(float64(pool.Stat().AcquiredConns()))
+}
+
+// TraceQueryStart is a no-op; pgx requires the pool tracer to be a query
+// tracer as well
+func (t *Tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+// TraceQueryEnd is a no-op
+func (t *Tracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
@@ -0,0 +1,40 @@
+package dbpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireContext_Err(t *testing.T) {
+	t.Run("own deadline reports exhaustion", func(t *testing.T) {
+		caller := context.Background()
+		inner, cancel := context.WithTimeout(caller, time.Millisecond)
+		defer cancel()
+		ac := &acquireContext{Context: inner, caller: caller}
+
+		<-ac.Done()
+		err := ac.Err()
+		assert.True(t, IsExhausted(err))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "still reads as a timeout")
+	})
+
+	t.Run("caller ending is passed through", func(t *testing.T) {
+		caller, cancelCaller := context.WithCancel(context.Background())
+		inner, cancel := context.WithTimeout(caller, time.Hour)
+		defer cancel()
+		ac := &acquireContext{Context: inner, caller: caller}
+
+		cancelCaller()
+		<-ac.Done()
+		assert.Equal(t, context.Canceled, ac.Err())
+	})
+
+	t.Run("unbounded wait", func(t *testing.T) {
+		ac := &acquireContext{Context: context.Background(), caller: context.Background()}
+		assert.NoError(t, ac.Err())
+	})
+}
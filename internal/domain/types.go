@@ -15,6 +15,14 @@ type BidRequest struct {
 	MaxBid    decimal.Decimal `json:"max_bid,omitempty"` // For auto-bidding
 	TraceID   string          `json:"trace_id,omitempty"`
 	CreatedAt time.Time       `json:"created_at"`
+	
+	// ClientSubmittedAt is the client's claimed send time, honored within the engine's bid grace
+	ClientSubmittedAt time.Time `json:"client_submitted_at,omitempty"`
+	
+	// Proxy bidding: ProxyOnly enters at the minimum next bid with MaxBid as the
+	// ceiling; AutoBid marks a bid the engine placed to defend a leader's proxy
+	ProxyOnly bool `json:"proxy_only,omitempty"`
+	AutoBid   bool `json:"auto_bid,omitempty"`
 }
 
 // BidResult is the outcome of processing a bid
@@ -26,23 +34,72 @@ type BidResult struct {
 	Amount          decimal.Decimal `json:"amount"`
 	PreviousHighBid decimal.Decimal `json:"previous_high_bid,omitempty"`
 	NewHighBid      decimal.Decimal `json:"new_high_bid,omitempty"`
+	NextMinBid      decimal.Decimal `json:"next_min_bid,omitempty"` // Lowest amount that would be accepted now; set when a bid loses on price
 	AuctionID       int64           `json:"auction_id"`
 	ProcessedAt     time.Time       `json:"processed_at"`
 	Retries         int             `json:"retries,omitempty"`
+	
+	// Auction state read once the bid finished, so status pollers see the outcome in context
+	Auction   *AuctionSnapshot `json:"auction,omitempty"`
+	IsWinning bool             `json:"is_winning"`
+}
+
+// AuctionSnapshot is the public view of an auction at bid completion
+type AuctionSnapshot struct {
+	Status     string          `json:"status"`
+	CurrentBid decimal.Decimal `json:"current_bid"`
+	BidCount   int             `json:"bid_count"`
+	EndsAt     time.Time       `json:"ends_at"`
 }
 
 // BidEvent is broadcast to SSE subscribers
 type BidEvent struct {
-	Type             string          `json:"type"` // "bid_accepted", "bid_outbid", "auction_extended"
+	Type             string          `json:"type"` // "bid_accepted", "bid_outbid", "auction_extended", "viewer_count"
 	AuctionID        int64           `json:"auction_id"`
 	Amount           decimal.Decimal `json:"amount,omitempty"`
 	BidderID         int64           `json:"bidder_id,omitempty"`
 	BidCount         int             `json:"bid_count,omitempty"`
 	EndsAt           time.Time       `json:"ends_at,omitempty"`
 	ExtensionApplied bool            `json:"extension_applied,omitempty"`
+	Viewers          int             `json:"viewers,omitempty"`
+	Version          int             `json:"version,omitempty"` // Auction version after the change, when known
 	Timestamp        time.Time       `json:"timestamp"`
 }
 
+// Notification is an in-app notification; it is stored in the notifications
+// table and pushed to the owner's notification stream
+type Notification struct {
+	ID        int64          `json:"id"`
+	UserID    int64          `json:"-"`
+	Type      string         `json:"type"` // One of NotificationTypes
+	Title     string         `json:"title"`
+	Message   string         `json:"message,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+	Read      bool           `json:"read"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// NotificationTypes are the notification types the server creates, which the
+// notifications list accepts as ?type= filters
+var NotificationTypes = map[string]bool{
+	"outbid":           true,
+	"auction_ending":   true,
+	"auction_won":      true,
+	"auction_lost":     true,
+	"auction_relisted": true,
+	"draft_stale":      true,
+	"draft_archived":   true,
+}
+
+// AuctionEvent is one entry in an auction's append-only audit log
+type AuctionEvent struct {
+	ID        int64          `json:"id"`
+	AuctionID int64          `json:"auction_id"`
+	Type      string         `json:"type"` // "bid_accepted", "outbid", "extended", "closed", "cancelled"
+	Payload   map[string]any `json:"payload"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
 // SSEMessage wraps events for SSE transmission
 type SSEMessage struct {
 	Event string `json:"event"`
@@ -57,19 +114,69 @@ type AuctionState struct {
 	CurrentBidUserID   *int64
 	BidCount           int
 	Version            int
+	StartsAt           time.Time
 	EndsAt             time.Time
 	ExtensionCount     int
 	MaxExtensions      int
+	ExtendedSeconds    int // Time added by extensions so far
 	SnipeThresholdMins int
 	ExtensionMins      int
+	StartingPrice      decimal.Decimal // Floor for the opening bid
+	
+	// Reserve-met extension (per auction, fires at most once)
+	ReservePrice            decimal.NullDecimal
+	ExtendOnReserveMet      bool
+	ReserveExtensionApplied bool
+	
+	// Snipe extensions only when the bid changes the leader (per auction)
+	ExtendOnLeaderChangeOnly bool
+	
+	// Per-auction bid step; the engine default applies when unset
+	MinIncrement decimal.NullDecimal
+	
+	// Proxy ceiling on the current leader's bid, when they bid with a max
+	LeaderMaxBid decimal.NullDecimal
+	
+	// Invite-only auctions take bids from invited users alone
+	Private       bool
+	BidderInvited bool // Whether the bidder being processed is invited
+	
+	// hide_bidder_identity for the bidder being processed and the current
+	// leader, so broadcasts don't name either when they've opted out
+	BidderHidden bool
+	LeaderHidden bool
 }
 
 // User verification status
 type UserVerification struct {
-	UserID     int64
-	CanBid     bool
-	Reason     string
-	VerifiedAt *time.Time
+	UserID     int64      `json:"user_id"`
+	CanBid     bool       `json:"can_bid"`
+	Reason     string     `json:"reason,omitempty"` // First blocker, for a single prompt
+	Reasons    []string   `json:"reasons"`          // Every blocker, empty when CanBid
+	VerifiedAt *time.Time `json:"id_verified_at"`
+}
+
+// Reasons a user can't bid yet
+const (
+	BidBlockerIDNotVerified   = "id_not_verified"
+	BidBlockerNoPaymentMethod = "no_payment_method"
+)
+
+// NewUserVerification works out whether a user can bid from their ID
+// verification and payment profile, listing what still blocks them
+func NewUserVerification(userID int64, verifiedAt *time.Time, hasPaymentMethod bool) UserVerification {
+	v := UserVerification{UserID: userID, VerifiedAt: verifiedAt, Reasons: make([]string, 0, 2)}
+	if verifiedAt == nil {
+		v.Reasons = append(v.Reasons, BidBlockerIDNotVerified)
+	}
+	if !hasPaymentMethod {
+		v.Reasons = append(v.Reasons, BidBlockerNoPaymentMethod)
+	}
+	v.CanBid = len(v.Reasons) == 0
+	if !v.CanBid {
+		v.Reason = v.Reasons[0]
+	}
+	return v
 }
 
 // Pagination
@@ -78,6 +185,8 @@ type Pagination struct {
 	Offset int `json:"offset"`
 }
 
+// PaginatedResponse is the envelope every list endpoint returns. Endpoints
+// with extra top-level fields embed it.
 type PaginatedResponse[T any] struct {
 	Items      []T   `json:"items"`
 	Total      int64 `json:"total"`
@@ -86,6 +195,21 @@ type PaginatedResponse[T any] struct {
 	HasMore    bool  `json:"has_more"`
 }
 
+// NewPaginatedResponse wraps one page of items, total being the count across
+// all pages. A nil page is sent as an empty list.
+func NewPaginatedResponse[T any](items []T, total int64, limit, offset int) PaginatedResponse[T] {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	return PaginatedResponse[T]{
+		Items:   items,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: int64(offset+len(items)) < total,
+	}
+}
+
 // API response wrappers
 type APIResponse struct {
 	Success bool   `json:"success"`
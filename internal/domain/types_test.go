@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPaginatedResponse(t *testing.T) {
+	page := NewPaginatedResponse([]int{1, 2}, 5, 2, 2)
+	assert.True(t, page.HasMore)
+	assert.False(t, NewPaginatedResponse([]int{5}, 5, 2, 4).HasMore)
+
+	// A nil page still encodes as an empty list
+	data, err := json.Marshal(NewPaginatedResponse[string](nil, 0, 20, 0))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items":[],"total":0,"limit":20,"offset":0,"has_more":false}`, string(data))
+}
@@ -0,0 +1,31 @@
+package domain
+
+// Allowed values for categorical vehicle fields. Stored verbatim, so the
+// casing here is what lands in the database and what dropdowns display.
+var (
+	TitleStatuses   = []string{"clean", "salvage", "rebuilt", "flood", "lemon", "parts_only"}
+	ConditionGrades = []string{"A+", "A", "A-", "B+", "B", "B-", "C+", "C", "C-", "D", "F"}
+	FuelTypes       = []string{"Gasoline", "Diesel", "Hybrid", "Plug-in Hybrid", "Electric", "Flex Fuel"}
+	Drivetrains     = []string{"FWD", "RWD", "AWD", "4WD"}
+	Transmissions   = []string{"Automatic", "Manual", "CVT", "Dual-Clutch", "PDK"}
+)
+
+// VehicleEnumFields maps each categorical field's JSON name to its allowed values
+var VehicleEnumFields = map[string][]string{
+	"title_status":    TitleStatuses,
+	"condition_grade": ConditionGrades,
+	"fuel_type":       FuelTypes,
+	"drivetrain":      Drivetrains,
+	"transmission":    Transmissions,
+}
+
+// IsValidVehicleEnum reports whether value is allowed for the given field.
+// Unknown fields are never valid.
+func IsValidVehicleEnum(field, value string) bool {
+	for _, allowed := range VehicleEnumFields[field] {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
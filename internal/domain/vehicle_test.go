@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidVehicleEnum(t *testing.T) {
+	tests := []struct {
+		field string
+		value string
+		want  bool
+	}{
+		{"title_status", "clean", true},
+		{"title_status", "salvage", true},
+		{"title_status", "Clean", false},
+		{"title_status", "totaled-ish", false},
+		{"condition_grade", "A+", true},
+		{"condition_grade", "B", true},
+		{"condition_grade", "Z", false},
+		{"fuel_type", "Electric", true},
+		{"fuel_type", "Nuclear", false},
+		{"drivetrain", "AWD", true},
+		{"drivetrain", "6WD", false},
+		{"transmission", "Manual", true},
+		{"transmission", "", false},
+		{"body_type", "Sedan", false}, // not an enum field
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+"="+tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidVehicleEnum(tt.field, tt.value))
+		})
+	}
+}
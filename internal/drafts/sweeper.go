@@ -0,0 +1,252 @@
+package drafts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NotificationPublisher pushes stored notifications to their owners' open streams
+type NotificationPublisher interface {
+	PublishNotification(n domain.Notification)
+}
+
+// Sweeper finds drafts nobody has touched in a while. It warns the seller
+// first, then archives the draft if it is still untouched after a grace
+// period. Archived drafts keep archived_at set so the seller can restore them.
+type Sweeper struct {
+	db           *pgxpool.Pool
+	logger       *slog.Logger
+	interval     time.Duration
+	staleAfter   time.Duration
+	archiveAfter time.Duration
+	batchSize    int
+	now          func() time.Time
+	publisher    NotificationPublisher
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Option configures the sweeper
+type Option func(*Sweeper)
+
+// WithInterval sets how often drafts are swept
+func WithInterval(d time.Duration) Option {
+	return func(s *Sweeper) {
+		s.interval = d
+	}
+}
+
+// WithStaleAfter sets how long a draft may sit unedited before the seller is warned
+func WithStaleAfter(d time.Duration) Option {
+	return func(s *Sweeper) {
+		s.staleAfter = d
+	}
+}
+
+// WithArchiveAfter sets how long after the warning an untouched draft is archived
+func WithArchiveAfter(d time.Duration) Option {
+	return func(s *Sweeper) {
+		s.archiveAfter = d
+	}
+}
+
+// WithClock sets the time source (tests)
+func WithClock(now func() time.Time) Option {
+	return func(s *Sweeper) {
+		s.now = now
+	}
+}
+
+// WithPublisher pushes draft_stale / draft_archived to sellers' notification streams
+func WithPublisher(p NotificationPublisher) Option {
+	return func(s *Sweeper) {
+		s.publisher = p
+	}
+}
+
+// New creates a draft sweeper
+func New(db *pgxpool.Pool, logger *slog.Logger, opts ...Option) *Sweeper {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sweeper{
+		db:           db,
+		logger:       logger,
+		interval:     time.Hour,
+		staleAfter:   30 * 24 * time.Hour,
+		archiveAfter: 7 * 24 * time.Hour,
+		batchSize:    500,
+		now:          time.Now,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start begins sweeping every interval
+func (s *Sweeper) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				if _, _, err := s.RunOnce(s.ctx); err != nil && s.ctx.Err() == nil {
+					s.logger.Error("draft_sweep_failed", slog.String("error", err.Error()))
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("draft_sweeper_started",
+		slog.Duration("interval", s.interval),
+		slog.Duration("stale_after", s.staleAfter),
+		slog.Duration("archive_after", s.archiveAfter),
+	)
+}
+
+// Stop waits for an in-flight sweep to finish
+func (s *Sweeper) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	s.logger.Info("draft_sweeper_stopped")
+}
+
+// staleDraft is a draft picked up by a sweep
+type staleDraft struct {
+	vehicleID int64
+	sellerID  int64
+	year      int
+	make      string
+	model     string
+}
+
+func (d staleDraft) name() string {
+	return fmt.Sprintf("%d %s %s", d.year, d.make, d.model)
+}
+
+// RunOnce archives drafts whose warning has expired, then warns the sellers
+// of newly stale drafts. It returns how many drafts were warned and archived.
+func (s *Sweeper) RunOnce(ctx context.Context) (warned, archived int, err error) {
+	now := s.now()
+
+	archived, err = s.sweep(ctx, `
+		UPDATE vehicles SET status = 'archived', archived_at = $1
+		WHERE id IN (
+			SELECT id FROM vehicles
+			WHERE status = 'draft' AND stale_draft_notified_at <= $2
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, seller_id, year, make, model
+	`, now, now.Add(-s.archiveAfter), func(d staleDraft) domain.Notification {
+		return domain.Notification{
+			UserID:  d.sellerID,
+			Type:    "draft_archived",
+			Title:   "Draft archived",
+			Message: fmt.Sprintf("Your %s draft was archived after going unedited. You can restore it from your listings.", d.name()),
+			Data:    map[string]any{"vehicle_id": d.vehicleID},
+		}
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("archive drafts: %w", err)
+	}
+
+	archiveAt := now.Add(s.archiveAfter)
+	warned, err = s.sweep(ctx, `
+		UPDATE vehicles SET stale_draft_notified_at = $1
+		WHERE id IN (
+			SELECT id FROM vehicles
+			WHERE status = 'draft' AND stale_draft_notified_at IS NULL AND updated_at <= $2
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, seller_id, year, make, model
+	`, now, now.Add(-s.staleAfter), func(d staleDraft) domain.Notification {
+		return domain.Notification{
+			UserID:  d.sellerID,
+			Type:    "draft_stale",
+			Title:   "Your draft will be archived soon",
+			Message: fmt.Sprintf("Your %s draft hasn't been edited in a while. Edit or submit it by %s to keep it.", d.name(), archiveAt.Format("Jan 2, 2006")),
+			Data: map[string]any{
+				"vehicle_id": d.vehicleID,
+				"archive_at": archiveAt.UTC().Format(time.RFC3339),
+			},
+		}
+	})
+	if err != nil {
+		return 0, archived, fmt.Errorf("warn stale drafts: %w", err)
+	}
+
+	if warned > 0 || archived > 0 {
+		s.logger.Info("draft_sweep_completed",
+			slog.Int("warned", warned),
+			slog.Int("archived", archived),
+		)
+	}
+	return warned, archived, nil
+}
+
+// sweep runs one marking UPDATE and notifies each affected seller in the
+// same transaction, publishing the notifications after commit
+func (s *Sweeper) sweep(ctx context.Context, query string, now, cutoff time.Time, notification func(staleDraft) domain.Notification) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, now, cutoff, s.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	drafts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (staleDraft, error) {
+		var d staleDraft
+		err := row.Scan(&d.vehicleID, &d.sellerID, &d.year, &d.make, &d.model)
+		return d, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	notifications := make([]domain.Notification, 0, len(drafts))
+	for _, d := range drafts {
+		n := notification(d)
+		err := tx.QueryRow(ctx, `
+			INSERT INTO notifications (user_id, type, title, message, data)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		`, n.UserID, n.Type, n.Title, n.Message, n.Data).Scan(&n.ID, &n.CreatedAt)
+		if err != nil {
+			return 0, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	if s.publisher != nil {
+		for _, n := range notifications {
+			s.publisher.PublishNotification(n)
+		}
+	}
+	return len(drafts), nil
+}
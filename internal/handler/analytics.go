@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// analyticsBuckets maps the accepted ?bucket= values to date_trunc units
+var analyticsBuckets = map[string]string{
+	"hour": "hour",
+	"day":  "day",
+}
+
+type BidBucket struct {
+	Start   string `json:"start"`
+	Bids    int    `json:"bids"`
+	HighBid string `json:"high_bid"`
+}
+
+type AuctionAnalyticsResponse struct {
+	AuctionID     int64       `json:"auction_id"`
+	Status        string      `json:"status"`
+	WatcherCount  int         `json:"watcher_count"`
+	UniqueBidders int         `json:"unique_bidders"`
+	TotalBids     int         `json:"total_bids"`
+	Extensions    int         `json:"extensions"`
+	Bucket        string      `json:"bucket"`
+	BidsOverTime  []BidBucket `json:"bids_over_time"`
+}
+
+// GetAuctionAnalytics reports engagement on one of the caller's auctions:
+// watchers, distinct bidders, anti-sniping extensions, and accepted bids
+// grouped by hour or day. SSE viewer counts are only held in memory by the
+// broker, so they aren't included here.
+func (h *AuctionHandler) GetAuctionAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "hour"
+	}
+	unit, ok := analyticsBuckets[bucket]
+	if !ok {
+		h.jsonError(w, "bucket must be hour or day", http.StatusBadRequest)
+		return
+	}
+
+	resp := AuctionAnalyticsResponse{AuctionID: auctionID, Bucket: bucket}
+	var sellerID int64
+	err = h.db.QueryRow(ctx, `
+		SELECT v.seller_id, a.status::text
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+	`, auctionID).Scan(&sellerID, &resp.Status)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "auction") {
+		return
+	}
+
+	err = h.db.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM watchlist WHERE auction_id = $1),
+			(SELECT COUNT(DISTINCT user_id) FROM bids WHERE auction_id = $1 AND status <> 'retracted'),
+			(SELECT COUNT(*) FROM bids WHERE auction_id = $1 AND status <> 'retracted'),
+			(SELECT COUNT(*) FROM auction_events WHERE auction_id = $1 AND type = $2)
+	`, auctionID, bidengine.EventExtended).Scan(&resp.WatcherCount, &resp.UniqueBidders, &resp.TotalBids, &resp.Extensions)
+	if err != nil {
+		h.logger.Error("failed to aggregate auction analytics", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT date_trunc($2, created_at) AS bucket, COUNT(*), MAX(amount)
+		FROM bids
+		WHERE auction_id = $1 AND status <> 'retracted'
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`, auctionID, unit)
+	if err != nil {
+		h.logger.Error("failed to query bid buckets", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	resp.BidsOverTime = make([]BidBucket, 0)
+	for rows.Next() {
+		var start time.Time
+		var highBid float64
+		var b BidBucket
+		if err := rows.Scan(&start, &b.Bids, &highBid); err != nil {
+			writeQueryError(w, err)
+			return
+		}
+		b.Start = start.UTC().Format(time.RFC3339)
+		b.HighBid = strconv.FormatFloat(highBid, 'f', 2, 64)
+		resp.BidsOverTime = append(resp.BidsOverTime, b)
+	}
+	if err := rows.Err(); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
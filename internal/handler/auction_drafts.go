@@ -0,0 +1,380 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type UpdateDraftAuctionRequest struct {
+	// StartsAt and EndsAt replace the draft's schedule; either may be omitted
+	StartsAt string `json:"starts_at"`
+	EndsAt   string `json:"ends_at"`
+	// ReservePrice replaces the vehicle's reserve
+	ReservePrice *float64 `json:"reserve_price" validate:"omitempty,gt=0"`
+}
+
+// readyToList checks the seller's ID verification, listing and auction
+// limits and the vehicle's image minimum before an auction goes live, writing
+// the error response if any fails. It runs in the transaction that takes the
+// auction live and locks the seller's row first, so concurrent creates and
+// publishes for one seller count one at a time and can't overshoot the limits.
+func (h *AuctionHandler) readyToList(w http.ResponseWriter, r *http.Request, tx pgx.Tx, sellerID, vehicleID int64) bool {
+	ctx := r.Context()
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, sellerID); err != nil {
+		h.logger.Error("failed to lock seller", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return false
+	}
+
+	// Auctions can be created without submitting the vehicle, and a draft
+	// may have changed hands since, so SubmitVehicle's check isn't enough
+	verified, err := sellerCanList(ctx, tx, sellerID)
+	if err != nil {
+		h.logger.Error("failed to check seller verification", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return false
+	}
+	if !verified {
+		middleware.AuditDenied(h.logger, r, "auction", middleware.DeniedSellerNotVerified)
+		writeSellerNotVerified(w, http.StatusForbidden, "complete ID verification before listing a vehicle")
+		return false
+	}
+
+	atLimit, err := listingLimitReached(ctx, tx, sellerID, vehicleID, h.cfg.MaxActiveListingsPerSeller)
+	if err != nil {
+		h.logger.Error("failed to count active listings", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return false
+	}
+	if atLimit {
+		h.jsonError(w, listingLimitMessage(h.cfg.MaxActiveListingsPerSeller), http.StatusConflict)
+		return false
+	}
+
+	atLimit, err = auctionLimitReached(ctx, tx, sellerID, h.cfg.MaxActiveAuctionsPerSeller)
+	if err != nil {
+		h.logger.Error("failed to count active auctions", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return false
+	}
+	if atLimit {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": fmt.Sprintf("active auction limit reached: sellers may run at most %d auctions at once", h.cfg.MaxActiveAuctionsPerSeller),
+			"code":  "seller_auction_limit_reached",
+		})
+		return false
+	}
+
+	have, missing, err := missingImages(ctx, tx, vehicleID, h.cfg.MinImagesToSubmit)
+	if err != nil {
+		h.logger.Error("failed to count vehicle images", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return false
+	}
+	if missing > 0 {
+		h.jsonError(w, missingImagesMessage(h.cfg.MinImagesToSubmit, have, missing), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// auctionLimitReached reports whether the seller already runs limit open
+// (scheduled or active) auctions. Admins are exempt, and a limit of 0
+// disables the check.
+func auctionLimitReached(ctx context.Context, db rowQuerier, sellerID int64, limit int) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+
+	var admin bool
+	var open int
+	err := db.QueryRow(ctx, `
+		SELECT u.role = 'admin',
+		       (SELECT COUNT(*) FROM auctions a
+		        JOIN vehicles v ON a.vehicle_id = v.id
+		        WHERE v.seller_id = u.id AND a.status IN ('scheduled', 'active'))
+		FROM users u
+		WHERE u.id = $1
+	`, sellerID).Scan(&admin, &open)
+	if err != nil {
+		return false, err
+	}
+	return !admin && open >= limit, nil
+}
+
+// canViewDraft reports whether the requester is the draft's seller or an admin
+func (h *AuctionHandler) canViewDraft(r *http.Request, sellerID int64) bool {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		return false
+	}
+	if userID == sellerID {
+		return true
+	}
+
+	var admin bool
+	err := h.db.QueryRow(r.Context(), `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'admin')`, userID).Scan(&admin)
+	if err != nil {
+		h.logger.Error("failed to check draft auction access", slog.String("error", err.Error()))
+		return false
+	}
+	return admin
+}
+
+// UpdateDraftAuction lets the seller change a draft auction's schedule and the
+// vehicle's reserve before publishing it
+func (h *AuctionHandler) UpdateDraftAuction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateDraftAuctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		sellerID, vehicleID int64
+		status              string
+		startsAt, endsAt    time.Time
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT v.seller_id, a.vehicle_id, a.status::text, a.starts_at, a.ends_at
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+		FOR UPDATE OF a
+	`, auctionID).Scan(&sellerID, &vehicleID, &status, &startsAt, &endsAt)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "auction") {
+		return
+	}
+	if status != "draft" {
+		h.jsonError(w, "only draft auctions can be edited", http.StatusConflict)
+		return
+	}
+
+	if req.StartsAt != "" {
+		if startsAt, err = time.Parse(time.RFC3339, req.StartsAt); err != nil {
+			h.jsonError(w, "invalid starts_at format (use RFC3339)", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.EndsAt != "" {
+		if endsAt, err = time.Parse(time.RFC3339, req.EndsAt); err != nil {
+			h.jsonError(w, "invalid ends_at format (use RFC3339)", http.StatusBadRequest)
+			return
+		}
+	}
+	if !endsAt.After(startsAt) {
+		h.jsonError(w, "ends_at must be after starts_at", http.StatusBadRequest)
+		return
+	}
+	// A new schedule has to be one that could be published
+	if req.StartsAt != "" || req.EndsAt != "" {
+		if msg := h.endsAtTooSoon(endsAt, time.Now()); msg != "" {
+			h.jsonError(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE auctions SET starts_at = $2, ends_at = $3, version = version + 1, updated_at = NOW()
+		WHERE id = $1
+	`, auctionID, startsAt, endsAt)
+	if err != nil {
+		h.logger.Error("failed to update draft auction", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to update auction", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"auction_id": auctionID,
+		"status":     status,
+		"starts_at":  startsAt,
+		"ends_at":    endsAt,
+	}
+
+	if req.ReservePrice != nil {
+		// The reserve lives on the vehicle, so leave it alone while another
+		// auction for the vehicle is live
+		tag, err := tx.Exec(ctx, `
+			UPDATE vehicles SET reserve_price = $2, version = version + 1, updated_at = NOW()
+			WHERE id = $1 AND NOT EXISTS (
+				SELECT 1 FROM auctions WHERE vehicle_id = $1 AND status IN ('scheduled', 'active')
+			)
+		`, vehicleID, *req.ReservePrice)
+		if err != nil {
+			h.logger.Error("failed to update reserve for draft", slog.String("error", err.Error()))
+			h.jsonError(w, "failed to update auction", http.StatusInternalServerError)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			h.jsonError(w, "reserve can't change while the vehicle has an open auction", http.StatusConflict)
+			return
+		}
+		resp["reserve_price"] = strconv.FormatFloat(*req.ReservePrice, 'f', 2, 64)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.jsonError(w, "failed to update auction", http.StatusInternalServerError)
+		return
+	}
+	h.expireAuction(auctionID)
+
+	h.logger.Info("draft_auction_updated",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("seller_id", userID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// PublishAuction takes a draft live: scheduled if it starts in the future,
+// active otherwise. The listing limit and image minimum that CreateAuction
+// skipped for the draft are checked here.
+func (h *AuctionHandler) PublishAuction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		sellerID, vehicleID int64
+		status              string
+		startsAt, endsAt    time.Time
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT v.seller_id, a.vehicle_id, a.status::text, a.starts_at, a.ends_at
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+		FOR UPDATE OF a
+	`, auctionID).Scan(&sellerID, &vehicleID, &status, &startsAt, &endsAt)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "auction") {
+		return
+	}
+	if status != "draft" {
+		h.jsonError(w, "only draft auctions can be published", http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	if msg := h.endsAtTooSoon(endsAt, now); msg != "" {
+		h.jsonError(w, msg+": edit the draft's schedule before publishing", http.StatusBadRequest)
+		return
+	}
+
+	if !h.readyToList(w, r, tx, sellerID, vehicleID) {
+		return
+	}
+
+	status = "scheduled"
+	if startsAt.Before(now) {
+		status = "active"
+	}
+
+	// Bump version so in-flight OCC bids see the change
+	_, err = tx.Exec(ctx, `
+		UPDATE auctions SET status = $2::auction_status, version = version + 1, updated_at = NOW()
+		WHERE id = $1
+	`, auctionID, status)
+	if isUniqueViolation(err, "idx_auctions_vehicle_open") {
+		h.jsonError(w, "vehicle already has an open auction", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to publish auction", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to publish auction", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE vehicles SET status = 'active' WHERE id = $1`, vehicleID); err != nil {
+		h.logger.Error("failed to activate vehicle", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to publish auction", http.StatusInternalServerError)
+		return
+	}
+
+	err = bidengine.RecordAuctionEvent(ctx, tx, auctionID, bidengine.EventPublished, map[string]any{
+		"published_by": userID,
+		"status":       status,
+	})
+	if err != nil {
+		h.logger.Error("failed to record publish event", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to publish auction", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.jsonError(w, "failed to publish auction", http.StatusInternalServerError)
+		return
+	}
+	h.expireAuction(auctionID)
+
+	h.logger.Info("auction_published",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("vehicle_id", vehicleID),
+		slog.Int64("seller_id", userID),
+		slog.String("status", status),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auction_id": auctionID,
+		"status":     status,
+		"starts_at":  startsAt,
+		"ends_at":    endsAt,
+		"message":    "Auction published",
+	})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetAuctionEvents returns an auction's audit trail oldest first, so replaying
+// the page in order reproduces every state change. Admin only.
+func (h *AuctionHandler) GetAuctionEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := httpx.Pagination(r)
+
+	var exists bool
+	err = h.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM auctions WHERE id = $1)`, auctionID).Scan(&exists)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	if !exists {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT id, auction_id, type, payload, created_at
+		FROM auction_events
+		WHERE auction_id = $1
+		ORDER BY id ASC
+		LIMIT $2 OFFSET $3
+	`, auctionID, limit, offset)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	events := make([]domain.AuctionEvent, 0)
+	for rows.Next() {
+		var e domain.AuctionEvent
+		if err := rows.Scan(&e.ID, &e.AuctionID, &e.Type, &e.Payload, &e.CreatedAt); err != nil {
+			writeQueryError(w, err)
+			return
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	var total int64
+	if err := h.db.QueryRow(ctx, `SELECT COUNT(*) FROM auction_events WHERE auction_id = $1`, auctionID).Scan(&total); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events":   events,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+		"has_more": int64(offset+len(events)) < total,
+	})
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// canViewPrivate reports whether the requester may see a private auction: its
+// seller, an admin, or an invited bidder
+func (h *AuctionHandler) canViewPrivate(r *http.Request, auctionID, sellerID int64) bool {
+	allowed, err := privateAuctionAccess(r.Context(), h.db, middleware.GetUserID(r.Context()), auctionID, sellerID)
+	if err != nil {
+		h.logger.Error("failed to check private auction access", slog.String("error", err.Error()))
+		return false
+	}
+	return allowed
+}
+
+// privateAuctionAccess is canViewPrivate for callers outside AuctionHandler
+func privateAuctionAccess(ctx context.Context, db *pgxpool.Pool, userID, auctionID, sellerID int64) (bool, error) {
+	if userID == 0 {
+		return false, nil
+	}
+	if userID == sellerID {
+		return true, nil
+	}
+
+	var allowed bool
+	err := db.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'admin')
+		    OR EXISTS(SELECT 1 FROM auction_invites WHERE auction_id = $2 AND user_id = $1)
+	`, userID, auctionID).Scan(&allowed)
+	return allowed, err
+}
+
+// InviteBidder adds a user to a private auction's invite list, letting them
+// see and bid on it. Inviting someone already invited is a no-op.
+func (h *AuctionHandler) InviteBidder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		UserID int64 `json:"user_id" validate:"required,gt=0"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	var sellerID int64
+	var visibility string
+	err = h.db.QueryRow(ctx, `
+		SELECT v.seller_id, a.visibility
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+	`, auctionID).Scan(&sellerID, &visibility)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "auction") {
+		return
+	}
+	if visibility != "private" {
+		h.jsonError(w, "only private auctions take invites", http.StatusConflict)
+		return
+	}
+	if req.UserID == sellerID {
+		h.jsonError(w, "sellers can't invite themselves", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.db.Exec(ctx, `
+		INSERT INTO auction_invites (auction_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (auction_id, user_id) DO NOTHING
+	`, auctionID, req.UserID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		h.jsonError(w, "user not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to invite bidder", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to invite bidder", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("auction_bidder_invited",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("user_id", req.UserID),
+		slog.Int64("seller_id", userID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auction_id": auctionID,
+		"user_id":    req.UserID,
+		"message":    "Bidder invited",
+	})
+}
+
+// RevokeInvite removes a user from a private auction's invite list. Bids they
+// already placed stand.
+func (h *AuctionHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+	inviteeID, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var sellerID int64
+	err = h.db.QueryRow(ctx, `
+		SELECT v.seller_id FROM auctions a JOIN vehicles v ON a.vehicle_id = v.id WHERE a.id = $1
+	`, auctionID).Scan(&sellerID)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "auction") {
+		return
+	}
+
+	tag, err := h.db.Exec(ctx, `DELETE FROM auction_invites WHERE auction_id = $1 AND user_id = $2`, auctionID, inviteeID)
+	if err != nil {
+		h.logger.Error("failed to revoke invite", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to revoke invite", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		h.jsonError(w, "invite not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
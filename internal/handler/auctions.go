@@ -1,30 +1,55 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata" // tz query param must not depend on host zoneinfo
 
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
 )
 
 type AuctionHandler struct {
-	db       *pgxpool.Pool
-	logger   *slog.Logger
-	validate *validator.Validate
+	db          *pgxpool.Pool
+	logger      *slog.Logger
+	cfg         *config.Config
+	broadcaster bidengine.Broadcaster
+	notifier    bidengine.Notifier
+	validate    *validator.Validate
+	cache       *AuctionCache // Optional read cache for GetAuction
+	payments    PaymentQueue  // Optional; charges buy-now orders
 }
 
-func NewAuctionHandler(db *pgxpool.Pool, logger *slog.Logger) *AuctionHandler {
-	return &AuctionHandler{
-		db:       db,
-		logger:   logger,
-		validate: validator.New(),
+func NewAuctionHandler(db *pgxpool.Pool, logger *slog.Logger, cfg *config.Config, broadcaster bidengine.Broadcaster, notifier bidengine.Notifier, opts ...AuctionHandlerOption) *AuctionHandler {
+	h := &AuctionHandler{
+		db:          db,
+		logger:      logger,
+		cfg:         cfg,
+		broadcaster: broadcaster,
+		notifier:    notifier,
+		validate:    newValidator(),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 type AuctionResponse struct {
@@ -33,9 +58,12 @@ type AuctionResponse struct {
 	Status            string  `json:"status"`
 	StartsAt          string  `json:"starts_at"`
 	EndsAt            string  `json:"ends_at"`
+	StartsAtLocal     string  `json:"starts_at_local,omitempty"` // Only with ?tz=
+	EndsAtLocal       string  `json:"ends_at_local,omitempty"`
 	CurrentBid        string  `json:"current_bid"`
 	CurrentBidUserID  *int64  `json:"current_bid_user_id,omitempty"`
 	BidCount          int     `json:"bid_count"`
+	SecondsRemaining  int64   `json:"seconds_remaining"` // Authoritative countdown, 0 once ended
 	
 	// Vehicle info (joined)
 	Year              int     `json:"year,omitempty"`
@@ -49,49 +77,192 @@ type AuctionResponse struct {
 	LocationState     *string `json:"location_state,omitempty"`
 }
 
-// ListAuctions returns active auctions
+// auctionSorts maps ?sort= values to ORDER BY clauses; only these are ever
+// interpolated into the listing query
+var auctionSorts = map[string]string{
+	"ending_soon":   "a.ends_at ASC, a.id ASC",
+	"starting_soon": "a.starts_at ASC, a.id ASC",
+}
+
+// maxBatchAuctionIDs caps how many auctions one ?ids= request can fetch
+const maxBatchAuctionIDs = 50
+
+// auctionListColumns is the listing view of an auction, scanned by
+// scanAuctions. Private leaders aren't identified in listings.
+const auctionListColumns = `
+		SELECT a.id, a.vehicle_id, a.status::text, a.starts_at, a.ends_at,
+		       a.current_bid,
+		       CASE WHEN lu.hide_bidder_identity THEN NULL ELSE a.current_bid_user_id END,
+		       a.bid_count,
+		       v.year, v.make, v.model, v.trim, v.mileage,
+		       v.starting_price, v.exterior_color, v.location_city, v.location_state
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		LEFT JOIN users lu ON lu.id = a.current_bid_user_id`
+
+// ListAuctions returns auctions by status, active by default. Scheduled listings sort by start time
+// unless ?sort= says otherwise, and ?starts_within= narrows them to auctions
+// opening within that duration. ?ids= fetches specific auctions instead.
 func (h *AuctionHandler) ListAuctions(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	
-	limit := 20
-	offset := 0
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
 	
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
-	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
-			offset = parsed
-		}
+	if r.URL.Query().Has("ids") {
+		h.listAuctionsByID(ctx, w, r)
+		return
 	}
 	
+	limit, offset := httpx.Pagination(r)
+	
 	status := r.URL.Query().Get("status")
 	if status == "" {
 		status = "active"
 	}
+	if status == "draft" {
+		h.jsonError(w, "draft auctions are only visible to their seller", http.StatusBadRequest)
+		return
+	}
 	
-	query := `
-		SELECT a.id, a.vehicle_id, a.status::text, a.starts_at, a.ends_at,
-		       a.current_bid, a.current_bid_user_id, a.bid_count,
-		       v.year, v.make, v.model, v.trim, v.mileage,
-		       v.starting_price, v.exterior_color, v.location_city, v.location_state
-		FROM auctions a
-		JOIN vehicles v ON a.vehicle_id = v.id
-		WHERE a.status::text = $1
-		ORDER BY a.ends_at ASC
-		LIMIT $2 OFFSET $3
-	`
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = "ending_soon"
+		if status == "scheduled" {
+			sort = "starting_soon"
+		}
+	}
+	orderBy, ok := auctionSorts[sort]
+	if !ok {
+		h.jsonError(w, "invalid sort: use ending_soon or starting_soon", http.StatusBadRequest)
+		return
+	}
 	
-	rows, err := h.db.Query(ctx, query, status, limit, offset)
+	loc, err := parseTimezone(r)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	now := time.Now()
+	where := `a.status::text = $1 AND NOT a.hidden AND a.visibility = 'public'`
+	args := []interface{}{status}
+	if within := r.URL.Query().Get("starts_within"); within != "" {
+		d, err := time.ParseDuration(within)
+		if err != nil || d <= 0 {
+			h.jsonError(w, "invalid starts_within: use a positive duration like 24h", http.StatusBadRequest)
+			return
+		}
+		args = append(args, now.Add(d))
+		where += fmt.Sprintf(" AND a.starts_at <= $%d", len(args))
+	}
+	
+	query := auctionListColumns + `
+		WHERE ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT ` + fmt.Sprintf("$%d OFFSET $%d", len(args)+1, len(args)+2)
+	
+	rows, err := h.db.Query(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		h.logger.Error("failed to query auctions", slog.String("error", err.Error()))
-		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		writeQueryError(w, err)
+		return
+	}
+	defer rows.Close()
+	
+	auctions, err := h.scanAuctions(rows, now, loc)
+	if err != nil {
+		h.logger.Error("failed to read auctions", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+	
+	// Get total count
+	var total int64
+	err = h.db.QueryRow(ctx, `SELECT COUNT(*) FROM auctions a WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		h.logger.Error("failed to count auctions", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+	
+	resp := newAuctionList(domain.NewPaginatedResponse(auctions, total, limit, offset), now, loc)
+	if wantFacets(r) {
+		facets, err := queryFacets(ctx, h.db, `
+			FROM auctions a
+			JOIN vehicles v ON a.vehicle_id = v.id
+			WHERE `+where, args...)
+		if err != nil {
+			h.logger.Error("failed to count auction facets", slog.String("error", err.Error()))
+			writeQueryError(w, err)
+			return
+		}
+		resp.Facets = facets
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// listAuctionsByID serves ListAuctions?ids=1,2,3: the listed auctions in the
+// order asked for, whatever their status. Unknown, hidden, draft and private
+// IDs are skipped.
+func (h *AuctionHandler) listAuctionsByID(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDList(r.URL.Query().Get("ids"), maxBatchAuctionIDs)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	loc, err := parseTimezone(r)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	now := time.Now()
+	rows, err := h.db.Query(ctx, auctionListColumns+`
+		WHERE a.id = ANY($1) AND NOT a.hidden AND a.status <> 'draft' AND a.visibility = 'public'
+		ORDER BY array_position($1, a.id)
+	`, ids)
+	if err != nil {
+		h.logger.Error("failed to query auctions by id", slog.String("error", err.Error()))
+		writeQueryError(w, err)
 		return
 	}
 	defer rows.Close()
 	
+	auctions, err := h.scanAuctions(rows, now, loc)
+	if err != nil {
+		h.logger.Error("failed to read auctions", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+	
+	resp := newAuctionList(domain.NewPaginatedResponse(auctions, int64(len(auctions)), len(ids), 0), now, loc)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// auctionList is the ListAuctions response: a page of auctions plus the
+// server's clock for countdowns
+type auctionList struct {
+	domain.PaginatedResponse[AuctionResponse]
+	ServerTime string  `json:"server_time"`
+	Timezone   string  `json:"timezone,omitempty"` // Set when ?tz= asked for local times
+	Facets     *Facets `json:"facets,omitempty"`
+}
+
+func newAuctionList(page domain.PaginatedResponse[AuctionResponse], now time.Time, loc *time.Location) *auctionList {
+	list := &auctionList{PaginatedResponse: page, ServerTime: now.UTC().Format(time.RFC3339)}
+	if loc != nil {
+		list.Timezone = loc.String()
+	}
+	return list
+}
+
+// scanAuctions reads rows selected with auctionListColumns. A row that fails
+// to scan is logged and left out.
+func (h *AuctionHandler) scanAuctions(rows pgx.Rows, now time.Time, loc *time.Location) ([]AuctionResponse, error) {
 	auctions := make([]AuctionResponse, 0)
 	for rows.Next() {
 		var a AuctionResponse
@@ -113,86 +284,223 @@ func (h *AuctionHandler) ListAuctions(w http.ResponseWriter, r *http.Request) {
 		a.EndsAt = endsAt.Format(time.RFC3339)
 		a.CurrentBid = strconv.FormatFloat(currentBid, 'f', 2, 64)
 		a.StartingPrice = strconv.FormatFloat(startingPrice, 'f', 2, 64)
+		a.SecondsRemaining = secondsRemaining(a.Status, endsAt, now)
+		if loc != nil {
+			a.StartsAtLocal = startsAt.In(loc).Format(time.RFC3339)
+			a.EndsAtLocal = endsAt.In(loc).Format(time.RFC3339)
+		}
 		
 		auctions = append(auctions, a)
 	}
-	
-	// Get total count
-	var total int64
-	h.db.QueryRow(ctx, `SELECT COUNT(*) FROM auctions WHERE status::text = $1`, status).Scan(&total)
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"auctions": auctions,
-		"total":    total,
-		"limit":    limit,
-		"offset":   offset,
-		"has_more": int64(offset+len(auctions)) < total,
-	})
+	return auctions, rows.Err()
 }
 
-// GetAuction returns a single auction with full details
-func (h *AuctionHandler) GetAuction(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
-		return
+// parseIDList parses a comma-separated list of positive IDs, dropping
+// duplicates and keeping the first-seen order
+func parseIDList(raw string, limit int) ([]int64, error) {
+	ids := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid id %q in ids", part)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("ids must list at least one auction id")
 	}
+	if len(ids) > limit {
+		return nil, fmt.Errorf("ids is limited to %d auctions", limit)
+	}
+	return ids, nil
+}
+
+// auctionDetail is GetAuction's response body
+type auctionDetail struct {
+	AuctionResponse
+	CurrentBid      *string `json:"current_bid"` // null until the first bid lands
+	HasBids         bool    `json:"has_bids"`
+	VIN             string  `json:"vin"`
+	Description     *string `json:"description,omitempty"`
+	Visibility      string  `json:"visibility"`
+	ExtensionCount  int     `json:"extension_count"`
+	MaxExtensions   int     `json:"max_extensions"`
+	UniqueBidders   int     `json:"unique_bidders"` // Distinct users behind bid_count
+	SellerFirstName *string `json:"seller_first_name,omitempty"`
+	SellerLastName  *string `json:"seller_last_name,omitempty"`
+	HighBidderAlias *string `json:"high_bidder_alias,omitempty"` // Replaces current_bid_user_id for private leaders
 	
+	// Once the auction is over: is_final is set, seconds_remaining is
+	// dropped, and participants see the winner
+	IsFinal          bool    `json:"is_final"`
+	SecondsRemaining *int64  `json:"seconds_remaining,omitempty"` // Shadows AuctionResponse's, omitted when final
+	WinnerID         *int64  `json:"winner_id,omitempty"`
+	WinningBid       *string `json:"winning_bid,omitempty"`
+}
+
+// auctionRow is an auction as GetAuction reads it, before it's tailored to
+// the viewer. The read cache shares rows between requests, so copy detail
+// before changing it.
+type auctionRow struct {
+	detail           auctionDetail
+	startsAt, endsAt time.Time
+	currentBid       float64
+	startingPrice    float64
+	hidden           bool
+	sellerID         int64
+	privateLeader    bool
+	winnerID         *int64
+	winningBid       decimal.NullDecimal
+	version          int
+}
+
+// loadAuction reads an auction for GetAuction, through the read cache when
+// there is one
+func (h *AuctionHandler) loadAuction(ctx context.Context, id int64) (auctionRow, error) {
+	if h.cache == nil {
+		return h.queryAuction(ctx, id)
+	}
+	return h.cache.rows.Get(id, func() (auctionRow, int, error) {
+		row, err := h.queryAuction(ctx, id)
+		return row, row.version, err
+	})
+}
+
+func (h *AuctionHandler) queryAuction(ctx context.Context, id int64) (auctionRow, error) {
 	query := `
 		SELECT a.id, a.vehicle_id, a.status::text, a.starts_at, a.ends_at,
 		       a.current_bid, a.current_bid_user_id, a.bid_count,
-		       a.extension_count, a.max_extensions,
+		       a.extension_count, a.max_extensions, a.hidden, a.visibility,
+		       (SELECT COUNT(DISTINCT b.user_id) FROM bids b
+		        WHERE b.auction_id = a.id AND b.status NOT IN ('rejected', 'retracted')) AS unique_bidders,
 		       v.vin, v.year, v.make, v.model, v.trim, v.mileage,
 		       v.starting_price, v.exterior_color, v.description,
 		       v.location_city, v.location_state,
-		       u.first_name as seller_first_name, u.last_name as seller_last_name
+		       u.first_name as seller_first_name, u.last_name as seller_last_name,
+		       v.seller_id, COALESCE(lu.hide_bidder_identity, false),
+		       a.winner_id, a.winning_bid, a.version
 		FROM auctions a
 		JOIN vehicles v ON a.vehicle_id = v.id
 		JOIN users u ON v.seller_id = u.id
+		LEFT JOIN users lu ON lu.id = a.current_bid_user_id
 		WHERE a.id = $1
 	`
 	
-	var auction struct {
-		AuctionResponse
-		VIN             string  `json:"vin"`
-		Description     *string `json:"description,omitempty"`
-		ExtensionCount  int     `json:"extension_count"`
-		MaxExtensions   int     `json:"max_extensions"`
-		SellerFirstName *string `json:"seller_first_name,omitempty"`
-		SellerLastName  *string `json:"seller_last_name,omitempty"`
-	}
-	
-	var startsAt, endsAt time.Time
-	var currentBid, startingPrice float64
-	
-	err = h.db.QueryRow(ctx, query, id).Scan(
-		&auction.ID, &auction.VehicleID, &auction.Status, &startsAt, &endsAt,
-		&currentBid, &auction.CurrentBidUserID, &auction.BidCount,
-		&auction.ExtensionCount, &auction.MaxExtensions,
+	var row auctionRow
+	auction := &row.detail
+	err := h.db.QueryRow(ctx, query, id).Scan(
+		&auction.ID, &auction.VehicleID, &auction.Status, &row.startsAt, &row.endsAt,
+		&row.currentBid, &auction.CurrentBidUserID, &auction.BidCount,
+		&auction.ExtensionCount, &auction.MaxExtensions, &row.hidden, &auction.Visibility, &auction.UniqueBidders,
 		&auction.VIN, &auction.Year, &auction.Make, &auction.Model,
-		&auction.Trim, &auction.Mileage, &startingPrice,
+		&auction.Trim, &auction.Mileage, &row.startingPrice,
 		&auction.ExteriorColor, &auction.Description,
 		&auction.LocationCity, &auction.LocationState,
 		&auction.SellerFirstName, &auction.SellerLastName,
+		&row.sellerID, &row.privateLeader,
+		&row.winnerID, &row.winningBid, &row.version,
 	)
+	return row, err
+}
+
+// GetAuction returns a single auction with full details
+func (h *AuctionHandler) GetAuction(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
 	
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+	
+	row, err := h.loadAuction(ctx, id)
+	if isQueryTimeout(err) {
+		writeQueryError(w, err)
+		return
+	}
 	if err != nil {
 		h.jsonError(w, "auction not found", http.StatusNotFound)
 		return
 	}
+	auction := row.detail
+	startsAt, endsAt := row.startsAt, row.endsAt
+	sellerID, privateLeader, winnerID := row.sellerID, row.privateLeader, row.winnerID
+	
+	// Hidden auctions are only visible to admins and people who already bid
+	if row.hidden && !h.canViewHidden(r, id) {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+	
+	// Drafts are only visible to their seller and admins
+	if auction.Status == "draft" && !h.canViewDraft(r, sellerID) {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+	
+	// Private auctions are only visible to their seller, admins and invitees
+	if auction.Visibility == "private" && !h.canViewPrivate(r, id, sellerID) {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+	
+	// A private leader is only identified to themselves, and to the seller once
+	// the auction has ended and they need to know who won
+	if privateLeader && auction.CurrentBidUserID != nil {
+		viewerID := middleware.GetUserID(r.Context())
+		wonBy := auction.Status == "ended" && viewerID == sellerID
+		if viewerID != *auction.CurrentBidUserID && !wonBy {
+			alias, err := h.bidderAliasFor(ctx, id, *auction.CurrentBidUserID)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				writeQueryError(w, err)
+				return
+			}
+			auction.CurrentBidUserID = nil
+			if alias != "" {
+				auction.HighBidderAlias = &alias
+			}
+		}
+	}
 	
 	auction.StartsAt = startsAt.Format(time.RFC3339)
 	auction.EndsAt = endsAt.Format(time.RFC3339)
-	auction.CurrentBid = strconv.FormatFloat(currentBid, 'f', 2, 64)
-	auction.StartingPrice = strconv.FormatFloat(startingPrice, 'f', 2, 64)
+	// current_bid defaults to 0, which reads like a real $0 bid; report no
+	// bids explicitly instead
+	auction.HasBids = auction.BidCount > 0
+	if auction.HasBids {
+		current := strconv.FormatFloat(row.currentBid, 'f', 2, 64)
+		auction.CurrentBid = &current
+	}
+	auction.StartingPrice = strconv.FormatFloat(row.startingPrice, 'f', 2, 64)
+	
+	now := time.Now()
+	auction.IsFinal = auction.Status == "ended" || auction.Status == "cancelled"
+	if !auction.IsFinal {
+		remaining := secondsRemaining(auction.Status, endsAt, now)
+		auction.SecondsRemaining = &remaining
+	} else if winnerID != nil && h.canSeeWinner(r, id, sellerID, *winnerID, privateLeader) {
+		auction.WinnerID = winnerID
+		if row.winningBid.Valid {
+			bid := row.winningBid.Decimal.StringFixed(2)
+			auction.WinningBid = &bid
+		}
+	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"auction": auction,
+		"auction":     auction,
+		"server_time": now.UTC().Format(time.RFC3339),
 	})
 }
 
@@ -211,6 +519,26 @@ func (h *AuctionHandler) CreateAuction(w http.ResponseWriter, r *http.Request) {
 		StartsAt      string `json:"starts_at" validate:"required"`
 		EndsAt        string `json:"ends_at" validate:"required"`
 		MaxExtensions int    `json:"max_extensions"`
+		
+		// ExtendOnReserveMet grants a one-time extension when the reserve is first met near the end
+		ExtendOnReserveMet bool `json:"extend_on_reserve_met"`
+		
+		// ExtendOnLeaderChangeOnly skips snipe extensions for bids by the current leader
+		ExtendOnLeaderChangeOnly bool `json:"extend_on_leader_change_only"`
+		
+		// MinIncrement overrides the default bid step for this auction
+		MinIncrement *decimal.Decimal `json:"min_increment"`
+		
+		// AutoRelistPriceDrops opts in to relisting when the reserve isn't met: one
+		// entry per relist, the percent to cut starting and reserve prices by
+		AutoRelistPriceDrops []int `json:"auto_relist_price_drops" validate:"omitempty,max=5,dive,min=1,max=50"`
+		
+		// Draft holds the auction back until the seller publishes it
+		Draft bool `json:"draft"`
+		
+		// Visibility is public (default) or private: invite-only, and left out
+		// of public listings
+		Visibility string `json:"visibility" validate:"omitempty,oneof=public private"`
 	}
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -219,7 +547,7 @@ func (h *AuctionHandler) CreateAuction(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	if err := h.validate.Struct(req); err != nil {
-		h.jsonError(w, "validation error: "+err.Error(), http.StatusBadRequest)
+		writeValidationError(w, err)
 		return
 	}
 	
@@ -239,23 +567,45 @@ func (h *AuctionHandler) CreateAuction(w http.ResponseWriter, r *http.Request) {
 		h.jsonError(w, "ends_at must be after starts_at", http.StatusBadRequest)
 		return
 	}
+	// Ordering alone allows both times to be in the past
+	if msg := h.endsAtTooSoon(endsAt, time.Now()); msg != "" {
+		h.jsonError(w, msg, http.StatusBadRequest)
+		return
+	}
+	
+	if req.MinIncrement != nil {
+		if msg := validateMinIncrement(*req.MinIncrement); msg != "" {
+			h.jsonError(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
 	
 	// Verify user owns the vehicle
 	var vehicleOwnerID int64
 	err = h.db.QueryRow(ctx, `SELECT seller_id FROM vehicles WHERE id = $1`, req.VehicleID).Scan(&vehicleOwnerID)
+	if !requireOwner(w, r, h.logger, err, vehicleOwnerID, userID, "vehicle") {
+		return
+	}
+	
+	tx, err := h.db.Begin(ctx)
 	if err != nil {
-		h.jsonError(w, "vehicle not found", http.StatusNotFound)
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback(ctx)
 	
-	if vehicleOwnerID != userID {
-		h.jsonError(w, "not authorized to auction this vehicle", http.StatusForbidden)
+	// Drafts are checked against the listing limit and image minimum when
+	// they're published instead
+	if !req.Draft && !h.readyToList(w, r, tx, vehicleOwnerID, req.VehicleID) {
 		return
 	}
 	
 	// Determine initial status
 	status := "scheduled"
-	if startsAt.Before(time.Now()) {
+	switch {
+	case req.Draft:
+		status = "draft"
+	case startsAt.Before(time.Now()):
 		status = "active"
 	}
 	
@@ -264,22 +614,43 @@ func (h *AuctionHandler) CreateAuction(w http.ResponseWriter, r *http.Request) {
 		maxExtensions = 10
 	}
 	
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = "public"
+	}
+	
 	query := `
-		INSERT INTO auctions (vehicle_id, status, starts_at, ends_at, max_extensions)
-		VALUES ($1, $2::auction_status, $3, $4, $5)
+		INSERT INTO auctions (vehicle_id, status, starts_at, ends_at, max_extensions, extend_on_reserve_met, auto_relist_price_drops, extend_on_leader_change_only, min_increment, visibility)
+		VALUES ($1, $2::auction_status, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id
 	`
 	
+	priceDrops := req.AutoRelistPriceDrops
+	if priceDrops == nil {
+		priceDrops = []int{}
+	}
+	
 	var auctionID int64
-	err = h.db.QueryRow(ctx, query, req.VehicleID, status, startsAt, endsAt, maxExtensions).Scan(&auctionID)
+	err = tx.QueryRow(ctx, query, req.VehicleID, status, startsAt, endsAt, maxExtensions, req.ExtendOnReserveMet, priceDrops, req.ExtendOnLeaderChangeOnly, req.MinIncrement, visibility).Scan(&auctionID)
 	if err != nil {
 		h.logger.Error("failed to create auction", slog.String("error", err.Error()))
 		h.jsonError(w, "failed to create auction", http.StatusInternalServerError)
 		return
 	}
 	
-	// Update vehicle status
-	h.db.Exec(ctx, `UPDATE vehicles SET status = 'active' WHERE id = $1`, req.VehicleID)
+	// Update vehicle status; a draft leaves it alone until publish
+	if !req.Draft {
+		if _, err := tx.Exec(ctx, `UPDATE vehicles SET status = 'active' WHERE id = $1`, req.VehicleID); err != nil {
+			h.logger.Error("failed to activate vehicle", slog.String("error", err.Error()))
+			h.jsonError(w, "failed to create auction", http.StatusInternalServerError)
+			return
+		}
+	}
+	
+	if err := tx.Commit(ctx); err != nil {
+		h.jsonError(w, "failed to create auction", http.StatusInternalServerError)
+		return
+	}
 	
 	h.logger.Info("auction_created",
 		slog.Int64("auction_id", auctionID),
@@ -292,13 +663,396 @@ func (h *AuctionHandler) CreateAuction(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"auction_id": auctionID,
 		"status":     status,
+		"visibility": visibility,
 		"message":    "Auction created successfully",
 	})
 }
 
+// maxMinIncrement keeps per-auction increments within the NUMERIC(10,2) column
+var maxMinIncrement = decimal.NewFromInt(100000)
+
+// endsAtTooSoon checks an auction's end against MIN_AUCTION_DURATION,
+// returning a message when it isn't far enough past now, or "" when it is.
+// Every path that puts an auction up for bidding applies it.
+func (h *AuctionHandler) endsAtTooSoon(endsAt, now time.Time) string {
+	minDuration := h.cfg.MinAuctionDuration
+	if endsAt.After(now.Add(minDuration)) {
+		return ""
+	}
+	if minDuration > 0 {
+		return fmt.Sprintf("ends_at must be at least %s from now", minDuration)
+	}
+	return "ends_at must be in the future"
+}
+
+// validateMinIncrement checks a seller-supplied bid step, returning a message
+// for the client or "" when it's acceptable
+func validateMinIncrement(inc decimal.Decimal) string {
+	if !inc.IsPositive() {
+		return "min_increment must be greater than 0"
+	}
+	// Checked on the exponent first: rescaling 1e-10000000 costs seconds
+	if inc.Exponent() > maxAmountExponent {
+		return "min_increment must be at most " + maxMinIncrement.StringFixed(2)
+	}
+	if inc.Exponent() < minAmountExponent || !inc.Equal(inc.Truncate(2)) {
+		return "min_increment must have at most 2 decimal places"
+	}
+	if inc.GreaterThan(maxMinIncrement) {
+		return "min_increment must be at most " + maxMinIncrement.StringFixed(2)
+	}
+	return ""
+}
+
+// CancelAuction lets the seller withdraw an auction that is scheduled, or
+// active with no bids yet. The vehicle goes back to draft.
+func (h *AuctionHandler) CancelAuction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+	
+	var sellerID, vehicleID int64
+	var status string
+	var bidCount int
+	err = h.db.QueryRow(ctx, `
+		SELECT v.seller_id, a.vehicle_id, a.status::text, a.bid_count
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+	`, auctionID).Scan(&sellerID, &vehicleID, &status, &bidCount)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "auction") {
+		return
+	}
+	if status != "scheduled" && status != "active" {
+		h.jsonError(w, "only scheduled or active auctions can be cancelled", http.StatusBadRequest)
+		return
+	}
+	if bidCount > 0 {
+		h.jsonError(w, "cannot cancel an auction that has bids", http.StatusForbidden)
+		return
+	}
+	
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	
+	// Re-check under the update and bump version so in-flight OCC bids lose
+	tag, err := tx.Exec(ctx, `
+		UPDATE auctions SET status = 'cancelled', version = version + 1
+		WHERE id = $1 AND bid_count = 0 AND status IN ('scheduled', 'active')
+	`, auctionID)
+	if err != nil {
+		h.logger.Error("failed to cancel auction", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to cancel auction", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		h.jsonError(w, "cannot cancel an auction that has bids", http.StatusForbidden)
+		return
+	}
+	
+	if _, err := tx.Exec(ctx, `UPDATE vehicles SET status = 'draft' WHERE id = $1`, vehicleID); err != nil {
+		h.logger.Error("failed to revert vehicle status", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to cancel auction", http.StatusInternalServerError)
+		return
+	}
+	
+	err = bidengine.RecordAuctionEvent(ctx, tx, auctionID, bidengine.EventCancelled, map[string]any{
+		"cancelled_by":    userID,
+		"previous_status": status,
+	})
+	if err != nil {
+		h.logger.Error("failed to record cancel event", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to cancel auction", http.StatusInternalServerError)
+		return
+	}
+	
+	if err := tx.Commit(ctx); err != nil {
+		h.jsonError(w, "failed to cancel auction", http.StatusInternalServerError)
+		return
+	}
+	
+	if h.broadcaster != nil {
+		h.broadcaster.Broadcast(domain.BidEvent{
+			Type:      "auction_cancelled",
+			AuctionID: auctionID,
+			Timestamp: time.Now(),
+		})
+	}
+	
+	h.logger.Info("auction_cancelled",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("vehicle_id", vehicleID),
+		slog.Int64("seller_id", userID),
+	)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auction_id": auctionID,
+		"status":     "cancelled",
+		"message":    "Auction cancelled",
+	})
+}
+
+// ForceCloseAuction lets an admin end an auction immediately (e.g. fraud)
+func (h *AuctionHandler) ForceCloseAuction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminID := middleware.GetUserID(ctx)
+	
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+	
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// Body is optional
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	var status string
+	var version, bidCount int
+	var currentBid float64
+	var leaderID *int64
+	err = h.db.QueryRow(ctx, `
+		SELECT status::text, version, bid_count, current_bid, current_bid_user_id
+		FROM auctions WHERE id = $1
+	`, auctionID).Scan(&status, &version, &bidCount, &currentBid, &leaderID)
+	if err != nil {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+	if status != "scheduled" && status != "active" {
+		h.jsonError(w, "only scheduled or active auctions can be closed", http.StatusBadRequest)
+		return
+	}
+	
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	
+	// OCC: a bid landing between the read and this update wins and we report a conflict
+	var endsAt time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE auctions SET status = 'ended', ends_at = LEAST(ends_at, NOW()), version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING ends_at
+	`, auctionID, version).Scan(&endsAt)
+	if err == pgx.ErrNoRows {
+		h.jsonError(w, "auction was modified concurrently, retry", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to close auction", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to close auction", http.StatusInternalServerError)
+		return
+	}
+	
+	err = bidengine.RecordAuctionEvent(ctx, tx, auctionID, bidengine.EventClosed, map[string]any{
+		"reason":          "admin",
+		"admin_id":        adminID,
+		"note":            req.Reason,
+		"previous_status": status,
+		"leader_id":       leaderID,
+		"final_price":     currentBid,
+		"bid_count":       bidCount,
+	})
+	if err != nil {
+		h.logger.Error("failed to record close event", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to close auction", http.StatusInternalServerError)
+		return
+	}
+	
+	if err := tx.Commit(ctx); err != nil {
+		h.jsonError(w, "failed to close auction", http.StatusInternalServerError)
+		return
+	}
+	
+	if h.broadcaster != nil {
+		event := domain.BidEvent{
+			Type:      "auction_ended",
+			AuctionID: auctionID,
+			Amount:    decimal.NewFromFloat(currentBid),
+			BidCount:  bidCount,
+			EndsAt:    endsAt,
+			Timestamp: time.Now(),
+		}
+		if leaderID != nil {
+			event.BidderID = *leaderID
+		}
+		h.broadcaster.Broadcast(event)
+	}
+	// An admin close sells nothing, so the leader hears it was closed, not won
+	if h.notifier != nil && leaderID != nil {
+		h.notifier.NotifyUser(*leaderID, webhook.EventAuctionClosedByAdmin, map[string]interface{}{
+			"auction_id": auctionID,
+			"amount":     decimal.NewFromFloat(currentBid),
+			"ended_at":   endsAt,
+			"reason":     req.Reason,
+		})
+	}
+	if events, ok := h.notifier.(bidengine.EventNotifier); ok {
+		events.NotifyEvent("auction.ended", map[string]any{
+			"auction_id":  auctionID,
+			"reason":      "admin",
+			"sold":        false,
+			"final_price": strconv.FormatFloat(currentBid, 'f', 2, 64),
+			"bid_count":   bidCount,
+			"ended_at":    endsAt,
+		})
+	}
+	
+	h.logger.Info("admin_auction_closed",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("admin_id", adminID),
+		slog.String("previous_status", status),
+		slog.String("reason", req.Reason),
+	)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auction_id": auctionID,
+		"status":     "ended",
+		"ends_at":    endsAt.Format(time.RFC3339),
+	})
+}
+
+// ExtendAuction lets an admin push back an auction's end (e.g. after an outage)
+func (h *AuctionHandler) ExtendAuction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminID := middleware.GetUserID(ctx)
+	
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+	
+	var req struct {
+		Minutes int    `json:"minutes" validate:"required,min=1,max=10080"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	
+	var status string
+	var version int
+	var previousEndsAt time.Time
+	err = h.db.QueryRow(ctx, `
+		SELECT status::text, version, ends_at FROM auctions WHERE id = $1
+	`, auctionID).Scan(&status, &version, &previousEndsAt)
+	if err != nil {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+	if status != "scheduled" && status != "active" {
+		h.jsonError(w, "only scheduled or active auctions can be extended", http.StatusBadRequest)
+		return
+	}
+	
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	
+	// Extend from now if the deadline already slipped past during an outage
+	var endsAt time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE auctions SET ends_at = GREATEST(ends_at, NOW()) + make_interval(mins => $3), version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING ends_at
+	`, auctionID, version, req.Minutes).Scan(&endsAt)
+	if err == pgx.ErrNoRows {
+		h.jsonError(w, "auction was modified concurrently, retry", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to extend auction", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to extend auction", http.StatusInternalServerError)
+		return
+	}
+	
+	err = bidengine.RecordAuctionEvent(ctx, tx, auctionID, bidengine.EventExtended, map[string]any{
+		"reason":           "admin",
+		"admin_id":         adminID,
+		"note":             req.Reason,
+		"minutes":          req.Minutes,
+		"previous_ends_at": previousEndsAt,
+		"ends_at":          endsAt,
+	})
+	if err != nil {
+		h.logger.Error("failed to record extend event", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to extend auction", http.StatusInternalServerError)
+		return
+	}
+	
+	if err := tx.Commit(ctx); err != nil {
+		h.jsonError(w, "failed to extend auction", http.StatusInternalServerError)
+		return
+	}
+	
+	if h.broadcaster != nil {
+		h.broadcaster.Broadcast(domain.BidEvent{
+			Type:             "auction_extended",
+			AuctionID:        auctionID,
+			EndsAt:           endsAt,
+			ExtensionApplied: true,
+			Timestamp:        time.Now(),
+		})
+	}
+	
+	h.logger.Info("admin_auction_extended",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("admin_id", adminID),
+		slog.Int("minutes", req.Minutes),
+		slog.Time("previous_ends_at", previousEndsAt),
+		slog.Time("ends_at", endsAt),
+		slog.String("reason", req.Reason),
+	)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auction_id": auctionID,
+		"status":     status,
+		"ends_at":    endsAt.Format(time.RFC3339),
+	})
+}
+
+// bidHistoryPageSize is GetBidHistory's default page
+const bidHistoryPageSize = 50
+
 // GetBidHistory returns bid history for an auction
 func (h *AuctionHandler) GetBidHistory(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
 	
 	idStr := chi.URLParam(r, "id")
 	auctionID, err := strconv.ParseInt(idStr, 10, 64)
@@ -307,26 +1061,44 @@ func (h *AuctionHandler) GetBidHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	limit := 50
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
+	// Private auctions' bids are only visible to their seller, admins and
+	// invitees. An unknown auction still gets an empty history.
+	var visibility string
+	var sellerID int64
+	err = h.db.QueryRow(ctx, `
+		SELECT a.visibility, v.seller_id
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+	`, auctionID).Scan(&visibility, &sellerID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		writeQueryError(w, err)
+		return
+	}
+	if visibility == "private" && !h.canViewPrivate(r, auctionID, sellerID) {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
 	}
 	
-	query := `
+	// Bid history kept its own default page when the list endpoints moved
+	// to PAGE_SIZE_DEFAULT
+	limit := httpx.Limit(r, min(bidHistoryPageSize, httpx.MaxPageSize), httpx.MaxPageSize)
+	offset := httpx.Offset(r)
+	
+	query := bidderNumbersCTE + `
 		SELECT b.id, b.amount, b.status::text, b.previous_high_bid, b.created_at,
-		       u.first_name, u.last_name
+		       u.first_name, u.last_name, u.hide_bidder_identity, bn.n
 		FROM bids b
 		JOIN users u ON b.user_id = u.id
-		WHERE b.auction_id = $1
-		ORDER BY b.created_at DESC
-		LIMIT $2
+		JOIN bidder_numbers bn ON bn.user_id = b.user_id
+		WHERE b.auction_id = $1 AND b.status <> 'retracted'
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $2 OFFSET $3
 	`
 	
-	rows, err := h.db.Query(ctx, query, auctionID, limit)
+	rows, err := h.db.Query(ctx, query, auctionID, limit, offset)
 	if err != nil {
-		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		writeQueryError(w, err)
 		return
 	}
 	defer rows.Close()
@@ -339,6 +1111,7 @@ func (h *AuctionHandler) GetBidHistory(w http.ResponseWriter, r *http.Request) {
 		CreatedAt       string  `json:"created_at"`
 		BidderFirstName *string `json:"bidder_first_name,omitempty"`
 		BidderLastName  *string `json:"bidder_last_name,omitempty"`
+		BidderAlias     *string `json:"bidder_alias,omitempty"` // Shown instead of the name for private bidders
 	}
 	
 	bids := make([]BidHistoryItem, 0)
@@ -347,15 +1120,22 @@ func (h *AuctionHandler) GetBidHistory(w http.ResponseWriter, r *http.Request) {
 		var amount float64
 		var previousHighBid *float64
 		var createdAt time.Time
+		var private bool
+		var bidderNumber int
 		
 		err := rows.Scan(
 			&b.ID, &amount, &b.Status, &previousHighBid, &createdAt,
-			&b.BidderFirstName, &b.BidderLastName,
+			&b.BidderFirstName, &b.BidderLastName, &private, &bidderNumber,
 		)
 		if err != nil {
 			continue
 		}
 		
+		if private {
+			alias := bidderAlias(bidderNumber)
+			b.BidderFirstName, b.BidderLastName, b.BidderAlias = nil, nil, &alias
+		}
+		
 		b.Amount = strconv.FormatFloat(amount, 'f', 2, 64)
 		b.CreatedAt = createdAt.Format(time.RFC3339)
 		if previousHighBid != nil {
@@ -366,15 +1146,91 @@ func (h *AuctionHandler) GetBidHistory(w http.ResponseWriter, r *http.Request) {
 		bids = append(bids, b)
 	}
 	
+	// Get total count
+	var total int64
+	h.db.QueryRow(ctx, `SELECT COUNT(*) FROM bids WHERE auction_id = $1 AND status <> 'retracted'`, auctionID).Scan(&total)
+	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"bids": bids,
+		"bids":     bids,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+		"has_more": int64(offset+len(bids)) < total,
 	})
 }
 
+// canSeeWinner reports whether the requester took part in the auction, as its
+// seller or a bidder, and so may see who won. A winner who hides their
+// identity is only revealed to the seller and themselves.
+func (h *AuctionHandler) canSeeWinner(r *http.Request, auctionID, sellerID, winnerID int64, privateWinner bool) bool {
+	userID := middleware.GetUserID(r.Context())
+	switch {
+	case userID == 0:
+		return false
+	case userID == sellerID || userID == winnerID:
+		return true
+	case privateWinner:
+		return false
+	}
+	
+	var bid bool
+	err := h.db.QueryRow(r.Context(), `SELECT EXISTS(SELECT 1 FROM bids WHERE auction_id = $1 AND user_id = $2 AND status <> 'retracted')`, auctionID, userID).Scan(&bid)
+	if err != nil {
+		h.logger.Error("failed to check auction participation", slog.String("error", err.Error()))
+		return false
+	}
+	return bid
+}
+
+// canViewHidden reports whether the requester is an admin or has bid on the auction
+func (h *AuctionHandler) canViewHidden(r *http.Request, auctionID int64) bool {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		return false
+	}
+	
+	var allowed bool
+	err := h.db.QueryRow(r.Context(), `
+		SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'admin')
+		    OR EXISTS(SELECT 1 FROM bids WHERE auction_id = $2 AND user_id = $1)
+	`, userID, auctionID).Scan(&allowed)
+	if err != nil {
+		h.logger.Error("failed to check hidden auction access", slog.String("error", err.Error()))
+		return false
+	}
+	return allowed
+}
+
 func (h *AuctionHandler) jsonError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
+// secondsRemaining is the whole seconds until endsAt, clamped to zero once the
+// deadline has passed or the auction is no longer open
+func secondsRemaining(status string, endsAt, now time.Time) int64 {
+	if status == "ended" || status == "cancelled" {
+		return 0
+	}
+	remaining := int64(endsAt.Sub(now) / time.Second)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// parseTimezone reads the optional ?tz= IANA zone used to render local
+// timestamps next to the UTC ones. Returns nil when the param is absent.
+func parseTimezone(r *http.Request) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, errors.New("invalid tz: must be an IANA timezone name")
+	}
+	return loc, nil
+}
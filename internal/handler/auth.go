@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -24,62 +29,53 @@ func NewAuthHandler(db *pgxpool.Pool, logger *slog.Logger) *AuthHandler {
 }
 
 // ClerkSync syncs a Clerk user with the local database
-// Called from frontend after Clerk sign-in
+// Called from frontend after Clerk sign-in. The Clerk ID and email come from
+// the verified session token (or, without an email claim, Clerk's Backend
+// API); the body only supplies names.
 func (h *AuthHandler) ClerkSync(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	clerkUserID := middleware.GetClerkUserID(ctx)
+	email := middleware.GetClerkEmail(ctx)
+	if clerkUserID == "" {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	if email == "" {
+		h.jsonError(w, "clerk account has no email address", http.StatusBadRequest)
+		return
+	}
+
 	var req struct {
-		ClerkUserID string `json:"clerk_user_id"`
-		Email       string `json:"email"`
-		FirstName   string `json:"first_name"`
-		LastName    string `json:"last_name"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
 		h.jsonError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if req.ClerkUserID == "" || req.Email == "" {
-		h.jsonError(w, "clerk_user_id and email are required", http.StatusBadRequest)
+	userID, isNew, err := h.syncClerkUser(ctx, clerkUserID, email, req.FirstName, req.LastName)
+	if errors.Is(err, errEmailLinked) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "email is linked to another account",
+			"code":  "email_in_use",
+		})
 		return
 	}
-
-	// Find or create user
-	var userID int64
-	var isNew bool
-
-	// Try to find by email first
-	err := h.db.QueryRow(ctx, `SELECT id FROM users WHERE email = $1`, req.Email).Scan(&userID)
 	if err != nil {
-		// User doesn't exist, create new
-		err = h.db.QueryRow(ctx, `
-			INSERT INTO users (clerk_user_id, email, first_name, last_name, role)
-			VALUES ($1, $2, $3, $4, 'buyer')
-			RETURNING id
-		`, req.ClerkUserID, req.Email, req.FirstName, req.LastName).Scan(&userID)
-		if err != nil {
-			h.logger.Error("failed to create user", slog.String("error", err.Error()))
-			h.jsonError(w, "failed to create user", http.StatusInternalServerError)
-			return
-		}
-		isNew = true
+		h.logger.Error("failed to sync user", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to sync user", http.StatusInternalServerError)
+		return
+	}
+	if isNew {
 		h.logger.Info("user_created",
 			slog.Int64("user_id", userID),
-			slog.String("email", req.Email),
+			slog.String("email", email),
 		)
-	} else {
-		// Update existing user with Clerk ID if not set
-		_, err = h.db.Exec(ctx, `
-			UPDATE users SET
-				clerk_user_id = COALESCE(clerk_user_id, $1),
-				first_name = COALESCE(NULLIF($2, ''), first_name),
-				last_name = COALESCE(NULLIF($3, ''), last_name)
-			WHERE id = $4
-		`, req.ClerkUserID, req.FirstName, req.LastName, userID)
-		if err != nil {
-			h.logger.Error("failed to update user", slog.String("error", err.Error()))
-		}
 	}
 
 	// Get full user data
@@ -124,6 +120,61 @@ func (h *AuthHandler) ClerkSync(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// errEmailLinked means the synced email belongs to a user tied to a different
+// Clerk account
+var errEmailLinked = errors.New("email linked to another clerk account")
+
+// syncClerkUser finds the local user for a Clerk account, creating it if
+// needed, and brings its email and names up to date. The Clerk ID is the
+// identity; email only links a local account that has no Clerk ID yet, and
+// an account bound to one Clerk ID is never moved to another.
+// Concurrent syncs of the same new user both end up with the one row.
+func (h *AuthHandler) syncClerkUser(ctx context.Context, clerkUserID, email, firstName, lastName string) (int64, bool, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		// Known Clerk account: follow an email change if the new one is free
+		var userID int64
+		err := h.db.QueryRow(ctx, `
+			UPDATE users SET
+				email = $2,
+				first_name = COALESCE(NULLIF($3, ''), first_name),
+				last_name = COALESCE(NULLIF($4, ''), last_name),
+				updated_at = NOW()
+			WHERE clerk_user_id = $1
+			RETURNING id
+		`, clerkUserID, email, firstName, lastName).Scan(&userID)
+		if isUniqueViolation(err, "users_email_key") {
+			return 0, false, errEmailLinked
+		}
+		if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+			return userID, false, err
+		}
+
+		// New to us: create it, or link the local account with this email
+		var inserted bool
+		err = h.db.QueryRow(ctx, `
+			INSERT INTO users (clerk_user_id, email, first_name, last_name, role)
+			VALUES ($1, $2, $3, $4, 'buyer')
+			ON CONFLICT (email) DO UPDATE SET
+				clerk_user_id = EXCLUDED.clerk_user_id,
+				first_name = COALESCE(NULLIF(EXCLUDED.first_name, ''), users.first_name),
+				last_name = COALESCE(NULLIF(EXCLUDED.last_name, ''), users.last_name),
+				updated_at = NOW()
+			WHERE users.clerk_user_id IS NULL OR users.clerk_user_id = EXCLUDED.clerk_user_id
+			RETURNING id, xmax = 0
+		`, clerkUserID, email, firstName, lastName).Scan(&userID, &inserted)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return 0, false, errEmailLinked
+		case isUniqueViolation(err, "users_clerk_user_id_key"):
+			// A concurrent sync created this Clerk user under another email;
+			// take the update path against its row
+			continue
+		}
+		return userID, inserted, err
+	}
+	return 0, false, errors.New("clerk sync did not settle")
+}
+
 // Me returns the current user's profile
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -143,13 +194,16 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		Role              string     `json:"role"`
 		IDVerifiedAt      *time.Time `json:"id_verified_at"`
 		CreatedAt         time.Time  `json:"created_at"`
+		HideBidder        bool       `json:"hide_bidder_identity"`
 	}
 	var paymentProfileID *string
 
 	err := h.db.QueryRow(ctx, `
-		SELECT id, email, first_name, last_name, phone, role, id_verified_at, authorize_payment_profile_id, created_at
+		SELECT id, email, first_name, last_name, phone, role, id_verified_at, authorize_payment_profile_id, created_at,
+		       hide_bidder_identity
 		FROM users WHERE id = $1
-	`, userID).Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.Phone, &user.Role, &user.IDVerifiedAt, &paymentProfileID, &user.CreatedAt)
+	`, userID).Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.Phone, &user.Role, &user.IDVerifiedAt, &paymentProfileID, &user.CreatedAt,
+		&user.HideBidder)
 	if err != nil {
 		h.jsonError(w, "user not found", http.StatusNotFound)
 		return
@@ -169,9 +223,38 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		"has_payment_method": hasPaymentMethod,
 		"can_bid":            user.IDVerifiedAt != nil && hasPaymentMethod,
 		"created_at":         user.CreatedAt.Format(time.RFC3339),
+
+		"hide_bidder_identity": user.HideBidder,
 	})
 }
 
+// BidEligibility reports whether the current user can bid and, if not, each
+// thing they still need to do, so the client can prompt for it
+func (h *AuthHandler) BidEligibility(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var idVerifiedAt *time.Time
+	var paymentProfileID *string
+	err := h.db.QueryRow(ctx, `
+		SELECT id_verified_at, authorize_payment_profile_id FROM users WHERE id = $1
+	`, userID).Scan(&idVerifiedAt, &paymentProfileID)
+	if err != nil {
+		h.jsonError(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	hasPaymentMethod := paymentProfileID != nil && *paymentProfileID != ""
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.NewUserVerification(userID, idVerifiedAt, hasPaymentMethod))
+}
+
 // UpdateProfile updates the current user's profile
 func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -186,6 +269,9 @@ func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		FirstName *string `json:"first_name"`
 		LastName  *string `json:"last_name"`
 		Phone     *string `json:"phone"`
+
+		// HideBidderIdentity shows the user as a per-auction "Bidder N" instead of by name
+		HideBidderIdentity *bool `json:"hide_bidder_identity"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -197,9 +283,10 @@ func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		UPDATE users SET
 			first_name = COALESCE($2, first_name),
 			last_name = COALESCE($3, last_name),
-			phone = COALESCE($4, phone)
+			phone = COALESCE($4, phone),
+			hide_bidder_identity = COALESCE($5, hide_bidder_identity)
 		WHERE id = $1
-	`, userID, req.FirstName, req.LastName, req.Phone)
+	`, userID, req.FirstName, req.LastName, req.Phone, req.HideBidderIdentity)
 
 	if err != nil {
 		h.logger.Error("failed to update profile", slog.String("error", err.Error()))
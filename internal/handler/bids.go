@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -18,22 +20,119 @@ import (
 )
 
 type BidHandler struct {
-	engine   *bidengine.Engine
-	logger   *slog.Logger
-	validate *validator.Validate
+	engine       *bidengine.Engine
+	logger       *slog.Logger
+	validate     *validator.Validate
+	syncResponse bool
+	waitTimeout  time.Duration
+	maxAmount    decimal.Decimal
 }
 
-func NewBidHandler(engine *bidengine.Engine, logger *slog.Logger) *BidHandler {
-	return &BidHandler{
-		engine:   engine,
-		logger:   logger,
-		validate: validator.New(),
+// BidHandlerOption configures a BidHandler
+type BidHandlerOption func(*BidHandler)
+
+// WithSyncResponse makes PlaceBid answer with the terminal result when the
+// engine runs in sync mode, instead of a ticket to poll. No effect in async mode.
+func WithSyncResponse(enabled bool) BidHandlerOption {
+	return func(h *BidHandler) {
+		h.syncResponse = enabled
+	}
+}
+
+// WithWaitTimeout bounds how long PlaceBid?wait=true holds the request open
+// for the final result before falling back to 202 + ticket
+func WithWaitTimeout(d time.Duration) BidHandlerOption {
+	return func(h *BidHandler) {
+		h.waitTimeout = d
+	}
+}
+
+// WithMaxAmount caps any single amount or max_bid; larger values are rejected
+// with amount_out_of_range before they reach the engine. Zero disables the cap.
+func WithMaxAmount(max decimal.Decimal) BidHandlerOption {
+	return func(h *BidHandler) {
+		h.maxAmount = max
+	}
+}
+
+func NewBidHandler(engine *bidengine.Engine, logger *slog.Logger, opts ...BidHandlerOption) *BidHandler {
+	h := &BidHandler{
+		engine:      engine,
+		logger:      logger,
+		validate:    newValidator(),
+		waitTimeout: 2 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// errInvalidBidAmount is returned while decoding an amount that isn't numeric
+var errInvalidBidAmount = errors.New("invalid bid amount")
+
+// errAmountOutOfRange is an amount whose exponent alone puts it past any cap
+var errAmountOutOfRange = errors.New("bid amount out of range")
+
+// Bounds on an amount's text and exponent, checked before any decimal
+// arithmetic: comparing "1e10000000" rescales it to ten million digits
+const (
+	maxAmountLength   = 64
+	maxAmountExponent = 15 // Far past BID_MAX_AMOUNT and the NUMERIC(10,2) columns
+	minAmountExponent = -20
+)
+
+// parseBidAmount parses a client amount, rejecting strings and exponents too
+// large to do arithmetic on cheaply
+func parseBidAmount(s string) (decimal.Decimal, error) {
+	if len(s) > maxAmountLength {
+		return decimal.Zero, errInvalidBidAmount
+	}
+	amount, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero, errInvalidBidAmount
+	}
+	switch {
+	case amount.Exponent() > maxAmountExponent:
+		return decimal.Zero, errAmountOutOfRange
+	case amount.Exponent() < minAmountExponent:
+		return decimal.Zero, errInvalidBidAmount
+	}
+	return amount, nil
+}
+
+// BidAmount is a bid amount sent as either a JSON number or a numeric string.
+// Booleans, objects, arrays and null are rejected while decoding.
+type BidAmount string
+
+func (a *BidAmount) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	switch {
+	case len(data) == 0:
+		return errInvalidBidAmount
+	case data[0] == '"':
+		if err := json.Unmarshal(data, (*string)(&n)); err != nil {
+			return errInvalidBidAmount
+		}
+	case data[0] == '-' || (data[0] >= '0' && data[0] <= '9'):
+		n = json.Number(data)
+	default:
+		return errInvalidBidAmount
 	}
+	*a = BidAmount(n)
+	return nil
+}
+
+func (a BidAmount) String() string {
+	return string(a)
 }
 
 type PlaceBidRequest struct {
-	Amount json.Number `json:"amount" validate:"required"` // Accepts both "150.00" and 150.00
+	Amount BidAmount   `json:"amount" validate:"required"` // Accepts both "150.00" and 150.00
 	MaxBid json.Number `json:"max_bid,omitempty"`          // For auto-bidding (future)
+	
+	// SubmittedAt is when the client sent the bid; near-deadline bids may be honored from it
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
 }
 
 type PlaceBidResponse struct {
@@ -64,18 +163,26 @@ func (h *BidHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req PlaceBidRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if errors.Is(err, errInvalidBidAmount) {
+			h.jsonError(w, "invalid bid amount", http.StatusBadRequest)
+			return
+		}
 		h.jsonError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 	
 	// Validate
 	if err := h.validate.Struct(req); err != nil {
-		h.jsonError(w, "validation error: "+err.Error(), http.StatusBadRequest)
+		writeValidationError(w, err)
 		return
 	}
 	
-	// Parse amount (json.Number handles both string "150.00" and number 150.00)
-	amount, err := decimal.NewFromString(req.Amount.String())
+	// Parse amount (BidAmount holds either string "150.00" or number 150.00)
+	amount, err := parseBidAmount(req.Amount.String())
+	if errors.Is(err, errAmountOutOfRange) {
+		h.writeAmountOutOfRange(w)
+		return
+	}
 	if err != nil {
 		h.jsonError(w, "invalid bid amount", http.StatusBadRequest)
 		return
@@ -85,6 +192,10 @@ func (h *BidHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 		h.jsonError(w, "bid amount must be positive", http.StatusBadRequest)
 		return
 	}
+	if !h.amountInRange(amount) {
+		h.writeAmountOutOfRange(w)
+		return
+	}
 	
 	// Generate ticket ID for tracking
 	ticketID := uuid.New().String()
@@ -98,17 +209,102 @@ func (h *BidHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 		TraceID:   tracing.TraceIDFromContext(ctx),
 		CreatedAt: time.Now(),
 	}
+	if req.SubmittedAt != nil {
+		bidReq.ClientSubmittedAt = *req.SubmittedAt
+	}
 	
 	// Parse max bid if provided
 	if req.MaxBid.String() != "" {
-		maxBid, err := decimal.NewFromString(req.MaxBid.String())
+		maxBid, err := parseBidAmount(req.MaxBid.String())
+		if errors.Is(err, errAmountOutOfRange) || (err == nil && !h.amountInRange(maxBid)) {
+			h.writeAmountOutOfRange(w)
+			return
+		}
 		if err == nil && maxBid.GreaterThan(amount) {
 			bidReq.MaxBid = maxBid
 		}
 	}
 	
+	h.submitBid(w, r, bidReq)
+}
+
+// PlaceProxyBid registers a max-only proxy bid. The engine enters it at the
+// minimum next bid and raises it automatically as others bid, up to max_bid.
+func (h *BidHandler) PlaceProxyBid(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+	
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	
+	var req struct {
+		MaxBid BidAmount `json:"max_bid" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if errors.Is(err, errInvalidBidAmount) {
+			h.jsonError(w, "invalid max bid", http.StatusBadRequest)
+			return
+		}
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	
+	maxBid, err := parseBidAmount(req.MaxBid.String())
+	if errors.Is(err, errAmountOutOfRange) {
+		h.writeAmountOutOfRange(w)
+		return
+	}
+	if err != nil {
+		h.jsonError(w, "invalid max bid", http.StatusBadRequest)
+		return
+	}
+	if maxBid.LessThanOrEqual(decimal.Zero) {
+		h.jsonError(w, "max bid must be positive", http.StatusBadRequest)
+		return
+	}
+	if !h.amountInRange(maxBid) {
+		h.writeAmountOutOfRange(w)
+		return
+	}
+	
+	h.submitBid(w, r, domain.BidRequest{
+		TicketID:  uuid.New().String(),
+		AuctionID: auctionID,
+		UserID:    userID,
+		MaxBid:    maxBid,
+		ProxyOnly: true,
+		TraceID:   tracing.TraceIDFromContext(ctx),
+		CreatedAt: time.Now(),
+	})
+}
+
+// submitBid hands a bid to the engine and answers with the result when it is
+// already available, or 202 + ticket to poll
+func (h *BidHandler) submitBid(w http.ResponseWriter, r *http.Request, bidReq domain.BidRequest) {
+	ctx := r.Context()
+	
+	// A sync-mode bid runs inside this request, so a client that hangs up
+	// aborts it. A queued bid outlives the request once its ticket is
+	// returned and only keeps the request's values, not its cancellation.
+	bidCtx := context.WithoutCancel(ctx)
+	if h.engine.SyncMode() {
+		bidCtx = ctx
+	}
+	
 	// Submit to engine
-	if err := h.engine.Submit(bidReq); err != nil {
+	if err := h.engine.SubmitContext(bidCtx, bidReq); err != nil {
 		if err == bidengine.ErrQueueFull {
 			h.jsonError(w, "system busy, please retry", http.StatusServiceUnavailable)
 			return
@@ -118,18 +314,33 @@ func (h *BidHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	h.logger.Info("bid_submitted",
-		slog.String("ticket_id", ticketID),
-		slog.Int64("auction_id", auctionID),
-		slog.Int64("user_id", userID),
-		slog.String("amount", amount.String()),
+		slog.String("ticket_id", bidReq.TicketID),
+		slog.Int64("auction_id", bidReq.AuctionID),
+		slog.Int64("user_id", bidReq.UserID),
+		slog.String("amount", bidReq.Amount.String()),
 		slog.String("request_id", middleware.GetRequestID(ctx)),
 	)
 	
+	// In sync mode the bid already ran inside Submit, so skip the poll step.
+	// ?wait=true asks to hold the request for the result instead of polling.
+	if h.syncResponse && h.engine.SyncMode() {
+		if result, err := h.engine.GetResult(bidReq.TicketID, time.Second); err == nil {
+			h.writeBidResult(w, result)
+			return
+		}
+	} else if r.URL.Query().Get("wait") == "true" {
+		if result, err := h.engine.GetResult(bidReq.TicketID, h.waitTimeout); err == nil {
+			h.writeBidResult(w, result)
+			return
+		}
+		// Still queued or processing; the client polls as usual
+	}
+	
 	// Return 202 Accepted with ticket
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(PlaceBidResponse{
-		TicketID: ticketID,
+		TicketID: bidReq.TicketID,
 		Status:   "queued",
 		Message:  "Bid submitted for processing",
 	})
@@ -164,6 +375,39 @@ func (h *BidHandler) GetBidStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// writeBidResult writes a bid's terminal result: 200 when accepted, 409 when rejected
+func (h *BidHandler) writeBidResult(w http.ResponseWriter, result domain.BidResult) {
+	status := http.StatusOK
+	switch result.Status {
+	case "accepted":
+	case "rejected":
+		status = http.StatusConflict
+	default:
+		status = http.StatusInternalServerError
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// amountInRange reports whether amount is within the configured sanity cap
+func (h *BidHandler) amountInRange(amount decimal.Decimal) bool {
+	return !h.maxAmount.IsPositive() || amount.LessThanOrEqual(h.maxAmount)
+}
+
+// writeAmountOutOfRange rejects an amount above the cap with a stable reason
+// clients can match on, like the engine's rejection reasons
+func (h *BidHandler) writeAmountOutOfRange(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":      "bid amount out of range",
+		"reason":     "amount_out_of_range",
+		"max_amount": h.maxAmount.StringFixed(2),
+	})
+}
+
 func (h *BidHandler) jsonError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
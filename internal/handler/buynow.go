@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// buyNowMaxRetries bounds re-reads after losing an OCC race to a bid that
+// didn't close the auction
+const buyNowMaxRetries = 3
+
+var (
+	errBuyNowNotFound    = errors.New("auction not found")
+	errBuyNowOwnVehicle  = errors.New("sellers cannot buy their own vehicle")
+	errBuyNowUnavailable = errors.New("auction has no buy-now price")
+	errBuyNowClosed      = errors.New("auction is no longer active")
+	errBuyNowOutbid      = errors.New("bidding has passed the buy-now price")
+	errBuyNowConflict    = errors.New("auction was modified concurrently, retry")
+)
+
+// PaymentQueue charges for new orders in the background
+type PaymentQueue interface {
+	Enqueue(orderID int64)
+}
+
+// WithPaymentQueue hands each buy-now order to q to charge the buyer.
+// Without one, orders stay pending_payment.
+func WithPaymentQueue(q PaymentQueue) AuctionHandlerOption {
+	return func(h *AuctionHandler) {
+		h.payments = q
+	}
+}
+
+// purchase is a completed buy-now
+type purchase struct {
+	orderID   int64
+	vehicleID int64
+	sellerID  int64
+	price     decimal.Decimal
+	bidCount  int
+	endedAt   time.Time
+}
+
+// BuyNow ends an active auction at its buy-now price and creates the order.
+// It goes through the same version-checked update as bids, so when buy-now
+// requests race each other or a bid, exactly one wins.
+func (h *AuctionHandler) BuyNow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	var p purchase
+	for attempt := 0; attempt <= buyNowMaxRetries; attempt++ {
+		p, err = h.attemptBuyNow(ctx, auctionID, userID)
+		if !errors.Is(err, errBuyNowConflict) {
+			break
+		}
+	}
+	switch {
+	case errors.Is(err, errBuyNowNotFound):
+		h.jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errBuyNowOwnVehicle):
+		h.jsonError(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, errBuyNowUnavailable):
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errBuyNowClosed), errors.Is(err, errBuyNowOutbid), errors.Is(err, errBuyNowConflict):
+		h.jsonError(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.Error("failed to buy now", slog.Int64("auction_id", auctionID), slog.String("error", err.Error()))
+		h.jsonError(w, "failed to complete purchase", http.StatusInternalServerError)
+		return
+	}
+
+	if h.payments != nil {
+		h.payments.Enqueue(p.orderID)
+	}
+	if h.broadcaster != nil {
+		h.broadcaster.Broadcast(domain.BidEvent{
+			Type:      "auction_ended",
+			AuctionID: auctionID,
+			BidderID:  userID,
+			Amount:    p.price,
+			BidCount:  p.bidCount,
+			EndsAt:    p.endedAt,
+			Timestamp: time.Now(),
+		})
+	}
+	if h.notifier != nil {
+		h.notifier.NotifyUser(userID, "auction.won", map[string]interface{}{
+			"auction_id": auctionID,
+			"amount":     p.price,
+			"ended_at":   p.endedAt,
+			"buy_now":    true,
+		})
+	}
+	if events, ok := h.notifier.(bidengine.EventNotifier); ok {
+		events.NotifyEvent("auction.ended", map[string]any{
+			"auction_id":  auctionID,
+			"vehicle_id":  p.vehicleID,
+			"reason":      "buy_now",
+			"sold":        true,
+			"winner_id":   userID,
+			"final_price": p.price.StringFixed(2),
+			"bid_count":   p.bidCount,
+			"ended_at":    p.endedAt,
+		})
+		events.NotifyEvent("order.created", map[string]any{
+			"order_id":   p.orderID,
+			"auction_id": auctionID,
+			"vehicle_id": p.vehicleID,
+			"buyer_id":   userID,
+			"seller_id":  p.sellerID,
+			"sale_price": p.price.StringFixed(2),
+		})
+	}
+
+	h.logger.Info("auction_bought_now",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("order_id", p.orderID),
+		slog.Int64("buyer_id", userID),
+		slog.String("price", p.price.StringFixed(2)),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auction_id": auctionID,
+		"order_id":   p.orderID,
+		"vehicle_id": p.vehicleID,
+		"price":      p.price.StringFixed(2),
+		"status":     "ended",
+	})
+}
+
+// attemptBuyNow reads the auction and tries a single OCC update. It returns
+// errBuyNowConflict when another writer bumped the version first.
+func (h *AuctionHandler) attemptBuyNow(ctx context.Context, auctionID, userID int64) (purchase, error) {
+	var (
+		p           purchase
+		status      string
+		version     int
+		endsAt      time.Time
+		currentBid  decimal.Decimal
+		buyNowPrice decimal.NullDecimal
+		uninvited   bool
+	)
+	err := h.db.QueryRow(ctx, `
+		SELECT a.status::text, a.version, a.ends_at, a.current_bid, a.bid_count,
+		       a.vehicle_id, v.seller_id, v.buy_now_price,
+		       a.visibility = 'private'
+		       AND NOT EXISTS(SELECT 1 FROM auction_invites i WHERE i.auction_id = a.id AND i.user_id = $2)
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+	`, auctionID, userID).Scan(&status, &version, &endsAt, &currentBid, &p.bidCount, &p.vehicleID, &p.sellerID, &buyNowPrice, &uninvited)
+	if err == pgx.ErrNoRows {
+		return p, errBuyNowNotFound
+	}
+	if err != nil {
+		return p, err
+	}
+	// Private auctions don't exist for anyone not invited
+	if uninvited && p.sellerID != userID {
+		return p, errBuyNowNotFound
+	}
+	if p.sellerID == userID {
+		return p, errBuyNowOwnVehicle
+	}
+	if !buyNowPrice.Valid {
+		return p, errBuyNowUnavailable
+	}
+	if status != "active" || !time.Now().Before(endsAt) {
+		return p, errBuyNowClosed
+	}
+	if currentBid.GreaterThanOrEqual(buyNowPrice.Decimal) {
+		return p, errBuyNowOutbid
+	}
+	p.price = buyNowPrice.Decimal
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return p, err
+	}
+	defer tx.Rollback(ctx)
+
+	// OCC update - only succeeds if no bid, close or other buy-now got in first
+	err = tx.QueryRow(ctx, `
+		UPDATE auctions SET
+			status = 'ended',
+			current_bid = $3,
+			current_bid_user_id = $4,
+			winner_id = $4,
+			winning_bid = $3,
+			ends_at = LEAST(ends_at, NOW()),
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND version = $2 AND status = 'active'
+		RETURNING ends_at
+	`, auctionID, version, p.price, userID).Scan(&p.endedAt)
+	if err == pgx.ErrNoRows {
+		return p, errBuyNowConflict
+	}
+	if err != nil {
+		return p, fmt.Errorf("end auction: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO orders (auction_id, buyer_id, seller_id, vehicle_id, sale_price, total_price)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id
+	`, auctionID, userID, p.sellerID, p.vehicleID, p.price).Scan(&p.orderID)
+	if err != nil {
+		return p, fmt.Errorf("create order: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE vehicles SET status = 'sold' WHERE id = $1`, p.vehicleID); err != nil {
+		return p, fmt.Errorf("mark vehicle sold: %w", err)
+	}
+	err = bidengine.RecordAuctionEvent(ctx, tx, auctionID, bidengine.EventClosed, map[string]any{
+		"reason":      "buy_now",
+		"winner_id":   userID,
+		"final_price": p.price,
+		"bid_count":   p.bidCount,
+		"order_id":    p.orderID,
+	})
+	if err != nil {
+		return p, fmt.Errorf("record close event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return p, err
+	}
+	return p, nil
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/dbpool"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// queryContext bounds a read handler's database work so a slow database fails
+// the request instead of holding it until the server write timeout. A zero
+// timeout leaves the request context as it is.
+func queryContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// isQueryTimeout reports whether a query failed because its deadline passed
+func isQueryTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err)
+}
+
+// poolRetryAfter is the Retry-After, in seconds, sent when the connection
+// pool is saturated
+const poolRetryAfter = "1"
+
+// writeQueryError maps a failed read to a response: 503 with Retry-After when
+// every pooled connection stayed busy, 504 when the query ran out of time, 503
+// when the database couldn't be reached, and 500 otherwise.
+func writeQueryError(w http.ResponseWriter, err error) {
+	message, status := "internal error", http.StatusInternalServerError
+	var connectErr *pgconn.ConnectError
+	switch {
+	case dbpool.IsExhausted(err):
+		message, status = "server busy, please retry", http.StatusServiceUnavailable
+		w.Header().Set("Retry-After", poolRetryAfter)
+	case isQueryTimeout(err):
+		message, status = "database timeout", http.StatusGatewayTimeout
+	case errors.As(err, &connectErr):
+		message, status = "database unavailable", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
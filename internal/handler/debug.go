@@ -3,13 +3,17 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
 	"github.com/ayubfarah/vehicle-auc/internal/realtime"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -44,6 +48,19 @@ func (h *DebugHandler) BidEngineStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// SlowBids lists auctions by OCC contention (retries and recent processing time)
+func (h *DebugHandler) SlowBids(w http.ResponseWriter, r *http.Request) {
+	limit := httpx.Limit(r, 20, 100)
+	
+	auctions := h.engine.SlowBids(limit)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auctions": auctions,
+		"count":    len(auctions),
+	})
+}
+
 // SSEStats returns current SSE broker statistics
 func (h *DebugHandler) SSEStats(w http.ResponseWriter, r *http.Request) {
 	stats := h.broker.Stats()
@@ -76,45 +93,29 @@ func (h *DebugHandler) AllStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Seed creates sample data for development/testing
-// Only available in development and test environments
-func (h *DebugHandler) Seed(w http.ResponseWriter, r *http.Request) {
-	env := os.Getenv("ENVIRONMENT")
-	if env != "development" && env != "test" && env != "" {
-		http.Error(w, "seed only available in development/test", http.StatusForbidden)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-	defer cancel()
-
-	// Start transaction
-	tx, err := h.db.Begin(ctx)
-	if err != nil {
-		h.logger.Error("failed to start transaction for seed", slog.String("error", err.Error()))
-		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback(ctx)
+// seedStep is one idempotent statement of the seed data set
+type seedStep struct {
+	name string
+	sql  string
+}
 
-	// Insert seed users
-	_, err = tx.Exec(ctx, `
+// seedSteps upsert the fixed seed rows. Rows with fixed IDs are overwritten
+// back to their seed values; rows without one are only inserted if missing,
+// so running the seed again leaves the same data.
+var seedSteps = []seedStep{
+	{"users", `
 		INSERT INTO users (id, clerk_user_id, email, first_name, last_name, phone, role, id_verified_at, created_at) VALUES
 		(1, 'clerk_seed_seller1', 'seller1@test.com', 'John', 'Dealer', '555-0101', 'seller', NOW(), NOW()),
 		(2, 'clerk_seed_seller2', 'seller2@test.com', 'Sarah', 'Motors', '555-0102', 'seller', NOW(), NOW()),
 		(3, 'clerk_seed_buyer1', 'buyer1@test.com', 'Mike', 'Thompson', '555-0201', 'buyer', NOW(), NOW()),
 		(4, 'clerk_seed_buyer2', 'buyer2@test.com', 'Emily', 'Chen', '555-0202', 'buyer', NOW(), NOW()),
 		(5, 'clerk_seed_buyer3', 'buyer3@test.com', 'David', 'Wilson', '555-0203', 'buyer', NULL, NOW())
-		ON CONFLICT (id) DO UPDATE SET email = EXCLUDED.email, first_name = EXCLUDED.first_name
-	`)
-	if err != nil {
-		h.logger.Error("failed to seed users", slog.String("error", err.Error()))
-		http.Error(w, "failed to seed users: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Insert seed vehicles
-	_, err = tx.Exec(ctx, `
+		ON CONFLICT (id) DO UPDATE SET
+			clerk_user_id = EXCLUDED.clerk_user_id, email = EXCLUDED.email,
+			first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name,
+			phone = EXCLUDED.phone, role = EXCLUDED.role, id_verified_at = EXCLUDED.id_verified_at
+	`},
+	{"vehicles", `
 		INSERT INTO vehicles (id, seller_id, vin, year, make, model, trim, body_type, exterior_color, interior_color, mileage, engine, transmission, drivetrain, fuel_type, title_status, condition_grade, description, starting_price, reserve_price, location_city, location_state, location_zip, status) VALUES
 		(1, 1, 'JH4KA8260MC000001', 2021, 'Honda', 'Accord', 'Sport', 'Sedan', 'Crystal Black Pearl', 'Black', 28500, '1.5L Turbo I4', 'CVT', 'FWD', 'Gasoline', 'clean', 'A', 'One owner, always garaged. Full service history.', 22000.00, 20000.00, 'Los Angeles', 'CA', '90001', 'active'),
 		(2, 1, '1HGBH41JXMN000002', 2022, 'Toyota', 'Camry', 'XSE', 'Sedan', 'Wind Chill Pearl', 'Red', 15200, '2.5L I4', 'Automatic', 'FWD', 'Gasoline', 'clean', 'A+', 'Like new condition. All maintenance at dealership.', 26000.00, 24000.00, 'San Francisco', 'CA', '94102', 'active'),
@@ -126,17 +127,14 @@ func (h *DebugHandler) Seed(w http.ResponseWriter, r *http.Request) {
 		(8, 2, '5J6RW2H85KL000008', 2020, 'Honda', 'CR-V', 'Touring', 'SUV', 'Modern Steel', 'Gray', 38500, '1.5L Turbo I4', 'CVT', 'AWD', 'Gasoline', 'clean', 'B+', 'Popular CR-V with all the features.', 28000.00, 26000.00, 'Tucson', 'AZ', '85701', 'active'),
 		(9, 2, 'WVWZZZ3CZWE000009', 2023, 'Porsche', '911', 'Carrera', 'Coupe', 'Guards Red', 'Black', 3200, '3.0L Twin-Turbo H6', 'PDK', 'RWD', 'Gasoline', 'clean', 'A+', 'Barely driven 911! Sport Chrono package.', 125000.00, 120000.00, 'Las Vegas', 'NV', '89101', 'active'),
 		(10, 2, '1N4BL4BV4KC000010', 2022, 'Nissan', 'Altima', 'SV', 'Sedan', 'Gun Metallic', 'Charcoal', 22100, '2.5L I4', 'CVT', 'FWD', 'Gasoline', 'clean', 'A', 'Reliable daily driver. Great fuel economy.', 21000.00, 19000.00, 'Henderson', 'NV', '89002', 'active')
-		ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, starting_price = EXCLUDED.starting_price
-	`)
-	if err != nil {
-		h.logger.Error("failed to seed vehicles", slog.String("error", err.Error()))
-		http.Error(w, "failed to seed vehicles: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Insert vehicle images
-	_, err = tx.Exec(ctx, `
-		INSERT INTO vehicle_images (vehicle_id, s3_key, url, is_primary, display_order) VALUES
+		ON CONFLICT (id) DO UPDATE SET
+			seller_id = EXCLUDED.seller_id, vin = EXCLUDED.vin, year = EXCLUDED.year,
+			make = EXCLUDED.make, model = EXCLUDED.model, status = EXCLUDED.status,
+			starting_price = EXCLUDED.starting_price, reserve_price = EXCLUDED.reserve_price
+	`},
+	{"images", `
+		INSERT INTO vehicle_images (vehicle_id, s3_key, url, is_primary, display_order)
+		SELECT * FROM (VALUES
 		(1, 'vehicles/1/main.jpg', 'https://images.unsplash.com/photo-1619767886558-efdc259cde1a?w=800', true, 0),
 		(2, 'vehicles/2/main.jpg', 'https://images.unsplash.com/photo-1621007947382-bb3c3994e3fb?w=800', true, 0),
 		(3, 'vehicles/3/main.jpg', 'https://images.unsplash.com/photo-1555215695-3004980ad54e?w=800', true, 0),
@@ -147,16 +145,12 @@ func (h *DebugHandler) Seed(w http.ResponseWriter, r *http.Request) {
 		(8, 'vehicles/8/main.jpg', 'https://images.unsplash.com/photo-1568844293986-8c1a5e1a5d5b?w=800', true, 0),
 		(9, 'vehicles/9/main.jpg', 'https://images.unsplash.com/photo-1503376780353-7e6692767b70?w=800', true, 0),
 		(10, 'vehicles/10/main.jpg', 'https://images.unsplash.com/photo-1609521263047-f8f205293f24?w=800', true, 0)
-		ON CONFLICT DO NOTHING
-	`)
-	if err != nil {
-		h.logger.Error("failed to seed images", slog.String("error", err.Error()))
-		http.Error(w, "failed to seed images: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Insert auctions with dynamic end times
-	_, err = tx.Exec(ctx, `
+		) AS seed(vehicle_id, s3_key, url, is_primary, display_order)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM vehicle_images i WHERE i.vehicle_id = seed.vehicle_id AND i.s3_key = seed.s3_key
+		)
+	`},
+	{"auctions", `
 		INSERT INTO auctions (id, vehicle_id, status, starts_at, ends_at, current_bid, current_bid_user_id, bid_count, version) VALUES
 		(1, 1, 'active', NOW() - INTERVAL '5 days', NOW() + INTERVAL '2 hours', 24500.00, 3, 12, 12),
 		(2, 2, 'active', NOW() - INTERVAL '4 days', NOW() + INTERVAL '6 hours', 27000.00, 4, 8, 8),
@@ -164,21 +158,21 @@ func (h *DebugHandler) Seed(w http.ResponseWriter, r *http.Request) {
 		(4, 5, 'active', NOW() - INTERVAL '2 days', NOW() + INTERVAL '2 days', 57000.00, 4, 4, 4),
 		(5, 6, 'active', NOW() - INTERVAL '1 day', NOW() + INTERVAL '3 days', 39000.00, 3, 2, 2),
 		(6, 9, 'active', NOW() - INTERVAL '12 hours', NOW() + INTERVAL '5 days', 126000.00, 4, 1, 1)
-		ON CONFLICT (id) DO UPDATE SET 
-			current_bid = EXCLUDED.current_bid, 
-			bid_count = EXCLUDED.bid_count,
+		ON CONFLICT (id) DO UPDATE SET
+			vehicle_id = EXCLUDED.vehicle_id,
+			status = EXCLUDED.status,
+			starts_at = EXCLUDED.starts_at,
 			ends_at = EXCLUDED.ends_at,
-			status = EXCLUDED.status
-	`)
-	if err != nil {
-		h.logger.Error("failed to seed auctions", slog.String("error", err.Error()))
-		http.Error(w, "failed to seed auctions: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Insert sample bids
-	_, err = tx.Exec(ctx, `
-		INSERT INTO bids (auction_id, user_id, amount, status, previous_high_bid, created_at) VALUES
+			current_bid = EXCLUDED.current_bid,
+			current_bid_user_id = EXCLUDED.current_bid_user_id,
+			bid_count = EXCLUDED.bid_count,
+			winner_id = NULL,
+			winning_bid = NULL,
+			version = auctions.version + 1
+	`},
+	{"bids", `
+		INSERT INTO bids (auction_id, user_id, amount, status, previous_high_bid, created_at)
+		SELECT seed.auction_id, seed.user_id, seed.amount, seed.status::bid_status, seed.previous_high_bid, seed.created_at FROM (VALUES
 		(1, 3, 22500.00, 'outbid', 22000.00, NOW() - INTERVAL '4 days'),
 		(1, 4, 23000.00, 'outbid', 22500.00, NOW() - INTERVAL '4 days' + INTERVAL '2 hours'),
 		(1, 3, 24500.00, 'accepted', 24000.00, NOW() - INTERVAL '2 days'),
@@ -187,44 +181,110 @@ func (h *DebugHandler) Seed(w http.ResponseWriter, r *http.Request) {
 		(4, 4, 57000.00, 'accepted', 56500.00, NOW() - INTERVAL '6 hours'),
 		(5, 3, 39000.00, 'accepted', 38500.00, NOW() - INTERVAL '6 hours'),
 		(6, 4, 126000.00, 'accepted', 125000.00, NOW() - INTERVAL '2 hours')
-		ON CONFLICT DO NOTHING
-	`)
-	if err != nil {
-		h.logger.Error("failed to seed bids", slog.String("error", err.Error()))
-		http.Error(w, "failed to seed bids: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Insert watchlist
-	_, err = tx.Exec(ctx, `
+		) AS seed(auction_id, user_id, amount, status, previous_high_bid, created_at)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM bids b
+			WHERE b.auction_id = seed.auction_id AND b.user_id = seed.user_id AND b.amount = seed.amount
+		)
+	`},
+	{"watchlist", `
 		INSERT INTO watchlist (user_id, auction_id) VALUES
 		(3, 2), (3, 4), (4, 1), (4, 3), (5, 6)
 		ON CONFLICT DO NOTHING
-	`)
-	if err != nil {
-		h.logger.Error("failed to seed watchlist", slog.String("error", err.Error()))
-		http.Error(w, "failed to seed watchlist: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Insert notifications
-	_, err = tx.Exec(ctx, `
-		INSERT INTO notifications (user_id, type, title, message, data, created_at) VALUES
+	`},
+	{"notifications", `
+		INSERT INTO notifications (user_id, type, title, message, data, created_at)
+		SELECT seed.user_id, seed.type, seed.title, seed.message, seed.data::jsonb, seed.created_at FROM (VALUES
 		(3, 'outbid', 'You''ve been outbid!', 'Someone placed a higher bid on the 2022 Toyota Camry', '{"auction_id": 2, "new_bid": 27000}', NOW() - INTERVAL '1 day'),
 		(4, 'outbid', 'You''ve been outbid!', 'Someone placed a higher bid on the 2021 Honda Accord', '{"auction_id": 1, "new_bid": 24500}', NOW() - INTERVAL '2 days'),
 		(3, 'auction_ending', 'Auction ending soon!', 'The 2021 Honda Accord auction ends in 2 hours', '{"auction_id": 1}', NOW() - INTERVAL '1 hour')
-		ON CONFLICT DO NOTHING
-	`)
+		) AS seed(user_id, type, title, message, data, created_at)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = seed.user_id AND n.type = seed.type AND n.message = seed.message
+		)
+	`},
+	{"sequences", `
+		SELECT setval('users_id_seq', COALESCE((SELECT MAX(id) FROM users), 1)),
+		       setval('vehicles_id_seq', COALESCE((SELECT MAX(id) FROM vehicles), 1)),
+		       setval('auctions_id_seq', COALESCE((SELECT MAX(id) FROM auctions), 1))
+	`},
+}
+
+// seedTables lists the tables ClearSeed empties, children before parents
+var seedTables = []string{
+	"notifications",
+	"watchlist",
+	"reviews",
+	"fulfillments",
+	"orders",
+	"bids",
+	"auctions",
+	"vehicle_images",
+	"vehicles",
+	"users",
+}
+
+// seedExecer is satisfied by both the pool and a transaction
+type seedExecer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// clearSeedTables deletes everything the seed touches
+func clearSeedTables(ctx context.Context, db seedExecer) error {
+	for _, table := range seedTables {
+		if _, err := db.Exec(ctx, "DELETE FROM "+table); err != nil {
+			return fmt.Errorf("clear %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Seed creates sample data for development/testing. It can be run any number
+// of times; ?reset=true clears existing data first, in the same transaction,
+// for when leftover rows conflict with the seed.
+// Only available in development and test environments
+func (h *DebugHandler) Seed(w http.ResponseWriter, r *http.Request) {
+	env := os.Getenv("ENVIRONMENT")
+	if env != "development" && env != "test" && env != "" {
+		http.Error(w, "seed only available in development/test", http.StatusForbidden)
+		return
+	}
+	reset := r.URL.Query().Get("reset") == "true"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	// Start transaction
+	tx, err := h.db.Begin(ctx)
 	if err != nil {
-		h.logger.Error("failed to seed notifications", slog.String("error", err.Error()))
-		http.Error(w, "failed to seed notifications: "+err.Error(), http.StatusInternalServerError)
+		h.logger.Error("failed to start transaction for seed", slog.String("error", err.Error()))
+		http.Error(w, "failed to start transaction", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	// Reset sequences
-	_, _ = tx.Exec(ctx, `SELECT setval('users_id_seq', COALESCE((SELECT MAX(id) FROM users), 1))`)
-	_, _ = tx.Exec(ctx, `SELECT setval('vehicles_id_seq', COALESCE((SELECT MAX(id) FROM vehicles), 1))`)
-	_, _ = tx.Exec(ctx, `SELECT setval('auctions_id_seq', COALESCE((SELECT MAX(id) FROM auctions), 1))`)
+	if reset {
+		if err := clearSeedTables(ctx, tx); err != nil {
+			h.logger.Error("failed to reset before seed", slog.String("error", err.Error()))
+			http.Error(w, "failed to reset: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, step := range seedSteps {
+		if _, err := tx.Exec(ctx, step.sql); err != nil {
+			h.logger.Error("failed to seed", slog.String("step", step.name), slog.String("error", err.Error()))
+			// Existing rows that clash with the seed (same email, VIN, ...) need a reset
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" && !reset {
+				http.Error(w, "failed to seed "+step.name+": existing data conflicts with the seed ("+pgErr.ConstraintName+"); retry with ?reset=true", http.StatusConflict)
+				return
+			}
+			http.Error(w, "failed to seed "+step.name+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		h.logger.Error("failed to commit seed transaction", slog.String("error", err.Error()))
@@ -232,10 +292,11 @@ func (h *DebugHandler) Seed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("seed data created successfully")
+	h.logger.Info("seed data created successfully", slog.Bool("reset", reset))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "seed data created successfully",
+		"reset":   reset,
 		"data": map[string]int{
 			"users":         5,
 			"vehicles":      10,
@@ -259,24 +320,10 @@ func (h *DebugHandler) ClearSeed(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	// Delete in order to respect foreign keys
-	tables := []string{
-		"notifications",
-		"watchlist",
-		"bids",
-		"auctions",
-		"vehicle_images",
-		"vehicles",
-		"users",
-	}
-
-	for _, table := range tables {
-		_, err := h.db.Exec(ctx, "DELETE FROM "+table)
-		if err != nil {
-			h.logger.Error("failed to clear table", slog.String("table", table), slog.String("error", err.Error()))
-			http.Error(w, "failed to clear "+table+": "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+	if err := clearSeedTables(ctx, h.db); err != nil {
+		h.logger.Error("failed to clear seed data", slog.String("error", err.Error()))
+		http.Error(w, "failed to "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	h.logger.Info("seed data cleared successfully")
@@ -285,4 +332,3 @@ func (h *DebugHandler) ClearSeed(w http.ResponseWriter, r *http.Request) {
 		"message": "all seed data cleared",
 	})
 }
-
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Blocklist finds disallowed terms in seller-written text that is shown
+// publicly
+type Blocklist interface {
+	// Match returns the first blocked term in text, or "" if there is none
+	Match(text string) string
+}
+
+// wordBlocklist matches whole words and phrases case-insensitively, so a
+// blocked "ass" doesn't reject "class"
+type wordBlocklist struct {
+	re *regexp.Regexp
+}
+
+// NewWordBlocklist creates a Blocklist of whole-word terms. Blank terms are
+// ignored; with none left it matches nothing.
+func NewWordBlocklist(terms []string) Blocklist {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			quoted = append(quoted, regexp.QuoteMeta(term))
+		}
+	}
+	if len(quoted) == 0 {
+		return wordBlocklist{}
+	}
+	return wordBlocklist{re: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)}
+}
+
+func (b wordBlocklist) Match(text string) string {
+	if b.re == nil {
+		return ""
+	}
+	return strings.ToLower(b.re.FindString(text))
+}
+
+// VehicleHandlerOption configures a VehicleHandler
+type VehicleHandlerOption func(*VehicleHandler)
+
+// WithBlocklist replaces the description blocklist built from
+// DESCRIPTION_BLOCKLIST
+func WithBlocklist(b Blocklist) VehicleHandlerOption {
+	return func(h *VehicleHandler) {
+		h.blocklist = b
+	}
+}
+
+// cleanDescription strips control characters (keeping newlines and tabs) and
+// surrounding whitespace from a description, then checks it against the
+// length limit and blocklist. It returns the cleaned text, or a message for
+// the description field when it's rejected.
+func (h *VehicleHandler) cleanDescription(desc string) (string, string) {
+	desc = strings.ReplaceAll(desc, "\r\n", "\n")
+	desc = strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, desc)
+	desc = strings.TrimSpace(desc)
+
+	if max := h.cfg.DescriptionMaxLength; max > 0 && utf8.RuneCountInString(desc) > max {
+		return "", fmt.Sprintf("must be at most %d characters", max)
+	}
+	if term := h.blocklist.Match(desc); term != "" {
+		return "", fmt.Sprintf("contains a disallowed term: %q", term)
+	}
+	return desc, ""
+}
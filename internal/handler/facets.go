@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// unspecifiedFacet buckets listings whose vehicle has no body type recorded
+const unspecifiedFacet = "unspecified"
+
+// Facets holds listing counts per filter value for the current filter set
+type Facets struct {
+	BodyType map[string]int64 `json:"body_type"`
+	Make     map[string]int64 `json:"make"`
+}
+
+// wantFacets reports whether the caller asked for facet counts. They cost an
+// extra grouped query, so they're opt-in via ?facets=true.
+func wantFacets(r *http.Request) bool {
+	return r.URL.Query().Get("facets") == "true"
+}
+
+// queryFacets counts rows per body type and make in one grouped query.
+// fromWhere is the listing's FROM/WHERE clause with vehicles aliased as v.
+func queryFacets(ctx context.Context, db *pgxpool.Pool, fromWhere string, args ...any) (*Facets, error) {
+	rows, err := db.Query(ctx, `
+		SELECT GROUPING(v.body_type) = 0, COALESCE(v.body_type, '`+unspecifiedFacet+`'), v.make, COUNT(*)
+		`+fromWhere+`
+		GROUP BY GROUPING SETS ((v.body_type), (v.make))
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := &Facets{
+		BodyType: make(map[string]int64),
+		Make:     make(map[string]int64),
+	}
+	for rows.Next() {
+		var byBodyType bool
+		var bodyType string
+		var vehicleMake *string
+		var count int64
+		if err := rows.Scan(&byBodyType, &bodyType, &vehicleMake, &count); err != nil {
+			return nil, err
+		}
+		if byBodyType {
+			facets.BodyType[bodyType] = count
+		} else if vehicleMake != nil {
+			facets.Make[*vehicleMake] = count
+		}
+	}
+	return facets, rows.Err()
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// ListFeaturedAuctions returns the active auctions admins picked for the
+// homepage, ending soonest first
+func (h *AuctionHandler) ListFeaturedAuctions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
+
+	limit := httpx.Limit(r, 12, 50)
+
+	rows, err := h.db.Query(ctx, `
+		SELECT a.id, a.vehicle_id, a.status::text, a.starts_at, a.ends_at,
+		       a.current_bid,
+		       CASE WHEN lu.hide_bidder_identity THEN NULL ELSE a.current_bid_user_id END,
+		       a.bid_count,
+		       v.year, v.make, v.model, v.trim, v.mileage,
+		       v.starting_price, v.exterior_color, v.location_city, v.location_state
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		LEFT JOIN users lu ON lu.id = a.current_bid_user_id
+		WHERE a.featured AND a.status = 'active' AND NOT a.hidden AND a.visibility = 'public'
+		ORDER BY a.ends_at ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		h.logger.Error("failed to query featured auctions", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	auctions := make([]AuctionResponse, 0)
+	for rows.Next() {
+		var a AuctionResponse
+		var startsAt, endsAt time.Time
+		var currentBid, startingPrice float64
+		err := rows.Scan(
+			&a.ID, &a.VehicleID, &a.Status, &startsAt, &endsAt,
+			&currentBid, &a.CurrentBidUserID, &a.BidCount,
+			&a.Year, &a.Make, &a.Model, &a.Trim, &a.Mileage,
+			&startingPrice, &a.ExteriorColor, &a.LocationCity, &a.LocationState,
+		)
+		if err != nil {
+			h.logger.Error("failed to scan featured auction", slog.String("error", err.Error()))
+			continue
+		}
+		a.StartsAt = startsAt.Format(time.RFC3339)
+		a.EndsAt = endsAt.Format(time.RFC3339)
+		a.CurrentBid = strconv.FormatFloat(currentBid, 'f', 2, 64)
+		a.StartingPrice = strconv.FormatFloat(startingPrice, 'f', 2, 64)
+		a.SecondsRemaining = secondsRemaining(a.Status, endsAt, now)
+		auctions = append(auctions, a)
+	}
+	if err := rows.Err(); err != nil {
+		h.logger.Error("failed to read featured auctions", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auctions":    auctions,
+		"server_time": now.UTC().Format(time.RFC3339),
+	})
+}
+
+// SetFeatured lets an admin add an auction to or remove it from the featured list
+func (h *AuctionHandler) SetFeatured(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminID := middleware.GetUserID(ctx)
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Featured *bool `json:"featured" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	tag, err := h.db.Exec(ctx, `UPDATE auctions SET featured = $2 WHERE id = $1`, auctionID, *req.Featured)
+	if err != nil {
+		h.logger.Error("failed to update featured flag", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to update auction", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+
+	h.logger.Info("admin_auction_featured",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("admin_id", adminID),
+		slog.Bool("featured", *req.Featured),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auction_id": auctionID,
+		"featured":   *req.Featured,
+	})
+}
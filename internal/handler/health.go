@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/version"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -25,6 +26,22 @@ type HealthResponse struct {
 	Timestamp string            `json:"timestamp"`
 	Uptime    string            `json:"uptime"`
 	Checks    map[string]string `json:"checks"`
+	BuildInfo
+}
+
+// BuildInfo identifies the running build; see the version package
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+func currentBuild() BuildInfo {
+	return BuildInfo{
+		Version:   version.Version,
+		Commit:    version.Commit,
+		BuildTime: version.BuildTime,
+	}
 }
 
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
@@ -45,6 +62,7 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Uptime:    time.Since(h.startTime).Round(time.Second).String(),
 		Checks:    checks,
+		BuildInfo: currentBuild(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -54,6 +72,13 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// Version reports the running build without touching the database, for
+// deploy verification
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentBuild())
+}
+
 func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 	// Readiness check - can the service accept traffic?
 	ctx := r.Context()
@@ -13,6 +13,7 @@ import (
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -28,6 +29,8 @@ type ImageHandler struct {
 type S3Presigner interface {
 	GenerateUploadURL(ctx context.Context, bucket, key, contentType string, expires time.Duration) (string, error)
 	DeleteObject(ctx context.Context, bucket, key string) error
+	// ObjectExists issues a HEAD request to confirm an object was uploaded
+	ObjectExists(ctx context.Context, bucket, key string) (bool, error)
 }
 
 func NewImageHandler(db *pgxpool.Pool, logger *slog.Logger, cfg *config.Config, s3 S3Presigner) *ImageHandler {
@@ -59,12 +62,7 @@ func (h *ImageHandler) GetUploadURL(w http.ResponseWriter, r *http.Request) {
 	// Check ownership
 	var sellerID int64
 	err = h.db.QueryRow(ctx, `SELECT seller_id FROM vehicles WHERE id = $1`, vehicleID).Scan(&sellerID)
-	if err != nil {
-		h.jsonError(w, "vehicle not found", http.StatusNotFound)
-		return
-	}
-	if sellerID != userID {
-		h.jsonError(w, "not authorized", http.StatusForbidden)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
 		return
 	}
 
@@ -133,12 +131,7 @@ func (h *ImageHandler) AddImage(w http.ResponseWriter, r *http.Request) {
 	// Check ownership
 	var sellerID int64
 	err = h.db.QueryRow(ctx, `SELECT seller_id FROM vehicles WHERE id = $1`, vehicleID).Scan(&sellerID)
-	if err != nil {
-		h.jsonError(w, "vehicle not found", http.StatusNotFound)
-		return
-	}
-	if sellerID != userID {
-		h.jsonError(w, "not authorized", http.StatusForbidden)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
 		return
 	}
 
@@ -157,17 +150,43 @@ func (h *ImageHandler) AddImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Confirm the client actually uploaded to the presigned URL
+	if h.cfg.ImageVerifyUploads && h.s3 != nil {
+		exists, err := h.s3.ObjectExists(ctx, h.cfg.AWSS3Bucket, req.S3Key)
+		if err != nil {
+			h.logger.Error("failed to verify upload", slog.String("error", err.Error()), slog.String("s3_key", req.S3Key))
+			h.jsonError(w, "failed to verify upload", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			h.logger.Warn("image_upload_missing",
+				slog.Int64("vehicle_id", vehicleID),
+				slog.String("s3_key", req.S3Key),
+			)
+			h.jsonError(w, "uploaded image not found in storage", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.logger.Error("failed to begin transaction", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to add image", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
 	// If marking as primary, unset other primary images
 	if req.IsPrimary {
-		h.db.Exec(ctx, `UPDATE vehicle_images SET is_primary = false WHERE vehicle_id = $1`, vehicleID)
+		tx.Exec(ctx, `UPDATE vehicle_images SET is_primary = false WHERE vehicle_id = $1`, vehicleID)
 	}
 
 	// Get next display order
 	var maxOrder int
-	h.db.QueryRow(ctx, `SELECT COALESCE(MAX(display_order), 0) FROM vehicle_images WHERE vehicle_id = $1`, vehicleID).Scan(&maxOrder)
+	tx.QueryRow(ctx, `SELECT COALESCE(MAX(display_order), 0) FROM vehicle_images WHERE vehicle_id = $1`, vehicleID).Scan(&maxOrder)
 
 	var imageID int64
-	err = h.db.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
 		INSERT INTO vehicle_images (vehicle_id, s3_key, url, is_primary, display_order)
 		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id
@@ -179,6 +198,22 @@ func (h *ImageHandler) AddImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	isPrimary := req.IsPrimary
+	if h.cfg.ImageAutoPrimary {
+		isPrimary, err = reconcilePrimaryImage(ctx, tx, vehicleID, imageID)
+		if err != nil {
+			h.logger.Error("failed to reconcile primary image", slog.String("error", err.Error()))
+			h.jsonError(w, "failed to add image", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.logger.Error("failed to commit image", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to add image", http.StatusInternalServerError)
+		return
+	}
+
 	h.logger.Info("image_added",
 		slog.Int64("image_id", imageID),
 		slog.Int64("vehicle_id", vehicleID),
@@ -189,7 +224,7 @@ func (h *ImageHandler) AddImage(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message":    "Image added",
 		"image_id":   imageID,
-		"is_primary": req.IsPrimary,
+		"is_primary": isPrimary,
 	})
 }
 
@@ -220,12 +255,7 @@ func (h *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	// Check ownership
 	var sellerID int64
 	err = h.db.QueryRow(ctx, `SELECT seller_id FROM vehicles WHERE id = $1`, vehicleID).Scan(&sellerID)
-	if err != nil {
-		h.jsonError(w, "vehicle not found", http.StatusNotFound)
-		return
-	}
-	if sellerID != userID {
-		h.jsonError(w, "not authorized", http.StatusForbidden)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
 		return
 	}
 
@@ -258,6 +288,29 @@ func (h *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Image deleted"})
 }
 
+// reconcilePrimaryImage ensures exactly one primary image exists for a vehicle.
+// An existing primary (including one just hinted via is_primary) is kept;
+// otherwise the first uploaded image is promoted. Reports whether imageID
+// ended up as the primary.
+func reconcilePrimaryImage(ctx context.Context, tx pgx.Tx, vehicleID, imageID int64) (bool, error) {
+	var primaryID int64
+	err := tx.QueryRow(ctx, `
+		WITH chosen AS (
+			SELECT id FROM vehicle_images
+			WHERE vehicle_id = $1
+			ORDER BY is_primary DESC, display_order ASC, id ASC
+			LIMIT 1
+		)
+		UPDATE vehicle_images SET is_primary = (id = (SELECT id FROM chosen))
+		WHERE vehicle_id = $1
+		RETURNING (SELECT id FROM chosen)
+	`, vehicleID).Scan(&primaryID)
+	if err != nil {
+		return false, err
+	}
+	return primaryID == imageID, nil
+}
+
 func (h *ImageHandler) jsonError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
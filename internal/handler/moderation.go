@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ModerationHandler handles the admin moderation queue
+type ModerationHandler struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewModerationHandler(db *pgxpool.Pool, logger *slog.Logger) *ModerationHandler {
+	return &ModerationHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListQueue returns moderation entries, pending by default
+func (h *ModerationHandler) ListQueue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "pending"
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT m.id, m.vehicle_id, m.status::text, m.reason, m.reviewer_id, m.created_at, m.reviewed_at,
+		       v.year, v.make, v.model, v.seller_id
+		FROM moderation_queue m
+		JOIN vehicles v ON m.vehicle_id = v.id
+		WHERE m.status::text = $1
+		ORDER BY m.created_at ASC
+		LIMIT 100
+	`, status)
+	if err != nil {
+		h.logger.Error("failed to query moderation queue", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var (
+			id, vehicleID, sellerID int64
+			entryStatus             string
+			reason                  *string
+			reviewerID              *int64
+			createdAt               time.Time
+			reviewedAt              *time.Time
+			year                    int
+			vehicleMake, model      string
+		)
+		if err := rows.Scan(&id, &vehicleID, &entryStatus, &reason, &reviewerID, &createdAt, &reviewedAt,
+			&year, &vehicleMake, &model, &sellerID); err != nil {
+			h.logger.Error("failed to scan moderation entry", slog.String("error", err.Error()))
+			continue
+		}
+		items = append(items, map[string]interface{}{
+			"id":          id,
+			"vehicle_id":  vehicleID,
+			"status":      entryStatus,
+			"reason":      reason,
+			"reviewer_id": reviewerID,
+			"created_at":  createdAt.Format(time.RFC3339),
+			"reviewed_at": reviewedAt,
+			"vehicle": map[string]interface{}{
+				"year":      year,
+				"make":      vehicleMake,
+				"model":     model,
+				"seller_id": sellerID,
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items": items,
+		"count": len(items),
+	})
+}
+
+// ReviewVehicle records a moderation decision for a vehicle. Flagged or
+// rejected vehicles have their auction hidden from public lists; approving
+// a vehicle makes it visible again.
+func (h *ModerationHandler) ReviewVehicle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reviewerID := middleware.GetUserID(ctx)
+	if reviewerID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	vehicleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid vehicle id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var hide bool
+	switch req.Status {
+	case "approved":
+		hide = false
+	case "flagged", "rejected":
+		hide = true
+	default:
+		h.jsonError(w, "status must be one of approved, flagged, rejected", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM vehicles WHERE id = $1)`, vehicleID).Scan(&exists); err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		h.jsonError(w, "vehicle not found", http.StatusNotFound)
+		return
+	}
+
+	// Resolve the oldest pending entry, or record a new decision if none is queued
+	var entryID int64
+	err = tx.QueryRow(ctx, `
+		UPDATE moderation_queue SET
+			status = $2::moderation_status, reason = $3, reviewer_id = $4, reviewed_at = NOW()
+		WHERE id = (
+			SELECT id FROM moderation_queue
+			WHERE vehicle_id = $1 AND status = 'pending'
+			ORDER BY created_at ASC LIMIT 1
+		)
+		RETURNING id
+	`, vehicleID, req.Status, nilIfEmpty(req.Reason), reviewerID).Scan(&entryID)
+	if err == pgx.ErrNoRows {
+		err = tx.QueryRow(ctx, `
+			INSERT INTO moderation_queue (vehicle_id, status, reason, reviewer_id, reviewed_at)
+			VALUES ($1, $2::moderation_status, $3, $4, NOW())
+			RETURNING id
+		`, vehicleID, req.Status, nilIfEmpty(req.Reason), reviewerID).Scan(&entryID)
+	}
+	if err != nil {
+		h.logger.Error("failed to record moderation decision", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to record decision", http.StatusInternalServerError)
+		return
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE auctions SET hidden = $2, hidden_reason = CASE WHEN $2 THEN $3 ELSE NULL END
+		WHERE vehicle_id = $1
+	`, vehicleID, hide, nilIfEmpty(req.Reason))
+	if err != nil {
+		h.logger.Error("failed to update auction visibility", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to record decision", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.jsonError(w, "failed to record decision", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("vehicle_moderated",
+		slog.Int64("vehicle_id", vehicleID),
+		slog.String("status", req.Status),
+		slog.Int64("reviewer_id", reviewerID),
+		slog.Int64("auctions_affected", tag.RowsAffected()),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":             entryID,
+		"vehicle_id":     vehicleID,
+		"status":         req.Status,
+		"auction_hidden": hide && tag.RowsAffected() > 0,
+	})
+}
+
+func (h *ModerationHandler) jsonError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -2,11 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -35,92 +39,84 @@ func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	limit := 20
-	offset := 0
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, _ := strconv.Atoi(l); parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
-	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, _ := strconv.Atoi(o); parsed >= 0 {
-			offset = parsed
-		}
-	}
+	limit, offset := httpx.Pagination(r)
 
 	unreadOnly := r.URL.Query().Get("unread") == "true"
 
-	var query string
-	var args []interface{}
-	if unreadOnly {
-		query = `
-			SELECT id, type, title, message, data, read_at, created_at
-			FROM notifications
-			WHERE user_id = $1 AND read_at IS NULL
-			ORDER BY created_at DESC
-			LIMIT $2 OFFSET $3
-		`
-		args = []interface{}{userID, limit, offset}
-	} else {
-		query = `
-			SELECT id, type, title, message, data, read_at, created_at
-			FROM notifications
-			WHERE user_id = $1
-			ORDER BY created_at DESC
-			LIMIT $2 OFFSET $3
-		`
-		args = []interface{}{userID, limit, offset}
-	}
-
-	rows, err := h.db.Query(ctx, query, args...)
+	types, err := notificationTypes(r)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// A nil types array matches every type
+	rows, err := h.db.Query(ctx, `
+		SELECT id, type, title, message, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1
+		  AND ($2::text[] IS NULL OR type = ANY($2))
+		  AND (NOT $3 OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5
+	`, userID, types, unreadOnly, limit, offset)
 	if err != nil {
 		h.jsonError(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	notifications := make([]map[string]interface{}, 0)
+	// Same shape as the notification stream pushes
+	notifications := make([]domain.Notification, 0)
 	for rows.Next() {
 		var (
-			id                    int64
-			notifType, title      string
-			message               *string
-			data                  []byte
-			readAt                *time.Time
-			createdAt             time.Time
+			n       domain.Notification
+			message *string
+			data    []byte
+			readAt  *time.Time
 		)
-		rows.Scan(&id, &notifType, &title, &message, &data, &readAt, &createdAt)
-
-		notif := map[string]interface{}{
-			"id":         id,
-			"type":       notifType,
-			"title":      title,
-			"message":    message,
-			"read":       readAt != nil,
-			"created_at": createdAt.Format(time.RFC3339),
+		rows.Scan(&n.ID, &n.Type, &n.Title, &message, &data, &readAt, &n.CreatedAt)
+		if message != nil {
+			n.Message = *message
 		}
 		if data != nil {
-			var parsedData interface{}
-			if json.Unmarshal(data, &parsedData) == nil {
-				notif["data"] = parsedData
-			}
+			json.Unmarshal(data, &n.Data)
 		}
-		notifications = append(notifications, notif)
+		n.Read = readAt != nil
+		notifications = append(notifications, n)
 	}
 
-	// Get counts
+	// Get counts, within the type filter if one was given
 	var total, unread int64
-	h.db.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1`, userID).Scan(&total)
-	h.db.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&unread)
+	h.db.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE read_at IS NULL)
+		FROM notifications
+		WHERE user_id = $1 AND ($2::text[] IS NULL OR type = ANY($2))
+	`, userID, types).Scan(&total, &unread)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"notifications": notifications,
-		"total":         total,
-		"unread":        unread,
-		"limit":         limit,
-		"offset":        offset,
-	})
+	json.NewEncoder(w).Encode(struct {
+		domain.PaginatedResponse[domain.Notification]
+		Unread int64 `json:"unread"`
+	}{domain.NewPaginatedResponse(notifications, total, limit, offset), unread})
+}
+
+// notificationTypes reads ?type= filters, repeated or comma-separated. It
+// returns nil when there are none and an error naming any unknown type.
+func notificationTypes(r *http.Request) ([]string, error) {
+	var types []string
+	for _, param := range r.URL.Query()["type"] {
+		for _, t := range strings.Split(param, ",") {
+			t = strings.TrimSpace(t)
+			if t == "" {
+				continue
+			}
+			if !domain.NotificationTypes[t] {
+				return nil, fmt.Errorf("unknown notification type %q", t)
+			}
+			types = append(types, t)
+		}
+	}
+	return types, nil
 }
 
 // GetUnreadCount returns count of unread notifications
@@ -175,6 +171,50 @@ func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Notification marked as read"})
 }
 
+// maxMarkReadIDs bounds how many notifications one bulk mark-read can name
+const maxMarkReadIDs = 100
+
+// MarkReadBulk marks the listed notifications as read. IDs that don't belong
+// to the caller or are already read are skipped; the response reports how
+// many were updated.
+func (h *NotificationHandler) MarkReadBulk(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		IDs []int64 `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 {
+		h.jsonError(w, "ids must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) > maxMarkReadIDs {
+		h.jsonError(w, "at most "+strconv.Itoa(maxMarkReadIDs)+" ids per request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec(ctx, `
+		UPDATE notifications SET read_at = NOW()
+		WHERE id = ANY($1) AND user_id = $2 AND read_at IS NULL
+	`, req.IDs, userID)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"updated": result.RowsAffected()})
+}
+
 // MarkAllRead marks all notifications as read
 func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
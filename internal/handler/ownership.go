@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+)
+
+// requireOwner finishes an ownership lookup. A resource the caller doesn't own
+// gets the same 404 as a missing one, so non-owners can't enumerate IDs by
+// telling a 403 apart from a 404; only the not-owned case is audited as an
+// authz denial. It writes the error response and returns false when the
+// handler should stop.
+func requireOwner(w http.ResponseWriter, r *http.Request, logger *slog.Logger, lookupErr error, ownerID, userID int64, resource string) bool {
+	if isQueryTimeout(lookupErr) {
+		writeQueryError(w, lookupErr)
+		return false
+	}
+	if lookupErr == nil && ownerID != userID {
+		middleware.AuditDenied(logger, r, resource, middleware.DeniedNotOwner)
+	}
+	if lookupErr != nil || ownerID != userID {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": resource + " not found"})
+		return false
+	}
+	return true
+}
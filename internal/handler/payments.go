@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/payments"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PaymentHandler exposes a user's payment setup
+type PaymentHandler struct {
+	db      *pgxpool.Pool
+	logger  *slog.Logger
+	gateway payments.Gateway
+}
+
+func NewPaymentHandler(db *pgxpool.Pool, logger *slog.Logger, gateway payments.Gateway) *PaymentHandler {
+	return &PaymentHandler{
+		db:      db,
+		logger:  logger,
+		gateway: gateway,
+	}
+}
+
+// GetPaymentStatus returns masked payment methods and verification status
+func (h *PaymentHandler) GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var idVerifiedAt *time.Time
+	var profileID *string
+	err := h.db.QueryRow(ctx, `
+		SELECT id_verified_at, authorize_payment_profile_id FROM users WHERE id = $1
+	`, userID).Scan(&idVerifiedAt, &profileID)
+	if err != nil {
+		h.jsonError(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	hasProfile := profileID != nil && *profileID != ""
+	methods := make([]payments.PaymentMethod, 0)
+
+	if hasProfile {
+		if h.gateway == nil {
+			h.jsonError(w, "payment gateway unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		found, err := h.gateway.ListPaymentMethods(ctx, *profileID)
+		if err != nil {
+			h.logger.Error("failed to list payment methods",
+				slog.Int64("user_id", userID),
+				slog.String("error", err.Error()),
+			)
+			h.jsonError(w, "failed to fetch payment methods", http.StatusBadGateway)
+			return
+		}
+
+		for _, m := range found {
+			// Defensive: only ever expose the last four digits
+			m.Last4 = lastFour(m.Last4)
+			methods = append(methods, m)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"has_payment_method": len(methods) > 0,
+		"payment_methods":    methods,
+		"verification": map[string]interface{}{
+			"is_id_verified": idVerifiedAt != nil,
+			"id_verified_at": idVerifiedAt,
+		},
+		"can_bid": idVerifiedAt != nil && len(methods) > 0,
+	})
+}
+
+// lastFour keeps only the trailing four characters of a card number
+func lastFour(s string) string {
+	if len(s) <= 4 {
+		return s
+	}
+	return s[len(s)-4:]
+}
+
+func (h *PaymentHandler) jsonError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// minPricingSamples is the fewest comparable sales needed to suggest prices
+	minPricingSamples = 3
+
+	// pricingYearWindow is how many model years either side count as comparable
+	pricingYearWindow = 2
+
+	// defaultSellThrough is reported when there isn't enough history to estimate
+	defaultSellThrough = 0.5
+)
+
+// marketQuery selects the comparable vehicles for a market-data aggregation.
+// An empty Model matches any model of the make.
+type marketQuery struct {
+	Make             string
+	Model            string
+	MinYear          int
+	MaxYear          int
+	ExcludeVehicleID int64
+	Target           float64 // price a sale must reach to count in AtOrAbove
+}
+
+// marketData summarizes ended auctions for comparable vehicles
+type marketData struct {
+	Ended     int
+	Sold      int
+	AtOrAbove int
+	P25       *float64
+	Median    *float64
+}
+
+// SellThrough returns the share of ended auctions that sold
+func (m marketData) SellThrough() float64 {
+	if m.Ended == 0 {
+		return 0
+	}
+	return float64(m.Sold) / float64(m.Ended)
+}
+
+// queryMarketData aggregates historical sold prices for comparable vehicles
+func queryMarketData(ctx context.Context, db *pgxpool.Pool, q marketQuery) (marketData, error) {
+	var m marketData
+	err := db.QueryRow(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE a.winner_id IS NOT NULL),
+			COUNT(*) FILTER (WHERE a.winner_id IS NOT NULL AND a.winning_bid >= $6),
+			percentile_cont(0.25) WITHIN GROUP (ORDER BY a.winning_bid) FILTER (WHERE a.winner_id IS NOT NULL),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY a.winning_bid) FILTER (WHERE a.winner_id IS NOT NULL)
+		FROM auctions a
+		JOIN vehicles v ON v.id = a.vehicle_id
+		WHERE a.status = 'ended'
+		  AND LOWER(v.make) = LOWER($1)
+		  AND ($2 = '' OR LOWER(v.model) = LOWER($2))
+		  AND v.year BETWEEN $3 AND $4
+		  AND v.id <> $5
+	`, q.Make, q.Model, q.MinYear, q.MaxYear, q.ExcludeVehicleID, q.Target).Scan(
+		&m.Ended, &m.Sold, &m.AtOrAbove, &m.P25, &m.Median,
+	)
+	return m, err
+}
+
+type PriceRange struct {
+	Low  string `json:"low"`
+	High string `json:"high"`
+}
+
+type PricingInsightsResponse struct {
+	VehicleID              int64       `json:"vehicle_id"`
+	Comparables            string      `json:"comparables"` // make_model, make, or none
+	SampleSize             int         `json:"sample_size"`
+	Confidence             string      `json:"confidence"`
+	MedianSalePrice        *string     `json:"median_sale_price"`
+	SuggestedStartingPrice *PriceRange `json:"suggested_starting_price"`
+	SuggestedReservePrice  *PriceRange `json:"suggested_reserve_price"`
+	SellThroughRate        *float64    `json:"sell_through_rate"`
+	EstimatedSellThrough   float64     `json:"estimated_sell_through"`
+	Message                string      `json:"message,omitempty"`
+}
+
+// GetPricingInsights suggests starting and reserve prices for a seller's
+// vehicle from recent sales of similar vehicles
+func (h *VehicleHandler) GetPricingInsights(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	vehicleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid vehicle id", http.StatusBadRequest)
+		return
+	}
+
+	var sellerID int64
+	var year int
+	var vehicleMake, model string
+	var startingPrice float64
+	var reservePrice *float64
+	err = h.db.QueryRow(ctx, `
+		SELECT seller_id, year, make, model, starting_price, reserve_price
+		FROM vehicles WHERE id = $1
+	`, vehicleID).Scan(&sellerID, &year, &vehicleMake, &model, &startingPrice, &reservePrice)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
+		return
+	}
+
+	// Sell-through is judged against the price the seller actually needs
+	target := startingPrice
+	if reservePrice != nil {
+		target = *reservePrice
+	}
+
+	query := marketQuery{
+		Make:             vehicleMake,
+		Model:            model,
+		MinYear:          year - pricingYearWindow,
+		MaxYear:          year + pricingYearWindow,
+		ExcludeVehicleID: vehicleID,
+		Target:           target,
+	}
+	comparables := "make_model"
+	market, err := queryMarketData(ctx, h.db, query)
+	if err == nil && market.Sold < minPricingSamples {
+		// Widen to the whole make before giving up
+		query.Model = ""
+		comparables = "make"
+		market, err = queryMarketData(ctx, h.db, query)
+	}
+	if err != nil {
+		h.logger.Error("failed to aggregate market data", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := PricingInsightsResponse{
+		VehicleID:            vehicleID,
+		Comparables:          comparables,
+		SampleSize:           market.Sold,
+		Confidence:           pricingConfidence(market.Sold, comparables),
+		EstimatedSellThrough: defaultSellThrough,
+	}
+
+	if market.Sold < minPricingSamples || market.P25 == nil || market.Median == nil {
+		resp.Comparables = "none"
+		resp.Message = "not enough comparable sales to suggest prices"
+	} else {
+		median := roundPrice(*market.Median)
+		resp.MedianSalePrice = &median
+		resp.SuggestedStartingPrice = &PriceRange{
+			Low:  roundPrice(*market.P25 * 0.6),
+			High: roundPrice(*market.P25 * 0.8),
+		}
+		resp.SuggestedReservePrice = &PriceRange{
+			Low:  roundPrice(*market.P25),
+			High: median,
+		}
+
+		rate := math.Round(market.SellThrough()*100) / 100
+		resp.SellThroughRate = &rate
+
+		// Scale the base rate by how often comparable sales cleared this price
+		estimate := market.SellThrough() * float64(market.AtOrAbove) / float64(market.Sold)
+		resp.EstimatedSellThrough = math.Round(estimate*100) / 100
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// pricingConfidence grades an estimate by how many sales back it
+func pricingConfidence(sold int, comparables string) string {
+	switch {
+	case sold < minPricingSamples:
+		return "insufficient_data"
+	case sold < 10 || comparables == "make":
+		return "low"
+	case sold < 30:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// roundPrice rounds to the nearest hundred dollars
+func roundPrice(v float64) string {
+	return decimal.NewFromFloat(math.Round(v/100) * 100).StringFixed(2)
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+)
+
+// bidderNumbersCTE numbers an auction's bidders ($1) in the order they first
+// bid. The number is the stable per-auction pseudonym for bidders who hide
+// their identity, so the same person reads as the same "Bidder N" everywhere.
+const bidderNumbersCTE = `
+	WITH bidder_numbers AS (
+		SELECT user_id, ROW_NUMBER() OVER (ORDER BY MIN(created_at), user_id) AS n
+		FROM bids
+		WHERE auction_id = $1
+		GROUP BY user_id
+	)
+`
+
+// bidderAlias formats a bidder number as the pseudonym shown in place of a name
+func bidderAlias(n int) string {
+	return fmt.Sprintf("Bidder %d", n)
+}
+
+// bidderAliasFor returns the pseudonym of one bidder on an auction
+func (h *AuctionHandler) bidderAliasFor(ctx context.Context, auctionID, userID int64) (string, error) {
+	var n int
+	err := h.db.QueryRow(ctx, bidderNumbersCTE+`
+		SELECT n FROM bidder_numbers WHERE user_id = $2
+	`, auctionID, userID).Scan(&n)
+	if err != nil {
+		return "", err
+	}
+	return bidderAlias(n), nil
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/cache"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+)
+
+// AuctionCache holds the rows GetAuction reads, so a busy auction page isn't
+// a query per view. Bids and other auction events expire entries through
+// Observe; changes that send no event, like moderation, show up within the
+// TTL.
+type AuctionCache struct {
+	rows *cache.Cache[int64, auctionRow]
+}
+
+// NewAuctionCache creates an auction cache whose entries live for ttl
+func NewAuctionCache(ttl time.Duration) *AuctionCache {
+	return &AuctionCache{rows: cache.New[int64, auctionRow]("auction", ttl)}
+}
+
+// Observe expires the cached row an auction event changed. Hook it to the
+// broker with realtime.WithEventHook so events relayed from other instances
+// count too.
+func (c *AuctionCache) Observe(event domain.BidEvent) {
+	if event.Type == "viewer_count" {
+		return
+	}
+	c.rows.Invalidate(event.AuctionID, event.Version)
+}
+
+// AuctionHandlerOption configures an AuctionHandler
+type AuctionHandlerOption func(*AuctionHandler)
+
+// WithAuctionCache serves GetAuction through c
+func WithAuctionCache(c *AuctionCache) AuctionHandlerOption {
+	return func(h *AuctionHandler) {
+		h.cache = c
+	}
+}
+
+// expireVehicle drops a cached vehicle once it changes, unless the cache
+// already holds version or later; 0 always drops it. Vehicle changes made
+// outside VehicleHandler show up within the TTL.
+func (h *VehicleHandler) expireVehicle(vehicleID int64, version int) {
+	if h.cache != nil {
+		h.cache.Invalidate(vehicleID, version)
+	}
+}
+
+// expireAuction drops a cached auction after a change that sends no event
+func (h *AuctionHandler) expireAuction(auctionID int64) {
+	if h.cache != nil {
+		h.cache.rows.Invalidate(auctionID, 0)
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+)
+
+// RecommendedAuction is an active auction suggested from the caller's
+// interests, with the score it was ranked by
+type RecommendedAuction struct {
+	AuctionResponse
+	BodyType *string `json:"body_type,omitempty"`
+	Score    int     `json:"score"`
+}
+
+// GetRecommendations suggests active auctions similar to the ones the caller
+// watches or has bid on. Each candidate scores 2 per interest sharing its
+// make, 1 per interest sharing its body type, and 1 more when its price falls
+// within the band the caller has shown interest in (25% either side).
+// Auctions already watched or bid on, and the caller's own listings, are left out.
+func (h *WatchlistHandler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	limit := httpx.Limit(r, 10, 50)
+
+	rows, err := h.db.Query(ctx, `
+		WITH seen AS (
+			SELECT auction_id FROM watchlist WHERE user_id = $1
+			UNION
+			SELECT auction_id FROM bids WHERE user_id = $1 AND status <> 'retracted'
+		),
+		interests AS (
+			SELECT LOWER(v.make) AS make, LOWER(v.body_type) AS body_type,
+			       GREATEST(a.current_bid, v.starting_price) AS price
+			FROM seen s
+			JOIN auctions a ON a.id = s.auction_id
+			JOIN vehicles v ON v.id = a.vehicle_id
+		),
+		makes AS (SELECT make, COUNT(*) AS n FROM interests GROUP BY make),
+		bodies AS (SELECT body_type, COUNT(*) AS n FROM interests WHERE body_type IS NOT NULL GROUP BY body_type),
+		band AS (SELECT MIN(price) * 0.75 AS low, MAX(price) * 1.25 AS high FROM interests),
+		scored AS (
+			SELECT a.id, a.vehicle_id, a.status::text AS status, a.starts_at, a.ends_at,
+			       a.current_bid, a.bid_count,
+			       v.year, v.make, v.model, v.trim, v.mileage, v.body_type,
+			       v.starting_price, v.exterior_color, v.location_city, v.location_state,
+			       COALESCE(m.n, 0) * 2 + COALESCE(bt.n, 0)
+			       + CASE WHEN GREATEST(a.current_bid, v.starting_price) BETWEEN band.low AND band.high THEN 1 ELSE 0 END AS score
+			FROM auctions a
+			JOIN vehicles v ON a.vehicle_id = v.id
+			CROSS JOIN band
+			LEFT JOIN makes m ON m.make = LOWER(v.make)
+			LEFT JOIN bodies bt ON bt.body_type = LOWER(v.body_type)
+			WHERE a.status = 'active' AND NOT a.hidden AND a.visibility = 'public'
+			  AND v.seller_id <> $1
+			  AND a.id NOT IN (SELECT auction_id FROM seen)
+		)
+		SELECT id, vehicle_id, status, starts_at, ends_at, current_bid, bid_count,
+		       year, make, model, trim, mileage, body_type,
+		       starting_price, exterior_color, location_city, location_state, score
+		FROM scored
+		WHERE score > 0
+		ORDER BY score DESC, ends_at ASC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		h.logger.Error("failed to query recommendations", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	recommendations := make([]RecommendedAuction, 0)
+	for rows.Next() {
+		var a RecommendedAuction
+		var startsAt, endsAt time.Time
+		var currentBid, startingPrice float64
+		err := rows.Scan(
+			&a.ID, &a.VehicleID, &a.Status, &startsAt, &endsAt, &currentBid, &a.BidCount,
+			&a.Year, &a.Make, &a.Model, &a.Trim, &a.Mileage, &a.BodyType,
+			&startingPrice, &a.ExteriorColor, &a.LocationCity, &a.LocationState, &a.Score,
+		)
+		if err != nil {
+			h.logger.Error("failed to scan recommendation", slog.String("error", err.Error()))
+			continue
+		}
+		a.StartsAt = startsAt.Format(time.RFC3339)
+		a.EndsAt = endsAt.Format(time.RFC3339)
+		a.CurrentBid = strconv.FormatFloat(currentBid, 'f', 2, 64)
+		a.StartingPrice = strconv.FormatFloat(startingPrice, 'f', 2, 64)
+		a.SecondsRemaining = secondsRemaining(a.Status, endsAt, now)
+		recommendations = append(recommendations, a)
+	}
+	if err := rows.Err(); err != nil {
+		h.logger.Error("failed to read recommendations", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recommendations": recommendations,
+	})
+}
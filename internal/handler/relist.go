@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/closer"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+type RelistAuctionRequest struct {
+	// ReservePrice replaces the vehicle's reserve for the new auction
+	ReservePrice *float64 `json:"reserve_price" validate:"omitempty,gt=0"`
+	// StartsAt defaults to now; EndsAt defaults to the previous auction's length
+	StartsAt string `json:"starts_at"`
+	EndsAt   string `json:"ends_at"`
+}
+
+// RelistAuction lets the seller put a vehicle back up after its auction ended
+// without a sale, optionally with a new reserve and schedule. The new auction
+// keeps the old one's bidding rules and starts with no bids.
+func (h *AuctionHandler) RelistAuction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	var req RelistAuctionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.jsonError(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	now := time.Now()
+	startsAt := now
+	if req.StartsAt != "" {
+		if startsAt, err = time.Parse(time.RFC3339, req.StartsAt); err != nil {
+			h.jsonError(w, "invalid starts_at format (use RFC3339)", http.StatusBadRequest)
+			return
+		}
+		if startsAt.Before(now) {
+			startsAt = now
+		}
+	}
+	var endsAt *time.Time
+	if req.EndsAt != "" {
+		parsed, err := time.Parse(time.RFC3339, req.EndsAt)
+		if err != nil {
+			h.jsonError(w, "invalid ends_at format (use RFC3339)", http.StatusBadRequest)
+			return
+		}
+		if !parsed.After(startsAt) {
+			h.jsonError(w, "ends_at must be after starts_at", http.StatusBadRequest)
+			return
+		}
+		endsAt = &parsed
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		sellerID, vehicleID int64
+		status              string
+		winnerID            *int64
+		relisted            bool
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT v.seller_id, a.vehicle_id, a.status::text, a.winner_id,
+		       EXISTS (SELECT 1 FROM auctions n WHERE n.relisted_from = a.id)
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+		FOR UPDATE OF a
+	`, auctionID).Scan(&sellerID, &vehicleID, &status, &winnerID, &relisted)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "auction") {
+		return
+	}
+	if status != "ended" || winnerID != nil {
+		h.jsonError(w, "only auctions that ended without a sale can be relisted", http.StatusConflict)
+		return
+	}
+	if relisted {
+		h.jsonError(w, "auction has already been relisted", http.StatusConflict)
+		return
+	}
+
+	// The vehicle may have been transferred since it was last listed
+	verified, err := sellerCanList(ctx, tx, sellerID)
+	if err != nil {
+		h.logger.Error("failed to check seller verification", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to relist auction", http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		middleware.AuditDenied(h.logger, r, "auction", middleware.DeniedSellerNotVerified)
+		writeSellerNotVerified(w, http.StatusForbidden, "complete ID verification before listing a vehicle")
+		return
+	}
+
+	if req.ReservePrice != nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE vehicles SET reserve_price = $2, version = version + 1, updated_at = NOW()
+			WHERE id = $1
+		`, vehicleID, *req.ReservePrice)
+		if err != nil {
+			h.logger.Error("failed to update reserve for relist", slog.String("error", err.Error()))
+			h.jsonError(w, "failed to relist auction", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	relisting, err := closer.RelistOnRequest(ctx, tx, auctionID, startsAt, endsAt, now)
+	if isUniqueViolation(err, "idx_auctions_vehicle_open") {
+		h.jsonError(w, "vehicle already has an open auction", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to relist auction", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to relist auction", http.StatusInternalServerError)
+		return
+	}
+	// Checked on the new auction, so a kept length that's now too short counts
+	if msg := h.endsAtTooSoon(relisting.EndsAt, now); msg != "" {
+		h.jsonError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE vehicles SET status = 'active' WHERE id = $1`, vehicleID); err != nil {
+		h.logger.Error("failed to reactivate vehicle", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to relist auction", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.jsonError(w, "failed to relist auction", http.StatusInternalServerError)
+		return
+	}
+
+	newStatus := "active"
+	if relisting.StartsAt.After(now) {
+		newStatus = "scheduled"
+	}
+
+	h.logger.Info("auction_relisted",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("new_auction_id", relisting.AuctionID),
+		slog.Int64("vehicle_id", vehicleID),
+		slog.Int64("seller_id", userID),
+	)
+
+	resp := map[string]interface{}{
+		"auction_id":     relisting.AuctionID,
+		"relisted_from":  auctionID,
+		"vehicle_id":     vehicleID,
+		"status":         newStatus,
+		"starts_at":      relisting.StartsAt,
+		"ends_at":        relisting.EndsAt,
+		"starting_price": relisting.StartingPrice.StringFixed(2),
+	}
+	if relisting.ReservePrice.Valid {
+		resp["reserve_price"] = relisting.ReservePrice.Decimal.StringFixed(2)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
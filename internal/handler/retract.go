@@ -0,0 +1,366 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// retractMaxRetries bounds re-reads after losing an OCC race, e.g. to a bid
+// that may itself have outbid the one being retracted
+const retractMaxRetries = 3
+
+var (
+	errRetractNotFound     = errors.New("bid not found")
+	errRetractClosed       = errors.New("auction is no longer active")
+	errRetractWindowClosed = errors.New("bids can no longer be retracted")
+	errRetractNotHighest   = errors.New("only the current high bid can be retracted")
+	errRetractLimit        = errors.New("bid retraction limit reached")
+	errRetractConflict     = errors.New("auction was modified concurrently, retry")
+)
+
+// retraction is a completed bid retraction and the lead it restored
+type retraction struct {
+	amount     decimal.Decimal
+	currentBid decimal.Decimal
+	leaderID   *int64
+	hidden     bool // The restored leader hides their identity
+	bidCount   int
+	version    int
+}
+
+// RetractBid takes back the caller's bid while it is still the high bid and
+// no older than BID_RETRACT_WINDOW. The auction reverts to the bid before it
+// under the same version check as bidding, so a bid landing at the same time
+// either wins the race or makes the retraction fail.
+func (h *AuctionHandler) RetractBid(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+	bidID, err := strconv.ParseInt(chi.URLParam(r, "bidId"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid bid id", http.StatusBadRequest)
+		return
+	}
+
+	var rt retraction
+	for attempt := 0; attempt <= retractMaxRetries; attempt++ {
+		rt, err = h.attemptRetract(ctx, auctionID, bidID, userID)
+		if !errors.Is(err, errRetractConflict) {
+			break
+		}
+	}
+	switch {
+	case errors.Is(err, errRetractNotFound):
+		h.jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errRetractWindowClosed):
+		h.retractForbidden(w, err, "retract_window_closed")
+		return
+	case errors.Is(err, errRetractNotHighest):
+		h.retractForbidden(w, err, "bid_not_highest")
+		return
+	case errors.Is(err, errRetractLimit):
+		h.retractForbidden(w, err, "retract_limit_reached")
+		return
+	case errors.Is(err, errRetractClosed), errors.Is(err, errRetractConflict):
+		h.jsonError(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.Error("failed to retract bid",
+			slog.Int64("auction_id", auctionID),
+			slog.Int64("bid_id", bidID),
+			slog.String("error", err.Error()),
+		)
+		h.jsonError(w, "failed to retract bid", http.StatusInternalServerError)
+		return
+	}
+
+	if h.broadcaster != nil {
+		event := domain.BidEvent{
+			Type:      "bid_retracted",
+			AuctionID: auctionID,
+			Amount:    rt.currentBid,
+			BidCount:  rt.bidCount,
+			Version:   rt.version,
+			Timestamp: time.Now(),
+		}
+		if rt.leaderID != nil && !rt.hidden {
+			event.BidderID = *rt.leaderID
+		}
+		h.broadcaster.Broadcast(event)
+	}
+
+	// A private restored leader reads as their alias, as in GetAuction
+	resp := map[string]interface{}{
+		"auction_id":          auctionID,
+		"bid_id":              bidID,
+		"status":              "retracted",
+		"current_bid":         rt.currentBid.StringFixed(2),
+		"current_bid_user_id": rt.leaderID,
+		"bid_count":           rt.bidCount,
+	}
+	if rt.leaderID != nil && rt.hidden && *rt.leaderID != userID {
+		resp["current_bid_user_id"] = nil
+		alias, err := h.bidderAliasFor(ctx, auctionID, *rt.leaderID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			h.logger.Error("failed to look up bidder alias", slog.String("error", err.Error()))
+		}
+		if alias != "" {
+			resp["high_bidder_alias"] = alias
+		}
+	}
+
+	h.logger.Info("bid_retracted",
+		slog.Int64("auction_id", auctionID),
+		slog.Int64("bid_id", bidID),
+		slog.Int64("user_id", userID),
+		slog.String("amount", rt.amount.StringFixed(2)),
+		slog.String("current_bid", rt.currentBid.StringFixed(2)),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *AuctionHandler) retractForbidden(w http.ResponseWriter, err error, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": err.Error(),
+		"code":  code,
+	})
+}
+
+// attemptRetract checks the bid can still be retracted and tries a single
+// OCC update. It returns errRetractConflict when another writer bumped the
+// version first.
+func (h *AuctionHandler) attemptRetract(ctx context.Context, auctionID, bidID, userID int64) (retraction, error) {
+	var (
+		rt        retraction
+		bidderID  int64
+		bidStatus string
+		status    string
+		endsAt    time.Time
+		leaderID  *int64
+		inWindow  bool
+	)
+	err := h.db.QueryRow(ctx, `
+		SELECT b.user_id, b.amount, b.status::text,
+		       b.created_at >= NOW() - make_interval(secs => $3),
+		       a.status::text, a.ends_at, a.version, a.current_bid, a.current_bid_user_id
+		FROM bids b
+		JOIN auctions a ON a.id = b.auction_id
+		WHERE b.id = $1 AND b.auction_id = $2
+	`, bidID, auctionID, h.cfg.BidRetractWindow.Seconds()).Scan(
+		&bidderID, &rt.amount, &bidStatus, &inWindow,
+		&status, &endsAt, &rt.version, &rt.currentBid, &leaderID)
+	if err == pgx.ErrNoRows {
+		return rt, errRetractNotFound
+	}
+	if err != nil {
+		return rt, err
+	}
+	// Other people's bids aren't anyone else's to see, let alone retract
+	if bidderID != userID {
+		return rt, errRetractNotFound
+	}
+	if status != "active" || !time.Now().Before(endsAt) {
+		return rt, errRetractClosed
+	}
+	if h.cfg.BidRetractWindow <= 0 || !inWindow {
+		return rt, errRetractWindowClosed
+	}
+	if bidStatus != "accepted" || leaderID == nil || *leaderID != userID || !rt.amount.Equal(rt.currentBid) {
+		return rt, errRetractNotHighest
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return rt, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Capped so a bidder can't repeatedly outbid a proxy and take it back to
+	// learn its max for free. The user row lock makes concurrent retractions
+	// by the same bidder count one at a time.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return rt, fmt.Errorf("lock bidder: %w", err)
+	}
+	var onAuction, today int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE auction_id = $2),
+		       COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '24 hours')
+		FROM bid_retractions
+		WHERE user_id = $1
+	`, userID, auctionID).Scan(&onAuction, &today)
+	if err != nil {
+		return rt, fmt.Errorf("count retractions: %w", err)
+	}
+	if (h.cfg.BidRetractMaxPerAuction > 0 && onAuction >= h.cfg.BidRetractMaxPerAuction) ||
+		(h.cfg.BidRetractMaxPerDay > 0 && today >= h.cfg.BidRetractMaxPerDay) {
+		return rt, errRetractLimit
+	}
+
+	// The lead passes back to the last standing bid before this one. Accepted
+	// bids only ever raise the price, so that bid held the lead until now.
+	var restoredID *int64
+	rt.currentBid, rt.leaderID = decimal.Zero, nil
+	err = tx.QueryRow(ctx, `
+		SELECT b.id, b.user_id, b.amount, u.hide_bidder_identity
+		FROM bids b
+		JOIN users u ON u.id = b.user_id
+		WHERE b.auction_id = $1 AND b.id < $2 AND b.status IN ('accepted', 'outbid')
+		ORDER BY b.id DESC LIMIT 1
+	`, auctionID, bidID).Scan(&restoredID, &rt.leaderID, &rt.currentBid, &rt.hidden)
+	if err != nil && err != pgx.ErrNoRows {
+		return rt, fmt.Errorf("find previous bid: %w", err)
+	}
+
+	// OCC update - only succeeds if no bid got in since the read
+	err = tx.QueryRow(ctx, `
+		UPDATE auctions SET
+			current_bid = $3,
+			current_bid_user_id = $4,
+			bid_count = GREATEST(bid_count - 1, 0),
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND version = $2 AND status = 'active'
+		RETURNING bid_count, version
+	`, auctionID, rt.version, rt.currentBid, rt.leaderID).Scan(&rt.bidCount, &rt.version)
+	if err == pgx.ErrNoRows {
+		return rt, errRetractConflict
+	}
+	if err != nil {
+		return rt, fmt.Errorf("revert auction: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE bids SET status = 'retracted' WHERE id = $1`, bidID); err != nil {
+		return rt, fmt.Errorf("mark bid retracted: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bid_retractions (bid_id, auction_id, user_id, amount)
+		VALUES ($1, $2, $3, $4)
+	`, bidID, auctionID, userID, rt.amount)
+	if err != nil {
+		return rt, fmt.Errorf("record retraction: %w", err)
+	}
+	if restoredID != nil {
+		if _, err := tx.Exec(ctx, `UPDATE bids SET status = 'accepted' WHERE id = $1`, *restoredID); err != nil {
+			return rt, fmt.Errorf("restore previous bid: %w", err)
+		}
+	}
+	err = bidengine.RecordAuctionEvent(ctx, tx, auctionID, bidengine.EventRetracted, map[string]any{
+		"bid_id":          bidID,
+		"user_id":         userID,
+		"amount":          rt.amount,
+		"restored_bid_id": restoredID,
+		"current_bid":     rt.currentBid,
+		"bid_count":       rt.bidCount,
+	})
+	if err != nil {
+		return rt, fmt.Errorf("record retract event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return rt, err
+	}
+	return rt, nil
+}
+
+// ListRetractions shows admins recent bid retractions, newest first,
+// optionally narrowed to one ?user_id= or ?auction_id=. Each entry carries
+// how many retractions that bidder has made in total.
+func (h *AuctionHandler) ListRetractions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
+
+	fields := map[string]string{}
+	idParam := func(param string) *int64 {
+		raw := r.URL.Query().Get(param)
+		if raw == "" {
+			return nil
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			fields[param] = "must be an integer id"
+			return nil
+		}
+		return &id
+	}
+	userID, auctionID := idParam("user_id"), idParam("auction_id")
+	if len(fields) > 0 {
+		writeFieldErrors(w, fields)
+		return
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT br.id, br.bid_id, br.auction_id, br.user_id, u.email, br.amount, br.created_at,
+		       COUNT(*) OVER (PARTITION BY br.user_id)
+		FROM bid_retractions br
+		JOIN users u ON u.id = br.user_id
+		WHERE ($1::bigint IS NULL OR br.user_id = $1)
+		  AND ($2::bigint IS NULL OR br.auction_id = $2)
+		ORDER BY br.created_at DESC, br.id DESC
+		LIMIT 100
+	`, userID, auctionID)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	items := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var (
+			id, bidID, entryAuctionID, entryUserID int64
+			email                                  string
+			amount                                 decimal.Decimal
+			createdAt                              time.Time
+			userTotal                              int
+		)
+		if err := rows.Scan(&id, &bidID, &entryAuctionID, &entryUserID, &email, &amount, &createdAt, &userTotal); err != nil {
+			h.logger.Error("failed to scan retraction", slog.String("error", err.Error()))
+			continue
+		}
+		items = append(items, map[string]interface{}{
+			"id":               id,
+			"bid_id":           bidID,
+			"auction_id":       entryAuctionID,
+			"user_id":          entryUserID,
+			"email":            email,
+			"amount":           amount.StringFixed(2),
+			"created_at":       createdAt.Format(time.RFC3339),
+			"user_retractions": userTotal,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items": items,
+		"count": len(items),
+	})
+}
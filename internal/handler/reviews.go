@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReviewHandler handles buyer reviews of sellers
+type ReviewHandler struct {
+	db       *pgxpool.Pool
+	logger   *slog.Logger
+	validate *validator.Validate
+}
+
+func NewReviewHandler(db *pgxpool.Pool, logger *slog.Logger) *ReviewHandler {
+	return &ReviewHandler{
+		db:       db,
+		logger:   logger,
+		validate: newValidator(),
+	}
+}
+
+type ReviewResponse struct {
+	ID             int64   `json:"id"`
+	OrderID        int64   `json:"order_id"`
+	Rating         int     `json:"rating"`
+	Comment        *string `json:"comment,omitempty"`
+	BuyerFirstName *string `json:"buyer_first_name,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+// CreateReview lets the buyer of a delivered order rate its seller
+func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	orderID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid order id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Rating  int    `json:"rating" validate:"required,min=1,max=5"`
+		Comment string `json:"comment" validate:"max=2000"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	var buyerID, sellerID int64
+	var status string
+	err = h.db.QueryRow(ctx, `
+		SELECT buyer_id, seller_id, status::text FROM orders WHERE id = $1
+	`, orderID).Scan(&buyerID, &sellerID, &status)
+	if err != nil {
+		h.jsonError(w, "order not found", http.StatusNotFound)
+		return
+	}
+	// Only the parties to an order learn that it exists
+	if userID != buyerID && userID != sellerID {
+		middleware.AuditDenied(h.logger, r, "order", middleware.DeniedNotOwner)
+		h.jsonError(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if buyerID != userID {
+		middleware.AuditDenied(h.logger, r, "order", middleware.DeniedNotOwner)
+		h.jsonError(w, "only the buyer can review this order", http.StatusForbidden)
+		return
+	}
+	if status != "delivered" {
+		h.jsonError(w, "only completed orders can be reviewed", http.StatusBadRequest)
+		return
+	}
+
+	resp := ReviewResponse{OrderID: orderID, Rating: req.Rating}
+	if req.Comment != "" {
+		resp.Comment = &req.Comment
+	}
+	var createdAt time.Time
+	err = h.db.QueryRow(ctx, `
+		INSERT INTO reviews (order_id, seller_id, buyer_id, rating, comment)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, orderID, sellerID, userID, req.Rating, resp.Comment).Scan(&resp.ID, &createdAt)
+	if isUniqueViolation(err, "reviews_order_id_key") {
+		h.jsonError(w, "order already reviewed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to create review", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to create review", http.StatusInternalServerError)
+		return
+	}
+	resp.CreatedAt = createdAt.Format(time.RFC3339)
+
+	h.logger.Info("review_created",
+		slog.Int64("review_id", resp.ID),
+		slog.Int64("order_id", orderID),
+		slog.Int64("seller_id", sellerID),
+		slog.Int("rating", req.Rating),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetSellerReviews returns a seller's rating summary and their reviews, newest first
+func (h *ReviewHandler) GetSellerReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sellerID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid seller id", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := httpx.Pagination(r)
+
+	var total int64
+	var average *float64
+	counts := make([]int64, 5)
+	err = h.db.QueryRow(ctx, `
+		SELECT COUNT(*), AVG(rating)::float8,
+		       COUNT(*) FILTER (WHERE rating = 1), COUNT(*) FILTER (WHERE rating = 2),
+		       COUNT(*) FILTER (WHERE rating = 3), COUNT(*) FILTER (WHERE rating = 4),
+		       COUNT(*) FILTER (WHERE rating = 5)
+		FROM reviews WHERE seller_id = $1
+	`, sellerID).Scan(&total, &average, &counts[0], &counts[1], &counts[2], &counts[3], &counts[4])
+	if err != nil {
+		h.logger.Error("failed to aggregate reviews", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT r.id, r.order_id, r.rating, r.comment,
+		       CASE WHEN u.hide_bidder_identity THEN NULL ELSE u.first_name END, r.created_at
+		FROM reviews r
+		JOIN users u ON r.buyer_id = u.id
+		WHERE r.seller_id = $1
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT $2 OFFSET $3
+	`, sellerID, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to query reviews", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	reviews := make([]ReviewResponse, 0)
+	for rows.Next() {
+		var rv ReviewResponse
+		var createdAt time.Time
+		if err := rows.Scan(&rv.ID, &rv.OrderID, &rv.Rating, &rv.Comment, &rv.BuyerFirstName, &createdAt); err != nil {
+			h.logger.Error("failed to scan review", slog.String("error", err.Error()))
+			continue
+		}
+		rv.CreatedAt = createdAt.Format(time.RFC3339)
+		reviews = append(reviews, rv)
+	}
+
+	var averageRating *string
+	if average != nil {
+		s := strconv.FormatFloat(*average, 'f', 2, 64)
+		averageRating = &s
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"seller_id":      sellerID,
+		"average_rating": averageRating,
+		"rating_counts": map[string]int64{
+			"1": counts[0], "2": counts[1], "3": counts[2], "4": counts[3], "5": counts[4],
+		},
+		"reviews":  reviews,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+		"has_more": int64(offset+len(reviews)) < total,
+	})
+}
+
+func (h *ReviewHandler) jsonError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
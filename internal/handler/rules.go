@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+)
+
+// minBidIncrement is the smallest step a bid must clear the current bid by.
+// The engine only requires beating the current bid, so it is one cent at every price.
+var minBidIncrement = bidengine.MinBidIncrement
+
+// IncrementTier is one step of the bid increment schedule
+type IncrementTier struct {
+	From      string `json:"from"` // Applies once the current bid reaches this amount
+	Increment string `json:"increment"`
+}
+
+type AntiSnipeRules struct {
+	ThresholdMinutes        int  `json:"threshold_minutes"` // Bids this close to the end extend it
+	ExtensionMinutes        int  `json:"extension_minutes"`
+	MaxExtensions           int  `json:"max_extensions"`
+	ExtensionsUsed          int  `json:"extensions_used"`
+	ExtendOnReserveMet      bool `json:"extend_on_reserve_met"` // One extra extension when the reserve is first met
+	ReserveExtensionApplied bool `json:"reserve_extension_applied"`
+	LeaderChangeOnly        bool `json:"leader_change_only"`          // Bids by the current leader never extend
+	MaxTotalMinutes         *int `json:"max_total_minutes,omitempty"` // Cap on time extensions may add; absent when uncapped
+	ExtendedMinutes         int  `json:"extended_minutes"`            // Time added so far, rounded down
+}
+
+type ReserveRules struct {
+	HasReserve bool  `json:"has_reserve"`
+	ReserveMet *bool `json:"reserve_met,omitempty"`
+}
+
+type BuyNowRules struct {
+	Available bool    `json:"available"`
+	Price     *string `json:"price,omitempty"`
+}
+
+type AuctionRulesResponse struct {
+	AuctionID          int64           `json:"auction_id"`
+	Status             string          `json:"status"`
+	StartingPrice      string          `json:"starting_price"`
+	MinimumNextBid     string          `json:"minimum_next_bid"`
+	IncrementSchedule  []IncrementTier `json:"increment_schedule"`
+	AntiSnipe          AntiSnipeRules  `json:"anti_snipe"`
+	Reserve            ReserveRules    `json:"reserve"`
+	BuyNow             BuyNowRules     `json:"buy_now"`
+	MaxBidMultiple     *float64        `json:"max_bid_multiple,omitempty"` // Auto-bid ceiling vs current price
+	LateBidGraceMillis int64           `json:"late_bid_grace_ms"`          // Client submit lag honored at the deadline
+}
+
+// GetAuctionRules returns the bidding rules in force for an auction: increments,
+// anti-snipe extensions, reserve and buy-now availability. The reserve amount
+// itself stays private.
+func (h *AuctionHandler) GetAuctionRules(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
+
+	auctionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid auction id", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		resp            = AuctionRulesResponse{AuctionID: auctionID}
+		currentBid      decimal.Decimal
+		startingPrice   decimal.Decimal
+		bidCount        int
+		hidden          bool
+		visibility      string
+		sellerID        int64
+		reservePrice    decimal.NullDecimal
+		buyNowPrice     decimal.NullDecimal
+		minIncrement    decimal.NullDecimal
+		extendedSeconds int
+	)
+	err = h.db.QueryRow(ctx, `
+		SELECT a.status::text, a.current_bid, a.bid_count, a.hidden, a.visibility, v.seller_id,
+		       a.snipe_threshold_minutes, a.extension_minutes, a.max_extensions, a.extension_count,
+		       a.extend_on_reserve_met, a.reserve_extension_applied, a.extend_on_leader_change_only,
+		       a.extended_seconds, a.min_increment, v.starting_price, v.reserve_price, v.buy_now_price
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+	`, auctionID).Scan(
+		&resp.Status, &currentBid, &bidCount, &hidden, &visibility, &sellerID,
+		&resp.AntiSnipe.ThresholdMinutes, &resp.AntiSnipe.ExtensionMinutes,
+		&resp.AntiSnipe.MaxExtensions, &resp.AntiSnipe.ExtensionsUsed,
+		&resp.AntiSnipe.ExtendOnReserveMet, &resp.AntiSnipe.ReserveExtensionApplied, &resp.AntiSnipe.LeaderChangeOnly,
+		&extendedSeconds, &minIncrement, &startingPrice, &reservePrice, &buyNowPrice,
+	)
+	if isQueryTimeout(err) {
+		writeQueryError(w, err)
+		return
+	}
+	if err != nil {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+	if hidden && !h.canViewHidden(r, auctionID) {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+	if visibility == "private" && !h.canViewPrivate(r, auctionID, sellerID) {
+		h.jsonError(w, "auction not found", http.StatusNotFound)
+		return
+	}
+
+	// An auction-level min_increment replaces the default schedule
+	increment := minBidIncrement
+	if minIncrement.Valid {
+		increment = minIncrement.Decimal
+	}
+	resp.StartingPrice = startingPrice.StringFixed(2)
+	resp.IncrementSchedule = []IncrementTier{
+		{From: "0.00", Increment: increment.StringFixed(2)},
+	}
+	if bidCount == 0 {
+		resp.MinimumNextBid = startingPrice.StringFixed(2)
+	} else {
+		resp.MinimumNextBid = currentBid.Add(increment).StringFixed(2)
+	}
+
+	resp.Reserve.HasReserve = reservePrice.Valid
+	if reservePrice.Valid {
+		met := bidCount > 0 && currentBid.GreaterThanOrEqual(reservePrice.Decimal)
+		resp.Reserve.ReserveMet = &met
+	}
+
+	if buyNowPrice.Valid {
+		price := buyNowPrice.Decimal.StringFixed(2)
+		resp.BuyNow.Price = &price
+		resp.BuyNow.Available = resp.Status == "active" && currentBid.LessThan(buyNowPrice.Decimal)
+	}
+
+	if h.cfg.BidMaxMultiple > 0 {
+		multiple := h.cfg.BidMaxMultiple
+		resp.MaxBidMultiple = &multiple
+	}
+	resp.LateBidGraceMillis = h.cfg.BidEndGrace.Milliseconds()
+	resp.AntiSnipe.ExtendedMinutes = extendedSeconds / 60
+	if h.cfg.BidMaxExtensionTotal > 0 {
+		total := int(h.cfg.BidMaxExtensionTotal.Minutes())
+		resp.AntiSnipe.MaxTotalMinutes = &total
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,30 +1,61 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/cache"
 	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/ayubfarah/vehicle-auc/internal/realtime"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
 )
 
 type SSEHandler struct {
+	db     *pgxpool.Pool
 	broker *realtime.Broker
 	logger *slog.Logger
 	cfg    *config.Config
+	ticks  *TickCache
 }
 
-func NewSSEHandler(broker *realtime.Broker, logger *slog.Logger, cfg *config.Config) *SSEHandler {
-	return &SSEHandler{
+// SSEHandlerOption configures an SSEHandler
+type SSEHandlerOption func(*SSEHandler)
+
+// WithTickCache reads tick countdowns through c, so it can share the
+// broker's event hook. Without it the handler keeps its own cache, which
+// only expires by TTL.
+func WithTickCache(c *TickCache) SSEHandlerOption {
+	return func(h *SSEHandler) {
+		h.ticks = c
+	}
+}
+
+func NewSSEHandler(db *pgxpool.Pool, broker *realtime.Broker, logger *slog.Logger, cfg *config.Config, opts ...SSEHandlerOption) *SSEHandler {
+	h := &SSEHandler{
+		db:     db,
 		broker: broker,
 		logger: logger,
 		cfg:    cfg,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.ticks == nil {
+		h.ticks = NewTickCache(cfg.SSEKeepaliveInterval)
+	}
+	return h
 }
 
 // StreamAuction handles SSE connections for auction updates
@@ -36,6 +67,12 @@ func (h *SSEHandler) StreamAuction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Private auctions only stream to their seller, admins and invitees
+	if !h.canStream(r, auctionID) {
+		http.Error(w, "auction not found", http.StatusNotFound)
+		return
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -46,12 +83,16 @@ func (h *SSEHandler) StreamAuction(w http.ResponseWriter, r *http.Request) {
 	sub := &realtime.Subscriber{
 		ID:       uuid.New().String(),
 		UserID:   middleware.GetUserID(r.Context()),
+		IP:       middleware.ClientIP(r),
 		Messages: make(chan []byte, 100),
 		Done:     make(chan struct{}),
 	}
 
 	// Subscribe to auction
-	h.broker.Subscribe(auctionID, sub)
+	if err := h.broker.Subscribe(auctionID, sub); err != nil {
+		h.tooManyStreams(w, r, err)
+		return
+	}
 	defer h.broker.Unsubscribe(auctionID, sub)
 
 	// Get flusher
@@ -91,8 +132,14 @@ func (h *SSEHandler) StreamAuction(w http.ResponseWriter, r *http.Request) {
 			}
 			flusher.Flush()
 
+		case <-sub.Done:
+			// The broker is stopping; send its shutdown event and hang up
+			writePending(w, sub)
+			flusher.Flush()
+			return
+
 		case <-keepalive.C:
-			_, err := w.Write([]byte(": keepalive\n\n"))
+			_, err := w.Write(h.auctionTick(r.Context(), auctionID))
 			if err != nil {
 				return
 			}
@@ -101,3 +148,299 @@ func (h *SSEHandler) StreamAuction(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// canStream reports whether the requester may watch an auction's stream. An
+// auction that doesn't exist streams nothing, as before, so it isn't refused.
+func (h *SSEHandler) canStream(r *http.Request, auctionID int64) bool {
+	if h.db == nil {
+		return true
+	}
+
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
+
+	var (
+		visibility string
+		sellerID   int64
+	)
+	err := h.db.QueryRow(ctx, `
+		SELECT a.visibility, v.seller_id
+		FROM auctions a
+		JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE a.id = $1
+	`, auctionID).Scan(&visibility, &sellerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true
+	}
+	if err == nil && visibility != "private" {
+		return true
+	}
+	allowed := false
+	if err == nil {
+		allowed, err = privateAuctionAccess(ctx, h.db, middleware.GetUserID(r.Context()), auctionID, sellerID)
+	}
+	if err != nil {
+		h.logger.Error("failed to check auction stream access", slog.String("error", err.Error()))
+		return false
+	}
+	return allowed
+}
+
+// PublicAuctionFilter reports whether an auction may appear on the global
+// stream: public and not hidden by moderation. It fails closed, so an auction
+// that can't be read is left off. Used with realtime.WithGlobalFilter.
+func PublicAuctionFilter(db *pgxpool.Pool, timeout time.Duration) func(auctionID int64) bool {
+	return func(auctionID int64) bool {
+		ctx, cancel := queryContext(context.Background(), timeout)
+		defer cancel()
+
+		var public bool
+		err := db.QueryRow(ctx, `
+			SELECT visibility = 'public' AND NOT hidden FROM auctions WHERE id = $1
+		`, auctionID).Scan(&public)
+		return err == nil && public
+	}
+}
+
+// TickCache holds the auction state tick events report, so every stream on
+// an auction shares one read per keepalive interval instead of querying on
+// each tick. Auction events expire entries through Observe; changes that send
+// no event show up within the TTL.
+type TickCache struct {
+	states *cache.Cache[int64, tickState]
+}
+
+// tickState is the part of an auction a tick reports. missing marks an
+// auction that doesn't exist, so it isn't looked up again every tick.
+type tickState struct {
+	status     string
+	currentBid decimal.Decimal
+	bidCount   int
+	endsAt     time.Time
+	missing    bool
+}
+
+// NewTickCache creates a tick cache whose entries live for ttl
+func NewTickCache(ttl time.Duration) *TickCache {
+	return &TickCache{states: cache.New[int64, tickState]("auction_tick", ttl)}
+}
+
+// Observe expires the tick state an auction event changed. Hook it to the
+// broker with realtime.WithEventHook.
+func (c *TickCache) Observe(event domain.BidEvent) {
+	if event.Type == "viewer_count" {
+		return
+	}
+	c.states.Invalidate(event.AuctionID, event.Version)
+}
+
+// auctionTick builds the keepalive frame for an auction stream: a tick event
+// with the live countdown and price so idle viewers stay in sync without
+// polling. Falls back to a bare comment when ticks are disabled or the
+// auction can't be read.
+func (h *SSEHandler) auctionTick(ctx context.Context, auctionID int64) []byte {
+	keepalive := []byte(": keepalive\n\n")
+	if h.cfg.SSETickEvent == "" || h.db == nil {
+		return keepalive
+	}
+
+	state, err := h.ticks.states.Get(auctionID, func() (tickState, int, error) {
+		ctx, cancel := queryContext(ctx, h.cfg.DBQueryTimeout)
+		defer cancel()
+
+		var state tickState
+		var version int
+		err := h.db.QueryRow(ctx, `
+			SELECT status::text, current_bid, bid_count, ends_at, version FROM auctions WHERE id = $1
+		`, auctionID).Scan(&state.status, &state.currentBid, &state.bidCount, &state.endsAt, &version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tickState{missing: true}, 0, nil
+		}
+		return state, version, err
+	})
+	if err != nil || state.missing {
+		return keepalive
+	}
+
+	now := time.Now()
+	data, err := json.Marshal(map[string]interface{}{
+		"auction_id":        auctionID,
+		"status":            state.status,
+		"current_bid":       state.currentBid.StringFixed(2),
+		"bid_count":         state.bidCount,
+		"ends_at":           state.endsAt,
+		"seconds_remaining": secondsRemaining(state.status, state.endsAt, now),
+		"server_time":       now.UTC(),
+	})
+	if err != nil {
+		return keepalive
+	}
+	return []byte("event: " + h.cfg.SSETickEvent + "\ndata: " + string(data) + "\n\n")
+}
+
+
+// StreamNotifications pushes the caller's new notifications as they are created
+func (h *SSEHandler) StreamNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	sub := &realtime.Subscriber{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		IP:       middleware.ClientIP(r),
+		Messages: make(chan []byte, 100),
+		Done:     make(chan struct{}),
+	}
+	if err := h.broker.SubscribeUser(sub); err != nil {
+		h.tooManyStreams(w, r, err)
+		return
+	}
+	defer h.broker.UnsubscribeUser(sub)
+
+	h.logger.Info("sse_notification_stream_opened",
+		slog.String("subscriber_id", sub.ID),
+		slog.Int64("user_id", userID),
+		slog.String("request_id", middleware.GetRequestID(r.Context())),
+	)
+
+	w.Write([]byte("event: connected\ndata: {\"user_id\":" + strconv.FormatInt(userID, 10) + "}\n\n"))
+	flusher.Flush()
+
+	keepalive := time.NewTicker(h.cfg.SSEKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			h.logger.Info("sse_notification_stream_closed",
+				slog.String("subscriber_id", sub.ID),
+				slog.Int64("user_id", userID),
+			)
+			return
+
+		case msg := <-sub.Messages:
+			if _, err := w.Write(msg); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-sub.Done:
+			writePending(w, sub)
+			flusher.Flush()
+			return
+
+		case <-keepalive.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// StreamGlobal pushes a compact auction_update event whenever any auction gets
+// a bid, extends or closes, for site-wide views like the closing-soon ticker.
+// Updates are coalesced per auction by the broker, so a busy auction produces
+// at most one event per SSE_GLOBAL_INTERVAL.
+func (h *SSEHandler) StreamGlobal(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	sub := &realtime.Subscriber{
+		ID:       uuid.New().String(),
+		UserID:   middleware.GetUserID(r.Context()),
+		IP:       middleware.ClientIP(r),
+		Messages: make(chan []byte, 100),
+		Done:     make(chan struct{}),
+	}
+	if err := h.broker.SubscribeGlobal(sub); err != nil {
+		h.tooManyStreams(w, r, err)
+		return
+	}
+	defer h.broker.UnsubscribeGlobal(sub)
+
+	h.logger.Info("sse_global_stream_opened",
+		slog.String("subscriber_id", sub.ID),
+		slog.String("request_id", middleware.GetRequestID(r.Context())),
+	)
+
+	w.Write([]byte("event: connected\ndata: {}\n\n"))
+	flusher.Flush()
+
+	keepalive := time.NewTicker(h.cfg.SSEKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			h.logger.Info("sse_global_stream_closed",
+				slog.String("subscriber_id", sub.ID),
+			)
+			return
+
+		case msg := <-sub.Messages:
+			if _, err := w.Write(msg); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-sub.Done:
+			writePending(w, sub)
+			flusher.Flush()
+			return
+
+		case <-keepalive.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// tooManyStreams answers a subscription the broker turned away for being over
+// the per-user or per-IP stream cap
+func (h *SSEHandler) tooManyStreams(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn("sse_connection_rejected",
+		slog.Int64("user_id", middleware.GetUserID(r.Context())),
+		slog.String("client_ip", middleware.ClientIP(r)),
+		slog.String("reason", err.Error()),
+		slog.String("request_id", middleware.GetRequestID(r.Context())),
+	)
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+}
+
+// writePending writes whatever the broker queued for sub before closing it
+func writePending(w io.Writer, sub *realtime.Subscriber) {
+	for {
+		select {
+		case msg := <-sub.Messages:
+			if _, err := w.Write(msg); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// TransferVehicle moves a listing to another seller account, e.g. when a
+// dealership reassigns inventory between staff. The owner or an admin may
+// transfer it; its past and scheduled auctions follow the vehicle, but a
+// vehicle with a live auction stays put until that auction ends.
+func (h *VehicleHandler) TransferVehicle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	vehicleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid vehicle id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		ToUserID int64 `json:"to_user_id" validate:"required,gt=0"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	var sellerID int64
+	var status, callerRole string
+	err = h.db.QueryRow(ctx, `
+		SELECT v.seller_id, v.status::text, u.role::text
+		FROM vehicles v, users u
+		WHERE v.id = $1 AND u.id = $2
+	`, vehicleID, userID).Scan(&sellerID, &status, &callerRole)
+	isAdmin := err == nil && callerRole == "admin"
+	if !isAdmin && !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
+		return
+	}
+	if req.ToUserID == sellerID {
+		h.jsonError(w, "vehicle already belongs to this user", http.StatusBadRequest)
+		return
+	}
+
+	var targetRole string
+	var targetVerified bool
+	err = h.db.QueryRow(ctx, `
+		SELECT role::text, id_verified_at IS NOT NULL FROM users WHERE id = $1
+	`, req.ToUserID).Scan(&targetRole, &targetVerified)
+	if err == pgx.ErrNoRows {
+		h.jsonError(w, "target user not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to look up transfer target", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if targetRole != "seller" {
+		h.jsonError(w, "target user must be a seller", http.StatusBadRequest)
+		return
+	}
+	// Past draft the vehicle is in front of buyers, which only verified
+	// sellers may be; a draft is checked when it's listed
+	if status != "draft" && !targetVerified {
+		writeSellerNotVerified(w, http.StatusBadRequest, "target seller must complete ID verification to take over a listed vehicle")
+		return
+	}
+
+	// A listed vehicle counts against the new owner's active listing cap
+	if status == "active" {
+		atLimit, err := listingLimitReached(ctx, h.db, req.ToUserID, vehicleID, h.cfg.MaxActiveListingsPerSeller)
+		if err != nil {
+			h.logger.Error("failed to count active listings", slog.String("error", err.Error()))
+			h.jsonError(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if atLimit {
+			h.jsonError(w, listingLimitMessage(h.cfg.MaxActiveListingsPerSeller), http.StatusConflict)
+			return
+		}
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		h.logger.Error("failed to begin transaction", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Conditional on the seller we read, so two racing transfers can't both apply
+	tag, err := tx.Exec(ctx, `
+		UPDATE vehicles SET seller_id = $2 WHERE id = $1 AND seller_id = $3
+	`, vehicleID, req.ToUserID, sellerID)
+	if err != nil {
+		h.logger.Error("failed to transfer vehicle", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to transfer vehicle", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		h.jsonError(w, "vehicle was modified concurrently, retry", http.StatusConflict)
+		return
+	}
+
+	var live bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM auctions WHERE vehicle_id = $1 AND status = 'active')
+	`, vehicleID).Scan(&live)
+	if err != nil {
+		h.logger.Error("failed to check active auctions", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if live {
+		h.jsonError(w, "cannot transfer a vehicle with an active auction", http.StatusConflict)
+		return
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO vehicle_transfers (vehicle_id, from_user_id, to_user_id, transferred_by)
+		VALUES ($1, $2, $3, $4)
+	`, vehicleID, sellerID, req.ToUserID, userID)
+	if err != nil {
+		h.logger.Error("failed to record vehicle transfer", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to transfer vehicle", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.logger.Error("failed to commit vehicle transfer", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to transfer vehicle", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("vehicle_transferred",
+		slog.Int64("vehicle_id", vehicleID),
+		slog.Int64("from_user_id", sellerID),
+		slog.Int64("to_user_id", req.ToUserID),
+		slog.Int64("transferred_by", userID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vehicle_id":   vehicleID,
+		"from_user_id": sellerID,
+		"to_user_id":   req.ToUserID,
+		"message":      "Vehicle transferred",
+	})
+}
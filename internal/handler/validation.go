@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/go-playground/validator/v10"
+)
+
+// newValidator returns a validator that reports fields by their JSON name
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// validationFields converts validator errors into a field -> message map the
+// frontend can use to highlight inputs. Returns nil for any other error.
+func validationFields(err error) map[string]string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+
+	fields := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		fields[fe.Field()] = validationMessage(fe)
+	}
+	return fields
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "len":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must have length %s", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	default:
+		return "is invalid"
+	}
+}
+
+// vehicleEnumErrors checks categorical vehicle fields against the allowed
+// values in domain, skipping fields that weren't provided
+func vehicleEnumErrors(values map[string]*string) map[string]string {
+	fields := make(map[string]string)
+	for field, value := range values {
+		if value == nil || domain.IsValidVehicleEnum(field, *value) {
+			continue
+		}
+		fields[field] = "must be one of: " + strings.Join(domain.VehicleEnumFields[field], ", ")
+	}
+	return fields
+}
+
+// writeValidationError responds 400 with {"error": ..., "fields": {...}}
+func writeValidationError(w http.ResponseWriter, err error) {
+	if fields := validationFields(err); fields != nil {
+		writeFieldErrors(w, fields)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": "validation error: " + err.Error()})
+}
+
+func writeFieldErrors(w http.ResponseWriter, fields map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "validation failed",
+		"fields": fields,
+	})
+}
@@ -1,29 +1,52 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/cache"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type VehicleHandler struct {
-	db       *pgxpool.Pool
-	logger   *slog.Logger
-	validate *validator.Validate
+	db        *pgxpool.Pool
+	logger    *slog.Logger
+	cfg       *config.Config
+	validate  *validator.Validate
+	blocklist Blocklist
+	cache     *cache.Cache[int64, vehicleDetail] // GetVehicle reads; nil when READ_CACHE_TTL is 0
 }
 
-func NewVehicleHandler(db *pgxpool.Pool, logger *slog.Logger) *VehicleHandler {
-	return &VehicleHandler{
-		db:       db,
-		logger:   logger,
-		validate: validator.New(),
+func NewVehicleHandler(db *pgxpool.Pool, logger *slog.Logger, cfg *config.Config, opts ...VehicleHandlerOption) *VehicleHandler {
+	h := &VehicleHandler{
+		db:        db,
+		logger:    logger,
+		cfg:       cfg,
+		validate:  newValidator(),
+		blocklist: NewWordBlocklist(cfg.DescriptionBlocklist),
 	}
+	if cfg.ReadCacheTTL > 0 {
+		h.cache = cache.New[int64, vehicleDetail]("vehicle", cfg.ReadCacheTTL)
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 type VehicleResponse struct {
@@ -43,26 +66,15 @@ type VehicleResponse struct {
 
 // ListVehicles returns paginated vehicles
 func (h *VehicleHandler) ListVehicles(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
 	
 	// Parse query params
-	limit := 20
-	offset := 0
+	limit, offset := httpx.Pagination(r)
 	
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
-	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
-			offset = parsed
-		}
-	}
-	
-	// Optional filters
-	makeFilter := r.URL.Query().Get("make")
-	modelFilter := r.URL.Query().Get("model")
+	// Optional filters, matched anywhere in the field
+	makeFilter := containsPattern(r.URL.Query().Get("make"))
+	modelFilter := containsPattern(r.URL.Query().Get("model"))
 	status := r.URL.Query().Get("status")
 	if status == "" {
 		status = "active"
@@ -76,6 +88,7 @@ func (h *VehicleHandler) ListVehicles(w http.ResponseWriter, r *http.Request) {
 		WHERE status = $1
 		  AND ($2 = '' OR make ILIKE $2)
 		  AND ($3 = '' OR model ILIKE $3)
+		  AND NOT EXISTS (SELECT 1 FROM auctions a WHERE a.vehicle_id = vehicles.id AND a.hidden)
 		ORDER BY created_at DESC
 		LIMIT $4 OFFSET $5
 	`
@@ -83,7 +96,7 @@ func (h *VehicleHandler) ListVehicles(w http.ResponseWriter, r *http.Request) {
 	rows, err := h.db.Query(ctx, query, status, makeFilter, modelFilter, limit, offset)
 	if err != nil {
 		h.logger.Error("failed to query vehicles", slog.String("error", err.Error()))
-		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		writeQueryError(w, err)
 		return
 	}
 	defer rows.Close()
@@ -106,6 +119,11 @@ func (h *VehicleHandler) ListVehicles(w http.ResponseWriter, r *http.Request) {
 		v.StartingPrice = strconv.FormatFloat(startingPrice, 'f', 2, 64)
 		vehicles = append(vehicles, v)
 	}
+	if err := rows.Err(); err != nil {
+		h.logger.Error("failed to read vehicles", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
 	
 	// Get total count
 	var total int64
@@ -114,22 +132,84 @@ func (h *VehicleHandler) ListVehicles(w http.ResponseWriter, r *http.Request) {
 		WHERE status = $1
 		  AND ($2 = '' OR make ILIKE $2)
 		  AND ($3 = '' OR model ILIKE $3)
+		  AND NOT EXISTS (SELECT 1 FROM auctions a WHERE a.vehicle_id = vehicles.id AND a.hidden)
 	`
-	h.db.QueryRow(ctx, countQuery, status, makeFilter, modelFilter).Scan(&total)
+	if err := h.db.QueryRow(ctx, countQuery, status, makeFilter, modelFilter).Scan(&total); err != nil {
+		h.logger.Error("failed to count vehicles", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+	
+	resp := struct {
+		domain.PaginatedResponse[VehicleResponse]
+		Facets *Facets `json:"facets,omitempty"`
+	}{PaginatedResponse: domain.NewPaginatedResponse(vehicles, total, limit, offset)}
+	if wantFacets(r) {
+		facets, err := queryFacets(ctx, h.db, `
+			FROM vehicles v
+			WHERE v.status = $1
+			  AND ($2 = '' OR v.make ILIKE $2)
+			  AND ($3 = '' OR v.model ILIKE $3)
+			  AND NOT EXISTS (SELECT 1 FROM auctions a WHERE a.vehicle_id = v.id AND a.hidden)
+		`, status, makeFilter, modelFilter)
+		if err != nil {
+			h.logger.Error("failed to count vehicle facets", slog.String("error", err.Error()))
+			writeQueryError(w, err)
+			return
+		}
+		resp.Facets = facets
+	}
 	
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"vehicles": vehicles,
-		"total":    total,
-		"limit":    limit,
-		"offset":   offset,
-		"has_more": int64(offset+len(vehicles)) < total,
-	})
+	json.NewEncoder(w).Encode(resp)
+}
+
+// likeEscaper escapes LIKE's metacharacters, using Postgres's default escape
+// character, so user input only ever matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern turns a search term into an ILIKE pattern matching it
+// anywhere, or "" for no filter
+func containsPattern(term string) string {
+	if term == "" {
+		return ""
+	}
+	return "%" + likeEscaper.Replace(term) + "%"
+}
+
+// GetVehicleOptions returns the allowed values for categorical vehicle fields
+func (h *VehicleHandler) GetVehicleOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(domain.VehicleEnumFields)
+}
+
+// vehicleDetail is GetVehicle's response body
+type vehicleDetail struct {
+	VehicleResponse
+	BodyType        *string `json:"body_type,omitempty"`
+	InteriorColor   *string `json:"interior_color,omitempty"`
+	Engine          *string `json:"engine,omitempty"`
+	Transmission    *string `json:"transmission,omitempty"`
+	Drivetrain      *string `json:"drivetrain,omitempty"`
+	FuelType        *string `json:"fuel_type,omitempty"`
+	TitleStatus     *string `json:"title_status,omitempty"`
+	ConditionGrade  *string `json:"condition_grade,omitempty"`
+	Description     *string `json:"description,omitempty"`
+	ReservePrice    *string `json:"reserve_price,omitempty"`
+	BuyNowPrice     *string `json:"buy_now_price,omitempty"`
+	LocationCity    *string `json:"location_city,omitempty"`
+	LocationState   *string `json:"location_state,omitempty"`
+	LocationZip     *string `json:"location_zip,omitempty"`
+	SellerFirstName *string `json:"seller_first_name,omitempty"`
+	SellerLastName  *string `json:"seller_last_name,omitempty"`
+	Version         int     `json:"version"`
 }
 
 // GetVehicle returns a single vehicle
 func (h *VehicleHandler) GetVehicle(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
 	
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -138,6 +218,32 @@ func (h *VehicleHandler) GetVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	var vehicle vehicleDetail
+	if h.cache != nil {
+		vehicle, err = h.cache.Get(id, func() (vehicleDetail, int, error) {
+			v, err := h.queryVehicle(ctx, id)
+			return v, v.Version, err
+		})
+	} else {
+		vehicle, err = h.queryVehicle(ctx, id)
+	}
+	if isQueryTimeout(err) {
+		writeQueryError(w, err)
+		return
+	}
+	if err != nil {
+		h.jsonError(w, "vehicle not found", http.StatusNotFound)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(vehicle.Version))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vehicle": vehicle,
+	})
+}
+
+func (h *VehicleHandler) queryVehicle(ctx context.Context, id int64) (vehicleDetail, error) {
 	query := `
 		SELECT v.id, v.seller_id, v.vin, v.year, v.make, v.model, v.trim,
 		       v.body_type, v.exterior_color, v.interior_color, v.mileage,
@@ -145,37 +251,18 @@ func (h *VehicleHandler) GetVehicle(w http.ResponseWriter, r *http.Request) {
 		       v.title_status, v.condition_grade, v.description,
 		       v.starting_price, v.reserve_price, v.buy_now_price,
 		       v.location_city, v.location_state, v.location_zip,
-		       v.status, v.created_at,
+		       v.status, v.created_at, v.version,
 		       u.first_name as seller_first_name, u.last_name as seller_last_name
 		FROM vehicles v
 		JOIN users u ON v.seller_id = u.id
 		WHERE v.id = $1
 	`
 	
-	var vehicle struct {
-		VehicleResponse
-		BodyType        *string `json:"body_type,omitempty"`
-		InteriorColor   *string `json:"interior_color,omitempty"`
-		Engine          *string `json:"engine,omitempty"`
-		Transmission    *string `json:"transmission,omitempty"`
-		Drivetrain      *string `json:"drivetrain,omitempty"`
-		FuelType        *string `json:"fuel_type,omitempty"`
-		TitleStatus     *string `json:"title_status,omitempty"`
-		ConditionGrade  *string `json:"condition_grade,omitempty"`
-		Description     *string `json:"description,omitempty"`
-		ReservePrice    *string `json:"reserve_price,omitempty"`
-		BuyNowPrice     *string `json:"buy_now_price,omitempty"`
-		LocationCity    *string `json:"location_city,omitempty"`
-		LocationState   *string `json:"location_state,omitempty"`
-		LocationZip     *string `json:"location_zip,omitempty"`
-		SellerFirstName *string `json:"seller_first_name,omitempty"`
-		SellerLastName  *string `json:"seller_last_name,omitempty"`
-	}
-	
+	var vehicle vehicleDetail
 	var startingPrice, reservePrice, buyNowPrice *float64
 	var createdAt interface{}
 	
-	err = h.db.QueryRow(ctx, query, id).Scan(
+	err := h.db.QueryRow(ctx, query, id).Scan(
 		&vehicle.ID, &vehicle.SellerID, &vehicle.VIN, &vehicle.Year,
 		&vehicle.Make, &vehicle.Model, &vehicle.Trim,
 		&vehicle.BodyType, &vehicle.ExteriorColor, &vehicle.InteriorColor,
@@ -184,23 +271,17 @@ func (h *VehicleHandler) GetVehicle(w http.ResponseWriter, r *http.Request) {
 		&vehicle.ConditionGrade, &vehicle.Description,
 		&startingPrice, &reservePrice, &buyNowPrice,
 		&vehicle.LocationCity, &vehicle.LocationState, &vehicle.LocationZip,
-		&vehicle.Status, &createdAt,
+		&vehicle.Status, &createdAt, &vehicle.Version,
 		&vehicle.SellerFirstName, &vehicle.SellerLastName,
 	)
-	
 	if err != nil {
-		h.jsonError(w, "vehicle not found", http.StatusNotFound)
-		return
+		return vehicle, err
 	}
 	
 	if startingPrice != nil {
 		vehicle.StartingPrice = strconv.FormatFloat(*startingPrice, 'f', 2, 64)
 	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"vehicle": vehicle,
-	})
+	return vehicle, nil
 }
 
 // CreateVehicle creates a new vehicle listing
@@ -222,6 +303,13 @@ func (h *VehicleHandler) CreateVehicle(w http.ResponseWriter, r *http.Request) {
 		Mileage       int     `json:"mileage"`
 		StartingPrice float64 `json:"starting_price" validate:"required,gt=0"`
 		Description   string  `json:"description"`
+		
+		// Categorical fields, checked against domain.VehicleEnumFields
+		Transmission   *string `json:"transmission"`
+		Drivetrain     *string `json:"drivetrain"`
+		FuelType       *string `json:"fuel_type"`
+		TitleStatus    *string `json:"title_status"`
+		ConditionGrade *string `json:"condition_grade"`
 	}
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -230,13 +318,32 @@ func (h *VehicleHandler) CreateVehicle(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	if err := h.validate.Struct(req); err != nil {
-		h.jsonError(w, "validation error: "+err.Error(), http.StatusBadRequest)
+		writeValidationError(w, err)
 		return
 	}
+	if fields := vehicleEnumErrors(map[string]*string{
+		"transmission":    req.Transmission,
+		"drivetrain":      req.Drivetrain,
+		"fuel_type":       req.FuelType,
+		"title_status":    req.TitleStatus,
+		"condition_grade": req.ConditionGrade,
+	}); len(fields) > 0 {
+		writeFieldErrors(w, fields)
+		return
+	}
+	if req.Description != "" {
+		clean, msg := h.cleanDescription(req.Description)
+		if msg != "" {
+			writeFieldErrors(w, map[string]string{"description": msg})
+			return
+		}
+		req.Description = clean
+	}
 	
 	query := `
-		INSERT INTO vehicles (seller_id, vin, year, make, model, trim, mileage, starting_price, description, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'draft')
+		INSERT INTO vehicles (seller_id, vin, year, make, model, trim, mileage, starting_price, description,
+		                      transmission, drivetrain, fuel_type, title_status, condition_grade, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, COALESCE($13, 'clean'), $14, 'draft')
 		RETURNING id, created_at
 	`
 	
@@ -246,8 +353,19 @@ func (h *VehicleHandler) CreateVehicle(w http.ResponseWriter, r *http.Request) {
 		userID, req.VIN, req.Year, req.Make, req.Model,
 		nilIfEmpty(req.Trim), nilIfZero(req.Mileage),
 		req.StartingPrice, nilIfEmpty(req.Description),
+		req.Transmission, req.Drivetrain, req.FuelType,
+		req.TitleStatus, req.ConditionGrade,
 	).Scan(&vehicleID, &createdAt)
 	
+	if isUniqueViolation(err, "idx_vehicles_vin_listed") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "a vehicle with this VIN is already listed",
+			"code":  "vin_already_listed",
+		})
+		return
+	}
 	if err != nil {
 		h.logger.Error("failed to create vehicle", slog.String("error", err.Error()))
 		h.jsonError(w, "failed to create vehicle", http.StatusInternalServerError)
@@ -268,8 +386,106 @@ func (h *VehicleHandler) CreateVehicle(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// UpdateVehicle updates a vehicle listing
+// vehicleUpdate is the body of PATCH and PUT /vehicles/{id}. The validate tags
+// only apply to PUT, which must carry the full representation.
+type vehicleUpdate struct {
+	Year           *int     `json:"year" validate:"required,min=1900,max=2030"`
+	Make           *string  `json:"make" validate:"required,min=1"`
+	Model          *string  `json:"model" validate:"required,min=1"`
+	Trim           *string  `json:"trim"`
+	BodyType       *string  `json:"body_type"`
+	Engine         *string  `json:"engine"`
+	Transmission   *string  `json:"transmission"`
+	Drivetrain     *string  `json:"drivetrain"`
+	FuelType       *string  `json:"fuel_type"`
+	ExteriorColor  *string  `json:"exterior_color"`
+	InteriorColor  *string  `json:"interior_color"`
+	Mileage        *int     `json:"mileage"`
+	ConditionGrade *string  `json:"condition_grade"`
+	TitleStatus    *string  `json:"title_status"`
+	Description    *string  `json:"description"`
+	StartingPrice  *float64 `json:"starting_price" validate:"required,gt=0"`
+	ReservePrice   *float64 `json:"reserve_price"`
+	BuyNowPrice    *float64 `json:"buy_now_price"`
+	LocationCity   *string  `json:"location_city"`
+	LocationState  *string  `json:"location_state"`
+	LocationZip    *string  `json:"location_zip"`
+	Version        *int     `json:"version"` // Expected version; If-Match takes precedence
+}
+
+// patchVehicleQuery leaves columns whose parameter is NULL untouched. Any edit
+// clears a pending stale-draft warning.
+const patchVehicleQuery = `
+	UPDATE vehicles SET
+		year = COALESCE($2, year),
+		make = COALESCE($3, make),
+		model = COALESCE($4, model),
+		trim = COALESCE($5, trim),
+		body_type = COALESCE($6, body_type),
+		engine = COALESCE($7, engine),
+		transmission = COALESCE($8, transmission),
+		drivetrain = COALESCE($9, drivetrain),
+		exterior_color = COALESCE($10, exterior_color),
+		interior_color = COALESCE($11, interior_color),
+		mileage = COALESCE($12, mileage),
+		condition_grade = COALESCE($13, condition_grade),
+		title_status = COALESCE($14, title_status),
+		description = COALESCE($15, description),
+		starting_price = COALESCE($16, starting_price),
+		reserve_price = COALESCE($17, reserve_price),
+		buy_now_price = COALESCE($18, buy_now_price),
+		location_city = COALESCE($19, location_city),
+		location_state = COALESCE($20, location_state),
+		location_zip = COALESCE($21, location_zip),
+		fuel_type = COALESCE($23, fuel_type),
+		stale_draft_notified_at = NULL,
+		version = version + 1
+	WHERE id = $1 AND ($22::int IS NULL OR version = $22)
+	RETURNING version
+`
+
+// replaceVehicleQuery overwrites every editable column, clearing omitted ones.
+// title_status falls back to its column default rather than NULL.
+const replaceVehicleQuery = `
+	UPDATE vehicles SET
+		year = $2,
+		make = $3,
+		model = $4,
+		trim = $5,
+		body_type = $6,
+		engine = $7,
+		transmission = $8,
+		drivetrain = $9,
+		exterior_color = $10,
+		interior_color = $11,
+		mileage = $12,
+		condition_grade = $13,
+		title_status = COALESCE($14, 'clean'),
+		description = $15,
+		starting_price = $16,
+		reserve_price = $17,
+		buy_now_price = $18,
+		location_city = $19,
+		location_state = $20,
+		location_zip = $21,
+		fuel_type = $23,
+		stale_draft_notified_at = NULL,
+		version = version + 1
+	WHERE id = $1 AND ($22::int IS NULL OR version = $22)
+	RETURNING version
+`
+
+// UpdateVehicle partially updates a vehicle listing (PATCH); omitted fields keep their values
 func (h *VehicleHandler) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
+	h.updateVehicle(w, r, false)
+}
+
+// ReplaceVehicle replaces a vehicle listing (PUT); omitted optional fields are cleared
+func (h *VehicleHandler) ReplaceVehicle(w http.ResponseWriter, r *http.Request) {
+	h.updateVehicle(w, r, true)
+}
+
+func (h *VehicleHandler) updateVehicle(w http.ResponseWriter, r *http.Request, replace bool) {
 	ctx := r.Context()
 
 	userID := middleware.GetUserID(ctx)
@@ -289,12 +505,7 @@ func (h *VehicleHandler) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
 	var sellerID int64
 	var status string
 	err = h.db.QueryRow(ctx, `SELECT seller_id, status FROM vehicles WHERE id = $1`, vehicleID).Scan(&sellerID, &status)
-	if err != nil {
-		h.jsonError(w, "vehicle not found", http.StatusNotFound)
-		return
-	}
-	if sellerID != userID {
-		h.jsonError(w, "not authorized to edit this vehicle", http.StatusForbidden)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
 		return
 	}
 	if status == "sold" {
@@ -302,79 +513,100 @@ func (h *VehicleHandler) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Year          *int     `json:"year"`
-		Make          *string  `json:"make"`
-		Model         *string  `json:"model"`
-		Trim          *string  `json:"trim"`
-		BodyType      *string  `json:"body_type"`
-		Engine        *string  `json:"engine"`
-		Transmission  *string  `json:"transmission"`
-		Drivetrain    *string  `json:"drivetrain"`
-		ExteriorColor *string  `json:"exterior_color"`
-		InteriorColor *string  `json:"interior_color"`
-		Mileage       *int     `json:"mileage"`
-		ConditionGrade *string `json:"condition_grade"`
-		TitleStatus   *string  `json:"title_status"`
-		Description   *string  `json:"description"`
-		StartingPrice *float64 `json:"starting_price"`
-		ReservePrice  *float64 `json:"reserve_price"`
-		BuyNowPrice   *float64 `json:"buy_now_price"`
-		LocationCity  *string  `json:"location_city"`
-		LocationState *string  `json:"location_state"`
-		LocationZip   *string  `json:"location_zip"`
-	}
-
+	var req vehicleUpdate
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.jsonError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	query := `
-		UPDATE vehicles SET
-			year = COALESCE($2, year),
-			make = COALESCE($3, make),
-			model = COALESCE($4, model),
-			trim = COALESCE($5, trim),
-			body_type = COALESCE($6, body_type),
-			engine = COALESCE($7, engine),
-			transmission = COALESCE($8, transmission),
-			drivetrain = COALESCE($9, drivetrain),
-			exterior_color = COALESCE($10, exterior_color),
-			interior_color = COALESCE($11, interior_color),
-			mileage = COALESCE($12, mileage),
-			condition_grade = COALESCE($13, condition_grade),
-			title_status = COALESCE($14, title_status),
-			description = COALESCE($15, description),
-			starting_price = COALESCE($16, starting_price),
-			reserve_price = COALESCE($17, reserve_price),
-			buy_now_price = COALESCE($18, buy_now_price),
-			location_city = COALESCE($19, location_city),
-			location_state = COALESCE($20, location_state),
-			location_zip = COALESCE($21, location_zip)
-		WHERE id = $1
-	`
+	if replace {
+		if err := h.validate.Struct(req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+	}
+	if fields := vehicleEnumErrors(map[string]*string{
+		"transmission":    req.Transmission,
+		"drivetrain":      req.Drivetrain,
+		"fuel_type":       req.FuelType,
+		"title_status":    req.TitleStatus,
+		"condition_grade": req.ConditionGrade,
+	}); len(fields) > 0 {
+		writeFieldErrors(w, fields)
+		return
+	}
+	if req.Description != nil {
+		clean, msg := h.cleanDescription(*req.Description)
+		if msg != "" {
+			writeFieldErrors(w, map[string]string{"description": msg})
+			return
+		}
+		req.Description = &clean
+	}
+
+	// Optional OCC precondition: If-Match header or version field
+	expectedVersion := req.Version
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		v, err := parseVersionETag(ifMatch)
+		if err != nil {
+			h.jsonError(w, "invalid If-Match header", http.StatusBadRequest)
+			return
+		}
+		expectedVersion = &v
+	}
+
+	query := patchVehicleQuery
+	if replace {
+		query = replaceVehicleQuery
+	}
 
-	_, err = h.db.Exec(ctx, query, vehicleID,
+	var newVersion int
+	err = h.db.QueryRow(ctx, query, vehicleID,
 		req.Year, req.Make, req.Model, req.Trim, req.BodyType,
 		req.Engine, req.Transmission, req.Drivetrain,
 		req.ExteriorColor, req.InteriorColor, req.Mileage,
 		req.ConditionGrade, req.TitleStatus, req.Description,
 		req.StartingPrice, req.ReservePrice, req.BuyNowPrice,
 		req.LocationCity, req.LocationState, req.LocationZip,
-	)
+		expectedVersion, req.FuelType,
+	).Scan(&newVersion)
+	if err == pgx.ErrNoRows && expectedVersion != nil {
+		// No row matched: a stale version, unless the vehicle was deleted
+		// since the ownership check
+		var exists bool
+		err = h.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM vehicles WHERE id = $1)`, vehicleID).Scan(&exists)
+		if err == nil && exists {
+			// Version mismatch - someone else saved first
+			h.logger.Info("vehicle_update_conflict",
+				slog.Int64("vehicle_id", vehicleID),
+				slog.Int("expected_version", *expectedVersion),
+			)
+			h.jsonError(w, "vehicle was modified by another request, reload and retry", http.StatusConflict)
+			return
+		}
+		if err == nil {
+			err = pgx.ErrNoRows
+		}
+	}
+	if err == pgx.ErrNoRows {
+		h.jsonError(w, "vehicle not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		h.logger.Error("failed to update vehicle", slog.String("error", err.Error()))
 		h.jsonError(w, "failed to update vehicle", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("vehicle_updated", slog.Int64("vehicle_id", vehicleID))
+	h.expireVehicle(vehicleID, newVersion)
+	h.logger.Info("vehicle_updated", slog.Int64("vehicle_id", vehicleID), slog.Int("version", newVersion))
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(newVersion))
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message":    "Vehicle updated",
 		"vehicle_id": vehicleID,
+		"version":    newVersion,
 	})
 }
 
@@ -404,12 +636,7 @@ func (h *VehicleHandler) DeleteVehicle(w http.ResponseWriter, r *http.Request) {
 		       EXISTS(SELECT 1 FROM auctions a WHERE a.vehicle_id = v.id AND a.status = 'active')
 		FROM vehicles v WHERE v.id = $1
 	`, vehicleID).Scan(&sellerID, &status, &hasActiveAuction)
-	if err != nil {
-		h.jsonError(w, "vehicle not found", http.StatusNotFound)
-		return
-	}
-	if sellerID != userID {
-		h.jsonError(w, "not authorized to delete this vehicle", http.StatusForbidden)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
 		return
 	}
 	if status == "sold" {
@@ -428,6 +655,7 @@ func (h *VehicleHandler) DeleteVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.expireVehicle(vehicleID, 0)
 	h.logger.Info("vehicle_deleted", slog.Int64("vehicle_id", vehicleID))
 
 	w.Header().Set("Content-Type", "application/json")
@@ -451,7 +679,7 @@ func (h *VehicleHandler) SubmitVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check ownership and required fields
+	// Check ownership, seller verification and required fields
 	var sellerID int64
 	var status string
 	var year, mileage *int
@@ -461,23 +689,54 @@ func (h *VehicleHandler) SubmitVehicle(w http.ResponseWriter, r *http.Request) {
 		SELECT seller_id, status, year, make, model, starting_price, mileage
 		FROM vehicles WHERE id = $1
 	`, vehicleID).Scan(&sellerID, &status, &year, &vinMake, &model, &startingPrice, &mileage)
-	if err != nil {
-		h.jsonError(w, "vehicle not found", http.StatusNotFound)
-		return
-	}
-	if sellerID != userID {
-		h.jsonError(w, "not authorized", http.StatusForbidden)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
 		return
 	}
 	if status != "draft" {
 		h.jsonError(w, "only draft vehicles can be submitted", http.StatusBadRequest)
 		return
 	}
+	// Buyers only ever see listings from sellers who passed ID verification:
+	// creating, publishing and relisting an auction check it too, and a
+	// listed vehicle only transfers to a verified seller
+	canList, err := sellerCanList(ctx, h.db, sellerID)
+	if err != nil {
+		h.logger.Error("failed to check seller verification", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if !canList {
+		middleware.AuditDenied(h.logger, r, "vehicle", middleware.DeniedSellerNotVerified)
+		writeSellerNotVerified(w, http.StatusForbidden, "complete ID verification before listing a vehicle")
+		return
+	}
 	if year == nil || vinMake == nil || model == nil || startingPrice == nil {
 		h.jsonError(w, "missing required fields (year, make, model, starting_price)", http.StatusBadRequest)
 		return
 	}
 
+	atLimit, err := listingLimitReached(ctx, h.db, userID, vehicleID, h.cfg.MaxActiveListingsPerSeller)
+	if err != nil {
+		h.logger.Error("failed to count active listings", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if atLimit {
+		h.jsonError(w, listingLimitMessage(h.cfg.MaxActiveListingsPerSeller), http.StatusConflict)
+		return
+	}
+
+	have, missing, err := missingImages(ctx, h.db, vehicleID, h.cfg.MinImagesToSubmit)
+	if err != nil {
+		h.logger.Error("failed to count vehicle images", slog.String("error", err.Error()))
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if missing > 0 {
+		h.jsonError(w, missingImagesMessage(h.cfg.MinImagesToSubmit, have, missing), http.StatusBadRequest)
+		return
+	}
+
 	// Update to active
 	_, err = h.db.Exec(ctx, `UPDATE vehicles SET status = 'active' WHERE id = $1`, vehicleID)
 	if err != nil {
@@ -486,6 +745,12 @@ func (h *VehicleHandler) SubmitVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Queue for moderation review; the listing stays live unless an admin flags it
+	if _, err := h.db.Exec(ctx, `INSERT INTO moderation_queue (vehicle_id) VALUES ($1)`, vehicleID); err != nil {
+		h.logger.Error("failed to enqueue vehicle for moderation", slog.String("error", err.Error()))
+	}
+
+	h.expireVehicle(vehicleID, 0)
 	h.logger.Info("vehicle_submitted", slog.Int64("vehicle_id", vehicleID))
 
 	w.Header().Set("Content-Type", "application/json")
@@ -495,9 +760,66 @@ func (h *VehicleHandler) SubmitVehicle(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// RestoreVehicle brings a draft archived by the stale-draft sweeper back to draft
+func (h *VehicleHandler) RestoreVehicle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	vehicleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid vehicle id", http.StatusBadRequest)
+		return
+	}
+
+	var sellerID int64
+	var status string
+	var archivedAt *time.Time
+	err = h.db.QueryRow(ctx, `
+		SELECT seller_id, status, archived_at FROM vehicles WHERE id = $1
+	`, vehicleID).Scan(&sellerID, &status, &archivedAt)
+	if !requireOwner(w, r, h.logger, err, sellerID, userID, "vehicle") {
+		return
+	}
+	if status != "archived" || archivedAt == nil {
+		h.jsonError(w, "only archived drafts can be restored", http.StatusBadRequest)
+		return
+	}
+
+	// updated_at is bumped by trigger, so the stale clock restarts
+	tag, err := h.db.Exec(ctx, `
+		UPDATE vehicles SET status = 'draft', archived_at = NULL, stale_draft_notified_at = NULL
+		WHERE id = $1 AND status = 'archived' AND archived_at IS NOT NULL
+	`, vehicleID)
+	if err != nil {
+		h.logger.Error("failed to restore vehicle", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to restore vehicle", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		h.jsonError(w, "only archived drafts can be restored", http.StatusBadRequest)
+		return
+	}
+
+	h.expireVehicle(vehicleID, 0)
+	h.logger.Info("vehicle_restored", slog.Int64("vehicle_id", vehicleID), slog.Int64("seller_id", userID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vehicle_id": vehicleID,
+		"status":     "draft",
+		"message":    "Vehicle restored to draft",
+	})
+}
+
 // GetVehicleImages returns images for a vehicle
 func (h *VehicleHandler) GetVehicleImages(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
 
 	idStr := chi.URLParam(r, "id")
 	vehicleID, err := strconv.ParseInt(idStr, 10, 64)
@@ -511,7 +833,7 @@ func (h *VehicleHandler) GetVehicleImages(w http.ResponseWriter, r *http.Request
 		FROM vehicle_images WHERE vehicle_id = $1 ORDER BY display_order
 	`, vehicleID)
 	if err != nil {
-		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		writeQueryError(w, err)
 		return
 	}
 	defer rows.Close()
@@ -542,6 +864,94 @@ func (h *VehicleHandler) jsonError(w http.ResponseWriter, message string, status
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
+// rowQuerier is satisfied by both the pool and a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// listingLimitReached reports whether the seller already has limit active
+// vehicles, not counting vehicleID itself. A limit of 0 means unlimited.
+func listingLimitReached(ctx context.Context, db rowQuerier, sellerID, vehicleID int64, limit int) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+
+	var active int
+	err := db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM vehicles
+		WHERE seller_id = $1 AND status = 'active' AND id <> $2
+	`, sellerID, vehicleID).Scan(&active)
+	if err != nil {
+		return false, err
+	}
+	return active >= limit, nil
+}
+
+// sellerCanList reports whether the seller has passed ID verification.
+// Admins list on the platform's behalf and are exempt.
+func sellerCanList(ctx context.Context, db rowQuerier, sellerID int64) (bool, error) {
+	var ok bool
+	err := db.QueryRow(ctx, `
+		SELECT role = 'admin' OR id_verified_at IS NOT NULL FROM users WHERE id = $1
+	`, sellerID).Scan(&ok)
+	return ok, err
+}
+
+// writeSellerNotVerified writes a seller_not_verified coded error
+func writeSellerNotVerified(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+		"code":  "seller_not_verified",
+	})
+}
+
+func listingLimitMessage(limit int) string {
+	return fmt.Sprintf("active listing limit reached: sellers may have at most %d active listings", limit)
+}
+
+// missingImages counts the vehicle's images against the required minimum and
+// returns how many it has and how many more it needs. A min of 0 disables it.
+func missingImages(ctx context.Context, db rowQuerier, vehicleID int64, min int) (int, int, error) {
+	if min <= 0 {
+		return 0, 0, nil
+	}
+
+	var have int
+	err := db.QueryRow(ctx, `SELECT COUNT(*) FROM vehicle_images WHERE vehicle_id = $1`, vehicleID).Scan(&have)
+	if err != nil {
+		return 0, 0, err
+	}
+	if have >= min {
+		return have, 0, nil
+	}
+	return have, min - have, nil
+}
+
+func missingImagesMessage(min, have, missing int) string {
+	return fmt.Sprintf("at least %d images are required: %d uploaded, %d more needed", min, have, missing)
+}
+
+// versionETag formats a row version as a strong ETag
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseVersionETag accepts an If-Match value written by versionETag, with or
+// without quotes or a weak prefix
+func parseVersionETag(value string) (int, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	return strconv.Atoi(strings.Trim(value, `"`))
+}
+
+// isUniqueViolation reports whether err is a Postgres unique violation
+// (SQLSTATE 23505) on the given constraint or index
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
 func nilIfEmpty(s string) interface{} {
 	if s == "" {
 		return nil
@@ -7,6 +7,9 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/httpx"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -16,18 +19,40 @@ import (
 type WatchlistHandler struct {
 	db     *pgxpool.Pool
 	logger *slog.Logger
+	cfg    *config.Config
 }
 
-func NewWatchlistHandler(db *pgxpool.Pool, logger *slog.Logger) *WatchlistHandler {
+func NewWatchlistHandler(db *pgxpool.Pool, logger *slog.Logger, cfg *config.Config) *WatchlistHandler {
 	return &WatchlistHandler{
 		db:     db,
 		logger: logger,
+		cfg:    cfg,
 	}
 }
 
+// WatchlistItem is one watched auction with enough of its vehicle to list it
+type WatchlistItem struct {
+	ID          int64            `json:"id"`
+	AuctionID   int64            `json:"auction_id"`
+	Status      string           `json:"status"`
+	CurrentBid  string           `json:"current_bid"`
+	EndsAt      string           `json:"ends_at"`
+	EndsAtLocal string           `json:"ends_at_local,omitempty"`
+	Vehicle     WatchlistVehicle `json:"vehicle"`
+	AddedAt     string           `json:"added_at"`
+}
+
+type WatchlistVehicle struct {
+	Year  int     `json:"year"`
+	Make  string  `json:"make"`
+	Model string  `json:"model"`
+	Trim  *string `json:"trim"`
+}
+
 // GetWatchlist returns user's watchlist
 func (h *WatchlistHandler) GetWatchlist(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, cancel := queryContext(r.Context(), h.cfg.DBQueryTimeout)
+	defer cancel()
 
 	userID := middleware.GetUserID(ctx)
 	if userID == 0 {
@@ -35,17 +60,12 @@ func (h *WatchlistHandler) GetWatchlist(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	limit := 20
-	offset := 0
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, _ := strconv.Atoi(l); parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
-	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, _ := strconv.Atoi(o); parsed >= 0 {
-			offset = parsed
-		}
+	limit, offset := httpx.Pagination(r)
+
+	loc, err := parseTimezone(r)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	rows, err := h.db.Query(ctx, `
@@ -60,50 +80,53 @@ func (h *WatchlistHandler) GetWatchlist(w http.ResponseWriter, r *http.Request)
 		LIMIT $2 OFFSET $3
 	`, userID, limit, offset)
 	if err != nil {
-		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		h.logger.Error("failed to query watchlist", slog.String("error", err.Error()))
+		writeQueryError(w, err)
 		return
 	}
 	defer rows.Close()
 
-	items := make([]map[string]interface{}, 0)
+	items := make([]WatchlistItem, 0)
 	for rows.Next() {
 		var (
-			id, auctionID                       int64
-			createdAt, endsAt                   time.Time
-			status                              string
-			currentBid                          float64
-			year                                int
-			vehicleMake, model                  string
-			trim                                *string
+			item              WatchlistItem
+			createdAt, endsAt time.Time
+			currentBid        float64
 		)
-		rows.Scan(&id, &auctionID, &createdAt, &status, &currentBid, &endsAt, &year, &vehicleMake, &model, &trim)
-		items = append(items, map[string]interface{}{
-			"id":          id,
-			"auction_id":  auctionID,
-			"status":      status,
-			"current_bid": strconv.FormatFloat(currentBid, 'f', 2, 64),
-			"ends_at":     endsAt.Format(time.RFC3339),
-			"vehicle": map[string]interface{}{
-				"year":  year,
-				"make":  vehicleMake,
-				"model": model,
-				"trim":  trim,
-			},
-			"added_at": createdAt.Format(time.RFC3339),
-		})
+		rows.Scan(&item.ID, &item.AuctionID, &createdAt, &item.Status, &currentBid, &endsAt,
+			&item.Vehicle.Year, &item.Vehicle.Make, &item.Vehicle.Model, &item.Vehicle.Trim)
+		item.CurrentBid = strconv.FormatFloat(currentBid, 'f', 2, 64)
+		item.EndsAt = endsAt.Format(time.RFC3339)
+		item.AddedAt = createdAt.Format(time.RFC3339)
+		if loc != nil {
+			item.EndsAtLocal = endsAt.In(loc).Format(time.RFC3339)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		h.logger.Error("failed to read watchlist", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
 	}
 
 	// Get total count
 	var total int64
-	h.db.QueryRow(ctx, `SELECT COUNT(*) FROM watchlist WHERE user_id = $1`, userID).Scan(&total)
+	if err := h.db.QueryRow(ctx, `SELECT COUNT(*) FROM watchlist WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		h.logger.Error("failed to count watchlist", slog.String("error", err.Error()))
+		writeQueryError(w, err)
+		return
+	}
+
+	resp := struct {
+		domain.PaginatedResponse[WatchlistItem]
+		Timezone string `json:"timezone,omitempty"`
+	}{PaginatedResponse: domain.NewPaginatedResponse(items, total, limit, offset)}
+	if loc != nil {
+		resp.Timezone = loc.String()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"watchlist": items,
-		"total":     total,
-		"limit":     limit,
-		"offset":    offset,
-	})
+	json.NewEncoder(w).Encode(resp)
 }
 
 // AddToWatchlist adds an auction to user's watchlist
@@ -123,9 +146,13 @@ func (h *WatchlistHandler) AddToWatchlist(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Check auction exists
+	// Check auction exists and, if private, that the user is invited
 	var exists bool
-	h.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM auctions WHERE id = $1)`, auctionID).Scan(&exists)
+	h.db.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM auctions a WHERE a.id = $1 AND a.status <> 'draft'
+		    AND (a.visibility = 'public'
+		         OR EXISTS(SELECT 1 FROM auction_invites i WHERE i.auction_id = a.id AND i.user_id = $2)))
+	`, auctionID, userID).Scan(&exists)
 	if !exists {
 		h.jsonError(w, "auction not found", http.StatusNotFound)
 		return
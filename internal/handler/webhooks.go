@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookHandler manages a user's outbound webhooks for their own bid outcomes
+type WebhookHandler struct {
+	db       *pgxpool.Pool
+	logger   *slog.Logger
+	validate *validator.Validate
+	insecure bool
+}
+
+// WebhookHandlerOption configures a WebhookHandler
+type WebhookHandlerOption func(*WebhookHandler)
+
+// WithInsecureWebhookURLs accepts http URLs and hosts on loopback or private
+// networks (local development and tests)
+func WithInsecureWebhookURLs(enabled bool) WebhookHandlerOption {
+	return func(h *WebhookHandler) {
+		h.insecure = enabled
+	}
+}
+
+func NewWebhookHandler(db *pgxpool.Pool, logger *slog.Logger, opts ...WebhookHandlerOption) *WebhookHandler {
+	h := &WebhookHandler{
+		db:       db,
+		logger:   logger,
+		validate: newValidator(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type WebhookResponse struct {
+	ID        int64    `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at"`
+	Secret    string   `json:"secret,omitempty"` // Only returned on create
+}
+
+// CreateWebhook registers a callback URL for the caller's bid outcomes
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		URL    string   `json:"url" validate:"required,url,max=1000"`
+		Events []string `json:"events" validate:"dive,oneof=bid.accepted bid.outbid auction.won auction.closed_by_admin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if len(req.Events) == 0 {
+		req.Events = webhook.UserEvents
+	}
+
+	// Deliveries come from inside our network, so a URL pointing back into
+	// it would let users reach internal services
+	checkCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	err := webhook.CheckURL(checkCtx, req.URL, h.insecure)
+	cancel()
+	if errors.Is(err, webhook.ErrPrivateAddress) {
+		h.jsonError(w, "url must resolve to a public address", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	secret := hex.EncodeToString(secretBytes)
+
+	resp := WebhookResponse{URL: req.URL, Events: req.Events, Active: true, Secret: secret}
+	var createdAt time.Time
+	err = h.db.QueryRow(ctx, `
+		INSERT INTO user_webhooks (user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, userID, req.URL, secret, req.Events).Scan(&resp.ID, &createdAt)
+	if err != nil {
+		h.logger.Error("failed to create webhook", slog.String("error", err.Error()))
+		h.jsonError(w, "failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+	resp.CreatedAt = createdAt.Format(time.RFC3339)
+
+	h.logger.Info("webhook_created",
+		slog.Int64("webhook_id", resp.ID),
+		slog.Int64("user_id", userID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListWebhooks returns the caller's webhooks (secrets are never listed)
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT id, url, events, active, created_at
+		FROM user_webhooks WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	webhooks := make([]WebhookResponse, 0)
+	for rows.Next() {
+		var wh WebhookResponse
+		var createdAt time.Time
+		if err := rows.Scan(&wh.ID, &wh.URL, &wh.Events, &wh.Active, &createdAt); err != nil {
+			continue
+		}
+		wh.CreatedAt = createdAt.Format(time.RFC3339)
+		webhooks = append(webhooks, wh)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhooks": webhooks,
+	})
+}
+
+// DeleteWebhook removes one of the caller's webhooks
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		h.jsonError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.jsonError(w, "invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.db.Exec(ctx, `DELETE FROM user_webhooks WHERE id = $1 AND user_id = $2`, webhookID, userID)
+	if err != nil {
+		h.jsonError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		h.jsonError(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) jsonError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
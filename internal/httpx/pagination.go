@@ -0,0 +1,57 @@
+// Package httpx holds small request-parsing helpers shared by the HTTP handlers.
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Page sizes applied by Pagination. Set once at startup from config via
+// SetPageSizes; the defaults match what the list endpoints used before.
+var (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// SetPageSizes overrides the package page sizes. Non-positive values keep the
+// current setting, and the default is clamped to the max.
+func SetPageSizes(defaultSize, maxSize int) {
+	if maxSize > 0 {
+		MaxPageSize = maxSize
+	}
+	if defaultSize > 0 {
+		DefaultPageSize = defaultSize
+	}
+	if DefaultPageSize > MaxPageSize {
+		DefaultPageSize = MaxPageSize
+	}
+}
+
+// Pagination reads ?limit= and ?offset= using the package page sizes. A
+// missing, malformed or non-positive limit falls back to DefaultPageSize, a
+// limit above MaxPageSize is clamped to it, and a missing, malformed or
+// negative offset is 0.
+func Pagination(r *http.Request) (limit, offset int) {
+	return Limit(r, DefaultPageSize, MaxPageSize), Offset(r)
+}
+
+// Offset reads ?offset=; a missing, malformed or negative offset is 0
+func Offset(r *http.Request) int {
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && parsed > 0 {
+		return parsed
+	}
+	return 0
+}
+
+// Limit reads ?limit= for top-N endpoints that keep their own sizes, with the
+// same fallback and clamping rules as Pagination.
+func Limit(r *http.Request, defaultSize, maxSize int) int {
+	parsed, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || parsed <= 0 {
+		return defaultSize
+	}
+	if parsed > maxSize {
+		return maxSize
+	}
+	return parsed
+}
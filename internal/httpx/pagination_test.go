@@ -0,0 +1,66 @@
+package httpx
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+	}{
+		{"defaults", "", 20, 0},
+		{"explicit", "?limit=5&offset=10", 5, 10},
+		{"limit at max", "?limit=100", 100, 0},
+		{"limit clamped to max", "?limit=500", 100, 0},
+		{"zero limit", "?limit=0", 20, 0},
+		{"negative limit", "?limit=-3", 20, 0},
+		{"malformed limit", "?limit=ten", 20, 0},
+		{"negative offset", "?offset=-1", 20, 0},
+		{"malformed offset", "?offset=1.5", 20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, offset := Pagination(httptest.NewRequest("GET", "/items"+tt.query, nil))
+			assert.Equal(t, tt.wantLimit, limit)
+			assert.Equal(t, tt.wantOffset, offset)
+		})
+	}
+}
+
+func TestSetPageSizes(t *testing.T) {
+	defaultSize, maxSize := DefaultPageSize, MaxPageSize
+	t.Cleanup(func() { DefaultPageSize, MaxPageSize = defaultSize, maxSize })
+
+	SetPageSizes(10, 30)
+	limit, _ := Pagination(httptest.NewRequest("GET", "/items", nil))
+	assert.Equal(t, 10, limit)
+	limit, _ = Pagination(httptest.NewRequest("GET", "/items?limit=50", nil))
+	assert.Equal(t, 30, limit)
+
+	// Zero keeps the current value; a default above the max is clamped
+	SetPageSizes(0, 0)
+	assert.Equal(t, 10, DefaultPageSize)
+	assert.Equal(t, 30, MaxPageSize)
+	SetPageSizes(40, 0)
+	assert.Equal(t, 30, DefaultPageSize)
+}
+
+func TestLimit(t *testing.T) {
+	assert.Equal(t, 12, Limit(httptest.NewRequest("GET", "/top", nil), 12, 50))
+	assert.Equal(t, 7, Limit(httptest.NewRequest("GET", "/top?limit=7", nil), 12, 50))
+	assert.Equal(t, 50, Limit(httptest.NewRequest("GET", "/top?limit=51", nil), 12, 50))
+	assert.Equal(t, 12, Limit(httptest.NewRequest("GET", "/top?limit=abc", nil), 12, 50))
+}
+
+func TestOffset(t *testing.T) {
+	assert.Equal(t, 0, Offset(httptest.NewRequest("GET", "/items", nil)))
+	assert.Equal(t, 40, Offset(httptest.NewRequest("GET", "/items?offset=40", nil)))
+	assert.Equal(t, 0, Offset(httptest.NewRequest("GET", "/items?offset=-5", nil)))
+	assert.Equal(t, 0, Offset(httptest.NewRequest("GET", "/items?offset=abc", nil)))
+}
@@ -55,6 +55,22 @@ var (
 		},
 	)
 
+	DBPoolWait = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "db_pool_wait_seconds",
+			Help:    "Time spent waiting to acquire a pooled database connection",
+			Buckets: []float64{.0001, .001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+	)
+
+	ReadCacheRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "read_cache_requests_total",
+			Help: "Detail reads answered from the in-memory cache (hit) or the database (miss)",
+		},
+		[]string{"cache", "result"},
+	)
+
 	// ==========================================================================
 	// Auction Metrics
 	// ==========================================================================
@@ -155,6 +171,17 @@ var (
 		},
 	)
 
+	// ==========================================================================
+	// Security Metrics
+	// ==========================================================================
+	AuthzDeniedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "authz_denied_total",
+			Help: "Total requests refused by authentication or authorization checks",
+		},
+		[]string{"reason"},
+	)
+
 	// ==========================================================================
 	// User Metrics
 	// ==========================================================================
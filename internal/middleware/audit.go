@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/go-chi/chi/v5"
+)
+
+// Reasons recorded on authz_denied logs and the authz_denied_total metric
+const (
+	DeniedMissingCredentials = "missing_credentials"
+	DeniedInvalidToken       = "invalid_token"
+	DeniedUnknownUser        = "unknown_user"
+	DeniedRoleLookupFailed   = "role_lookup_failed"
+	DeniedRoleNotPermitted   = "role_not_permitted"
+	DeniedNotOwner           = "not_owner"
+	DeniedSellerNotVerified  = "seller_not_verified"
+)
+
+// AuditDenied records a refused request: one structured authz_denied log line
+// with the caller, resource and action, and an authz_denied_total increment
+// labeled by reason. The action is the method and route pattern, falling back
+// to the raw path outside a chi router.
+func AuditDenied(logger *slog.Logger, r *http.Request, resource, reason string) {
+	metrics.AuthzDeniedTotal.WithLabelValues(reason).Inc()
+	if logger == nil {
+		return
+	}
+
+	route := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+	logger.Warn("authz_denied",
+		slog.Int64("user_id", GetUserID(r.Context())),
+		slog.String("resource", resource),
+		slog.String("action", r.Method+" "+route),
+		slog.String("reason", reason),
+		slog.String("request_id", GetRequestID(r.Context())),
+	)
+}
@@ -26,15 +26,40 @@ type ClerkAuth struct {
 	jwksURL   string
 	secretKey string
 	db        *pgxpool.Pool
+	keys      *jwks // nil without CLERK_JWKS_URL
+	apiURL    string
+	users     *clerkUsers // nil without CLERK_SECRET_KEY
 }
 
-func NewClerkAuth(logger *slog.Logger, jwksURL, secretKey string, db *pgxpool.Pool) *ClerkAuth {
-	return &ClerkAuth{
+// ClerkAuthOption configures a ClerkAuth
+type ClerkAuthOption func(*ClerkAuth)
+
+// WithClerkAPIURL points Backend API lookups somewhere other than Clerk's
+// production API (tests)
+func WithClerkAPIURL(url string) ClerkAuthOption {
+	return func(c *ClerkAuth) {
+		c.apiURL = url
+	}
+}
+
+func NewClerkAuth(logger *slog.Logger, jwksURL, secretKey string, db *pgxpool.Pool, opts ...ClerkAuthOption) *ClerkAuth {
+	c := &ClerkAuth{
 		logger:    logger,
 		jwksURL:   jwksURL,
 		secretKey: secretKey,
 		db:        db,
+		apiURL:    defaultClerkAPIURL,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	if jwksURL != "" {
+		c.keys = newJWKS(jwksURL)
+	}
+	if secretKey != "" {
+		c.users = newClerkUsers(c.apiURL, secretKey)
+	}
+	return c
 }
 
 // Middleware returns the auth middleware handler
@@ -58,10 +83,7 @@ func (c *ClerkAuth) Middleware(next http.Handler) http.Handler {
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			c.logger.Warn("missing authorization header",
-				slog.String("path", r.URL.Path),
-				slog.String("request_id", GetRequestID(r.Context())),
-			)
+			AuditDenied(c.logger, r, "session", DeniedMissingCredentials)
 			c.unauthorized(w, "missing authorization header")
 			return
 		}
@@ -69,6 +91,7 @@ func (c *ClerkAuth) Middleware(next http.Handler) http.Handler {
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			AuditDenied(c.logger, r, "session", DeniedMissingCredentials)
 			c.unauthorized(w, "invalid authorization header format")
 			return
 		}
@@ -82,6 +105,7 @@ func (c *ClerkAuth) Middleware(next http.Handler) http.Handler {
 				slog.String("error", err.Error()),
 				slog.String("request_id", GetRequestID(r.Context())),
 			)
+			AuditDenied(c.logger, r, "session", DeniedInvalidToken)
 			c.unauthorized(w, "invalid token")
 			return
 		}
@@ -98,32 +122,83 @@ func (c *ClerkAuth) Middleware(next http.Handler) http.Handler {
 				slog.String("error", err.Error()),
 				slog.String("request_id", GetRequestID(r.Context())),
 			)
+			AuditDenied(c.logger, r, "session", DeniedUnknownUser)
 			c.unauthorized(w, "user not found - please sync your account")
 			return
 		}
 
 		// Add to context
-		ctx := WithUserID(r.Context(), userID)
-		ctx = context.WithValue(ctx, "clerk_user_id", claims.UserID)
-		ctx = context.WithValue(ctx, "clerk_email", claims.Email)
+		ctx := WithClerkClaims(WithUserID(r.Context(), userID), claims.UserID, claims.Email)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireSession accepts any request with a valid Clerk session token and
+// puts its verified Clerk ID and email in the context, whether or not a
+// local user exists yet. It's for the sync endpoint that creates that user.
+// A token without an email claim (Clerk's default) has the email looked up
+// from Clerk's Backend API by its verified Clerk ID.
+func (c *ClerkAuth) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.Header.Get("Authorization"), " ")
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			AuditDenied(c.logger, r, "session", DeniedMissingCredentials)
+			c.unauthorized(w, "missing authorization header")
+			return
+		}
+
+		claims, err := c.validateToken(parts[1])
+		if err != nil {
+			c.logger.Warn("token validation failed",
+				slog.String("error", err.Error()),
+				slog.String("request_id", GetRequestID(r.Context())),
+			)
+			AuditDenied(c.logger, r, "session", DeniedInvalidToken)
+			c.unauthorized(w, "invalid token")
+			return
+		}
+
+		email := claims.Email
+		if email == "" && c.users != nil {
+			email, err = c.users.primaryEmail(r.Context(), claims.UserID)
+			if err != nil {
+				c.logger.Error("failed to look up clerk user email",
+					slog.String("clerk_user_id", claims.UserID),
+					slog.String("error", err.Error()),
+					slog.String("request_id", GetRequestID(r.Context())),
+				)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadGateway)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": "failed to look up account email",
+				})
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClerkClaims(r.Context(), claims.UserID, email)))
+	})
+}
+
+// validateToken checks a Clerk session token's RS256 signature against the
+// JWKS. Without CLERK_JWKS_URL, development and test environments accept
+// unsigned tokens and rely on the database lookup; production rejects them.
 func (c *ClerkAuth) validateToken(tokenString string) (*ClerkClaims, error) {
 	claims := &ClerkClaims{}
 
-	// Clerk uses RS256 (RSA) signing. For proper validation, we'd need to:
-	// 1. Fetch JWKS from c.jwksURL
-	// 2. Find the key matching the token's "kid" header
-	// 3. Validate the signature with that public key
-	//
-	// For development, we parse without signature verification and rely on
-	// the database lookup to confirm the user exists.
-	// TODO: Implement proper JWKS validation for production
-
-	token, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+	var token *jwt.Token
+	var err error
+	if c.keys != nil {
+		token, err = jwt.ParseWithClaims(tokenString, claims, c.keys.keyfunc,
+			jwt.WithValidMethods([]string{"RS256"}),
+			jwt.WithExpirationRequired(),
+		)
+	} else if env := os.Getenv("ENVIRONMENT"); env == "development" || env == "test" || env == "" {
+		token, _, err = jwt.NewParser().ParseUnverified(tokenString, claims)
+	} else {
+		return nil, fmt.Errorf("token verification requires CLERK_JWKS_URL")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
@@ -185,12 +260,17 @@ func (c *ClerkAuth) OptionalAuth(next http.Handler) http.Handler {
 		if err == nil {
 			ctx = WithUserID(ctx, userID)
 		}
-		ctx = context.WithValue(ctx, "clerk_user_id", claims.UserID)
-		ctx = context.WithValue(ctx, "clerk_email", claims.Email)
+		ctx = WithClerkClaims(ctx, claims.UserID, claims.Email)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// WithClerkClaims adds a verified Clerk user ID and email to context
+func WithClerkClaims(ctx context.Context, clerkUserID, email string) context.Context {
+	ctx = context.WithValue(ctx, "clerk_user_id", clerkUserID)
+	return context.WithValue(ctx, "clerk_email", email)
+}
+
 // GetClerkUserID extracts Clerk user ID from context
 func GetClerkUserID(ctx context.Context) string {
 	if id, ok := ctx.Value("clerk_user_id").(string); ok {
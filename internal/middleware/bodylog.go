@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// redactedHeaders never reach the body log with their values
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+}
+
+// bodyRecorder keeps the status and the first limit bytes of a response
+type bodyRecorder struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (br *bodyRecorder) WriteHeader(code int) {
+	if br.status == 0 {
+		br.status = code
+	}
+	br.ResponseWriter.WriteHeader(code)
+}
+
+func (br *bodyRecorder) Write(b []byte) (int, error) {
+	if br.status == 0 {
+		br.status = http.StatusOK
+	}
+	if room := br.limit - br.body.Len(); room > 0 {
+		if len(b) > room {
+			br.body.Write(b[:room])
+			br.truncated = true
+		} else {
+			br.body.Write(b)
+		}
+	} else if len(b) > 0 {
+		br.truncated = true
+	}
+	return br.ResponseWriter.Write(b)
+}
+
+// LogBodies logs the request and response bodies of the routes it wraps, up to
+// limit bytes each, with credential headers redacted. It's meant for debugging
+// only; mount it behind DebugEndpointsEnabled. The request body is read ahead
+// and replayed, so the handler still sees all of it.
+func LogBodies(logger *slog.Logger, limit int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var reqBody []byte
+			reqTruncated := false
+			if r.Body != nil && r.Body != http.NoBody {
+				head, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+				if len(head) > limit {
+					reqBody, reqTruncated = head[:limit], true
+				} else {
+					reqBody = head
+				}
+				// Replay what was read, then the rest, including any read error
+				rest := io.Reader(r.Body)
+				if err != nil {
+					rest = &errReader{err: err}
+				}
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(head), rest), r.Body}
+			}
+
+			rec := &bodyRecorder{ResponseWriter: w, limit: limit}
+			next.ServeHTTP(rec, r)
+
+			logger.Info("http_body",
+				slog.String("request_id", GetRequestID(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Any("headers", redactHeaders(r.Header)),
+				slog.String("request_body", string(reqBody)),
+				slog.Bool("request_body_truncated", reqTruncated),
+				slog.String("response_body", rec.body.String()),
+				slog.Bool("response_body_truncated", rec.truncated),
+			)
+		})
+	}
+}
+
+// redactHeaders flattens headers for logging, masking credentials
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			out[name] = "[REDACTED]"
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// readCloser pairs a replaying reader with the original body's Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type errReader struct{ err error }
+
+func (e *errReader) Read([]byte) (int, error) { return 0, e.err }
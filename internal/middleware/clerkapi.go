@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultClerkAPIURL is Clerk's Backend API
+const defaultClerkAPIURL = "https://api.clerk.com/v1"
+
+// clerkUsers reads users from Clerk's Backend API with the instance's secret
+// key. Clerk's default session token carries no email claim, so the sync
+// endpoint looks the address up here instead.
+type clerkUsers struct {
+	baseURL   string
+	secretKey string
+	client    *http.Client
+}
+
+func newClerkUsers(baseURL, secretKey string) *clerkUsers {
+	return &clerkUsers{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// primaryEmail returns the primary email address of a Clerk user, or "" if
+// they have none
+func (u *clerkUsers) primaryEmail(ctx context.Context, clerkUserID string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.baseURL+"/users/"+url.PathEscape(clerkUserID), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.secretKey)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch clerk user: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch clerk user: status %d", resp.StatusCode)
+	}
+
+	var user struct {
+		PrimaryEmailAddressID string `json:"primary_email_address_id"`
+		EmailAddresses        []struct {
+			ID           string `json:"id"`
+			EmailAddress string `json:"email_address"`
+		} `json:"email_addresses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return "", fmt.Errorf("decode clerk user: %w", err)
+	}
+	for _, addr := range user.EmailAddresses {
+		if addr.ID == user.PrimaryEmailAddressID {
+			return addr.EmailAddress, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Compress gzips JSON responses of at least minSize bytes for clients that
+// accept gzip. Smaller responses are held until they finish or reach minSize.
+// Anything that isn't JSON, such as an SSE stream, and any response the
+// handler flushes early are passed straight through, never buffered.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, minSize: minSize}
+			defer cw.finish()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// gzip;q=0 is an explicit refusal
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers a response until it knows whether to gzip it: once
+// the body reaches minSize it switches to gzip, and a non-JSON content type
+// or a Flush switches it to plain passthrough.
+type compressWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     bytes.Buffer
+	gz      *gzip.Writer
+	plain   bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.plain || cw.gz != nil {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = code
+	}
+	if !cw.compressible() {
+		cw.passThrough()
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	switch {
+	case cw.plain:
+		return cw.ResponseWriter.Write(b)
+	case cw.gz != nil:
+		return cw.gz.Write(b)
+	}
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.compressible() {
+		cw.passThrough()
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf.Write(b)
+	if cw.buf.Len() >= cw.minSize {
+		if err := cw.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends everything so far. An undecided response goes out
+// uncompressed: a handler that flushes is streaming.
+func (cw *compressWriter) Flush() {
+	switch {
+	case cw.gz != nil:
+		cw.gz.Flush()
+	case !cw.plain:
+		cw.passThrough()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// passThrough sends the status and anything buffered as is, and stops
+// buffering
+func (cw *compressWriter) passThrough() {
+	cw.plain = true
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+	if cw.buf.Len() > 0 {
+		cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+}
+
+func (cw *compressWriter) startGzip() error {
+	h := cw.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	cw.gz = gzip.NewWriter(cw.ResponseWriter)
+	_, err := cw.gz.Write(cw.buf.Bytes())
+	cw.buf.Reset()
+	return err
+}
+
+// finish completes the response once the handler returns: closing the gzip
+// stream, or sending a response that stayed under minSize as is
+func (cw *compressWriter) finish() {
+	switch {
+	case cw.gz != nil:
+		cw.gz.Close()
+	case !cw.plain:
+		cw.passThrough()
+	}
+}
+
+// compressible reports whether the response is JSON the handler hasn't
+// encoded itself
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
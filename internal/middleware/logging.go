@@ -82,6 +82,7 @@ func Logging(logger *slog.Logger) func(http.Handler) http.Handler {
 				slog.Int("size", wrapped.size),
 				slog.Duration("duration", duration),
 				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("client_ip", ClientIP(r)),
 				slog.String("user_agent", r.UserAgent()),
 			)
 		})
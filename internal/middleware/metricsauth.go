@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// RequireToken guards an internal endpoint such as /metrics with a shared
+// secret. The token is accepted as a bearer token or as the basic-auth
+// password (any username), which covers both Prometheus scrape auth styles.
+// An empty token disables the guard.
+func RequireToken(token string, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, ok := presentedToken(r)
+			if !ok {
+				AuditDenied(logger, r, "metrics", DeniedMissingCredentials)
+				tokenUnauthorized(w)
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				AuditDenied(logger, r, "metrics", DeniedInvalidToken)
+				tokenUnauthorized(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// presentedToken extracts the secret from a Bearer or Basic Authorization header
+func presentedToken(r *http.Request) (string, bool) {
+	if _, password, ok := r.BasicAuth(); ok {
+		return password, true
+	}
+	scheme, value, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "bearer") || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+func tokenUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
@@ -1,14 +1,27 @@
 package middleware
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"io"
 	"log/slog"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRequestID_GeneratesID(t *testing.T) {
@@ -79,3 +92,402 @@ func TestGetRequestID_ReturnsEmpty(t *testing.T) {
 	assert.Empty(t, reqID)
 }
 
+
+func TestRealIP_IgnoresSpoofedHeadersFromUntrustedPeer(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	assert.NoError(t, err)
+
+	var got string
+	handler := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIP(r)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "203.0.113.7:54321"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	req.Header.Set("X-Real-IP", "5.6.7.8")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "203.0.113.7", got)
+}
+
+func TestRealIP_UsesForwardedForFromTrustedProxy(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	assert.NoError(t, err)
+
+	var got string
+	handler := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIP(r)
+	}))
+
+	tests := []struct {
+		name   string
+		peer   string
+		xff    string
+		realIP string
+		want   string
+	}{
+		{"single hop", "10.0.0.5:443", "198.51.100.9", "", "198.51.100.9"},
+		{"client-supplied prefix ignored", "10.0.0.5:443", "6.6.6.6, 198.51.100.9", "", "198.51.100.9"},
+		{"skips trusted hops", "10.0.0.5:443", "198.51.100.9, 192.168.1.1, 10.1.2.3", "", "198.51.100.9"},
+		{"garbage hop falls back to peer", "10.0.0.5:443", "198.51.100.9, not-an-ip", "", "10.0.0.5"},
+		{"x-real-ip", "192.168.1.1:443", "", "198.51.100.20", "198.51.100.20"},
+		{"no headers", "10.0.0.5:443", "", "", "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = tt.peer
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRealIP_NoTrustedProxiesUsesPeer(t *testing.T) {
+	var got string
+	handler := RealIP(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIP(r)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.5:443"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "10.0.0.5", got)
+}
+
+func TestParseTrustedProxies_RejectsInvalid(t *testing.T) {
+	_, err := ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	_, err = ParseTrustedProxies([]string{"proxy.internal"})
+	assert.Error(t, err)
+}
+
+func TestClerkAuth_MissingHeaderIsAudited(t *testing.T) {
+	auth := NewClerkAuth(slog.New(slog.NewTextHandler(io.Discard, nil)), "", "", nil)
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not run without credentials")
+	}))
+
+	t.Setenv("ENVIRONMENT", "production")
+	before := testutil.ToFloat64(metrics.AuthzDeniedTotal.WithLabelValues(DeniedMissingCredentials))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/me", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.AuthzDeniedTotal.WithLabelValues(DeniedMissingCredentials)))
+}
+
+// jwksServer serves key's public half as a JWKS with the given kid
+func jwksServer(t *testing.T, kid string, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": kid,
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClerkAuth_RequireSessionVerifiesSignature(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	t.Setenv("ENVIRONMENT", "production")
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	sign := func(method jwt.SigningMethod, signer any) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub":   "user_2abc",
+			"email": "buyer@example.com",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(signer)
+		require.NoError(t, err)
+		return signed
+	}
+
+	var gotID, gotEmail string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID, gotEmail = GetClerkUserID(r.Context()), GetClerkEmail(r.Context())
+	})
+	serve := func(auth *ClerkAuth, token string) int {
+		req := httptest.NewRequest("POST", "/api/auth/clerk-sync", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.RequireSession(next).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	auth := NewClerkAuth(logger, jwksServer(t, "key-1", key).URL, "", nil)
+	assert.Equal(t, http.StatusOK, serve(auth, sign(jwt.SigningMethodRS256, key)))
+	assert.Equal(t, "user_2abc", gotID)
+	assert.Equal(t, "buyer@example.com", gotEmail)
+
+	gotID = ""
+	assert.Equal(t, http.StatusUnauthorized, serve(auth, sign(jwt.SigningMethodRS256, forged)))
+	assert.Equal(t, http.StatusUnauthorized, serve(auth, sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType)))
+	assert.Empty(t, gotID)
+
+	// Production never falls back to unverified tokens
+	unconfigured := NewClerkAuth(logger, "", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, serve(unconfigured, sign(jwt.SigningMethodRS256, key)))
+}
+
+func TestClerkAuth_RequireSessionLooksUpMissingEmail(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	t.Setenv("ENVIRONMENT", "test")
+
+	var gotAuth, gotPath string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotPath = r.Header.Get("Authorization"), r.URL.Path
+		if r.URL.Path != "/users/user_2abc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":                       "user_2abc",
+			"primary_email_address_id": "idn_2",
+			"email_addresses": []map[string]string{
+				{"id": "idn_1", "email_address": "old@example.com"},
+				{"id": "idn_2", "email_address": "buyer@example.com"},
+			},
+		})
+	}))
+	t.Cleanup(api.Close)
+
+	// Clerk's default session token: no email claim
+	sign := func(sub string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": sub,
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		signed, err := token.SignedString([]byte("test"))
+		require.NoError(t, err)
+		return signed
+	}
+
+	var gotEmail string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEmail = GetClerkEmail(r.Context())
+	})
+	serve := func(auth *ClerkAuth, token string) int {
+		req := httptest.NewRequest("POST", "/api/auth/clerk-sync", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.RequireSession(next).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	auth := NewClerkAuth(logger, "", "sk_test_123", nil, WithClerkAPIURL(api.URL))
+	assert.Equal(t, http.StatusOK, serve(auth, sign("user_2abc")))
+	assert.Equal(t, "buyer@example.com", gotEmail)
+	assert.Equal(t, "Bearer sk_test_123", gotAuth)
+	assert.Equal(t, "/users/user_2abc", gotPath)
+
+	// A failed lookup doesn't reach the handler
+	gotEmail = ""
+	assert.Equal(t, http.StatusBadGateway, serve(auth, sign("user_unknown")))
+	assert.Empty(t, gotEmail)
+
+	// Without a secret key there's nothing to look up with
+	gotPath = ""
+	keyless := NewClerkAuth(logger, "", "", nil, WithClerkAPIURL(api.URL))
+	assert.Equal(t, http.StatusOK, serve(keyless, sign("user_2abc")))
+	assert.Empty(t, gotEmail)
+	assert.Empty(t, gotPath)
+}
+
+func TestRequireToken(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	metricsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# metrics"))
+	})
+
+	guarded := RequireToken("s3cret", logger)(metricsHandler)
+	tests := []struct {
+		name     string
+		setAuth  func(r *http.Request)
+		wantCode int
+	}{
+		{"no credentials", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"wrong bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"empty bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ") }, http.StatusUnauthorized},
+		{"wrong basic password", func(r *http.Request) { r.SetBasicAuth("prometheus", "nope") }, http.StatusUnauthorized},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") }, http.StatusOK},
+		{"basic", func(r *http.Request) { r.SetBasicAuth("prometheus", "s3cret") }, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			tt.setAuth(req)
+			rec := httptest.NewRecorder()
+			guarded.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusUnauthorized {
+				assert.NotContains(t, rec.Body.String(), "# metrics")
+				assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// Without a token the endpoint stays open
+	rec := httptest.NewRecorder()
+	RequireToken("", logger)(metricsHandler).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	SecurityHeaders(0)(ok).ServeHTTP(rec, httptest.NewRequest("GET", "/api/auctions", nil))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", rec.Header().Get("Referrer-Policy"))
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+
+	rec = httptest.NewRecorder()
+	SecurityHeaders(365*24*time.Hour)(ok).ServeHTTP(rec, httptest.NewRequest("GET", "/api/auctions", nil))
+	assert.Equal(t, "max-age=31536000; includeSubDomains", rec.Header().Get("Strict-Transport-Security"))
+}
+
+func TestLogBodies(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	var seen string
+	handler := LogBodies(logger, 16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		seen = string(body)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"ticket_id":"abc"}`))
+	}))
+
+	req := httptest.NewRequest("POST", "/api/auctions/7/bid", strings.NewReader(`{"amount":"150.00"}`))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	// The handler still reads the whole body, past the log limit
+	assert.Equal(t, `{"amount":"150.00"}`, seen)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, `{"ticket_id":"abc"}`, rec.Body.String())
+
+	var entry struct {
+		Msg                   string            `json:"msg"`
+		Status                int               `json:"status"`
+		Headers               map[string]string `json:"headers"`
+		RequestBody           string            `json:"request_body"`
+		RequestBodyTruncated  bool              `json:"request_body_truncated"`
+		ResponseBody          string            `json:"response_body"`
+		ResponseBodyTruncated bool              `json:"response_body_truncated"`
+	}
+	assert.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, "http_body", entry.Msg)
+	assert.Equal(t, http.StatusAccepted, entry.Status)
+	assert.Equal(t, `{"amount":"150.0`, entry.RequestBody)
+	assert.True(t, entry.RequestBodyTruncated)
+	assert.Equal(t, `{"ticket_id":"ab`, entry.ResponseBody)
+	assert.True(t, entry.ResponseBodyTruncated)
+	assert.Equal(t, "[REDACTED]", entry.Headers["Authorization"])
+	assert.Equal(t, "application/json", entry.Headers["Content-Type"])
+	assert.NotContains(t, logs.String(), "s3cret")
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"items":[` + strings.Repeat(`{"make":"Honda","model":"Civic"},`, 100) + `{}]}`
+	list := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("small") != "" {
+			w.Write([]byte(`{"items":[]}`))
+			return
+		}
+		w.Write([]byte(large))
+	})
+	handler := Compress(1024)(list)
+
+	serve := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("large list is gzipped", func(t *testing.T) {
+		rec := serve("/api/vehicles", "gzip, deflate, br")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		assert.Contains(t, rec.Header().Values("Vary"), "Accept-Encoding")
+		assert.Less(t, rec.Body.Len(), len(large))
+
+		zr, err := gzip.NewReader(rec.Body)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		assert.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("small response stays plain", func(t *testing.T) {
+		rec := serve("/api/vehicles?small=1", "gzip")
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, `{"items":[]}`, rec.Body.String())
+	})
+
+	t.Run("client without gzip", func(t *testing.T) {
+		for _, accept := range []string{"", "br", "gzip;q=0"} {
+			rec := serve("/api/vehicles", accept)
+			assert.Empty(t, rec.Header().Get("Content-Encoding"), accept)
+			assert.Equal(t, large, rec.Body.String(), accept)
+		}
+	})
+
+	t.Run("SSE is never compressed", func(t *testing.T) {
+		var flushed bool
+		stream := Compress(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			flusher, ok := w.(http.Flusher)
+			assert.True(t, ok, "streams must stay flushable")
+			for i := 0; i < 50; i++ {
+				w.Write([]byte("event: tick\ndata: {\"auction_id\":1}\n\n"))
+				flusher.Flush()
+			}
+			flushed = true
+		}))
+
+		req := httptest.NewRequest("GET", "/api/auctions/1/stream", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		stream.ServeHTTP(rec, req)
+
+		assert.True(t, flushed)
+		assert.True(t, rec.Flushed)
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.True(t, strings.HasPrefix(rec.Body.String(), "event: tick\n"))
+	})
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPKey holds the effective client IP resolved by RealIP
+const ClientIPKey contextKey = "client_ip"
+
+// ParseTrustedProxies parses a list of CIDRs. Bare addresses are treated as a
+// single-host prefix.
+func ParseTrustedProxies(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// RealIP resolves the effective client IP. X-Forwarded-For and X-Real-IP are
+// only honored when the direct peer is a trusted proxy; otherwise anyone could
+// spoof their address by sending the header themselves. For X-Forwarded-For
+// the rightmost hop that isn't a trusted proxy wins, since entries to its left
+// were supplied by the client.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trusted)
+			ctx := context.WithValue(r.Context(), ClientIPKey, ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetClientIP returns the client IP set by RealIP
+func GetClientIP(ctx context.Context) string {
+	if ip, ok := ctx.Value(ClientIPKey).(string); ok {
+		return ip
+	}
+	return ""
+}
+
+// ClientIP returns the client IP resolved by RealIP, falling back to the
+// direct peer when RealIP isn't installed
+func ClientIP(r *http.Request) string {
+	if ip := GetClientIP(r.Context()); ip != "" {
+		return ip
+	}
+	return remoteHost(r.RemoteAddr)
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := remoteHost(r.RemoteAddr)
+	if !isTrustedProxy(peer, trusted) {
+		return peer
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			addr, err := netip.ParseAddr(hop)
+			if err != nil {
+				// Garbage in the chain; don't trust anything left of it
+				return peer
+			}
+			if !isTrustedProxy(addr.String(), trusted) {
+				return addr.Unmap().String()
+			}
+		}
+		return peer
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if addr, err := netip.ParseAddr(realIP); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+
+	return peer
+}
+
+func isTrustedProxy(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost strips the port from a RemoteAddr
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const RoleKey contextKey = "role"
+
+// RequireRole only lets through users whose role is one of roles.
+// It must run after ClerkAuth.Middleware so the user ID is in context.
+func RequireRole(db *pgxpool.Pool, logger *slog.Logger, roles ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := GetUserID(r.Context())
+			if userID == 0 {
+				AuditDenied(logger, r, "role", DeniedMissingCredentials)
+				roleError(w, "authentication required", http.StatusUnauthorized)
+				return
+			}
+
+			var role string
+			err := db.QueryRow(r.Context(), `SELECT role::text FROM users WHERE id = $1`, userID).Scan(&role)
+			if err != nil {
+				logger.Warn("role lookup failed",
+					slog.Int64("user_id", userID),
+					slog.String("error", err.Error()),
+					slog.String("request_id", GetRequestID(r.Context())),
+				)
+				AuditDenied(logger, r, "role", DeniedRoleLookupFailed)
+				roleError(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			if !allowed[role] {
+				AuditDenied(logger, r, "role:"+role, DeniedRoleNotPermitted)
+				roleError(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithRole(r.Context(), role)))
+		})
+	}
+}
+
+// WithRole adds the user's role to context (useful for testing)
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, RoleKey, role)
+}
+
+// GetRole extracts the role set by RequireRole
+func GetRole(ctx context.Context) string {
+	if role, ok := ctx.Value(RoleKey).(string); ok {
+		return role
+	}
+	return ""
+}
+
+func roleError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -26,7 +26,7 @@ func Tracing(next http.Handler) http.Handler {
 			semconv.HTTPMethod(r.Method),
 			semconv.HTTPURL(r.URL.String()),
 			semconv.HTTPRoute(r.URL.Path),
-			attribute.String("http.client_ip", r.RemoteAddr),
+			attribute.String("http.client_ip", ClientIP(r)),
 		)
 
 		// Add trace ID to context for logging
@@ -0,0 +1,95 @@
+// Package migrate applies the embedded up migrations in filename order and
+// records each one in schema_migrations, so rerunning only applies new files.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	migrations "github.com/ayubfarah/vehicle-auc/migrations-go"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockKey is the advisory lock held while migrating, so test packages or
+// instances starting together don't apply the same file twice
+const lockKey = 7_202_035
+
+// Files lists the embedded up migrations in the order they are applied
+func Files() ([]string, error) {
+	names, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Up applies every migration not yet recorded, each in its own transaction
+// together with its schema_migrations row, and returns the versions applied.
+func Up(ctx context.Context, db *pgxpool.Pool) ([]string, error) {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	files, err := Files()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]string, 0)
+	for _, name := range files {
+		version := strings.TrimSuffix(name, ".up.sql")
+
+		var done bool
+		err := conn.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&done)
+		if err != nil {
+			return applied, err
+		}
+		if done {
+			continue
+		}
+
+		sql, err := fs.ReadFile(migrations.FS, name)
+		if err != nil {
+			return applied, err
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return applied, err
+		}
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			tx.Rollback(ctx)
+			return applied, fmt.Errorf("apply %s: %w", name, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			tx.Rollback(ctx)
+			return applied, fmt.Errorf("record %s: %w", name, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return applied, fmt.Errorf("commit %s: %w", name, err)
+		}
+		applied = append(applied, version)
+	}
+	return applied, nil
+}
@@ -0,0 +1,23 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles_OrderedUpMigrationsOnly(t *testing.T) {
+	files, err := Files()
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	assert.Equal(t, "001_initial_schema.up.sql", files[0])
+	for i, name := range files {
+		assert.True(t, strings.HasSuffix(name, ".up.sql"), name)
+		if i > 0 {
+			assert.Less(t, files[i-1], name)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profileResponse = "\xef\xbb\xbf" + `{
+	"profile": {"paymentProfiles": [
+		{"customerPaymentProfileId": "pp_1", "payment": {"creditCard": {"cardNumber": "XXXX1111", "expirationDate": "2030-12", "cardType": "Visa"}}},
+		{"defaultPaymentProfile": true, "customerPaymentProfileId": "pp_2", "payment": {"creditCard": {"cardNumber": "XXXX4444", "expirationDate": "2029-01", "cardType": "MasterCard"}}}
+	]},
+	"messages": {"resultCode": "Ok", "message": [{"code": "I00001", "text": "Successful."}]}
+}`
+
+// fakeAuthorizeNet answers profile lookups with profileResponse,
+// transactions with transaction and transaction history lookups with
+// history, recording the transaction requests
+func fakeAuthorizeNet(t *testing.T, transaction string, history ...string) (*AuthorizeNet, *[]string) {
+	var charges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		switch {
+		case strings.Contains(string(body), "getCustomerProfileRequest"):
+			io.WriteString(w, profileResponse)
+		case strings.Contains(string(body), "createTransactionRequest"):
+			charges = append(charges, string(body))
+			io.WriteString(w, transaction)
+		case strings.Contains(string(body), "getTransactionListForCustomerRequest") && len(history) > 0:
+			io.WriteString(w, history[0])
+		default:
+			t.Fatalf("unexpected request %s", body)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return NewAuthorizeNet(server.URL, "login", "key"), &charges
+}
+
+func TestAuthorizeNet_ListPaymentMethods(t *testing.T) {
+	gateway, _ := fakeAuthorizeNet(t, "")
+
+	methods, err := gateway.ListPaymentMethods(context.Background(), "cp_1")
+	require.NoError(t, err)
+	assert.Equal(t, []PaymentMethod{
+		{ID: "pp_1", Brand: "visa", Last4: "1111", ExpMonth: 12, ExpYear: 2030},
+		{ID: "pp_2", Brand: "mastercard", Last4: "4444", ExpMonth: 1, ExpYear: 2029, IsDefault: true},
+	}, methods)
+}
+
+func TestAuthorizeNet_Capture(t *testing.T) {
+	t.Run("approved charges the default card", func(t *testing.T) {
+		gateway, charges := fakeAuthorizeNet(t, `{"transactionResponse": {"responseCode": "1", "transId": "60001"}, "messages": {"resultCode": "Ok"}}`)
+
+		transactionID, err := gateway.Capture(context.Background(), "cp_1", decimal.RequireFromString("150.5"), 42)
+		require.NoError(t, err)
+		assert.Equal(t, "60001", transactionID)
+
+		require.Len(t, *charges, 1)
+		var req struct {
+			CreateTransactionRequest struct {
+				MerchantAuthentication merchantAuthentication `json:"merchantAuthentication"`
+				TransactionRequest     struct {
+					TransactionType string `json:"transactionType"`
+					Amount          string `json:"amount"`
+					Profile         struct {
+						CustomerProfileID string `json:"customerProfileId"`
+						PaymentProfile    struct {
+							PaymentProfileID string `json:"paymentProfileId"`
+						} `json:"paymentProfile"`
+					} `json:"profile"`
+					Order struct {
+						InvoiceNumber string `json:"invoiceNumber"`
+					} `json:"order"`
+				} `json:"transactionRequest"`
+			} `json:"createTransactionRequest"`
+		}
+		require.NoError(t, json.Unmarshal([]byte((*charges)[0]), &req))
+		tr := req.CreateTransactionRequest.TransactionRequest
+		assert.Equal(t, "login", req.CreateTransactionRequest.MerchantAuthentication.Name)
+		assert.Equal(t, "authCaptureTransaction", tr.TransactionType)
+		assert.Equal(t, "150.50", tr.Amount)
+		assert.Equal(t, "cp_1", tr.Profile.CustomerProfileID)
+		assert.Equal(t, "pp_2", tr.Profile.PaymentProfile.PaymentProfileID)
+		assert.Equal(t, "42", tr.Order.InvoiceNumber)
+	})
+
+	t.Run("declined wraps ErrDeclined", func(t *testing.T) {
+		gateway, _ := fakeAuthorizeNet(t, `{"transactionResponse": {"responseCode": "2", "errors": [{"errorCode": "2", "errorText": "This transaction has been declined."}]}, "messages": {"resultCode": "Error"}}`)
+
+		_, err := gateway.Capture(context.Background(), "cp_1", decimal.NewFromInt(100), 42)
+		require.ErrorIs(t, err, ErrDeclined)
+		assert.Contains(t, err.Error(), "This transaction has been declined.")
+	})
+
+	t.Run("held for review returns the transaction", func(t *testing.T) {
+		gateway, _ := fakeAuthorizeNet(t, `{"transactionResponse": {"responseCode": "4", "transId": "60002", "errors": [{"errorCode": "252", "errorText": "Your order has been received and is pending review."}]}, "messages": {"resultCode": "Ok"}}`)
+
+		transactionID, err := gateway.Capture(context.Background(), "cp_1", decimal.NewFromInt(100), 42)
+		require.ErrorIs(t, err, ErrHeldForReview)
+		assert.False(t, errors.Is(err, ErrDeclined))
+		assert.Equal(t, "60002", transactionID)
+	})
+
+	t.Run("processing errors are retryable", func(t *testing.T) {
+		gateway, _ := fakeAuthorizeNet(t, `{"transactionResponse": {"responseCode": "3", "errors": [{"errorCode": "11", "errorText": "A duplicate transaction has been submitted."}]}, "messages": {"resultCode": "Error"}}`)
+
+		_, err := gateway.Capture(context.Background(), "cp_1", decimal.NewFromInt(100), 42)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrDeclined))
+		assert.Contains(t, err.Error(), "A duplicate transaction has been submitted.")
+	})
+
+	t.Run("API errors are retryable", func(t *testing.T) {
+		gateway, _ := fakeAuthorizeNet(t, `{"messages": {"resultCode": "Error", "message": [{"code": "E00001", "text": "An error occurred during processing."}]}}`)
+
+		_, err := gateway.Capture(context.Background(), "cp_1", decimal.NewFromInt(100), 42)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrDeclined))
+	})
+}
+
+func TestAuthorizeNet_FindCapture(t *testing.T) {
+	const history = "\xef\xbb\xbf" + `{
+		"transactions": [
+			{"transId": "70004", "transactionStatus": "settledSuccessfully", "invoiceNumber": "41"},
+			{"transId": "70003", "transactionStatus": "FDSPendingReview", "invoiceNumber": "43"},
+			{"transId": "70002", "transactionStatus": "capturedPendingSettlement", "invoiceNumber": "42"},
+			{"transId": "70001", "transactionStatus": "declined", "invoiceNumber": "44"}
+		],
+		"messages": {"resultCode": "Ok", "message": [{"code": "I00001", "text": "Successful."}]}
+	}`
+	gateway, charges := fakeAuthorizeNet(t, "", history)
+	ctx := context.Background()
+
+	transactionID, err := gateway.FindCapture(ctx, "cp_1", 42)
+	require.NoError(t, err)
+	assert.Equal(t, "70002", transactionID)
+
+	transactionID, err = gateway.FindCapture(ctx, "cp_1", 43)
+	require.ErrorIs(t, err, ErrHeldForReview)
+	assert.Equal(t, "70003", transactionID)
+
+	// Declined charges and other orders' don't count
+	for _, orderID := range []int64{44, 45} {
+		transactionID, err = gateway.FindCapture(ctx, "cp_1", orderID)
+		require.NoError(t, err)
+		assert.Empty(t, transactionID)
+	}
+	assert.Empty(t, *charges, "a lookup never charges")
+}
@@ -0,0 +1,325 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+// errNoPaymentProfile is recorded on orders whose buyer has no profile on file
+var errNoPaymentProfile = errors.New("buyer has no payment profile on file")
+
+// Capturer charges buyers for their pending_payment orders off the request
+// and close paths. Orders handed to Enqueue are charged right away; a sweep
+// every interval picks up the rest, including ones whose last attempt hit a
+// transient gateway error and ones an enqueue missed. A declined charge, or
+// one still failing after maxAttempts, leaves the order payment_failed; one
+// held for fraud review leaves it payment_review.
+type Capturer struct {
+	db          *pgxpool.Pool
+	logger      *slog.Logger
+	gateway     Gateway
+	interval    time.Duration
+	timeout     time.Duration
+	maxAttempts int
+	batchSize   int
+	now         func() time.Time
+
+	queue chan int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Option configures the capturer
+type Option func(*Capturer)
+
+// WithInterval sets how often due orders are swept. A failed attempt waits
+// interval times its attempt number before the next one.
+func WithInterval(d time.Duration) Option {
+	return func(c *Capturer) {
+		c.interval = d
+	}
+}
+
+// WithTimeout bounds each gateway call
+func WithTimeout(d time.Duration) Option {
+	return func(c *Capturer) {
+		c.timeout = d
+	}
+}
+
+// WithMaxAttempts sets how many tries an order gets before it's failed
+func WithMaxAttempts(n int) Option {
+	return func(c *Capturer) {
+		if n > 0 {
+			c.maxAttempts = n
+		}
+	}
+}
+
+// WithClock sets the time source (tests)
+func WithClock(now func() time.Time) Option {
+	return func(c *Capturer) {
+		c.now = now
+	}
+}
+
+// NewCapturer creates a payment capturer charging through gateway
+func NewCapturer(db *pgxpool.Pool, logger *slog.Logger, gateway Gateway, opts ...Option) *Capturer {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Capturer{
+		db:          db,
+		logger:      logger,
+		gateway:     gateway,
+		interval:    time.Minute,
+		timeout:     20 * time.Second,
+		maxAttempts: 5,
+		batchSize:   50,
+		now:         time.Now,
+		queue:       make(chan int64, 1000),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Start begins charging enqueued orders and sweeping for due ones
+func (c *Capturer) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case orderID := <-c.queue:
+				if _, err := c.run(c.ctx, orderID); err != nil && c.ctx.Err() == nil {
+					c.logger.Error("payment_capture_failed_to_claim",
+						slog.Int64("order_id", orderID),
+						slog.String("error", err.Error()),
+					)
+				}
+			case <-ticker.C:
+				if _, err := c.RunOnce(c.ctx); err != nil && c.ctx.Err() == nil {
+					c.logger.Error("payment_capture_sweep_failed", slog.String("error", err.Error()))
+				}
+			}
+		}
+	}()
+
+	c.logger.Info("payment_capturer_started",
+		slog.Duration("interval", c.interval),
+		slog.Int("max_attempts", c.maxAttempts),
+	)
+}
+
+// Stop waits for an in-flight capture to finish. Orders still queued are
+// left pending_payment for the next sweep, on this instance or another.
+func (c *Capturer) Stop() {
+	c.cancel()
+	c.wg.Wait()
+	c.logger.Info("payment_capturer_stopped")
+}
+
+// Enqueue asks for an order to be charged soon. It never blocks: with the
+// queue full the order waits for the next sweep.
+func (c *Capturer) Enqueue(orderID int64) {
+	select {
+	case c.queue <- orderID:
+	default:
+		c.logger.Warn("payment_capture_queue_full", slog.Int64("order_id", orderID))
+	}
+}
+
+// RunOnce charges one batch of due orders and returns how many it tried
+func (c *Capturer) RunOnce(ctx context.Context) (int, error) {
+	return c.run(ctx, 0)
+}
+
+// run charges the due orders claim picks up
+func (c *Capturer) run(ctx context.Context, orderID int64) (int, error) {
+	due, err := c.claim(ctx, orderID)
+	if err != nil {
+		return 0, err
+	}
+	for _, o := range due {
+		c.charge(ctx, o)
+	}
+	return len(due), nil
+}
+
+// dueOrder is an order claimed for a capture attempt
+type dueOrder struct {
+	id        int64
+	auctionID int64
+	total     decimal.Decimal
+	attempt   int
+	profileID *string
+}
+
+// claim counts an attempt against due orders, all of them or just orderID,
+// and leases them past the gateway timeout so no other instance charges them
+// at the same time
+func (c *Capturer) claim(ctx context.Context, orderID int64) ([]dueOrder, error) {
+	now := c.now()
+	rows, err := c.db.Query(ctx, `
+		UPDATE orders o
+		SET payment_attempts = o.payment_attempts + 1, payment_retry_at = $2
+		FROM users u
+		WHERE u.id = o.buyer_id AND o.id IN (
+			SELECT id FROM orders
+			WHERE status = 'pending_payment' AND payment_attempts < $3
+			  AND (payment_retry_at IS NULL OR payment_retry_at <= $1)
+			  AND ($5::bigint = 0 OR id = $5)
+			ORDER BY id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING o.id, o.auction_id, o.total_price, o.payment_attempts, u.authorize_payment_profile_id
+	`, now, now.Add(2*c.timeout), c.maxAttempts, c.batchSize, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []dueOrder
+	for rows.Next() {
+		var o dueOrder
+		if err := rows.Scan(&o.id, &o.auctionID, &o.total, &o.attempt, &o.profileID); err != nil {
+			return nil, err
+		}
+		due = append(due, o)
+	}
+	return due, rows.Err()
+}
+
+// charge runs one capture attempt and records the outcome on the order: paid
+// with the transaction ID, payment_review with it while the gateway reviews
+// the charge, payment_failed with the reason, or still pending with a later
+// retry. A capture cut short by shutdown keeps its lease and is retried once
+// it runs out.
+func (c *Capturer) charge(ctx context.Context, o dueOrder) {
+	var transactionID string
+	var err error
+	if o.profileID == nil || *o.profileID == "" {
+		err = errNoPaymentProfile
+	} else {
+		callCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		start := time.Now()
+		transactionID, err = c.capture(callCtx, o)
+		cancel()
+		metrics.ExternalAPILatency.WithLabelValues("authorize_net", "capture").Observe(time.Since(start).Seconds())
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		metrics.ExternalAPICallsTotal.WithLabelValues("authorize_net", "capture", result).Inc()
+	}
+	if err != nil && ctx.Err() != nil {
+		c.logger.Warn("payment_capture_interrupted", slog.Int64("order_id", o.id))
+		return
+	}
+
+	if errors.Is(err, ErrHeldForReview) {
+		c.logger.Warn("payment_capture_held_for_review",
+			slog.Int64("order_id", o.id),
+			slog.Int64("auction_id", o.auctionID),
+			slog.String("transaction_id", transactionID),
+			slog.String("error", err.Error()),
+		)
+		c.record(ctx, o, "", `
+			UPDATE orders SET status = 'payment_review', payment_intent_id = $2, payment_error = $3,
+			       payment_retry_at = NULL, updated_at = NOW()
+			WHERE id = $1 AND status = 'pending_payment'
+		`, o.id, transactionID, err.Error())
+		return
+	}
+	if err == nil {
+		c.record(ctx, o, "payment_captured", `
+			UPDATE orders SET status = 'paid', paid_at = NOW(), payment_intent_id = $2,
+			       payment_error = NULL, payment_retry_at = NULL, updated_at = NOW()
+			WHERE id = $1 AND status = 'pending_payment'
+		`, o.id, transactionID)
+		return
+	}
+
+	permanent := errors.Is(err, ErrDeclined) || errors.Is(err, errNoPaymentProfile)
+	if permanent || o.attempt >= c.maxAttempts {
+		c.logger.Warn("payment_capture_failed",
+			slog.Int64("order_id", o.id),
+			slog.Int64("auction_id", o.auctionID),
+			slog.Int("attempt", o.attempt),
+			slog.String("error", err.Error()),
+		)
+		c.record(ctx, o, "", `
+			UPDATE orders SET status = 'payment_failed', payment_error = $2,
+			       payment_retry_at = NULL, updated_at = NOW()
+			WHERE id = $1 AND status = 'pending_payment'
+		`, o.id, err.Error())
+		return
+	}
+
+	retryAt := c.now().Add(c.interval * time.Duration(o.attempt))
+	c.logger.Warn("payment_capture_retrying",
+		slog.Int64("order_id", o.id),
+		slog.Int("attempt", o.attempt),
+		slog.Time("retry_at", retryAt),
+		slog.String("error", err.Error()),
+	)
+	c.record(ctx, o, "", `
+		UPDATE orders SET payment_error = $2, payment_retry_at = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 'pending_payment'
+	`, o.id, err.Error(), retryAt)
+}
+
+// capture charges an order's buyer. A retry first asks the gateway for a
+// charge an earlier attempt made: a timeout, cut connection or shutdown can
+// lose the reply to a capture that went through, and charging again would
+// bill the buyer twice.
+func (c *Capturer) capture(ctx context.Context, o dueOrder) (string, error) {
+	if o.attempt > 1 {
+		transactionID, err := c.gateway.FindCapture(ctx, *o.profileID, o.id)
+		if err != nil || transactionID != "" {
+			return transactionID, err
+		}
+	}
+	return c.gateway.Capture(ctx, *o.profileID, o.total, o.id)
+}
+
+// record writes a capture outcome, logging success under event when set
+func (c *Capturer) record(ctx context.Context, o dueOrder, event, query string, args ...any) {
+	// Recorded even during shutdown: a charge that went through must not be
+	// retried
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if _, err := c.db.Exec(ctx, query, args...); err != nil {
+		// A charge that went through needs a human to reconcile
+		c.logger.Error("payment_outcome_not_recorded",
+			slog.Int64("order_id", o.id),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if event != "" {
+		c.logger.Info(event,
+			slog.Int64("order_id", o.id),
+			slog.Int64("auction_id", o.auctionID),
+			slog.String("amount", o.total.StringFixed(2)),
+		)
+	}
+}
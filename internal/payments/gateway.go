@@ -0,0 +1,48 @@
+// Package payments talks to the payment gateway: it reads the cards stored on
+// a buyer's customer profile and charges orders against it in the background.
+package payments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// Gateway reads and charges customer payment profiles
+type Gateway interface {
+	// ListPaymentMethods returns already-masked methods for the gateway profile
+	ListPaymentMethods(ctx context.Context, profileID string) ([]PaymentMethod, error)
+
+	// Capture authorizes and captures amount against the profile's default
+	// payment method, returning the gateway's transaction ID. orderID is
+	// passed as the invoice reference so a retried charge can be matched up.
+	// A charge the gateway refused wraps ErrDeclined, and one it took but
+	// held for review wraps ErrHeldForReview alongside its transaction ID;
+	// any other error is worth retrying.
+	Capture(ctx context.Context, profileID string, amount decimal.Decimal, orderID int64) (string, error)
+
+	// FindCapture looks for a charge already made against the profile for
+	// orderID and returns its transaction ID, or "" when there is none. A
+	// held charge wraps ErrHeldForReview like Capture.
+	FindCapture(ctx context.Context, profileID string, orderID int64) (string, error)
+}
+
+// PaymentMethod is a masked card on file. Never holds a full PAN or CVV.
+type PaymentMethod struct {
+	ID        string `json:"id"`
+	Brand     string `json:"brand"`
+	Last4     string `json:"last4"`
+	ExpMonth  int    `json:"exp_month"`
+	ExpYear   int    `json:"exp_year"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// ErrDeclined marks a capture the gateway refused, such as a declined card.
+// Retrying it won't help, so the order is failed straight away.
+var ErrDeclined = errors.New("payment declined")
+
+// ErrHeldForReview marks a capture the gateway authorized but held for fraud
+// review. It may still settle, so the order is neither paid nor failed nor
+// charged again; it waits with the transaction ID until the review is done.
+var ErrHeldForReview = errors.New("payment held for review")
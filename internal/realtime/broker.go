@@ -2,13 +2,25 @@ package realtime
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/ayubfarah/vehicle-auc/internal/domain"
 	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/google/uuid"
 )
 
+// ErrTooManyConnections is returned by Subscribe and SubscribeUser when the
+// user already has the maximum number of open streams
+var ErrTooManyConnections = errors.New("too many open streams for this user")
+
+// ErrTooManyConnectionsFromIP is returned by Subscribe and SubscribeUser when
+// the subscriber's client IP already has the maximum number of open streams
+var ErrTooManyConnectionsFromIP = errors.New("too many open streams from this address")
+
 // Broker manages SSE connections and broadcasts events
 type Broker struct {
 	logger *slog.Logger
@@ -17,28 +29,139 @@ type Broker struct {
 	subscribers map[int64]map[*Subscriber]struct{}
 	mu          sync.RWMutex
 	
+	// Per-user notification stream subscribers
+	userSubscribers map[int64]map[*Subscriber]struct{}
+	
+	// Open streams of either kind per signed-in user, capped at
+	// maxConnsPerUser (0 disables the cap)
+	userConns       map[int64]int
+	maxConnsPerUser int
+	
+	// Open streams per client IP, signed in or not, capped at maxConnsPerIP
+	// (0 disables the cap)
+	ipConns       map[string]int
+	maxConnsPerIP int
+	
+	// Site-wide stream subscribers and the per-auction updates waiting for
+	// the next globalInterval flush
+	globalSubscribers map[*Subscriber]struct{}
+	globalInterval    time.Duration
+	globalMu          sync.Mutex
+	globalPending     map[int64]*AuctionUpdate
+	globalOrder       []int64
+	globalFilter      func(auctionID int64) bool
+	
 	// Event channel for broadcasting
 	events chan domain.BidEvent
 	
+	// Flush requests; the broadcast loop drains events then closes the ack
+	flushes chan chan struct{}
+	
+	// Auctions whose subscriber count changed since the last viewer_count
+	// event; flushed every viewerCountInterval (0 disables)
+	viewerCountInterval time.Duration
+	viewersDirty        map[int64]struct{}
+	
+	// Reconnect delay sent to open streams in the shutdown event
+	shutdownRetry time.Duration
+	
+	// Optional cross-instance relay: local broadcasts and notifications are
+	// queued on relayOut for it, and other instances' are delivered here
+	relay      Relay
+	instanceID string
+	relayOut   chan []byte
+	relayReady chan struct{}
+	
+	// Optional observer of every event fanned out, e.g. to expire caches
+	eventHook func(domain.BidEvent)
+	
 	// Lifecycle
-	done chan struct{}
+	done     chan struct{}
+	stopping bool // Set by Stop; late subscribers are dismissed right away
 }
 
 // Subscriber represents an SSE client connection
 type Subscriber struct {
 	ID       string
 	UserID   int64
+	IP       string // Client IP for the per-IP cap; empty isn't counted
 	Messages chan []byte
 	Done     chan struct{}
 }
 
+// BrokerOption configures the broker
+type BrokerOption func(*Broker)
+
+// WithViewerCountInterval enables periodic viewer_count events. Counts are
+// emitted at most once per interval per auction, and only when they changed.
+func WithViewerCountInterval(d time.Duration) BrokerOption {
+	return func(b *Broker) {
+		b.viewerCountInterval = d
+	}
+}
+
+// WithShutdownRetry sets the reconnect delay (the SSE retry: field) sent to
+// open streams when the broker stops. Zero leaves the client's default.
+func WithShutdownRetry(d time.Duration) BrokerOption {
+	return func(b *Broker) {
+		b.shutdownRetry = d
+	}
+}
+
+// WithMaxConnsPerUser caps how many streams, auction and notification
+// combined, one signed-in user can hold open. Anonymous viewers aren't
+// counted. Zero disables the cap.
+func WithMaxConnsPerUser(n int) BrokerOption {
+	return func(b *Broker) {
+		b.maxConnsPerUser = n
+	}
+}
+
+// WithMaxConnsPerIP caps how many streams of either kind one client IP can
+// hold open, anonymous or not. Zero disables the cap.
+func WithMaxConnsPerIP(n int) BrokerOption {
+	return func(b *Broker) {
+		b.maxConnsPerIP = n
+	}
+}
+
+// WithEventHook calls fn with every auction event this instance fans out,
+// including ones relayed from other instances, before subscribers get it. fn
+// runs on the broadcast loop and must not block. Hooks given more than once
+// all run, in order.
+func WithEventHook(fn func(domain.BidEvent)) BrokerOption {
+	return func(b *Broker) {
+		if prev := b.eventHook; prev != nil {
+			b.eventHook = func(event domain.BidEvent) {
+				prev(event)
+				fn(event)
+			}
+			return
+		}
+		b.eventHook = fn
+	}
+}
+
 // NewBroker creates a new SSE broker
-func NewBroker(logger *slog.Logger) *Broker {
+func NewBroker(logger *slog.Logger, opts ...BrokerOption) *Broker {
 	b := &Broker{
-		logger:      logger,
-		subscribers: make(map[int64]map[*Subscriber]struct{}),
-		events:      make(chan domain.BidEvent, 1000),
-		done:        make(chan struct{}),
+		logger:            logger,
+		subscribers:       make(map[int64]map[*Subscriber]struct{}),
+		userSubscribers:   make(map[int64]map[*Subscriber]struct{}),
+		userConns:         make(map[int64]int),
+		ipConns:           make(map[string]int),
+		globalSubscribers: make(map[*Subscriber]struct{}),
+		globalPending:     make(map[int64]*AuctionUpdate),
+		events:            make(chan domain.BidEvent, 1000),
+		flushes:           make(chan chan struct{}),
+		viewersDirty:      make(map[int64]struct{}),
+		instanceID:        uuid.New().String(),
+		relayOut:          make(chan []byte, 1000),
+		relayReady:        make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
 	}
 	return b
 }
@@ -46,31 +169,141 @@ func NewBroker(logger *slog.Logger) *Broker {
 // Start begins the broadcast loop
 func (b *Broker) Start() {
 	go b.broadcastLoop()
-	b.logger.Info("sse_broker_started")
+	if b.viewerCountInterval > 0 {
+		go b.viewerCountLoop()
+	}
+	if b.globalInterval > 0 {
+		go b.globalLoop()
+	}
+	if b.relay != nil {
+		b.startRelay()
+	}
+	b.logger.Info("sse_broker_started", slog.Bool("relay", b.relay != nil))
 }
 
-// Stop gracefully shuts down the broker
+// Stop gracefully shuts down the broker. Every open stream is sent a
+// server_shutting_down event with a retry: delay and its Done channel is
+// closed, so handlers hang up and clients reconnect to another instance
+// instead of seeing a dropped connection. Call it before shutting down the
+// HTTP server, which otherwise waits on the streams until its timeout.
 func (b *Broker) Stop() {
+	message := shutdownMessage(b.shutdownRetry)
+	
+	b.mu.Lock()
+	b.stopping = true
+	dismissed := 0
+	for _, set := range []map[int64]map[*Subscriber]struct{}{b.subscribers, b.userSubscribers} {
+		for _, subs := range set {
+			for sub := range subs {
+				dismiss(sub, message)
+				dismissed++
+			}
+		}
+	}
+	for sub := range b.globalSubscribers {
+		dismiss(sub, message)
+		dismissed++
+	}
+	b.mu.Unlock()
+	
 	close(b.done)
-	b.logger.Info("sse_broker_stopped")
+	b.logger.Info("sse_broker_stopped", slog.Int("streams_dismissed", dismissed))
+}
+
+// shutdownMessage is the last frame an open stream gets
+func shutdownMessage(retry time.Duration) []byte {
+	data, _ := json.Marshal(map[string]int64{"retry_ms": retry.Milliseconds()})
+	message := formatSSE("server_shutting_down", data)
+	if retry > 0 {
+		message = append([]byte("retry: "+strconv.FormatInt(retry.Milliseconds(), 10)+"\n"), message...)
+	}
+	return message
 }
 
-// Subscribe adds a subscriber for an auction
-func (b *Broker) Subscribe(auctionID int64, sub *Subscriber) {
+// dismiss hands a subscriber the shutdown message and closes its Done
+// channel. A full buffer loses its oldest message so the shutdown event
+// still gets through.
+func dismiss(sub *Subscriber, message []byte) {
+	select {
+	case sub.Messages <- message:
+	default:
+		select {
+		case <-sub.Messages:
+		default:
+		}
+		select {
+		case sub.Messages <- message:
+		default:
+		}
+	}
+	if sub.Done != nil {
+		close(sub.Done)
+	}
+}
+
+// admit counts a new stream against its user's and IP's caps, or neither if
+// either is full. Call with mu held.
+func (b *Broker) admit(sub *Subscriber) error {
+	if sub.UserID != 0 && b.maxConnsPerUser > 0 && b.userConns[sub.UserID] >= b.maxConnsPerUser {
+		return ErrTooManyConnections
+	}
+	if sub.IP != "" && b.maxConnsPerIP > 0 && b.ipConns[sub.IP] >= b.maxConnsPerIP {
+		return ErrTooManyConnectionsFromIP
+	}
+	if sub.UserID != 0 {
+		b.userConns[sub.UserID]++
+	}
+	if sub.IP != "" {
+		b.ipConns[sub.IP]++
+	}
+	return nil
+}
+
+// release gives a closed stream back to its user's and IP's caps. Call with
+// mu held.
+func (b *Broker) release(sub *Subscriber) {
+	if sub.UserID != 0 {
+		if b.userConns[sub.UserID] <= 1 {
+			delete(b.userConns, sub.UserID)
+		} else {
+			b.userConns[sub.UserID]--
+		}
+	}
+	if sub.IP != "" {
+		if b.ipConns[sub.IP] <= 1 {
+			delete(b.ipConns, sub.IP)
+		} else {
+			b.ipConns[sub.IP]--
+		}
+	}
+}
+
+// Subscribe adds a subscriber for an auction. It returns
+// ErrTooManyConnections or ErrTooManyConnectionsFromIP, without subscribing,
+// when the subscriber's user or IP is at its cap.
+func (b *Broker) Subscribe(auctionID int64, sub *Subscriber) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	
+	if err := b.admit(sub); err != nil {
+		return err
+	}
 	if b.subscribers[auctionID] == nil {
 		b.subscribers[auctionID] = make(map[*Subscriber]struct{})
 	}
 	b.subscribers[auctionID][sub] = struct{}{}
+	b.viewersDirty[auctionID] = struct{}{}
 	
 	metrics.SSEConnectionsActive.Inc()
+	if b.stopping {
+		dismiss(sub, shutdownMessage(b.shutdownRetry))
+	}
 	
 	b.logger.Debug("sse_subscriber_added",
 		slog.Int64("auction_id", auctionID),
 		slog.String("subscriber_id", sub.ID),
 	)
+	return nil
 }
 
 // Unsubscribe removes a subscriber
@@ -79,11 +312,15 @@ func (b *Broker) Unsubscribe(auctionID int64, sub *Subscriber) {
 	defer b.mu.Unlock()
 	
 	if subs, ok := b.subscribers[auctionID]; ok {
+		if _, subscribed := subs[sub]; subscribed {
+			b.release(sub)
+		}
 		delete(subs, sub)
 		if len(subs) == 0 {
 			delete(b.subscribers, auctionID)
 		}
 	}
+	b.viewersDirty[auctionID] = struct{}{}
 	
 	metrics.SSEConnectionsActive.Dec()
 	
@@ -93,8 +330,84 @@ func (b *Broker) Unsubscribe(auctionID int64, sub *Subscriber) {
 	)
 }
 
-// Broadcast sends an event to all subscribers of an auction
+// SubscribeUser adds a subscriber to its user's notification stream. Like
+// Subscribe it fails when the user or IP is at its cap.
+func (b *Broker) SubscribeUser(sub *Subscriber) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	if err := b.admit(sub); err != nil {
+		return err
+	}
+	if b.userSubscribers[sub.UserID] == nil {
+		b.userSubscribers[sub.UserID] = make(map[*Subscriber]struct{})
+	}
+	b.userSubscribers[sub.UserID][sub] = struct{}{}
+	
+	metrics.SSEConnectionsActive.Inc()
+	if b.stopping {
+		dismiss(sub, shutdownMessage(b.shutdownRetry))
+	}
+	return nil
+}
+
+// UnsubscribeUser removes a subscriber from its user's notification stream
+func (b *Broker) UnsubscribeUser(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	if subs, ok := b.userSubscribers[sub.UserID]; ok {
+		if _, subscribed := subs[sub]; subscribed {
+			b.release(sub)
+		}
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(b.userSubscribers, sub.UserID)
+		}
+	}
+	
+	metrics.SSEConnectionsActive.Dec()
+}
+
+// PublishNotification pushes a notification to every open stream of its owner,
+// on this instance and, with a relay, the others. Users without an open
+// stream still see it in the notifications list.
+func (b *Broker) PublishNotification(n domain.Notification) {
+	b.deliverNotification(n)
+	b.publishRelay(relayMessage{Notification: &n, UserID: n.UserID})
+}
+
+// deliverNotification pushes a notification to this instance's streams
+func (b *Broker) deliverNotification(n domain.Notification) {
+	data, err := json.Marshal(n)
+	if err != nil {
+		b.logger.Error("sse_notification_marshal_error",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	message := formatSSE("notification", data)
+	
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.userSubscribers[n.UserID] {
+		select {
+		case sub.Messages <- message:
+		default:
+			// Subscriber buffer full, skip
+		}
+	}
+}
+
+// Broadcast sends an event to all subscribers of an auction, on this instance
+// and, with a relay, the others
 func (b *Broker) Broadcast(event domain.BidEvent) {
+	b.enqueue(event)
+	b.publishRelay(relayMessage{Event: &event})
+}
+
+// enqueue hands an event to this instance's broadcast loop
+func (b *Broker) enqueue(event domain.BidEvent) {
 	select {
 	case b.events <- event:
 	default:
@@ -104,6 +417,23 @@ func (b *Broker) Broadcast(event domain.BidEvent) {
 	}
 }
 
+// Flush blocks until every event broadcast before the call has been fanned
+// out to subscribers, including global streams and pending viewer counts
+// without waiting for the coalescing window or the next viewer count tick. Broadcast itself stays asynchronous; Flush lets tests
+// assert on delivered messages without sleeping. Requires Start.
+func (b *Broker) Flush() {
+	ack := make(chan struct{})
+	select {
+	case b.flushes <- ack:
+	case <-b.done:
+		return
+	}
+	select {
+	case <-ack:
+	case <-b.done:
+	}
+}
+
 func (b *Broker) broadcastLoop() {
 	for {
 		select {
@@ -111,11 +441,74 @@ func (b *Broker) broadcastLoop() {
 			return
 		case event := <-b.events:
 			b.broadcastEvent(event)
+		case ack := <-b.flushes:
+			if b.viewerCountInterval > 0 {
+				b.emitViewerCounts()
+			}
+			b.drainEvents()
+			b.flushGlobal()
+			close(ack)
 		}
 	}
 }
 
+// drainEvents broadcasts everything already queued without waiting for more
+func (b *Broker) drainEvents() {
+	for {
+		select {
+		case event := <-b.events:
+			b.broadcastEvent(event)
+		default:
+			return
+		}
+	}
+}
+
+func (b *Broker) viewerCountLoop() {
+	ticker := time.NewTicker(b.viewerCountInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			b.emitViewerCounts()
+		}
+	}
+}
+
+// emitViewerCounts broadcasts the current subscriber count for every auction
+// whose audience changed since the last tick
+func (b *Broker) emitViewerCounts() {
+	b.mu.Lock()
+	counts := make(map[int64]int, len(b.viewersDirty))
+	for auctionID := range b.viewersDirty {
+		if n := len(b.subscribers[auctionID]); n > 0 {
+			counts[auctionID] = n
+		}
+	}
+	b.viewersDirty = make(map[int64]struct{})
+	b.mu.Unlock()
+	
+	now := time.Now()
+	for auctionID, n := range counts {
+		// Counts are per instance, so they aren't relayed
+		b.enqueue(domain.BidEvent{
+			Type:      "viewer_count",
+			AuctionID: auctionID,
+			Viewers:   n,
+			Timestamp: now,
+		})
+	}
+}
+
 func (b *Broker) broadcastEvent(event domain.BidEvent) {
+	if b.eventHook != nil {
+		b.eventHook(event)
+	}
+	b.recordGlobal(event)
+	
 	b.mu.RLock()
 	subs := b.subscribers[event.AuctionID]
 	count := len(subs)
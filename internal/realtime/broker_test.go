@@ -1,8 +1,11 @@
 package realtime
 
 import (
+	"context"
 	"log/slog"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBroker_StartStop(t *testing.T) {
@@ -90,14 +94,11 @@ func TestBroker_Broadcast(t *testing.T) {
 	}
 
 	broker.Broadcast(event)
+	broker.Flush()
 
 	// Should receive event
-	select {
-	case received := <-sub.Messages:
-		assert.Contains(t, string(received), "bid_accepted")
-	case <-time.After(200 * time.Millisecond):
-		t.Fatal("did not receive event")
-	}
+	require.Len(t, sub.Messages, 1)
+	assert.Contains(t, string(<-sub.Messages), "bid_accepted")
 }
 
 func TestBroker_BroadcastToMultipleSubscribers(t *testing.T) {
@@ -126,15 +127,11 @@ func TestBroker_BroadcastToMultipleSubscribers(t *testing.T) {
 	}
 
 	broker.Broadcast(event)
+	broker.Flush()
 
 	// All should receive
 	for i, sub := range subs {
-		select {
-		case <-sub.Messages:
-			// good
-		case <-time.After(200 * time.Millisecond):
-			t.Fatalf("subscriber %d did not receive event", i)
-		}
+		assert.Len(t, sub.Messages, 1, "subscriber %d", i)
 	}
 }
 
@@ -170,22 +167,11 @@ func TestBroker_BroadcastOnlyToTargetAuction(t *testing.T) {
 	}
 
 	broker.Broadcast(event)
+	broker.Flush()
 
-	// 42 should receive
-	select {
-	case <-sub42.Messages:
-		// good
-	case <-time.After(200 * time.Millisecond):
-		t.Fatal("auction 42 did not receive")
-	}
-
-	// 99 should NOT receive
-	select {
-	case <-sub99.Messages:
-		t.Fatal("auction 99 should not receive")
-	case <-time.After(50 * time.Millisecond):
-		// good
-	}
+	// 42 should receive, 99 should not
+	assert.Len(t, sub42.Messages, 1)
+	assert.Empty(t, sub99.Messages)
 }
 
 func TestBroker_Stats(t *testing.T) {
@@ -244,19 +230,401 @@ func TestBroker_SlowSubscriber(t *testing.T) {
 		})
 	}
 
-	// Should not panic - verify broker is still responsive
-	time.Sleep(100 * time.Millisecond)
+	// Should not block - the buffer fills and the rest are dropped
+	broker.Flush()
+	assert.Len(t, sub.Messages, 5)
+}
+
+func TestBroker_FlushAfterStop(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	broker := NewBroker(logger)
+	broker.Start()
+	broker.Stop()
+
+	// Must not hang once the broadcast loop is gone
+	broker.Flush()
+}
 
-	// Drain some messages
-	count := 0
-	for {
+func TestBroker_EventHook(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	var seen []int64
+	broker := NewBroker(logger, WithEventHook(func(event domain.BidEvent) {
+		seen = append(seen, event.AuctionID)
+	}))
+	broker.Start()
+	defer broker.Stop()
+
+	// Called even for auctions nobody is watching
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7})
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 8})
+	broker.Flush()
+
+	assert.Equal(t, []int64{7, 8}, seen)
+}
+
+func TestBroker_ViewerCount(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	// The ticker never fires during the test; Flush emits the counts
+	broker := NewBroker(logger, WithViewerCountInterval(time.Hour))
+	broker.Start()
+	defer broker.Stop()
+
+	auctionID := int64(42)
+
+	subs := make([]*Subscriber, 3)
+	for i := 0; i < 3; i++ {
+		subs[i] = &Subscriber{
+			ID:       uuid.New().String(),
+			UserID:   int64(i + 1),
+			Messages: make(chan []byte, 10),
+			Done:     make(chan struct{}),
+		}
+		broker.Subscribe(auctionID, subs[i])
+	}
+	broker.Flush()
+
+	// All subscribers should see a single throttled count of 3
+	for i, sub := range subs {
+		require.Len(t, sub.Messages, 1, "subscriber %d", i)
+		received := <-sub.Messages
+		assert.Contains(t, string(received), "event: viewer_count")
+		assert.Contains(t, string(received), `"viewers":3`)
+	}
+
+	// Unchanged count should not be re-sent
+	broker.Flush()
+	assert.Empty(t, subs[0].Messages, "viewer_count re-sent without a change")
+
+	broker.Unsubscribe(auctionID, subs[2])
+	broker.Flush()
+
+	require.Len(t, subs[0].Messages, 1)
+	assert.Contains(t, string(<-subs[0].Messages), `"viewers":2`)
+}
+
+func TestBroker_PublishNotificationOnlyToOwner(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	broker := NewBroker(logger)
+
+	owner := &Subscriber{ID: uuid.New().String(), UserID: 7, Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	other := &Subscriber{ID: uuid.New().String(), UserID: 8, Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	broker.SubscribeUser(owner)
+	broker.SubscribeUser(other)
+
+	broker.PublishNotification(domain.Notification{ID: 1, UserID: 7, Type: "auction_won", Title: "You won the auction"})
+
+	select {
+	case msg := <-owner.Messages:
+		assert.Contains(t, string(msg), "event: notification")
+		assert.Contains(t, string(msg), `"type":"auction_won"`)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("owner did not receive notification")
+	}
+	assert.Empty(t, other.Messages)
+
+	// Unsubscribed streams receive nothing
+	broker.UnsubscribeUser(owner)
+	broker.PublishNotification(domain.Notification{ID: 2, UserID: 7, Type: "auction_lost"})
+	assert.Empty(t, owner.Messages)
+}
+
+func TestBroker_StopSendsShutdownEvent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	broker := NewBroker(logger, WithShutdownRetry(3*time.Second))
+	broker.Start()
+
+	auctionSub := &Subscriber{ID: "auction", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	userSub := &Subscriber{ID: "user", UserID: 7, Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	// A full buffer still gets the shutdown event, in place of its oldest message
+	fullSub := &Subscriber{ID: "full", Messages: make(chan []byte, 1), Done: make(chan struct{})}
+	fullSub.Messages <- []byte("stale")
+	broker.Subscribe(1, auctionSub)
+	broker.SubscribeUser(userSub)
+	broker.Subscribe(2, fullSub)
+
+	broker.Stop()
+
+	want := "retry: 3000\nevent: server_shutting_down\ndata: {\"retry_ms\":3000}\n\n"
+	for _, sub := range []*Subscriber{auctionSub, userSub, fullSub} {
 		select {
-		case <-sub.Messages:
-			count++
-		case <-time.After(50 * time.Millisecond):
-			goto done
+		case <-sub.Done:
+		default:
+			t.Fatalf("%s: Done not closed", sub.ID)
 		}
+		require.Len(t, sub.Messages, 1, sub.ID)
+		assert.Equal(t, want, string(<-sub.Messages), sub.ID)
+	}
+
+	// Streams opened while stopping are turned away immediately
+	late := &Subscriber{ID: "late", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	broker.Subscribe(1, late)
+	<-late.Done
+	assert.Equal(t, want, string(<-late.Messages))
+}
+
+func TestBroker_MaxConnsPerUser(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	broker := NewBroker(logger, WithMaxConnsPerUser(2))
+	broker.Start()
+	defer broker.Stop()
+
+	newSub := func(userID int64) *Subscriber {
+		return &Subscriber{ID: uuid.New().String(), UserID: userID, Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	}
+
+	// Auction and notification streams share the user's allowance
+	first, second := newSub(1), newSub(1)
+	require.NoError(t, broker.Subscribe(42, first))
+	require.NoError(t, broker.SubscribeUser(second))
+	assert.ErrorIs(t, broker.Subscribe(43, newSub(1)), ErrTooManyConnections)
+	assert.ErrorIs(t, broker.SubscribeUser(newSub(1)), ErrTooManyConnections)
+
+	// Other users and anonymous viewers are unaffected
+	require.NoError(t, broker.Subscribe(42, newSub(2)))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, broker.Subscribe(42, newSub(0)))
+	}
+
+	// Closing a stream frees a slot; a rejected one never took one
+	broker.Unsubscribe(42, first)
+	require.NoError(t, broker.Subscribe(43, newSub(1)))
+	assert.ErrorIs(t, broker.Subscribe(43, newSub(1)), ErrTooManyConnections)
+
+	broker.mu.RLock()
+	assert.Equal(t, 2, broker.userConns[1])
+	broker.mu.RUnlock()
+}
+
+func TestBroker_MaxConnsPerIP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	broker := NewBroker(logger, WithMaxConnsPerUser(5), WithMaxConnsPerIP(3))
+	broker.Start()
+	defer broker.Stop()
+
+	newSub := func(userID int64, ip string) *Subscriber {
+		return &Subscriber{ID: uuid.New().String(), UserID: userID, IP: ip, Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	}
+
+	// Anonymous and signed-in streams from one address share its allowance
+	first := newSub(0, "203.0.113.7")
+	require.NoError(t, broker.Subscribe(42, first))
+	require.NoError(t, broker.Subscribe(43, newSub(0, "203.0.113.7")))
+	require.NoError(t, broker.SubscribeUser(newSub(1, "203.0.113.7")))
+	assert.ErrorIs(t, broker.Subscribe(42, newSub(0, "203.0.113.7")), ErrTooManyConnectionsFromIP)
+	assert.ErrorIs(t, broker.SubscribeUser(newSub(2, "203.0.113.7")), ErrTooManyConnectionsFromIP)
+
+	// Other addresses are unaffected
+	require.NoError(t, broker.Subscribe(42, newSub(0, "198.51.100.1")))
+
+	// A rejected stream takes no slot from its user either
+	broker.mu.RLock()
+	assert.Equal(t, 1, broker.userConns[1])
+	assert.Zero(t, broker.userConns[2])
+	broker.mu.RUnlock()
+
+	// Closing a stream frees the address's slot
+	broker.Unsubscribe(42, first)
+	require.NoError(t, broker.Subscribe(42, newSub(0, "203.0.113.7")))
+	assert.ErrorIs(t, broker.Subscribe(42, newSub(0, "203.0.113.7")), ErrTooManyConnectionsFromIP)
+
+	broker.mu.RLock()
+	assert.Equal(t, 3, broker.ipConns["203.0.113.7"])
+	broker.mu.RUnlock()
+}
+
+func TestBroker_GlobalStream(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	// A window long enough that only Flush sends anything
+	broker := NewBroker(logger, WithGlobalInterval(time.Hour))
+	broker.Start()
+	defer broker.Stop()
+
+	sub := &Subscriber{ID: "global", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	require.NoError(t, broker.SubscribeGlobal(sub))
+
+	endsAt := time.Now().Add(2 * time.Minute).UTC().Truncate(time.Second)
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(100), BidCount: 1, EndsAt: endsAt})
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 9, Amount: decimal.NewFromInt(50), BidCount: 1, EndsAt: endsAt})
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(120), BidCount: 2, EndsAt: endsAt})
+	broker.Broadcast(domain.BidEvent{Type: "auction_extended", AuctionID: 7, EndsAt: endsAt.Add(time.Minute)})
+	broker.Broadcast(domain.BidEvent{Type: "viewer_count", AuctionID: 7, Viewers: 3})
+	broker.Flush()
+
+	// One coalesced update per auction, in the order they first changed
+	require.Len(t, sub.Messages, 2)
+	assert.Equal(t,
+		`event: auction_update`+"\n"+`data: {"auction_id":7,"event":"extended","current_bid":"120","bid_count":2,"ends_at":"`+endsAt.Add(time.Minute).Format(time.RFC3339)+`","timestamp":"0001-01-01T00:00:00Z"}`+"\n\n",
+		string(<-sub.Messages))
+	assert.Contains(t, string(<-sub.Messages), `"auction_id":9,"event":"bid","current_bid":"50"`)
+
+	// A close sticks for the rest of the window
+	broker.Broadcast(domain.BidEvent{Type: "auction_ended", AuctionID: 9})
+	broker.Broadcast(domain.BidEvent{Type: "auction_extended", AuctionID: 9, EndsAt: endsAt})
+	broker.Flush()
+	require.Len(t, sub.Messages, 1)
+	assert.Contains(t, string(<-sub.Messages), `"auction_id":9,"event":"ended"`)
+
+	broker.UnsubscribeGlobal(sub)
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(130)})
+	broker.Flush()
+	assert.Empty(t, sub.Messages)
+}
+
+func TestBroker_GlobalStream_NoCoalescing(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	broker := NewBroker(logger)
+	broker.Start()
+	defer broker.Stop()
+
+	sub := &Subscriber{ID: "global", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	require.NoError(t, broker.SubscribeGlobal(sub))
+
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(100)})
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(120)})
+	broker.Flush()
+
+	require.Len(t, sub.Messages, 2)
+	assert.Contains(t, string(<-sub.Messages), `"current_bid":"100"`)
+	assert.Contains(t, string(<-sub.Messages), `"current_bid":"120"`)
+}
+
+func TestBroker_GlobalStream_Filter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	var asked []int64
+	broker := NewBroker(logger, WithGlobalInterval(time.Hour), WithGlobalFilter(func(auctionID int64) bool {
+		asked = append(asked, auctionID)
+		return auctionID != 9
+	}))
+	broker.Start()
+	defer broker.Stop()
+
+	sub := &Subscriber{ID: "global", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	require.NoError(t, broker.SubscribeGlobal(sub))
+	auctionSub := &Subscriber{ID: "auction", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	require.NoError(t, broker.Subscribe(9, auctionSub))
+
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(100)})
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 9, Amount: decimal.NewFromInt(50)})
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 9, Amount: decimal.NewFromInt(60)})
+	broker.Flush()
+
+	// The filtered auction stays off the global stream but its own stream
+	// still gets every event
+	require.Len(t, sub.Messages, 1)
+	assert.Contains(t, string(<-sub.Messages), `"auction_id":7`)
+	assert.Len(t, auctionSub.Messages, 2)
+	assert.Equal(t, []int64{7, 9}, asked, "asked once per auction per window")
+}
+
+func TestBroker_GlobalStream_FilterSkippedWithoutSubscribers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	var asked atomic.Int32
+	broker := NewBroker(logger, WithGlobalInterval(time.Hour), WithGlobalFilter(func(auctionID int64) bool {
+		asked.Add(1)
+		return true
+	}))
+	broker.Start()
+	defer broker.Stop()
+
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(100)})
+	broker.Flush()
+
+	assert.Zero(t, asked.Load())
+}
+
+func TestBroker_GlobalStream_SlowFilterDoesNotHoldUpAuctionStreams(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	release := make(chan struct{})
+	var asked atomic.Int32
+	broker := NewBroker(logger, WithGlobalInterval(10*time.Millisecond), WithGlobalFilter(func(auctionID int64) bool {
+		asked.Add(1)
+		<-release
+		return true
+	}))
+	broker.Start()
+	defer broker.Stop()
+	defer close(release)
+
+	sub := &Subscriber{ID: "global", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	require.NoError(t, broker.SubscribeGlobal(sub))
+	auctionSub := &Subscriber{ID: "auction", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	require.NoError(t, broker.Subscribe(7, auctionSub))
+
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(100)})
+	require.Eventually(t, func() bool { return asked.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The lookup is stuck, but the auction's own stream keeps flowing
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 7, Amount: decimal.NewFromInt(120)})
+	require.Eventually(t, func() bool { return len(auctionSub.Messages) == 2 }, time.Second, time.Millisecond)
+}
+
+// memoryRelay is an in-process Relay that brokers in one test share, standing
+// in for Postgres LISTEN/NOTIFY
+type memoryRelay struct {
+	mu       sync.Mutex
+	handlers []func([]byte)
+}
+
+func (m *memoryRelay) Publish(ctx context.Context, payload []byte) error {
+	m.mu.Lock()
+	handlers := append([]func([]byte){}, m.handlers...)
+	m.mu.Unlock()
+	for _, handle := range handlers {
+		handle(payload)
+	}
+	return nil
+}
+
+func (m *memoryRelay) Listen(ctx context.Context, ready func(), handle func([]byte)) error {
+	m.mu.Lock()
+	m.handlers = append(m.handlers, handle)
+	m.mu.Unlock()
+	ready()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBroker_RelayAcrossInstances(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	relay := &memoryRelay{}
+	instanceA := NewBroker(logger, WithRelay(relay))
+	instanceB := NewBroker(logger, WithRelay(relay))
+	for _, b := range []*Broker{instanceA, instanceB} {
+		b.Start()
+		defer b.Stop()
+		select {
+		case <-b.RelayReady():
+		case <-time.After(time.Second):
+			t.Fatal("relay never subscribed")
+		}
+	}
+
+	onA := &Subscriber{ID: uuid.New().String(), Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	onB := &Subscriber{ID: uuid.New().String(), Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	userOnB := &Subscriber{ID: uuid.New().String(), UserID: 7, Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	require.NoError(t, instanceA.Subscribe(42, onA))
+	require.NoError(t, instanceB.Subscribe(42, onB))
+	require.NoError(t, instanceB.SubscribeUser(userOnB))
+
+	// A bid processed on A reaches a stream held open on B
+	instanceA.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 42, Amount: decimal.NewFromInt(150)})
+	select {
+	case msg := <-onB.Messages:
+		assert.Contains(t, string(msg), "event: bid_accepted")
+		assert.Contains(t, string(msg), `"amount":"150"`)
+	case <-time.After(time.Second):
+		t.Fatal("instance B did not receive the relayed event")
+	}
+
+	// A delivers its own event once, ignoring the relay's echo
+	instanceA.Flush()
+	assert.Len(t, onA.Messages, 1)
+
+	instanceA.PublishNotification(domain.Notification{ID: 1, UserID: 7, Type: "auction_won", Title: "You won the auction"})
+	select {
+	case msg := <-userOnB.Messages:
+		assert.Contains(t, string(msg), `"type":"auction_won"`)
+	case <-time.After(time.Second):
+		t.Fatal("instance B did not receive the relayed notification")
 	}
-done:
-	assert.True(t, count > 0)
 }
@@ -0,0 +1,198 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/shopspring/decimal"
+)
+
+// globalEventKinds maps the auction events mirrored to the global stream to
+// the kind reported in AuctionUpdate.Event
+var globalEventKinds = map[string]string{
+	"bid_accepted":      "bid",
+	"auction_extended":  "extended",
+	"auction_ended":     "ended",
+	"auction_cancelled": "cancelled",
+}
+
+// AuctionUpdate is the compact auction_update event sent on the global
+// stream. Events for the same auction within one coalescing window are
+// merged, so fields carry the latest known values.
+type AuctionUpdate struct {
+	AuctionID  int64            `json:"auction_id"`
+	Event      string           `json:"event"` // "bid", "extended", "ended" or "cancelled"
+	CurrentBid *decimal.Decimal `json:"current_bid,omitempty"`
+	BidCount   int              `json:"bid_count,omitempty"`
+	EndsAt     *time.Time       `json:"ends_at,omitempty"`
+	Timestamp  time.Time        `json:"timestamp"`
+}
+
+// merge folds an auction event into the pending update. A close sticks for
+// the rest of the window so a late extension can't hide it.
+func (u *AuctionUpdate) merge(event domain.BidEvent, kind string) {
+	u.AuctionID = event.AuctionID
+	if u.Event != "ended" && u.Event != "cancelled" {
+		u.Event = kind
+	}
+	if !event.Amount.IsZero() {
+		amount := event.Amount
+		u.CurrentBid = &amount
+	}
+	if event.BidCount > 0 {
+		u.BidCount = event.BidCount
+	}
+	if !event.EndsAt.IsZero() {
+		endsAt := event.EndsAt
+		u.EndsAt = &endsAt
+	}
+	u.Timestamp = event.Timestamp
+}
+
+// WithGlobalInterval sets how often coalesced auction_update events are sent
+// to global streams. Zero sends every event as it happens.
+func WithGlobalInterval(d time.Duration) BrokerOption {
+	return func(b *Broker) {
+		b.globalInterval = d
+	}
+}
+
+// WithGlobalFilter restricts the global stream to auctions fn reports as
+// public, so private and hidden auctions don't show up site-wide. fn is asked
+// once per auction per coalescing window as the window is sent, with no lock
+// held and only while the global stream has subscribers. It should fail
+// closed. With no coalescing window it runs on the broadcast loop, so a slow
+// fn delays every stream.
+func WithGlobalFilter(fn func(auctionID int64) bool) BrokerOption {
+	return func(b *Broker) {
+		b.globalFilter = fn
+	}
+}
+
+// SubscribeGlobal adds a subscriber to the site-wide stream of bids,
+// extensions and closes. It counts against the same caps as Subscribe.
+func (b *Broker) SubscribeGlobal(sub *Subscriber) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.admit(sub); err != nil {
+		return err
+	}
+	b.globalSubscribers[sub] = struct{}{}
+
+	metrics.SSEConnectionsActive.Inc()
+	if b.stopping {
+		dismiss(sub, shutdownMessage(b.shutdownRetry))
+	}
+	return nil
+}
+
+// UnsubscribeGlobal removes a subscriber from the global stream
+func (b *Broker) UnsubscribeGlobal(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, subscribed := b.globalSubscribers[sub]; subscribed {
+		b.release(sub)
+		delete(b.globalSubscribers, sub)
+	}
+
+	metrics.SSEConnectionsActive.Dec()
+}
+
+// hasGlobalSubscribers reports whether anyone is on the global stream
+func (b *Broker) hasGlobalSubscribers() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.globalSubscribers) > 0
+}
+
+// recordGlobal queues an auction event for the global stream, merging it
+// with anything already pending for that auction
+func (b *Broker) recordGlobal(event domain.BidEvent) {
+	kind, ok := globalEventKinds[event.Type]
+	if !ok || !b.hasGlobalSubscribers() {
+		return
+	}
+
+	b.globalMu.Lock()
+	update, pending := b.globalPending[event.AuctionID]
+	if !pending {
+		update = &AuctionUpdate{}
+		b.globalPending[event.AuctionID] = update
+		b.globalOrder = append(b.globalOrder, event.AuctionID)
+	}
+	update.merge(event, kind)
+	b.globalMu.Unlock()
+
+	if b.globalInterval <= 0 {
+		b.flushGlobal()
+	}
+}
+
+func (b *Broker) globalLoop() {
+	ticker := time.NewTicker(b.globalInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			b.flushGlobal()
+		}
+	}
+}
+
+// flushGlobal sends pending updates to global subscribers, oldest auction
+// first
+func (b *Broker) flushGlobal() {
+	b.globalMu.Lock()
+	updates := make([]*AuctionUpdate, 0, len(b.globalOrder))
+	for _, auctionID := range b.globalOrder {
+		updates = append(updates, b.globalPending[auctionID])
+	}
+	b.globalPending = make(map[int64]*AuctionUpdate)
+	b.globalOrder = nil
+	b.globalMu.Unlock()
+
+	if len(updates) == 0 || !b.hasGlobalSubscribers() {
+		return
+	}
+	if b.globalFilter != nil {
+		public := updates[:0]
+		for _, update := range updates {
+			if b.globalFilter(update.AuctionID) {
+				public = append(public, update)
+			}
+		}
+		updates = public
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.globalSubscribers) == 0 {
+		return
+	}
+	for _, update := range updates {
+		data, err := json.Marshal(update)
+		if err != nil {
+			b.logger.Error("sse_event_marshal_error",
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		message := formatSSE("auction_update", data)
+		for sub := range b.globalSubscribers {
+			select {
+			case sub.Messages <- message:
+			default:
+				// Subscriber buffer full, skip
+			}
+		}
+		metrics.SSEMessagesSent.WithLabelValues("auction_update").Inc()
+	}
+}
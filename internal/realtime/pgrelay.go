@@ -0,0 +1,53 @@
+package realtime
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PGRelay is a Relay over Postgres LISTEN/NOTIFY on one channel. Payloads are
+// limited to Postgres's 8000 byte NOTIFY maximum, which bid events and
+// notifications stay well under.
+type PGRelay struct {
+	db      *pgxpool.Pool
+	channel string
+}
+
+// NewPGRelay creates a relay that notifies and listens on channel
+func NewPGRelay(db *pgxpool.Pool, channel string) *PGRelay {
+	return &PGRelay{db: db, channel: channel}
+}
+
+// Publish sends payload with pg_notify
+func (r *PGRelay) Publish(ctx context.Context, payload []byte) error {
+	_, err := r.db.Exec(ctx, `SELECT pg_notify($1, $2)`, r.channel, string(payload))
+	return err
+}
+
+// Listen takes a connection out of the pool and holds it in LISTEN for as
+// long as it runs
+func (r *PGRelay) Listen(ctx context.Context, ready func(), handle func(payload []byte)) error {
+	pooled, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// Take the connection out of the pool for good: it stays subscribed, and
+	// a cancelled wait leaves it unusable anyway
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{r.channel}.Sanitize()); err != nil {
+		return err
+	}
+	ready()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle([]byte(n.Payload))
+	}
+}
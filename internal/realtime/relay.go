@@ -0,0 +1,139 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+)
+
+// Relay carries broadcasts between broker instances, so a bid processed on
+// one server reaches streams held open by another. Without one the broker is
+// in-process only.
+type Relay interface {
+	// Publish sends a payload to every instance listening on the relay
+	Publish(ctx context.Context, payload []byte) error
+	// Listen delivers every published payload, including this instance's own,
+	// to handle until ctx ends or the subscription fails. It calls ready once
+	// it is subscribed.
+	Listen(ctx context.Context, ready func(), handle func(payload []byte)) error
+}
+
+// relayRetryDelay is how long the broker waits before resubscribing after
+// the relay drops; anything published in between is missed
+const relayRetryDelay = time.Second
+
+// relayPublishTimeout bounds each publish so a slow relay can't back up the
+// outbound queue indefinitely
+const relayPublishTimeout = 5 * time.Second
+
+// relayMessage is the envelope instances exchange. Origin lets an instance
+// skip its own messages, which it already delivered locally.
+type relayMessage struct {
+	Origin       string               `json:"origin"`
+	Event        *domain.BidEvent     `json:"event,omitempty"`
+	Notification *domain.Notification `json:"notification,omitempty"`
+	UserID       int64                `json:"user_id,omitempty"` // Notification.UserID isn't serialized
+}
+
+// WithRelay shares auction events and notifications with other instances
+// through relay. Viewer counts stay per instance.
+func WithRelay(relay Relay) BrokerOption {
+	return func(b *Broker) {
+		b.relay = relay
+	}
+}
+
+// RelayReady is closed once the relay is first subscribed, so callers can
+// wait before relying on cross-instance delivery. It never closes without a
+// relay.
+func (b *Broker) RelayReady() <-chan struct{} {
+	return b.relayReady
+}
+
+// startRelay runs the relay's listen and publish loops until Stop
+func (b *Broker) startRelay() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-b.done
+		cancel()
+	}()
+	go b.relayListenLoop(ctx)
+	go b.relayPublishLoop(ctx)
+}
+
+func (b *Broker) relayListenLoop(ctx context.Context) {
+	ready := sync.OnceFunc(func() { close(b.relayReady) })
+	for {
+		err := b.relay.Listen(ctx, ready, b.receiveRelayed)
+		if ctx.Err() != nil {
+			return
+		}
+		b.logger.Error("sse_relay_listen_failed", slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(relayRetryDelay):
+		}
+	}
+}
+
+func (b *Broker) relayPublishLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-b.relayOut:
+			pubCtx, cancel := context.WithTimeout(ctx, relayPublishTimeout)
+			err := b.relay.Publish(pubCtx, payload)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				b.logger.Error("sse_relay_publish_failed", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// publishRelay queues a message for the other instances without blocking the
+// caller
+func (b *Broker) publishRelay(msg relayMessage) {
+	if b.relay == nil {
+		return
+	}
+	msg.Origin = b.instanceID
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		b.logger.Error("sse_relay_marshal_error", slog.String("error", err.Error()))
+		return
+	}
+	select {
+	case b.relayOut <- payload:
+	default:
+		b.logger.Warn("sse_relay_dropped_queue_full")
+	}
+}
+
+// receiveRelayed delivers a message published by another instance to this
+// instance's streams
+func (b *Broker) receiveRelayed(payload []byte) {
+	var msg relayMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		b.logger.Warn("sse_relay_bad_message", slog.String("error", err.Error()))
+		return
+	}
+	if msg.Origin == b.instanceID {
+		return
+	}
+	if msg.Event != nil {
+		b.enqueue(*msg.Event)
+	}
+	if msg.Notification != nil {
+		n := *msg.Notification
+		n.UserID = msg.UserID
+		b.deliverNotification(n)
+	}
+}
@@ -0,0 +1,57 @@
+// Package results holds shared stores for finished bid results, so any server
+// instance can answer a ticket's status.
+package results
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces result keys in a Redis that may be shared
+const keyPrefix = "bidresult:"
+
+// RedisStore keeps each result as JSON under its ticket ID, expiring after ttl
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisStore creates a store on client. A non-positive ttl keeps results
+// until Redis evicts them.
+func NewRedisStore(client *redis.Client, ttl time.Duration) *RedisStore {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return &RedisStore{client: client, ttl: ttl}
+}
+
+// Put stores a result, replacing any earlier one for the ticket
+func (s *RedisStore) Put(ctx context.Context, result domain.BidResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, keyPrefix+result.TicketID, data, s.ttl).Err()
+}
+
+// Get returns the stored result for a ticket, or false if there is none
+func (s *RedisStore) Get(ctx context.Context, ticketID string) (domain.BidResult, bool, error) {
+	data, err := s.client.Get(ctx, keyPrefix+ticketID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return domain.BidResult{}, false, nil
+	}
+	if err != nil {
+		return domain.BidResult{}, false, err
+	}
+
+	var result domain.BidResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return domain.BidResult{}, false, err
+	}
+	return result, true, nil
+}
@@ -0,0 +1,13 @@
+// Package version holds build metadata stamped in at link time, e.g.
+//
+//	go build -ldflags "-X github.com/ayubfarah/vehicle-auc/internal/version.Commit=$(git rev-parse --short HEAD)"
+//
+// Unstamped builds (go run, tests) report Version "dev" and leave the rest
+// empty.
+package version
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = "" // RFC3339, UTC
+)
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrPrivateAddress marks a user webhook host that resolves somewhere the
+// server must not be made to call: loopback, private, link-local and other
+// non-public ranges
+var ErrPrivateAddress = errors.New("webhook host resolves to a non-public address")
+
+// CheckURL validates a user webhook URL at registration: it must be https
+// and its host must resolve only to public addresses. Deliveries check the
+// address again when they dial, since DNS can change after registration.
+// With insecure set, http and non-public hosts are allowed (local
+// development).
+func CheckURL(ctx context.Context, rawURL string, insecure bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "https" && !(insecure && u.Scheme == "http") {
+		return errors.New("url must use https")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("url must have a host")
+	}
+	if insecure {
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(addr) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// publicAddr reports whether addr is routable on the public internet
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && addr.IsGlobalUnicast() && !addr.IsPrivate() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is carrier-grade NAT space (RFC 6598), reachable from
+// inside some cloud networks though not private by RFC 1918
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// dialPublicOnly is a net.Dialer control hook that refuses connections to
+// non-public addresses. It sees the address actually being dialled, after
+// DNS resolution, so a host that re-resolves to an internal IP is caught.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addr) {
+		return fmt.Errorf("dial %s: %w", address, ErrPrivateAddress)
+	}
+	return nil
+}
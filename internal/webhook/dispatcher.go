@@ -0,0 +1,389 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// User-scoped events a bidder can subscribe to
+const (
+	EventBidAccepted = "bid.accepted"
+	EventBidOutbid   = "bid.outbid"
+	EventAuctionWon  = "auction.won"
+
+	// EventAuctionClosedByAdmin tells the leader an admin ended the auction
+	// early: nothing was sold to them
+	EventAuctionClosedByAdmin = "auction.closed_by_admin"
+)
+
+// UserEvents lists the events accepted when registering a user webhook
+var UserEvents = []string{EventBidAccepted, EventBidOutbid, EventAuctionWon, EventAuctionClosedByAdmin}
+
+// Auction lifecycle events sent to the operator-configured endpoints
+const (
+	EventAuctionEnded = "auction.ended"
+	EventOrderCreated = "order.created"
+)
+
+// Signature headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Payload is the JSON body POSTed to a webhook endpoint
+type Payload struct {
+	Event     string    `json:"event"`
+	Data      any       `json:"data"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Delivery is one payload bound for one endpoint. WebhookID is 0 for the
+// configured lifecycle endpoints.
+type Delivery struct {
+	WebhookID int64
+	URL       string
+	Secret    string
+	Event     string
+	Body      []byte
+
+	attempt int
+}
+
+// Endpoint is an operator-configured receiver for lifecycle events
+type Endpoint struct {
+	URL    string
+	Secret string
+}
+
+// userEvent is a NotifyUser call waiting for its webhooks to be looked up
+type userEvent struct {
+	userID int64
+	event  string
+	data   any
+	at     time.Time
+}
+
+// Dispatcher delivers signed webhook payloads off the caller's goroutine
+type Dispatcher struct {
+	db      *pgxpool.Pool
+	logger  *slog.Logger
+	timeout time.Duration
+	workers int
+
+	// client sends to the operator's endpoints; userClient sends to
+	// user-registered URLs and refuses to dial non-public addresses
+	client     *http.Client
+	userClient *http.Client
+	insecure   bool
+
+	endpoints    []Endpoint
+	maxRetries   int
+	retryBackoff time.Duration
+
+	lookups chan userEvent
+	queue   chan Delivery
+	wg      sync.WaitGroup
+	done    chan struct{}
+}
+
+// Option configures the dispatcher
+type Option func(*Dispatcher)
+
+// WithTimeout sets the per-delivery HTTP timeout
+func WithTimeout(d time.Duration) Option {
+	return func(w *Dispatcher) {
+		w.timeout = d
+	}
+}
+
+// WithInsecureURLs lets user webhooks reach loopback and private addresses
+// (local development and tests)
+func WithInsecureURLs(enabled bool) Option {
+	return func(w *Dispatcher) {
+		w.insecure = enabled
+	}
+}
+
+// WithWorkers sets the number of concurrent delivery goroutines
+func WithWorkers(n int) Option {
+	return func(w *Dispatcher) {
+		w.workers = n
+	}
+}
+
+// WithEndpoints sets the receivers for NotifyEvent
+func WithEndpoints(endpoints ...Endpoint) Option {
+	return func(w *Dispatcher) {
+		w.endpoints = endpoints
+	}
+}
+
+// WithRetries re-sends a delivery that failed with a network error, 429 or
+// 5xx up to n more times, waiting backoff, then twice that, and so on
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(w *Dispatcher) {
+		w.maxRetries = n
+		w.retryBackoff = backoff
+	}
+}
+
+// NewDispatcher creates a dispatcher; call Start before notifying
+func NewDispatcher(db *pgxpool.Pool, logger *slog.Logger, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		db:      db,
+		logger:  logger,
+		timeout: 5 * time.Second,
+		workers: 4,
+		lookups: make(chan userEvent, 1000),
+		queue:   make(chan Delivery, 1000),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.client = newClient(d.timeout, http.DefaultTransport.(*http.Transport).Clone())
+	userTransport := http.DefaultTransport.(*http.Transport).Clone()
+	if !d.insecure {
+		// No proxy: the dial check has to see the endpoint's own address
+		userTransport.Proxy = nil
+		userTransport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   dialPublicOnly,
+		}).DialContext
+	}
+	d.userClient = newClient(d.timeout, userTransport)
+	return d
+}
+
+// newClient returns a delivery client that doesn't follow redirects: a 3xx
+// is a failed delivery, so an endpoint can't bounce requests elsewhere
+func newClient(timeout time.Duration, transport *http.Transport) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Start launches the delivery workers
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	d.logger.Info("webhook_dispatcher_started", slog.Int("workers", d.workers))
+}
+
+// Stop waits for in-flight deliveries; queued ones and pending lookups are
+// dropped
+func (d *Dispatcher) Stop() {
+	close(d.done)
+	d.wg.Wait()
+	d.logger.Info("webhook_dispatcher_stopped")
+}
+
+// NotifyUser queues event for every active webhook userID registered for it.
+// Only that user's own endpoints are ever looked up, and the lookup happens
+// on a worker, so callers on the bid path never wait on the database.
+func (d *Dispatcher) NotifyUser(userID int64, event string, data any) {
+	select {
+	case d.lookups <- userEvent{userID: userID, event: event, data: data, at: time.Now().UTC()}:
+	default:
+		d.logger.Warn("webhook_dropped_queue_full",
+			slog.Int64("user_id", userID),
+			slog.String("event", event),
+		)
+	}
+}
+
+// lookup queues a delivery for each of the user's webhooks subscribed to the
+// event
+func (d *Dispatcher) lookup(ue userEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `
+		SELECT id, url, secret FROM user_webhooks
+		WHERE user_id = $1 AND active AND $2 = ANY(events)
+	`, ue.userID, ue.event)
+	if err != nil {
+		d.logger.Error("webhook_lookup_failed",
+			slog.Int64("user_id", ue.userID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	defer rows.Close()
+
+	var body []byte
+	for rows.Next() {
+		var del Delivery
+		if err := rows.Scan(&del.WebhookID, &del.URL, &del.Secret); err != nil {
+			continue
+		}
+		if body == nil {
+			body, err = json.Marshal(Payload{Event: ue.event, Data: ue.data, Timestamp: ue.at})
+			if err != nil {
+				d.logger.Error("webhook_marshal_failed", slog.String("error", err.Error()))
+				return
+			}
+		}
+		del.Event = ue.event
+		del.Body = body
+		d.enqueue(del)
+	}
+}
+
+// NotifyEvent queues a lifecycle event for every configured endpoint
+func (d *Dispatcher) NotifyEvent(event string, data any) {
+	if len(d.endpoints) == 0 {
+		return
+	}
+	body, err := json.Marshal(Payload{Event: event, Data: data, Timestamp: time.Now().UTC()})
+	if err != nil {
+		d.logger.Error("webhook_marshal_failed", slog.String("error", err.Error()))
+		return
+	}
+	for _, ep := range d.endpoints {
+		d.enqueue(Delivery{URL: ep.URL, Secret: ep.Secret, Event: event, Body: body})
+	}
+}
+
+func (d *Dispatcher) enqueue(del Delivery) {
+	select {
+	case d.queue <- del:
+	default:
+		d.logger.Warn("webhook_dropped_queue_full",
+			slog.Int64("webhook_id", del.WebhookID),
+			slog.String("event", del.Event),
+		)
+	}
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.done:
+			return
+		case ue := <-d.lookups:
+			d.lookup(ue)
+		case del := <-d.queue:
+			if err := d.deliver(del); err != nil {
+				d.logger.Warn("webhook_delivery_failed",
+					slog.Int64("webhook_id", del.WebhookID),
+					slog.String("event", del.Event),
+					slog.Int("attempt", del.attempt+1),
+					slog.String("error", err.Error()),
+				)
+				d.retry(del, err)
+			}
+		}
+	}
+}
+
+// retry schedules another attempt for a retryable failure. The wait happens
+// on a timer rather than in the worker, so a dead endpoint doesn't hold up
+// other deliveries.
+func (d *Dispatcher) retry(del Delivery, err error) {
+	if del.attempt >= d.maxRetries || !retryable(err) {
+		return
+	}
+	wait := d.retryBackoff << del.attempt
+	del.attempt++
+	time.AfterFunc(wait, func() {
+		select {
+		case <-d.done:
+		default:
+			d.enqueue(del)
+		}
+	})
+}
+
+// statusError is a delivery the endpoint answered with a non-2xx status
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("endpoint returned %d", e.code)
+}
+
+// retryable reports whether a failed delivery might succeed later: network
+// errors, rate limiting and server errors are, other client errors aren't
+func retryable(err error) bool {
+	var se *statusError
+	if !errors.As(err, &se) {
+		return true
+	}
+	return se.code == http.StatusTooManyRequests || se.code >= 500
+}
+
+// deliver POSTs one signed payload; any non-2xx response is an error
+func (d *Dispatcher) deliver(del Delivery) error {
+	start := time.Now()
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, del.URL, bytes.NewReader(del.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, del.Event)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(del.Secret, timestamp, del.Body))
+
+	client := d.client
+	if del.WebhookID != 0 {
+		client = d.userClient
+	}
+	resp, err := client.Do(req)
+	metrics.ExternalAPILatency.WithLabelValues("webhook", del.Event).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ExternalAPICallsTotal.WithLabelValues("webhook", del.Event, "error").Inc()
+		return err
+	}
+	resp.Body.Close()
+	metrics.ExternalAPICallsTotal.WithLabelValues("webhook", del.Event, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign computes the signature header value: "sha256=" + hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed by the webhook secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"bid.accepted"}`)
+	sig := Sign("secret", "1700000000", body)
+
+	assert.True(t, Verify("secret", "1700000000", body, sig))
+	assert.False(t, Verify("other", "1700000000", body, sig))
+	assert.False(t, Verify("secret", "1700000001", body, sig))
+	assert.False(t, Verify("secret", "1700000000", []byte(`{}`), sig))
+}
+
+func TestDispatcher_DeliverSigned(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var gotBody []byte
+	var gotHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil, logger, WithInsecureURLs(true))
+	err := d.deliver(Delivery{
+		WebhookID: 1,
+		URL:       server.URL,
+		Secret:    "s3cret",
+		Event:     EventBidAccepted,
+		Body:      []byte(`{"event":"bid.accepted"}`),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, EventBidAccepted, gotHeaders.Get(HeaderEvent))
+	assert.True(t, Verify("s3cret", gotHeaders.Get(HeaderTimestamp), gotBody, gotHeaders.Get(HeaderSignature)))
+}
+
+func TestDispatcher_DeliverNon2xxIsError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil, logger)
+	err := d.deliver(Delivery{URL: server.URL, Secret: "s", Event: EventBidOutbid, Body: []byte(`{}`)})
+	assert.Error(t, err)
+}
+
+func TestDispatcher_NotifyEventDeliversSigned(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	type received struct {
+		body    []byte
+		headers http.Header
+	}
+	got := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{body: body, headers: r.Header.Clone()}
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil, logger, WithEndpoints(Endpoint{URL: server.URL, Secret: "ops"}))
+	d.Start()
+	defer d.Stop()
+
+	d.NotifyEvent(EventOrderCreated, map[string]any{"order_id": 9})
+
+	select {
+	case r := <-got:
+		assert.Equal(t, EventOrderCreated, r.headers.Get(HeaderEvent))
+		assert.True(t, Verify("ops", r.headers.Get(HeaderTimestamp), r.body, r.headers.Get(HeaderSignature)))
+
+		var payload Payload
+		require.NoError(t, json.Unmarshal(r.body, &payload))
+		assert.Equal(t, EventOrderCreated, payload.Event)
+		assert.Equal(t, map[string]any{"order_id": float64(9)}, payload.Data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("lifecycle webhook not delivered")
+	}
+}
+
+func TestDispatcher_RetriesServerErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var calls atomic.Int32
+	delivered := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		close(delivered)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil, logger,
+		WithEndpoints(Endpoint{URL: server.URL, Secret: "ops"}),
+		WithRetries(3, time.Millisecond),
+	)
+	d.Start()
+	defer d.Stop()
+
+	d.NotifyEvent(EventAuctionEnded, map[string]any{"auction_id": 1})
+
+	select {
+	case <-delivered:
+		assert.Equal(t, int32(3), calls.Load())
+	case <-time.After(2 * time.Second):
+		t.Fatalf("delivery not retried to success, %d attempts", calls.Load())
+	}
+}
+
+func TestDispatcher_RetriesAreBounded(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+	}{
+		{"5xx stops after max retries", http.StatusBadGateway, 3},
+		{"4xx is not retried", http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			d := NewDispatcher(nil, logger,
+				WithEndpoints(Endpoint{URL: server.URL, Secret: "ops"}),
+				WithRetries(2, time.Millisecond),
+			)
+			d.Start()
+			defer d.Stop()
+
+			d.NotifyEvent(EventAuctionEnded, map[string]any{"auction_id": 1})
+
+			assert.Eventually(t, func() bool { return calls.Load() == tt.wantCalls }, 2*time.Second, 5*time.Millisecond)
+			time.Sleep(50 * time.Millisecond)
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestDispatcher_NotifyEventWithoutEndpoints(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	d := NewDispatcher(nil, logger)
+
+	d.NotifyEvent(EventAuctionEnded, map[string]any{"auction_id": 1})
+	assert.Empty(t, d.queue)
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		insecure bool
+		wantErr  bool
+	}{
+		{"https://93.184.216.34/hook", false, false},
+		{"https://[2606:2800:220:1:248:1893:25c8:1946]/hook", false, false},
+		{"http://93.184.216.34/hook", false, true},
+		{"ftp://93.184.216.34/hook", false, true},
+		{"https:///hook", false, true},
+		{"https://127.0.0.1/hook", false, true},
+		{"https://[::1]/hook", false, true},
+		{"https://10.1.2.3/hook", false, true},
+		{"https://172.16.0.1/hook", false, true},
+		{"https://192.168.1.1/hook", false, true},
+		{"https://169.254.169.254/latest/meta-data", false, true},
+		{"https://[fe80::1]/hook", false, true},
+		{"https://[fd00::1]/hook", false, true},
+		{"https://[::ffff:127.0.0.1]/hook", false, true},
+		{"https://100.64.0.1/hook", false, true},
+		{"https://0.0.0.0/hook", false, true},
+		{"http://127.0.0.1:8080/hook", true, false},
+		{"ftp://127.0.0.1/hook", true, true},
+	}
+	for _, tt := range tests {
+		err := CheckURL(context.Background(), tt.url, tt.insecure)
+		if tt.wantErr {
+			assert.Error(t, err, tt.url)
+		} else {
+			assert.NoError(t, err, tt.url)
+		}
+	}
+}
+
+func TestDispatcher_UserWebhooksRefusePrivateAddresses(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	// Checked when dialling, whatever the URL passed at registration
+	d := NewDispatcher(nil, logger)
+	err := d.deliver(Delivery{WebhookID: 1, URL: server.URL, Secret: "s", Event: EventBidAccepted, Body: []byte(`{}`)})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrPrivateAddress), err.Error())
+	assert.Zero(t, calls.Load())
+
+	// Operator endpoints are trusted configuration
+	require.NoError(t, d.deliver(Delivery{URL: server.URL, Secret: "s", Event: EventAuctionEnded, Body: []byte(`{}`)}))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDispatcher_DoesNotFollowRedirects(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var targetCalls atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetCalls.Add(1)
+	}))
+	defer target.Close()
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer redirector.Close()
+
+	d := NewDispatcher(nil, logger, WithInsecureURLs(true))
+	for _, webhookID := range []int64{0, 1} {
+		err := d.deliver(Delivery{WebhookID: webhookID, URL: redirector.URL, Secret: "s", Event: EventBidAccepted, Body: []byte(`{}`)})
+		var se *statusError
+		require.True(t, errors.As(err, &se), "webhook %d: %v", webhookID, err)
+		assert.Equal(t, http.StatusTemporaryRedirect, se.code)
+	}
+	assert.Zero(t, targetCalls.Load())
+}
+
+func TestDispatcher_NotifyUserDoesNotWaitOnLookup(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// Not started, and no database: the lookup is only queued
+	d := NewDispatcher(nil, logger)
+	d.NotifyUser(7, EventBidAccepted, map[string]any{"auction_id": 1})
+
+	require.Len(t, d.lookups, 1)
+	ue := <-d.lookups
+	assert.Equal(t, int64(7), ue.userID)
+	assert.Equal(t, EventBidAccepted, ue.event)
+	assert.Empty(t, d.queue)
+}
@@ -0,0 +1,2 @@
+-- Optimistic concurrency for vehicle edits, mirroring auctions.version
+ALTER TABLE vehicles ADD COLUMN version INT NOT NULL DEFAULT 1;
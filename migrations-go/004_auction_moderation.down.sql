@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_auctions_hidden;
+ALTER TABLE auctions DROP COLUMN IF EXISTS hidden_reason;
+ALTER TABLE auctions DROP COLUMN IF EXISTS hidden;
+
+DROP TABLE IF EXISTS moderation_queue;
+DROP TYPE IF EXISTS moderation_status;
@@ -0,0 +1,21 @@
+-- Moderation queue and soft-hidden auctions
+CREATE TYPE moderation_status AS ENUM ('pending', 'approved', 'flagged', 'rejected');
+
+CREATE TABLE moderation_queue (
+    id BIGSERIAL PRIMARY KEY,
+    vehicle_id BIGINT NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    status moderation_status NOT NULL DEFAULT 'pending',
+    reason TEXT,
+    reviewer_id BIGINT REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_moderation_queue_status ON moderation_queue(status, created_at);
+CREATE INDEX idx_moderation_queue_vehicle ON moderation_queue(vehicle_id);
+
+-- Hidden auctions stay intact for admins and existing bidders but drop out of public lists
+ALTER TABLE auctions ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE auctions ADD COLUMN hidden_reason TEXT;
+
+CREATE INDEX idx_auctions_hidden ON auctions(hidden) WHERE hidden;
@@ -0,0 +1,2 @@
+ALTER TABLE auctions DROP COLUMN IF EXISTS reserve_extension_applied;
+ALTER TABLE auctions DROP COLUMN IF EXISTS extend_on_reserve_met;
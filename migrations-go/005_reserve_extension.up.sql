@@ -0,0 +1,3 @@
+-- Optional one-time extension when the reserve is first met near the end
+ALTER TABLE auctions ADD COLUMN extend_on_reserve_met BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE auctions ADD COLUMN reserve_extension_applied BOOLEAN NOT NULL DEFAULT false;
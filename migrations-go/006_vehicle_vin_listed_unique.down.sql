@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_vehicles_vin_listed;
+ALTER TABLE vehicles ADD CONSTRAINT vehicles_vin_key UNIQUE (vin);
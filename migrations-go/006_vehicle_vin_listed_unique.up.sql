@@ -0,0 +1,3 @@
+-- A VIN may only be listed once at a time; sold/archived vehicles free it up for relisting
+ALTER TABLE vehicles DROP CONSTRAINT IF EXISTS vehicles_vin_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vehicles_vin_listed ON vehicles(vin) WHERE status NOT IN ('sold', 'archived');
@@ -0,0 +1,12 @@
+-- Per-user outbound webhooks for a bidder's own bid outcomes
+CREATE TABLE IF NOT EXISTS user_webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url VARCHAR(1000) NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_webhooks_user ON user_webhooks(user_id) WHERE active;
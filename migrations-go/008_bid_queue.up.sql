@@ -0,0 +1,12 @@
+-- Durable staging for accepted-but-unprocessed bids, replayed on engine start
+CREATE TABLE IF NOT EXISTS bid_queue (
+    ticket_id VARCHAR(64) PRIMARY KEY,
+    auction_id BIGINT NOT NULL REFERENCES auctions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    amount NUMERIC(12, 2) NOT NULL,
+    max_bid NUMERIC(12, 2),
+    client_submitted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bid_queue_created ON bid_queue(created_at);
@@ -0,0 +1,6 @@
+ALTER TABLE auctions DROP COLUMN IF EXISTS auto_relist_round;
+ALTER TABLE auctions DROP COLUMN IF EXISTS auto_relist_price_drops;
+ALTER TABLE auctions DROP COLUMN IF EXISTS relisted_from;
+
+DROP INDEX IF EXISTS idx_auctions_vehicle_open;
+ALTER TABLE auctions ADD CONSTRAINT auctions_vehicle_id_key UNIQUE (vehicle_id);
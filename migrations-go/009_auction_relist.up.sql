@@ -0,0 +1,11 @@
+-- A vehicle can be auctioned again after an unsold close; only one open auction at a time
+ALTER TABLE auctions DROP CONSTRAINT IF EXISTS auctions_vehicle_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_auctions_vehicle_open ON auctions(vehicle_id)
+    WHERE status IN ('scheduled', 'active');
+
+ALTER TABLE auctions ADD COLUMN relisted_from BIGINT REFERENCES auctions(id);
+
+-- Opt-in automatic relist when the reserve isn't met: one entry per relist,
+-- each the percent to cut starting and reserve prices by for that round
+ALTER TABLE auctions ADD COLUMN auto_relist_price_drops SMALLINT[] NOT NULL DEFAULT '{}';
+ALTER TABLE auctions ADD COLUMN auto_relist_round SMALLINT NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- Buyer reviews of sellers, one per completed order
+CREATE TABLE IF NOT EXISTS reviews (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT UNIQUE NOT NULL REFERENCES orders(id),
+    seller_id BIGINT NOT NULL REFERENCES users(id),
+    buyer_id BIGINT NOT NULL REFERENCES users(id),
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_seller ON reviews(seller_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_vehicles_drafts;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS stale_draft_notified_at;
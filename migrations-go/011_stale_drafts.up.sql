@@ -0,0 +1,5 @@
+-- Stale draft sweeping: warn sellers about untouched drafts, then archive them
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS stale_draft_notified_at TIMESTAMPTZ;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_vehicles_drafts ON vehicles(updated_at) WHERE status = 'draft';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS auction_events;
+DROP FUNCTION IF EXISTS reject_auction_event_update();
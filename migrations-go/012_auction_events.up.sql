@@ -0,0 +1,22 @@
+-- Append-only audit trail of auction state changes, written in the same
+-- transaction as the change itself so it can be replayed for disputes
+CREATE TABLE IF NOT EXISTS auction_events (
+    id BIGSERIAL PRIMARY KEY,
+    auction_id BIGINT NOT NULL REFERENCES auctions(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auction_events_auction ON auction_events(auction_id, id);
+
+-- Events are never edited once written
+CREATE OR REPLACE FUNCTION reject_auction_event_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'auction_events rows are immutable';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER auction_events_immutable BEFORE UPDATE ON auction_events
+    FOR EACH ROW EXECUTE FUNCTION reject_auction_event_update();
@@ -0,0 +1,2 @@
+-- Bidders who opt in are shown under a per-auction pseudonym instead of their name
+ALTER TABLE users ADD COLUMN IF NOT EXISTS hide_bidder_identity BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,12 @@
+-- Audit trail of listings moved between seller accounts, e.g. when a
+-- dealership reassigns inventory between staff
+CREATE TABLE IF NOT EXISTS vehicle_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    vehicle_id BIGINT NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    from_user_id BIGINT NOT NULL REFERENCES users(id),
+    to_user_id BIGINT NOT NULL REFERENCES users(id),
+    transferred_by BIGINT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_transfers_vehicle ON vehicle_transfers(vehicle_id, created_at);
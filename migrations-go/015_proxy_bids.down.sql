@@ -0,0 +1 @@
+ALTER TABLE bid_queue DROP COLUMN IF EXISTS proxy_only;
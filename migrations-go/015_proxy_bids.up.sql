@@ -0,0 +1,3 @@
+-- Proxy-only bids are staged without an amount; the engine prices them at
+-- processing time from the auction state
+ALTER TABLE bid_queue ADD COLUMN IF NOT EXISTS proxy_only BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_auctions_featured;
+ALTER TABLE auctions DROP COLUMN IF EXISTS featured;
@@ -0,0 +1,4 @@
+-- Admin-curated auctions for the homepage
+ALTER TABLE auctions ADD COLUMN IF NOT EXISTS featured BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_auctions_featured ON auctions(ends_at) WHERE featured AND status = 'active';
@@ -0,0 +1 @@
+ALTER TABLE auctions DROP COLUMN IF EXISTS extend_on_leader_change_only;
@@ -0,0 +1,3 @@
+-- Opt-in: snipe extensions only fire when a bid takes the lead from someone
+-- else, so a leader raising their own bid can't keep pushing the end out
+ALTER TABLE auctions ADD COLUMN extend_on_leader_change_only BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- Optional per-auction bid step, overriding the default increment
+ALTER TABLE auctions ADD COLUMN min_increment NUMERIC(10, 2) CHECK (min_increment > 0);
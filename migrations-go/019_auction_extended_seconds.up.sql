@@ -0,0 +1,3 @@
+-- Wall-clock time added by anti-snipe and reserve extensions, checked
+-- against the engine's cumulative extension cap
+ALTER TABLE auctions ADD COLUMN extended_seconds INT NOT NULL DEFAULT 0;
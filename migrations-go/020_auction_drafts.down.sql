@@ -0,0 +1,2 @@
+-- Postgres can't drop an enum value; retire any drafts instead
+UPDATE auctions SET status = 'cancelled' WHERE status = 'draft';
@@ -0,0 +1,4 @@
+-- Draft auctions are prepared by the seller and only scheduled once published.
+-- idx_auctions_vehicle_open still covers scheduled/active only, so drafts
+-- don't block the vehicle until publish.
+ALTER TYPE auction_status ADD VALUE IF NOT EXISTS 'draft' BEFORE 'scheduled';
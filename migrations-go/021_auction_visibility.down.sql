@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS auction_invites;
+ALTER TABLE auctions DROP COLUMN IF EXISTS visibility;
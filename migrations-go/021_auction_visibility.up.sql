@@ -0,0 +1,11 @@
+-- Private auctions are invite-only: kept out of public listings and open only
+-- to the bidders the seller invites
+ALTER TABLE auctions ADD COLUMN visibility VARCHAR(10) NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'private'));
+
+CREATE TABLE IF NOT EXISTS auction_invites (
+    auction_id BIGINT NOT NULL REFERENCES auctions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (auction_id, user_id)
+);
@@ -0,0 +1,3 @@
+-- Postgres can't drop an enum value; send failed orders back to pending instead
+UPDATE orders SET status = 'pending_payment' WHERE status = 'payment_failed';
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_error;
@@ -0,0 +1,6 @@
+-- Orders whose winner couldn't be charged when the auction closed.
+-- payment_error keeps the processor's reason; a successful capture stores its
+-- transaction ID in payment_intent_id.
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'payment_failed' AFTER 'pending_payment';
+
+ALTER TABLE orders ADD COLUMN payment_error TEXT;
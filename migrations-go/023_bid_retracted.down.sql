@@ -0,0 +1,2 @@
+-- Postgres can't drop an enum value; keep retracted bids as rejected instead
+UPDATE bids SET status = 'rejected' WHERE status = 'retracted';
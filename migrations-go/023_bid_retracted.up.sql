@@ -0,0 +1,3 @@
+-- Bids their bidder took back within BID_RETRACT_WINDOW. The auction reverts
+-- to the bid before it, which goes back to accepted.
+ALTER TYPE bid_status ADD VALUE IF NOT EXISTS 'retracted';
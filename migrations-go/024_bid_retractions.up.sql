@@ -0,0 +1,13 @@
+-- Every bid retraction, for the per-user and per-auction caps and for admins
+-- looking into bidders who retract to probe proxy maxes
+CREATE TABLE IF NOT EXISTS bid_retractions (
+    id BIGSERIAL PRIMARY KEY,
+    bid_id BIGINT NOT NULL REFERENCES bids(id) ON DELETE CASCADE,
+    auction_id BIGINT NOT NULL REFERENCES auctions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bid_retractions_user ON bid_retractions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_bid_retractions_auction ON bid_retractions(auction_id, user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_orders_payment_due;
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_retry_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_attempts;
@@ -0,0 +1,7 @@
+-- Captures run in the background and retry transient gateway failures.
+-- payment_attempts counts tries so far; payment_retry_at is when the order is
+-- next due, which also leases it to the instance charging it right now.
+ALTER TABLE orders ADD COLUMN payment_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN payment_retry_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_orders_payment_due ON orders(payment_retry_at) WHERE status = 'pending_payment';
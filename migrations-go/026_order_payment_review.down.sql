@@ -0,0 +1,3 @@
+-- Postgres can't drop an enum value; send held orders back to pending instead.
+-- Their next capture attempt finds the held transaction rather than charging again.
+UPDATE orders SET status = 'pending_payment' WHERE status = 'payment_review';
@@ -0,0 +1,4 @@
+-- Captures the processor authorized but held for fraud review. The order keeps
+-- the transaction ID in payment_intent_id and waits for the review to be
+-- settled in the gateway, instead of being charged again or failed.
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'payment_review' AFTER 'pending_payment';
@@ -0,0 +1,9 @@
+// Package migrations embeds the schema migrations so the binary and the test
+// fixtures can apply them without the SQL files on disk. Seed data is left
+// out; load it with psql or the debug seed endpoint.
+package migrations
+
+import "embed"
+
+//go:embed *.up.sql
+var FS embed.FS
@@ -2,9 +2,12 @@ package fixtures
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/migrate"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/require"
 )
@@ -36,27 +39,56 @@ func SetupTestDB(t *testing.T) *pgxpool.Pool {
 	return db
 }
 
-// SetupTestDBWithMigrations sets up DB and ensures schema exists
+// SetupTestDBWithMigrations sets up DB and ensures schema exists. A database
+// without the schema is migrated from the embedded files; one already tracked
+// in schema_migrations picks up any migrations added since. A database
+// migrated by hand with psql (make migrate-test) is used as is.
 func SetupTestDBWithMigrations(t *testing.T) *pgxpool.Pool {
 	t.Helper()
 
 	db := SetupTestDB(t)
+	MigrateTestDB(t, db)
+	return db
+}
+
+// MigrateTestDB brings db up to the embedded schema, see SetupTestDBWithMigrations
+func MigrateTestDB(t *testing.T, db *pgxpool.Pool) {
+	t.Helper()
 
-	// Check if schema exists by checking for users table
 	ctx := context.Background()
-	var exists bool
+	var hasUsers, tracked bool
 	err := db.QueryRow(ctx, `
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_name = 'users'
-		)
-	`).Scan(&exists)
+		SELECT to_regclass('users') IS NOT NULL, to_regclass('schema_migrations') IS NOT NULL
+	`).Scan(&hasUsers, &tracked)
 	require.NoError(t, err)
 
-	if !exists {
-		t.Skip("Database schema not initialized. Run migrations first: make migrate-test")
+	if hasUsers && !tracked {
+		return
 	}
+	_, err = migrate.Up(ctx, db)
+	require.NoError(t, err, "Failed to migrate test database")
+}
+
+// SetupEmptyTestDB creates a throwaway database next to the test database
+// and connects to it. Nothing is migrated; the database is dropped on cleanup.
+func SetupEmptyTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	admin := SetupTestDB(t)
+	ctx := context.Background()
+
+	name := fmt.Sprintf("vehicle_auc_empty_%d", time.Now().UnixNano())
+	_, err := admin.Exec(ctx, "CREATE DATABASE "+name)
+	require.NoError(t, err, "Failed to create empty test database")
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+	})
+
+	cfg := admin.Config().Copy()
+	cfg.ConnConfig.Database = name
+	db, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(t, err, "Failed to connect to empty test database")
+	t.Cleanup(db.Close)
 
 	return db
 }
-
@@ -82,7 +82,7 @@ func TestVehicle(t *testing.T, db *pgxpool.Pool, sellerID int64) int64 {
 			starting_price, status, location_city, location_state
 		) VALUES (
 			$1, $2, 2021, 'Honda', 'Accord', 'Sport', 35000,
-			15000.00, 'active', 'Los Angeles', 'CA'
+			100.00, 'active', 'Los Angeles', 'CA'
 		)
 		RETURNING id
 	`, sellerID, vin).Scan(&vehicleID)
@@ -205,6 +205,57 @@ func TestBid(t *testing.T, db *pgxpool.Pool, auctionID, userID int64, amount dec
 	return bidID
 }
 
+// TestOrder ends an auction for the vehicle with buyerID as winner and opens
+// an order for it in the given status
+func TestOrder(t *testing.T, db *pgxpool.Pool, vehicleID, buyerID int64, status string) int64 {
+	t.Helper()
+	ctx := context.Background()
+
+	var auctionID, sellerID int64
+	err := db.QueryRow(ctx, `
+		INSERT INTO auctions (vehicle_id, status, starts_at, ends_at, current_bid, current_bid_user_id, bid_count, winner_id, winning_bid)
+		VALUES ($1, 'ended', $2, $3, 20000, $4, 1, $4, 20000)
+		RETURNING id, (SELECT seller_id FROM vehicles WHERE id = $1)
+	`, vehicleID, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour), buyerID).Scan(&auctionID, &sellerID)
+	require.NoError(t, err)
+
+	var orderID int64
+	err = db.QueryRow(ctx, `
+		INSERT INTO orders (auction_id, buyer_id, seller_id, vehicle_id, sale_price, total_price, status)
+		VALUES ($1, $2, $3, $4, 20000, 20000, $5::order_status)
+		RETURNING id
+	`, auctionID, buyerID, sellerID, vehicleID, status).Scan(&orderID)
+	require.NoError(t, err)
+
+	return orderID
+}
+
+// TestImages attaches n images to a vehicle, the first one primary
+func TestImages(t *testing.T, db *pgxpool.Pool, vehicleID int64, n int) {
+	t.Helper()
+	ctx := context.Background()
+
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("vehicles/%d/%s.jpg", vehicleID, uuid.New().String()[:8])
+		_, err := db.Exec(ctx, `
+			INSERT INTO vehicle_images (vehicle_id, s3_key, url, is_primary, display_order)
+			VALUES ($1, $2, $3, $4, $5)
+		`, vehicleID, key, "https://example.com/"+key, i == 0, i)
+		require.NoError(t, err)
+	}
+}
+
+// AdminUser creates a user with admin role
+func AdminUser(t *testing.T, db *pgxpool.Pool) int64 {
+	t.Helper()
+
+	userID := TestUser(t, db)
+	_, err := db.Exec(context.Background(), `UPDATE users SET role = 'admin' WHERE id = $1`, userID)
+	require.NoError(t, err)
+
+	return userID
+}
+
 // BuyerUser creates a verified buyer user
 func BuyerUser(t *testing.T, db *pgxpool.Pool) int64 {
 	t.Helper()
@@ -236,6 +287,13 @@ func CleanupTestData(t *testing.T, db *pgxpool.Pool) {
 
 	// Delete in reverse order of dependencies
 	tables := []string{
+		"reviews",
+		"vehicle_transfers",
+		"auction_events",
+		"auction_invites",
+		"bid_queue",
+		"user_webhooks",
+		"moderation_queue",
 		"notifications",
 		"watchlist",
 		"fulfillments",
@@ -0,0 +1,243 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/webhook"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAdminAuctionRouter(db *pgxpool.Pool, logger *slog.Logger, userID int64, broadcaster *recordingBroadcaster) *chi.Mux {
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, broadcaster, nil)
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+		})
+	})
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(middleware.RequireRole(db, logger, "admin"))
+		r.Post("/auctions/{id}/close", auctionHandler.ForceCloseAuction)
+		r.Post("/auctions/{id}/extend", auctionHandler.ExtendAuction)
+		r.Post("/auctions/{id}/featured", auctionHandler.SetFeatured)
+	})
+	r.Get("/api/auctions/featured", auctionHandler.ListFeaturedAuctions)
+	return r
+}
+
+func adminPost(r http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminForceCloseAuction(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	adminID := fixtures.AdminUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	broadcaster := &recordingBroadcaster{}
+	r := setupAdminAuctionRouter(db, logger, adminID, broadcaster)
+
+	rec := adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/close", auctionID), `{"reason": "fraud"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var status string
+	var version int
+	var endsAt time.Time
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT status::text, version, ends_at FROM auctions WHERE id = $1`, auctionID).Scan(&status, &version, &endsAt))
+	assert.Equal(t, "ended", status)
+	assert.Equal(t, 1, version)
+	assert.False(t, endsAt.After(time.Now()))
+
+	events := broadcaster.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "auction_ended", events[0].Type)
+	assert.Equal(t, auctionID, events[0].AuctionID)
+
+	// Already ended
+	rec = adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/close", auctionID), "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// recordingNotifier captures per-user webhook events
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []string
+	users  []int64
+}
+
+func (n *recordingNotifier) NotifyUser(userID int64, event string, data any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.users = append(n.users, userID)
+	n.events = append(n.events, event)
+}
+
+func TestAdminForceCloseAuction_LeaderIsNotWinner(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	adminID := fixtures.AdminUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	bidderID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 15000, bidderID)
+
+	notifier := &recordingNotifier{}
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, notifier)
+	r := chi.NewRouter()
+	r.Post("/api/admin/auctions/{id}/close", func(w http.ResponseWriter, r *http.Request) {
+		auctionHandler.ForceCloseAuction(w, r.WithContext(middleware.WithUserID(r.Context(), adminID)))
+	})
+
+	rec := adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/close", auctionID), `{"reason": "fraud"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	assert.Equal(t, []int64{bidderID}, notifier.users)
+	assert.Equal(t, []string{webhook.EventAuctionClosedByAdmin}, notifier.events)
+
+	var winnerID *int64
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT winner_id FROM auctions WHERE id = $1`, auctionID).Scan(&winnerID))
+	assert.Nil(t, winnerID)
+}
+
+func TestAdminExtendAuction(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	adminID := fixtures.AdminUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	var originalEndsAt time.Time
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT ends_at FROM auctions WHERE id = $1`, auctionID).Scan(&originalEndsAt))
+
+	broadcaster := &recordingBroadcaster{}
+	r := setupAdminAuctionRouter(db, logger, adminID, broadcaster)
+
+	rec := adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/extend", auctionID), `{"minutes": 30, "reason": "outage"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var endsAt time.Time
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT ends_at FROM auctions WHERE id = $1`, auctionID).Scan(&endsAt))
+	assert.True(t, endsAt.Equal(originalEndsAt.Add(30*time.Minute)))
+
+	events := broadcaster.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "auction_extended", events[0].Type)
+	assert.True(t, events[0].EndsAt.Equal(endsAt))
+
+	// Minutes are required
+	rec = adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/extend", auctionID), `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp struct {
+		Fields map[string]string `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Contains(t, resp.Fields, "minutes")
+}
+
+func TestAdminAuctionActions_RejectNonAdmin(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	broadcaster := &recordingBroadcaster{}
+	r := setupAdminAuctionRouter(db, logger, sellerID, broadcaster)
+
+	rec := adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/close", auctionID), "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/extend", auctionID), `{"minutes": 30}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	var status string
+	var version int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT status::text, version FROM auctions WHERE id = $1`, auctionID).Scan(&status, &version))
+	assert.Equal(t, "active", status)
+	assert.Equal(t, 0, version)
+	assert.Empty(t, broadcaster.Events())
+}
+
+func TestFeaturedAuctions(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	adminID := fixtures.AdminUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	laterID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	soonerID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	endedID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	plainID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	_, err := db.Exec(context.Background(), `UPDATE auctions SET ends_at = NOW() + INTERVAL '1 hour' WHERE id = $1`, soonerID)
+	require.NoError(t, err)
+
+	r := setupAdminAuctionRouter(db, logger, adminID, &recordingBroadcaster{})
+	for _, id := range []int64{laterID, soonerID, endedID} {
+		rec := adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/featured", id), `{"featured": true}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	_, err = db.Exec(context.Background(), `UPDATE auctions SET status = 'ended' WHERE id = $1`, endedID)
+	require.NoError(t, err)
+
+	listFeatured := func() []int64 {
+		req := httptest.NewRequest("GET", "/api/auctions/featured", nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp struct {
+			Auctions []handler.AuctionResponse `json:"auctions"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		ids := make([]int64, 0, len(resp.Auctions))
+		for _, a := range resp.Auctions {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+
+	// Only featured auctions that are still active, ending soonest first
+	assert.Equal(t, []int64{soonerID, laterID}, listFeatured())
+	assert.NotContains(t, listFeatured(), plainID)
+
+	rec := adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/featured", soonerID), `{"featured": false}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []int64{laterID}, listFeatured())
+
+	rec = adminPost(r, "/api/admin/auctions/999999/featured", `{"featured": true}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/featured", plainID), `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Non-admins can't curate the list
+	rec = adminPost(setupAdminAuctionRouter(db, logger, buyerID, &recordingBroadcaster{}), fmt.Sprintf("/api/admin/auctions/%d/featured", plainID), `{"featured": true}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, []int64{laterID}, listFeatured())
+}
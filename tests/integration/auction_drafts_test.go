@@ -0,0 +1,209 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDraftAuction_EditThenPublish(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	_, err := db.Exec(ctx, `UPDATE vehicles SET status = 'draft' WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{MinAuctionDuration: time.Hour}, nil, nil)
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := r.Header.Get("X-Test-User"); id != "" {
+				var userID int64
+				fmt.Sscan(id, &userID)
+				r = r.WithContext(middleware.WithUserID(r.Context(), userID))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Get("/api/auctions", auctionHandler.ListAuctions)
+	r.Post("/api/auctions", auctionHandler.CreateAuction)
+	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
+	r.Put("/api/auctions/{id}", auctionHandler.UpdateDraftAuction)
+	r.Post("/api/auctions/{id}/publish", auctionHandler.PublishAuction)
+
+	send := func(method, path string, userID int64, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("X-Test-User", fmt.Sprint(userID))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Create the draft a day out; the vehicle stays a draft too
+	body := fmt.Sprintf(`{"vehicle_id": %d, "starts_at": %q, "ends_at": %q, "draft": true}`, vehicleID,
+		time.Now().Add(24*time.Hour).Format(time.RFC3339), time.Now().Add(48*time.Hour).Format(time.RFC3339))
+	rec := send("POST", "/api/auctions", sellerID, body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created struct {
+		AuctionID int64  `json:"auction_id"`
+		Status    string `json:"status"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.Equal(t, "draft", created.Status)
+	auctionID := created.AuctionID
+	path := "/api/auctions/" + itoa(auctionID)
+
+	var vehicleStatus string
+	require.NoError(t, db.QueryRow(ctx, `SELECT status::text FROM vehicles WHERE id = $1`, vehicleID).Scan(&vehicleStatus))
+	assert.Equal(t, "draft", vehicleStatus)
+
+	// Only the seller can see it
+	assert.Equal(t, http.StatusOK, send("GET", path, sellerID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", path, buyerID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", path, 0, "").Code)
+	assert.Equal(t, http.StatusBadRequest, send("GET", "/api/auctions?status=draft", 0, "").Code)
+	rec = send("GET", "/api/auctions?ids="+itoa(auctionID), 0, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	assert.Empty(t, list.Items)
+
+	// Bids are turned away
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+	result := submitSyncBid(t, engine, auctionID, buyerID, "150.00")
+	assert.Equal(t, "rejected", result.Status)
+	assert.Equal(t, "auction_not_active", result.Reason)
+
+	// Edits are validated, owner-only, and move it to start now
+	assert.Equal(t, http.StatusBadRequest, send("PUT", path, sellerID,
+		fmt.Sprintf(`{"ends_at": %q}`, time.Now().Format(time.RFC3339))).Code)
+	rec = send("PUT", path, sellerID, fmt.Sprintf(`{"starts_at": %q, "ends_at": %q}`,
+		time.Now().Format(time.RFC3339), time.Now().Add(30*time.Minute).Format(time.RFC3339)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "ends_at must be at least 1h0m0s from now")
+	assert.Equal(t, http.StatusNotFound, send("PUT", path, buyerID, `{"reserve_price": 1}`).Code)
+
+	startsAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	endsAt := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	rec = send("PUT", path, sellerID, fmt.Sprintf(`{"starts_at": %q, "ends_at": %q, "reserve_price": 9000}`,
+		startsAt.Format(time.RFC3339), endsAt.Format(time.RFC3339)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var edited struct {
+		Status       string    `json:"status"`
+		StartsAt     time.Time `json:"starts_at"`
+		EndsAt       time.Time `json:"ends_at"`
+		ReservePrice string    `json:"reserve_price"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&edited))
+	assert.Equal(t, "draft", edited.Status)
+	assert.True(t, startsAt.Equal(edited.StartsAt))
+	assert.True(t, endsAt.Equal(edited.EndsAt))
+	assert.Equal(t, "9000.00", edited.ReservePrice)
+
+	var reserve string
+	require.NoError(t, db.QueryRow(ctx, `SELECT reserve_price::text FROM vehicles WHERE id = $1`, vehicleID).Scan(&reserve))
+	assert.Equal(t, "9000.00", reserve)
+
+	// Publishing starts it straight away since starts_at has passed
+	assert.Equal(t, http.StatusNotFound, send("POST", path+"/publish", buyerID, "").Code)
+	rec = send("POST", path+"/publish", sellerID, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var published struct {
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&published))
+	assert.Equal(t, "active", published.Status)
+
+	require.NoError(t, db.QueryRow(ctx, `SELECT status::text FROM vehicles WHERE id = $1`, vehicleID).Scan(&vehicleStatus))
+	assert.Equal(t, "active", vehicleStatus)
+	assert.Contains(t, auctionEventTypes(t, db, auctionID), bidengine.EventPublished)
+
+	// It's public now, no longer a draft, and takes bids
+	assert.Equal(t, http.StatusOK, send("GET", path, buyerID, "").Code)
+	assert.Equal(t, http.StatusConflict, send("POST", path+"/publish", sellerID, "").Code)
+	assert.Equal(t, http.StatusConflict, send("PUT", path, sellerID, `{"reserve_price": 1}`).Code)
+	assert.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, buyerID, "150.00").Status)
+}
+
+func TestDraftAuction_PublishChecks(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{MinImagesToSubmit: 1, MinAuctionDuration: time.Hour}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/publish", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		auctionHandler.PublishAuction(w, r.WithContext(ctx))
+	})
+	publish := func(auctionID int64) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/auctions/"+itoa(auctionID)+"/publish", nil))
+		return rec
+	}
+	draft := func(endsAt time.Time) int64 {
+		var id int64
+		require.NoError(t, db.QueryRow(ctx, `
+			INSERT INTO auctions (vehicle_id, status, starts_at, ends_at)
+			VALUES ($1, 'draft', $2, $3)
+			RETURNING id
+		`, vehicleID, endsAt.Add(-24*time.Hour), endsAt).Scan(&id))
+		return id
+	}
+
+	// A draft whose end has passed, or is closer than the minimum duration,
+	// must be rescheduled first
+	for _, endsAt := range []time.Time{time.Now().Add(-time.Hour), time.Now().Add(30 * time.Minute)} {
+		rec := publish(draft(endsAt))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "ends_at must be at least 1h0m0s from now: edit the draft's schedule")
+	}
+
+	// The image minimum skipped at create applies on publish
+	auctionID := draft(time.Now().Add(24 * time.Hour))
+	rec := publish(auctionID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "0 uploaded, 1 more needed")
+
+	fixtures.TestImages(t, db, vehicleID, 1)
+	require.Equal(t, http.StatusOK, publish(auctionID).Code)
+
+	// A second draft for the same vehicle can't go live alongside the first
+	other := draft(time.Now().Add(24 * time.Hour))
+	rec = publish(other)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "open auction")
+
+	var status string
+	require.NoError(t, db.QueryRow(ctx, `SELECT status::text FROM auctions WHERE id = $1`, other).Scan(&status))
+	assert.Equal(t, "draft", status)
+}
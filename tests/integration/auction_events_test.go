@@ -0,0 +1,214 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func submitSyncBid(t *testing.T, engine *bidengine.Engine, auctionID, userID int64, amount string) domain.BidResult {
+	t.Helper()
+	req := domain.BidRequest{
+		TicketID:  uuid.New().String(),
+		AuctionID: auctionID,
+		UserID:    userID,
+		Amount:    decimal.RequireFromString(amount),
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, engine.Submit(req))
+	result, err := engine.GetResult(req.TicketID, 5*time.Second)
+	require.NoError(t, err)
+	return result
+}
+
+func auctionEventTypes(t *testing.T, db *pgxpool.Pool, auctionID int64) []string {
+	t.Helper()
+	rows, err := db.Query(context.Background(), `SELECT type FROM auction_events WHERE auction_id = $1 ORDER BY id`, auctionID)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	types := make([]string, 0)
+	for rows.Next() {
+		var eventType string
+		require.NoError(t, rows.Scan(&eventType))
+		types = append(types, eventType)
+	}
+	require.NoError(t, rows.Err())
+	return types
+}
+
+func TestAuctionEvents_WrittenWithAcceptedBid(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	buyerID := fixtures.BuyerUser(t, db)
+	verifiedID := fixtures.VerifiedUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	first := submitSyncBid(t, engine, auctionID, buyerID, "150.00")
+	require.Equal(t, "accepted", first.Status)
+
+	var bidID int64
+	var payload map[string]any
+	err := db.QueryRow(ctx, `
+		SELECT (payload->>'bid_id')::bigint, payload FROM auction_events
+		WHERE auction_id = $1 AND type = 'bid_accepted'
+	`, auctionID).Scan(&bidID, &payload)
+	require.NoError(t, err)
+	assert.Equal(t, first.BidID, bidID)
+	assert.Equal(t, "150", payload["amount"])
+	assert.EqualValues(t, buyerID, payload["user_id"])
+
+	// A rejected bid leaves no trace in the log
+	rejected := submitSyncBid(t, engine, auctionID, verifiedID, "120.00")
+	require.Equal(t, "rejected", rejected.Status)
+	assert.Equal(t, []string{"bid_accepted"}, auctionEventTypes(t, db, auctionID))
+
+	second := submitSyncBid(t, engine, auctionID, verifiedID, "175.00")
+	require.Equal(t, "accepted", second.Status)
+	assert.Equal(t, []string{"bid_accepted", "bid_accepted", "outbid"}, auctionEventTypes(t, db, auctionID))
+
+	var outbidUser int64
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT (payload->>'user_id')::bigint FROM auction_events
+		WHERE auction_id = $1 AND type = 'outbid'
+	`, auctionID).Scan(&outbidUser))
+	assert.Equal(t, buyerID, outbidUser)
+
+	// The log is append-only
+	_, err = db.Exec(ctx, `UPDATE auction_events SET type = 'tampered' WHERE auction_id = $1`, auctionID)
+	assert.Error(t, err)
+}
+
+func TestAuctionEvents_RolledBackOnConflict(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	engine := bidengine.NewEngine(db, logger, nil,
+		bidengine.WithSyncMode(true),
+		bidengine.WithMaxRetries(0),
+	)
+	engine.Start()
+	defer engine.Stop()
+
+	// Hold a competing version bump open so the bid reads version 0, then
+	// blocks on its OCC update until the competitor commits
+	competitor, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer competitor.Rollback(ctx)
+	_, err = competitor.Exec(ctx, `UPDATE auctions SET version = version + 1 WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+
+	results := make(chan domain.BidResult, 1)
+	go func() {
+		results <- submitSyncBid(t, engine, auctionID, buyerID, "150.00")
+	}()
+
+	require.Eventually(t, func() bool {
+		var waiting int
+		db.QueryRow(ctx, `SELECT COUNT(*) FROM pg_locks WHERE NOT granted`).Scan(&waiting)
+		return waiting > 0
+	}, 5*time.Second, 10*time.Millisecond, "bid never blocked on the competing update")
+	require.NoError(t, competitor.Commit(ctx))
+
+	result := <-results
+	assert.NotEqual(t, "accepted", result.Status)
+
+	var bids int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM bids WHERE auction_id = $1`, auctionID).Scan(&bids))
+	assert.Zero(t, bids)
+	assert.Empty(t, auctionEventTypes(t, db, auctionID))
+}
+
+func TestGetAuctionEvents(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	adminID := fixtures.AdminUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, buyerID, "150.00").Status)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	newRouter := func(userID int64) *chi.Mux {
+		r := chi.NewRouter()
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+			})
+		})
+		r.With(middleware.RequireRole(db, logger, "admin")).Get("/api/auctions/{id}/events", auctionHandler.GetAuctionEvents)
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Use(middleware.RequireRole(db, logger, "admin"))
+			r.Post("/auctions/{id}/close", auctionHandler.ForceCloseAuction)
+		})
+		return r
+	}
+	r := newRouter(adminID)
+
+	rec := adminPost(r, fmt.Sprintf("/api/admin/auctions/%d/close", auctionID), `{"reason": "fraud"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d/events", auctionID), nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Events []domain.AuctionEvent `json:"events"`
+		Total  int64                 `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Events, 2)
+	assert.EqualValues(t, 2, resp.Total)
+	assert.Equal(t, "bid_accepted", resp.Events[0].Type)
+	assert.Equal(t, "closed", resp.Events[1].Type)
+	assert.Equal(t, "admin", resp.Events[1].Payload["reason"])
+	assert.Equal(t, "fraud", resp.Events[1].Payload["note"])
+
+	// Non-admins can't read the audit trail
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d/events", auctionID), nil)
+	rec = httptest.NewRecorder()
+	newRouter(buyerID).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	req = httptest.NewRequest("GET", "/api/auctions/999999/events", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,191 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivateAuction_HiddenAndInviteOnly(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	invitedID := fixtures.BuyerUser(t, db)
+	outsiderID := fixtures.BuyerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+	_, err := db.Exec(ctx, `UPDATE auctions SET visibility = 'private' WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := r.Header.Get("X-Test-User"); id != "" {
+				var userID int64
+				fmt.Sscan(id, &userID)
+				r = r.WithContext(middleware.WithUserID(r.Context(), userID))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Get("/api/auctions", auctionHandler.ListAuctions)
+	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
+	r.Post("/api/auctions/{id}/invites", auctionHandler.InviteBidder)
+	r.Delete("/api/auctions/{id}/invites/{userId}", auctionHandler.RevokeInvite)
+
+	send := func(method, path string, userID int64, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("X-Test-User", fmt.Sprint(userID))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	path := "/api/auctions/" + itoa(auctionID)
+	invite := fmt.Sprintf(`{"user_id": %d}`, invitedID)
+
+	// Left out of the public list and the id batch
+	for _, listPath := range []string{"/api/auctions", "/api/auctions?ids=" + itoa(auctionID)} {
+		rec := send("GET", listPath, 0, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var list struct {
+			Items []struct {
+				ID int64 `json:"id"`
+			} `json:"items"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+		for _, item := range list.Items {
+			assert.NotEqual(t, auctionID, item.ID, listPath)
+		}
+	}
+
+	// Only the seller and invitees can see it, and only the seller invites
+	assert.Equal(t, http.StatusOK, send("GET", path, sellerID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", path, invitedID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", path, 0, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("POST", path+"/invites", outsiderID, invite).Code)
+	require.Equal(t, http.StatusCreated, send("POST", path+"/invites", sellerID, invite).Code)
+	assert.Equal(t, http.StatusCreated, send("POST", path+"/invites", sellerID, invite).Code, "re-inviting is a no-op")
+	assert.Equal(t, http.StatusOK, send("GET", path, invitedID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", path, outsiderID, "").Code)
+
+	// Bidding takes an invite
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	result := submitSyncBid(t, engine, auctionID, outsiderID, "150.00")
+	assert.Equal(t, "rejected", result.Status)
+	assert.Equal(t, "not_invited", result.Reason)
+	assert.Nil(t, result.Auction, "an uninvited bidder learns nothing about the auction")
+
+	result = submitSyncBid(t, engine, auctionID, invitedID, "150.00")
+	assert.Equal(t, "accepted", result.Status, result.Reason)
+
+	// Revoking shuts them out again
+	assert.Equal(t, http.StatusNoContent, send("DELETE", path+"/invites/"+itoa(invitedID), sellerID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", path+"/invites/"+itoa(invitedID), sellerID, "").Code)
+	result = submitSyncBid(t, engine, auctionID, invitedID, "200.00")
+	assert.Equal(t, "not_invited", result.Reason)
+}
+
+func TestInviteBidder_PublicAuction(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/invites", func(w http.ResponseWriter, r *http.Request) {
+		auctionHandler.InviteBidder(w, r.WithContext(middleware.WithUserID(r.Context(), sellerID)))
+	})
+
+	req := httptest.NewRequest("POST", "/api/auctions/"+itoa(auctionID)+"/invites",
+		strings.NewReader(fmt.Sprintf(`{"user_id": %d}`, buyerID)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestPrivateAuction_HistoryAndStreams(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	bidderID := fixtures.BuyerUser(t, db)
+	outsiderID := fixtures.BuyerUser(t, db)
+	publicID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 150, bidderID)
+	_, err := db.Exec(ctx, `UPDATE auctions SET visibility = 'private' WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `INSERT INTO auction_invites (auction_id, user_id) VALUES ($1, $2)`, auctionID, bidderID)
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	auctionHandler := handler.NewAuctionHandler(db, logger, cfg, nil, nil)
+	sseHandler := handler.NewSSEHandler(db, nil, logger, cfg)
+
+	// Route through the same optional auth as the server, so the handlers
+	// only see a user when the request's token names one. Outside
+	// production, tokens are read without verifying their signature.
+	t.Setenv("ENVIRONMENT", "test")
+	clerkAuth := middleware.NewClerkAuth(logger, "", "", db)
+	r := chi.NewRouter()
+	r.With(clerkAuth.OptionalAuth).Get("/api/auctions/{id}/bids", auctionHandler.GetBidHistory)
+	r.With(clerkAuth.OptionalAuth).Get("/api/auctions/{id}/stream", sseHandler.StreamAuction)
+
+	send := func(path string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if userID != 0 {
+			var clerkID string
+			require.NoError(t, db.QueryRow(ctx, `SELECT clerk_user_id FROM users WHERE id = $1`, userID).Scan(&clerkID))
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": clerkID}).SignedString([]byte("test"))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	path := "/api/auctions/" + itoa(auctionID)
+
+	// The bid history follows the auction's own visibility
+	assert.Equal(t, http.StatusOK, send(path+"/bids", sellerID).Code)
+	assert.Equal(t, http.StatusOK, send(path+"/bids", bidderID).Code)
+	assert.Equal(t, http.StatusNotFound, send(path+"/bids", outsiderID).Code)
+	assert.Equal(t, http.StatusNotFound, send(path+"/bids", 0).Code)
+
+	// So does its stream; refused requests never subscribe
+	assert.Equal(t, http.StatusNotFound, send(path+"/stream", outsiderID).Code)
+	assert.Equal(t, http.StatusNotFound, send(path+"/stream", 0).Code)
+
+	// And it's left off the global stream
+	public := handler.PublicAuctionFilter(db, 0)
+	assert.True(t, public(publicID))
+	assert.False(t, public(auctionID))
+	assert.False(t, public(auctionID+1000), "unknown auctions fail closed")
+}
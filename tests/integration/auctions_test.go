@@ -1,17 +1,26 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
 	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,7 +29,7 @@ func TestListAuctionsEmpty(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
-	auctionHandler := handler.NewAuctionHandler(db, logger)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
 
 	req := httptest.NewRequest("GET", "/api/auctions", nil)
 	rec := httptest.NewRecorder()
@@ -46,7 +55,7 @@ func TestListAuctionsWithData(t *testing.T) {
 	vehicleID := fixtures.TestVehicle(t, db, sellerID)
 	fixtures.TestAuction(t, db, vehicleID)
 
-	auctionHandler := handler.NewAuctionHandler(db, logger)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
 
 	req := httptest.NewRequest("GET", "/api/auctions", nil)
 	rec := httptest.NewRecorder()
@@ -59,7 +68,7 @@ func TestListAuctionsWithData(t *testing.T) {
 	err := json.Unmarshal(rec.Body.Bytes(), &resp)
 	require.NoError(t, err)
 
-	auctions := resp["auctions"].([]interface{})
+	auctions := resp["items"].([]interface{})
 	assert.Len(t, auctions, 1)
 
 	auction := auctions[0].(map[string]interface{})
@@ -78,7 +87,7 @@ func TestGetAuction(t *testing.T) {
 	vehicleID := fixtures.TestVehicle(t, db, sellerID)
 	auctionID := fixtures.TestAuction(t, db, vehicleID)
 
-	auctionHandler := handler.NewAuctionHandler(db, logger)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
 
 	r := chi.NewRouter()
 	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
@@ -99,13 +108,18 @@ func TestGetAuction(t *testing.T) {
 	assert.Equal(t, "Honda", auction["make"])
 	assert.Equal(t, "Accord", auction["model"])
 	assert.Contains(t, auction, "seller_first_name")
+
+	// A fresh auction has no bid rather than a $0 one
+	assert.Equal(t, false, auction["has_bids"])
+	assert.Contains(t, auction, "current_bid")
+	assert.Nil(t, auction["current_bid"])
 }
 
 func TestGetAuctionNotFound(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
-	auctionHandler := handler.NewAuctionHandler(db, logger)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
 
 	r := chi.NewRouter()
 	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
@@ -128,7 +142,7 @@ func TestGetBidHistory(t *testing.T) {
 	vehicleID := fixtures.TestVehicle(t, db, sellerID)
 	auctionID := fixtures.TestAuctionWithBid(t, db, vehicleID, 100, bidderID)
 
-	auctionHandler := handler.NewAuctionHandler(db, logger)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
 
 	r := chi.NewRouter()
 	r.Get("/api/auctions/{id}/bids", auctionHandler.GetBidHistory)
@@ -162,7 +176,7 @@ func TestAuctionWithCurrentBid(t *testing.T) {
 	vehicleID := fixtures.TestVehicle(t, db, sellerID)
 	auctionID := fixtures.TestAuctionWithBid(t, db, vehicleID, 5000, bidderID)
 
-	auctionHandler := handler.NewAuctionHandler(db, logger)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
 
 	r := chi.NewRouter()
 	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
@@ -180,5 +194,1058 @@ func TestAuctionWithCurrentBid(t *testing.T) {
 	auction := resp["auction"].(map[string]interface{})
 	assert.Equal(t, "5000.00", auction["current_bid"])
 	assert.Equal(t, float64(1), auction["bid_count"])
+	assert.Equal(t, true, auction["has_bids"])
+}
+
+func TestGetAuction_SecondsRemaining(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	activeID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	endedID := fixtures.TestAuction(t, db, fixtures.TestVehicleWithDetails(t, db, sellerID, 2021, "Ford", "F-150", 30000))
+
+	_, err := db.Exec(context.Background(), `
+		UPDATE auctions SET status = 'ended', ends_at = NOW() - INTERVAL '1 hour' WHERE id = $1
+	`, endedID)
+	require.NoError(t, err)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
+
+	get := func(id int64) (map[string]interface{}, string) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d", id), nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp["auction"].(map[string]interface{}), resp["server_time"].(string)
+	}
+
+	active, serverTime := get(activeID)
+	_, err = time.Parse(time.RFC3339, serverTime)
+	require.NoError(t, err)
+	remaining := active["seconds_remaining"].(float64)
+	assert.Greater(t, remaining, float64(0))
+	assert.LessOrEqual(t, remaining, (23 * time.Hour).Seconds())
+	assert.Equal(t, false, active["is_final"])
+
+	ended, _ := get(endedID)
+	assert.NotContains(t, ended, "seconds_remaining")
+	assert.Equal(t, true, ended["is_final"])
+}
+
+func TestGetAuction_EndedShowsWinnerToParticipants(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	winnerID := fixtures.BuyerUser(t, db)
+	loserID := fixtures.BuyerUser(t, db)
+	outsiderID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 12500, winnerID)
+	fixtures.TestBid(t, db, auctionID, loserID, decimal.NewFromInt(12000), "outbid")
+	_, err := db.Exec(context.Background(), `
+		UPDATE auctions SET status = 'ended', ends_at = NOW() - INTERVAL '1 hour',
+		       winner_id = $2, winning_bid = 12500
+		WHERE id = $1
+	`, auctionID, winnerID)
+	require.NoError(t, err)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if id := r.Header.Get("X-Test-User"); id != "" {
+			var userID int64
+			fmt.Sscan(id, &userID)
+			r = r.WithContext(middleware.WithUserID(r.Context(), userID))
+		}
+		auctionHandler.GetAuction(w, r)
+	})
+
+	get := func(userID int64) map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/auctions/"+itoa(auctionID), nil)
+		if userID != 0 {
+			req.Header.Set("X-Test-User", itoa(userID))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp struct {
+			Auction map[string]interface{} `json:"auction"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.Auction
+	}
+
+	for _, userID := range []int64{sellerID, winnerID, loserID} {
+		auction := get(userID)
+		assert.Equal(t, "ended", auction["status"])
+		assert.Equal(t, true, auction["is_final"])
+		assert.NotContains(t, auction, "seconds_remaining")
+		assert.Equal(t, float64(winnerID), auction["winner_id"])
+		assert.Equal(t, "12500.00", auction["winning_bid"])
+	}
+
+	// Non-participants still get the final state, without the winner
+	for _, userID := range []int64{outsiderID, 0} {
+		auction := get(userID)
+		assert.Equal(t, true, auction["is_final"])
+		assert.NotContains(t, auction, "seconds_remaining")
+		assert.NotContains(t, auction, "winner_id")
+		assert.NotContains(t, auction, "winning_bid")
+	}
+}
+
+func TestListAuctions_ByIDs(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	first := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	ended := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	hidden := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID)) // not asked for
+	_, err := db.Exec(ctx, `UPDATE auctions SET status = 'ended' WHERE id = $1`, ended)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `UPDATE auctions SET hidden = true WHERE id = $1`, hidden)
+	require.NoError(t, err)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+
+	list := func(ids string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		auctionHandler.ListAuctions(rec, httptest.NewRequest("GET", "/api/auctions?ids="+ids, nil))
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	// Requested order, any status, unknown and hidden IDs skipped, duplicates once
+	code, resp := list(fmt.Sprintf("%d,%d,999999,%d,%d", ended, first, hidden, ended))
+	require.Equal(t, http.StatusOK, code)
+	auctions := resp["items"].([]interface{})
+	require.Len(t, auctions, 2)
+	assert.Equal(t, float64(ended), auctions[0].(map[string]interface{})["id"])
+	assert.Equal(t, "ended", auctions[0].(map[string]interface{})["status"])
+	assert.Equal(t, float64(first), auctions[1].(map[string]interface{})["id"])
+	assert.Contains(t, auctions[1], "make")
+	assert.Equal(t, float64(2), resp["total"])
+	assert.Equal(t, false, resp["has_more"])
+
+	// Only unknown IDs: an empty list, not an error
+	code, resp = list("999998,999999")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp["items"])
+
+	tooMany := make([]string, 51)
+	for i := range tooMany {
+		tooMany[i] = itoa(int64(i + 1))
+	}
+	for _, ids := range []string{"", "1,abc", "0", strings.Join(tooMany, ",")} {
+		code, _ = list(ids)
+		assert.Equal(t, http.StatusBadRequest, code, "ids=%q", ids)
+	}
+}
+
+func TestListAuctions_ServerTime(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/auctions", nil)
+	rec := httptest.NewRecorder()
+	auctionHandler.ListAuctions(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	_, err := time.Parse(time.RFC3339, resp["server_time"].(string))
+	require.NoError(t, err)
+
+	auctions := resp["items"].([]interface{})
+	require.Len(t, auctions, 1)
+	assert.Greater(t, auctions[0].(map[string]interface{})["seconds_remaining"].(float64), float64(0))
+}
+
+func TestListAuctions_Timezone(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/auctions?tz=America/New_York", nil)
+	rec := httptest.NewRecorder()
+	auctionHandler.ListAuctions(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "America/New_York", resp["timezone"])
+
+	auctions := resp["items"].([]interface{})
+	require.Len(t, auctions, 1)
+	a := auctions[0].(map[string]interface{})
+
+	utc, err := time.Parse(time.RFC3339, a["ends_at"].(string))
+	require.NoError(t, err)
+	local, err := time.Parse(time.RFC3339, a["ends_at_local"].(string))
+	require.NoError(t, err)
+
+	// Same instant, rendered with the zone's offset
+	assert.True(t, utc.Equal(local))
+	_, offset := local.Zone()
+	assert.Contains(t, []int{-5 * 3600, -4 * 3600}, offset)
+	assert.NotEmpty(t, a["starts_at_local"])
+
+	// Unknown zones are rejected
+	req = httptest.NewRequest("GET", "/api/auctions?tz=Mars/Olympus", nil)
+	rec = httptest.NewRecorder()
+	auctionHandler.ListAuctions(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestListAuctions_ScheduledStartingSoon(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	scheduled := func(startsIn, runsFor time.Duration) int64 {
+		auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+		_, err := db.Exec(context.Background(), `
+			UPDATE auctions SET status = 'scheduled', starts_at = $2, ends_at = $3 WHERE id = $1
+		`, auctionID, time.Now().Add(startsIn), time.Now().Add(startsIn+runsFor))
+		require.NoError(t, err)
+		return auctionID
+	}
+	// Start order is the reverse of end order, so sorting by ends_at would fail
+	nextWeek := scheduled(7*24*time.Hour, time.Hour)
+	tomorrow := scheduled(24*time.Hour, 3*24*time.Hour)
+	inAnHour := scheduled(time.Hour, 10*24*time.Hour)
+	fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID)) // active, never listed here
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	list := func(query string) (int, []int64, float64) {
+		req := httptest.NewRequest("GET", "/api/auctions?"+query, nil)
+		rec := httptest.NewRecorder()
+		auctionHandler.ListAuctions(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil, 0
+		}
+
+		var resp struct {
+			Auctions []handler.AuctionResponse `json:"items"`
+			Total    float64                   `json:"total"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		ids := make([]int64, 0, len(resp.Auctions))
+		for _, a := range resp.Auctions {
+			ids = append(ids, a.ID)
+		}
+		return rec.Code, ids, resp.Total
+	}
+
+	_, ids, _ := list("status=scheduled&sort=starting_soon")
+	assert.Equal(t, []int64{inAnHour, tomorrow, nextWeek}, ids)
+
+	// Scheduled listings default to start order
+	_, ids, _ = list("status=scheduled")
+	assert.Equal(t, []int64{inAnHour, tomorrow, nextWeek}, ids)
+
+	_, ids, _ = list("status=scheduled&sort=ending_soon")
+	assert.Equal(t, []int64{nextWeek, tomorrow, inAnHour}, ids)
+
+	_, ids, total := list("status=scheduled&starts_within=48h")
+	assert.Equal(t, []int64{inAnHour, tomorrow}, ids)
+	assert.Equal(t, float64(2), total)
+
+	code, _, _ := list("status=scheduled&sort=vin")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = list("status=scheduled&starts_within=soon")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestCreateAuction_ListingLimit(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	fixtures.TestVehicle(t, db, sellerID) // already active
+	vehicleID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET status = 'draft' WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{MaxActiveListingsPerSeller: 1}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		auctionHandler.CreateAuction(w, r.WithContext(ctx))
+	})
+
+	body := fmt.Sprintf(`{"vehicle_id": %d, "starts_at": %q, "ends_at": %q}`, vehicleID,
+		time.Now().Format(time.RFC3339), time.Now().Add(24*time.Hour).Format(time.RFC3339))
+	req := httptest.NewRequest("POST", "/api/auctions", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "active listing limit reached")
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM auctions WHERE vehicle_id = $1`, vehicleID).Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestCreateAuction_MinImages(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{MinImagesToSubmit: 2}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		auctionHandler.CreateAuction(w, r.WithContext(ctx))
+	})
+
+	body := fmt.Sprintf(`{"vehicle_id": %d, "starts_at": %q, "ends_at": %q}`, vehicleID,
+		time.Now().Format(time.RFC3339), time.Now().Add(24*time.Hour).Format(time.RFC3339))
+	req := httptest.NewRequest("POST", "/api/auctions", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "0 uploaded, 2 more needed")
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM auctions WHERE vehicle_id = $1`, vehicleID).Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestCreateAuction_EndsAtInPast(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{MinAuctionDuration: time.Hour}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		auctionHandler.CreateAuction(w, r.WithContext(ctx))
+	})
+	create := func(startsAt, endsAt time.Time) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"vehicle_id": %d, "starts_at": %q, "ends_at": %q}`, vehicleID,
+			startsAt.Format(time.RFC3339), endsAt.Format(time.RFC3339))
+		req := httptest.NewRequest("POST", "/api/auctions", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Correctly ordered, but over before it starts
+	rec := create(time.Now().Add(-3*time.Hour), time.Now().Add(-time.Hour))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "ends_at must be at least 1h0m0s from now")
+
+	// In the future, but shorter than the minimum
+	rec = create(time.Now(), time.Now().Add(30*time.Minute))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM auctions WHERE vehicle_id = $1`, vehicleID).Scan(&count))
+	assert.Equal(t, 0, count)
+
+	rec = create(time.Now(), time.Now().Add(2*time.Hour))
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func TestCreateAuction_SellerAuctionLimit(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	adminID := fixtures.AdminUser(t, db)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{MaxActiveAuctionsPerSeller: 2}, nil, nil)
+	create := func(userID int64) *httptest.ResponseRecorder {
+		vehicleID := fixtures.TestVehicle(t, db, userID)
+		r := chi.NewRouter()
+		r.Post("/api/auctions", func(w http.ResponseWriter, r *http.Request) {
+			ctx := middleware.WithUserID(r.Context(), userID)
+			auctionHandler.CreateAuction(w, r.WithContext(ctx))
+		})
+
+		body := fmt.Sprintf(`{"vehicle_id": %d, "starts_at": %q, "ends_at": %q}`, vehicleID,
+			time.Now().Format(time.RFC3339), time.Now().Add(24*time.Hour).Format(time.RFC3339))
+		req := httptest.NewRequest("POST", "/api/auctions", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Up to the cap, then refused
+	for i := 0; i < 2; i++ {
+		rec := create(sellerID)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	rec := create(sellerID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var resp map[string]string
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "seller_auction_limit_reached", resp["code"])
+
+	// Admins aren't capped
+	for i := 0; i < 3; i++ {
+		rec := create(adminID)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateAuction_SellerAuctionLimitConcurrent(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{MaxActiveAuctionsPerSeller: 2}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		auctionHandler.CreateAuction(w, r.WithContext(ctx))
+	})
+
+	const attempts = 6
+	vehicleIDs := make([]int64, attempts)
+	for i := range vehicleIDs {
+		vehicleIDs[i] = fixtures.TestVehicle(t, db, sellerID)
+	}
+
+	// Requests racing past the count would all see room under the cap
+	codes := make([]int, attempts)
+	var wg sync.WaitGroup
+	for i, vehicleID := range vehicleIDs {
+		wg.Add(1)
+		go func(i int, vehicleID int64) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"vehicle_id": %d, "starts_at": %q, "ends_at": %q}`, vehicleID,
+				time.Now().Format(time.RFC3339), time.Now().Add(24*time.Hour).Format(time.RFC3339))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/auctions", strings.NewReader(body)))
+			codes[i] = rec.Code
+		}(i, vehicleID)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			created++
+		} else {
+			assert.Equal(t, http.StatusConflict, code)
+		}
+	}
+	assert.Equal(t, 2, created)
+
+	var open int
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM auctions a JOIN vehicles v ON a.vehicle_id = v.id
+		WHERE v.seller_id = $1 AND a.status IN ('scheduled', 'active')
+	`, sellerID).Scan(&open))
+	assert.Equal(t, 2, open)
+}
+
+func TestCreateAuction_MinIncrement(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		auctionHandler.CreateAuction(w, r.WithContext(ctx))
+	})
+
+	create := func(vehicleID int64, increment string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"vehicle_id": %d, "starts_at": %q, "ends_at": %q, "min_increment": %s}`, vehicleID,
+			time.Now().Format(time.RFC3339), time.Now().Add(24*time.Hour).Format(time.RFC3339), increment)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/auctions", strings.NewReader(body)))
+		return rec
+	}
+
+	for _, bad := range []string{`0`, `-5`, `"12.345"`, `250000`, `"1e-10000000"`, `"1e10000000"`} {
+		rec := create(fixtures.TestVehicle(t, db, sellerID), bad)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "min_increment %s", bad)
+		assert.Contains(t, rec.Body.String(), "min_increment")
+	}
+
+	rec := create(fixtures.TestVehicle(t, db, sellerID), `"250.50"`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp struct {
+		AuctionID int64 `json:"auction_id"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+	var stored string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT min_increment::text FROM auctions WHERE id = $1`, resp.AuctionID).Scan(&stored))
+	assert.Equal(t, "250.50", stored)
 }
 
+func TestRelistAuction(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{MinAuctionDuration: time.Hour}, nil, nil)
+	relist := func(auctionID int64, body string) *httptest.ResponseRecorder {
+		r := chi.NewRouter()
+		r.Post("/api/auctions/{id}/relist", func(w http.ResponseWriter, r *http.Request) {
+			ctx := middleware.WithUserID(r.Context(), sellerID)
+			auctionHandler.RelistAuction(w, r.WithContext(ctx))
+		})
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/auctions/"+itoa(auctionID)+"/relist", strings.NewReader(body)))
+		return rec
+	}
+
+	// Reserve not met: ended with a bid but no winner
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	unsoldID := fixtures.TestAuctionWithBid(t, db, vehicleID, 150, buyerID)
+	_, err := db.Exec(ctx, `
+		UPDATE auctions SET status = 'ended', starts_at = NOW() - INTERVAL '3 days', ends_at = NOW() - INTERVAL '1 day',
+		       min_increment = 50, extend_on_leader_change_only = true
+		WHERE id = $1
+	`, unsoldID)
+	require.NoError(t, err)
+
+	startsAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`{"reserve_price": 9000, "starts_at": %q}`, startsAt.Format(time.RFC3339))
+	rec := relist(unsoldID, body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp struct {
+		AuctionID    int64     `json:"auction_id"`
+		RelistedFrom int64     `json:"relisted_from"`
+		Status       string    `json:"status"`
+		EndsAt       time.Time `json:"ends_at"`
+		ReservePrice string    `json:"reserve_price"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, unsoldID, resp.RelistedFrom)
+	assert.Equal(t, "scheduled", resp.Status)
+	assert.Equal(t, "9000.00", resp.ReservePrice)
+	assert.WithinDuration(t, startsAt.Add(48*time.Hour), resp.EndsAt, time.Second, "keeps the previous length")
+
+	// Same vehicle, fresh bid state, bidding rules carried over
+	var (
+		newVehicleID int64
+		currentBid   float64
+		bidCount     int
+		leaderID     *int64
+		minIncrement string
+		leaderOnly   bool
+	)
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT vehicle_id, current_bid, bid_count, current_bid_user_id, min_increment::text, extend_on_leader_change_only
+		FROM auctions WHERE id = $1
+	`, resp.AuctionID).Scan(&newVehicleID, &currentBid, &bidCount, &leaderID, &minIncrement, &leaderOnly))
+	assert.Equal(t, vehicleID, newVehicleID)
+	assert.Zero(t, currentBid)
+	assert.Zero(t, bidCount)
+	assert.Nil(t, leaderID)
+	assert.Equal(t, "50.00", minIncrement)
+	assert.True(t, leaderOnly)
+
+	// Relisting the same auction twice is refused
+	assert.Equal(t, http.StatusConflict, relist(unsoldID, "").Code)
+
+	// A sold auction can't be relisted
+	soldVehicleID := fixtures.TestVehicle(t, db, sellerID)
+	soldID := fixtures.TestAuctionWithBid(t, db, soldVehicleID, 150, buyerID)
+	_, err = db.Exec(ctx, `UPDATE auctions SET status = 'ended', winner_id = $2, winning_bid = 150 WHERE id = $1`, soldID, buyerID)
+	require.NoError(t, err)
+	rec = relist(soldID, "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "without a sale")
+
+	var count int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM auctions WHERE vehicle_id = $1`, soldVehicleID).Scan(&count))
+	assert.Equal(t, 1, count)
+
+	// Nor can a seller who isn't verified, e.g. one who took the vehicle over
+	// as a draft
+	unverifiedID := fixtures.SellerUser(t, db)
+	unverifiedAuctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, unverifiedID))
+	_, err = db.Exec(ctx, `UPDATE users SET id_verified_at = NULL WHERE id = $1`, unverifiedID)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `UPDATE auctions SET status = 'ended' WHERE id = $1`, unverifiedAuctionID)
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/relist", func(w http.ResponseWriter, r *http.Request) {
+		auctionHandler.RelistAuction(w, r.WithContext(middleware.WithUserID(r.Context(), unverifiedID)))
+	})
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/auctions/"+itoa(unverifiedAuctionID)+"/relist", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "seller_not_verified")
+
+	// An auction that's still running can't be relisted either
+	assert.Equal(t, http.StatusConflict, relist(fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID)), "").Code)
+
+	// The new auction must run at least the minimum duration, whether the
+	// end is given or carried over from a short previous auction
+	shortVehicleID := fixtures.TestVehicle(t, db, sellerID)
+	shortID := fixtures.TestAuctionWithBid(t, db, shortVehicleID, 150, buyerID)
+	_, err = db.Exec(ctx, `
+		UPDATE auctions SET status = 'ended', starts_at = NOW() - INTERVAL '1 hour', ends_at = NOW() - INTERVAL '30 minutes'
+		WHERE id = $1
+	`, shortID)
+	require.NoError(t, err)
+	for _, body := range []string{
+		fmt.Sprintf(`{"ends_at": %q}`, time.Now().Add(30*time.Minute).Format(time.RFC3339)),
+		"",
+	} {
+		rec = relist(shortID, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		assert.Contains(t, rec.Body.String(), "ends_at must be at least 1h0m0s from now")
+	}
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM auctions WHERE vehicle_id = $1`, shortVehicleID).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+// recordingBroadcaster captures events broadcast by handlers
+type recordingBroadcaster struct {
+	mu     sync.Mutex
+	events []domain.BidEvent
+}
+
+func (b *recordingBroadcaster) Broadcast(event domain.BidEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+}
+
+func (b *recordingBroadcaster) Events() []domain.BidEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]domain.BidEvent{}, b.events...)
+}
+
+func cancelAuction(t *testing.T, h *handler.AuctionHandler, auctionID, userID int64) *httptest.ResponseRecorder {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), userID)
+		h.CancelAuction(w, r.WithContext(ctx))
+	})
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/auctions/%d/cancel", auctionID), nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCancelAuction_Allowed(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	scheduledVehicleID := fixtures.TestVehicle(t, db, sellerID)
+	scheduledID := fixtures.TestAuction(t, db, scheduledVehicleID)
+	_, err := db.Exec(context.Background(), `
+		UPDATE auctions SET status = 'scheduled', starts_at = NOW() + INTERVAL '1 hour' WHERE id = $1
+	`, scheduledID)
+	require.NoError(t, err)
+	activeID := fixtures.TestAuction(t, db, fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000))
+
+	broadcaster := &recordingBroadcaster{}
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, broadcaster, nil)
+
+	for _, auctionID := range []int64{scheduledID, activeID} {
+		rec := cancelAuction(t, auctionHandler, auctionID, sellerID)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var status string
+		require.NoError(t, db.QueryRow(context.Background(), `SELECT status::text FROM auctions WHERE id = $1`, auctionID).Scan(&status))
+		assert.Equal(t, "cancelled", status)
+	}
+
+	var vehicleStatus string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT status FROM vehicles WHERE id = $1`, scheduledVehicleID).Scan(&vehicleStatus))
+	assert.Equal(t, "draft", vehicleStatus)
+
+	events := broadcaster.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "auction_cancelled", events[0].Type)
+	assert.Equal(t, scheduledID, events[0].AuctionID)
+}
+
+func TestCancelAuction_Forbidden(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	withBidsID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 150, buyerID)
+	otherID := fixtures.TestAuction(t, db, fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000))
+
+	broadcaster := &recordingBroadcaster{}
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, broadcaster, nil)
+
+	// Auction with bids
+	rec := cancelAuction(t, auctionHandler, withBidsID, sellerID)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "has bids")
+
+	// Not the seller
+	rec = cancelAuction(t, auctionHandler, otherID, buyerID)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var status string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT status::text FROM auctions WHERE id = $1`, withBidsID).Scan(&status))
+	assert.Equal(t, "active", status)
+	assert.Empty(t, broadcaster.Events())
+}
+
+func TestGetBidHistory_Pagination(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	bidderID := fixtures.VerifiedUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	for _, amount := range []int64{100, 110, 120, 130, 140} {
+		fixtures.TestBid(t, db, auctionID, bidderID, decimal.NewFromInt(amount), "outbid")
+	}
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}/bids", auctionHandler.GetBidHistory)
+
+	page := func(query string) map[string]interface{} {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d/bids%s", auctionID, query), nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// Bid history keeps its own default page, not PAGE_SIZE_DEFAULT
+	resp := page("")
+	assert.Equal(t, float64(50), resp["limit"])
+
+	resp = page("?limit=2")
+	assert.Equal(t, float64(5), resp["total"])
+	assert.Equal(t, float64(2), resp["limit"])
+	assert.Equal(t, true, resp["has_more"])
+	assert.Len(t, resp["bids"].([]interface{}), 2)
+
+	resp = page("?limit=2&offset=4")
+	assert.Equal(t, float64(5), resp["total"])
+	assert.Equal(t, false, resp["has_more"])
+	assert.Len(t, resp["bids"].([]interface{}), 1)
+}
+
+func TestGetAuctionRules(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	_, err := db.Exec(context.Background(), `
+		UPDATE vehicles SET reserve_price = 500, buy_now_price = 2000 WHERE id = $1
+	`, vehicleID)
+	require.NoError(t, err)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+	_, err = db.Exec(context.Background(), `
+		UPDATE auctions SET current_bid = 250, bid_count = 3, extension_count = 1,
+		       snipe_threshold_minutes = 3, extension_minutes = 5, max_extensions = 4,
+		       extend_on_reserve_met = true
+		WHERE id = $1
+	`, auctionID)
+	require.NoError(t, err)
+
+	cfg := &config.Config{BidMaxMultiple: 10, BidEndGrace: 2 * time.Second}
+	auctionHandler := handler.NewAuctionHandler(db, logger, cfg, nil, nil)
+
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}/rules", auctionHandler.GetAuctionRules)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d/rules", auctionID), nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rules handler.AuctionRulesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rules))
+
+	assert.Equal(t, auctionID, rules.AuctionID)
+	assert.Equal(t, "active", rules.Status)
+	assert.Equal(t, "100.00", rules.StartingPrice)
+	assert.Equal(t, "250.01", rules.MinimumNextBid)
+	assert.Equal(t, []handler.IncrementTier{{From: "0.00", Increment: "0.01"}}, rules.IncrementSchedule)
+
+	assert.Equal(t, handler.AntiSnipeRules{
+		ThresholdMinutes:   3,
+		ExtensionMinutes:   5,
+		MaxExtensions:      4,
+		ExtensionsUsed:     1,
+		ExtendOnReserveMet: true,
+	}, rules.AntiSnipe)
+
+	assert.True(t, rules.Reserve.HasReserve)
+	require.NotNil(t, rules.Reserve.ReserveMet)
+	assert.False(t, *rules.Reserve.ReserveMet)
+	assert.NotContains(t, rec.Body.String(), "500.00", "reserve amount must stay private")
+
+	assert.True(t, rules.BuyNow.Available)
+	require.NotNil(t, rules.BuyNow.Price)
+	assert.Equal(t, "2000.00", *rules.BuyNow.Price)
+
+	require.NotNil(t, rules.MaxBidMultiple)
+	assert.Equal(t, 10.0, *rules.MaxBidMultiple)
+	assert.Equal(t, int64(2000), rules.LateBidGraceMillis)
+}
+
+func TestGetAuction_UniqueBidders(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	alice := fixtures.BuyerUser(t, db)
+	bob := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	// Alice bids twice, Bob once
+	for i, bid := range []struct {
+		userID int64
+		amount int64
+	}{{alice, 150}, {bob, 200}, {alice, 250}} {
+		ticketID := fmt.Sprintf("unique-bidders-%d", i)
+		require.NoError(t, engine.Submit(domain.BidRequest{
+			TicketID:  ticketID,
+			AuctionID: auctionID,
+			UserID:    bid.userID,
+			Amount:    decimal.NewFromInt(bid.amount),
+			CreatedAt: time.Now(),
+		}))
+		result, err := engine.GetResult(ticketID, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, "accepted", result.Status)
+	}
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d", auctionID), nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Auction struct {
+			BidCount      int `json:"bid_count"`
+			UniqueBidders int `json:"unique_bidders"`
+		} `json:"auction"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Auction.BidCount)
+	assert.Equal(t, 2, resp.Auction.UniqueBidders)
+}
+
+func TestBidderPrivacy_PseudonymReplacesName(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	alice := fixtures.CreateUser(t, db, "alice@example.com", "Alice", "Adams")
+	bob := fixtures.CreateUser(t, db, "bob@example.com", "Bob", "Brown")
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, alice, "150.00").Status)
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, bob, "200.00").Status)
+
+	authHandler := handler.NewAuthHandler(db, logger)
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := r.Header.Get("X-Test-User"); id != "" {
+				var userID int64
+				fmt.Sscan(id, &userID)
+				r = r.WithContext(middleware.WithUserID(r.Context(), userID))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Put("/api/auth/me", authHandler.UpdateProfile)
+	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
+	r.Get("/api/auctions/{id}/bids", auctionHandler.GetBidHistory)
+
+	send := func(method, path string, userID int64, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("X-Test-User", fmt.Sprint(userID))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		return rec
+	}
+	setPrivate := func(userID int64, private bool) {
+		send("PUT", "/api/auth/me", userID, fmt.Sprintf(`{"hide_bidder_identity": %t}`, private))
+	}
+	bidderNames := func() map[string]string {
+		var resp struct {
+			Bids []struct {
+				Amount    string  `json:"amount"`
+				FirstName *string `json:"bidder_first_name"`
+				Alias     *string `json:"bidder_alias"`
+			} `json:"bids"`
+		}
+		rec := send("GET", fmt.Sprintf("/api/auctions/%d/bids", auctionID), 0, "")
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		names := make(map[string]string)
+		for _, b := range resp.Bids {
+			switch {
+			case b.Alias != nil:
+				assert.Nil(t, b.FirstName)
+				names[b.Amount] = *b.Alias
+			case b.FirstName != nil:
+				names[b.Amount] = *b.FirstName
+			}
+		}
+		return names
+	}
+	type auctionView struct {
+		Auction struct {
+			CurrentBidUserID *int64  `json:"current_bid_user_id"`
+			HighBidderAlias  *string `json:"high_bidder_alias"`
+		} `json:"auction"`
+	}
+	getAuction := func(viewerID int64) auctionView {
+		var resp auctionView
+		rec := send("GET", fmt.Sprintf("/api/auctions/%d", auctionID), viewerID, "")
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	assert.Equal(t, map[string]string{"150.00": "Alice", "200.00": "Bob"}, bidderNames())
+
+	// Bob goes private: he becomes the second person to bid, by pseudonym
+	setPrivate(bob, true)
+	assert.Equal(t, map[string]string{"150.00": "Alice", "200.00": "Bidder 2"}, bidderNames())
+
+	view := getAuction(0)
+	assert.Nil(t, view.Auction.CurrentBidUserID)
+	require.NotNil(t, view.Auction.HighBidderAlias)
+	assert.Equal(t, "Bidder 2", *view.Auction.HighBidderAlias)
+
+	// The seller doesn't learn who is leading while the auction runs
+	assert.Nil(t, getAuction(sellerID).Auction.CurrentBidUserID)
+
+	// Bob still sees himself as the leader
+	view = getAuction(bob)
+	require.NotNil(t, view.Auction.CurrentBidUserID)
+	assert.Equal(t, bob, *view.Auction.CurrentBidUserID)
+	assert.Nil(t, view.Auction.HighBidderAlias)
+
+	// The winner is disclosed to the seller once the auction has ended
+	_, err := db.Exec(context.Background(), `UPDATE auctions SET status = 'ended' WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+	view = getAuction(sellerID)
+	require.NotNil(t, view.Auction.CurrentBidUserID)
+	assert.Equal(t, bob, *view.Auction.CurrentBidUserID)
+
+	// Turning it back off restores the name
+	setPrivate(bob, false)
+	assert.Equal(t, map[string]string{"150.00": "Alice", "200.00": "Bob"}, bidderNames())
+}
+
+func TestGetAuctionAnalytics(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	alice := fixtures.BuyerUser(t, db)
+	bob := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+	submitSyncBid(t, engine, auctionID, alice, "150")
+	submitSyncBid(t, engine, auctionID, bob, "200")
+	submitSyncBid(t, engine, auctionID, alice, "250")
+
+	for _, userID := range []int64{alice, bob} {
+		_, err := db.Exec(ctx, `INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)`, userID, auctionID)
+		require.NoError(t, err)
+	}
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	get := func(userID int64, query string) *httptest.ResponseRecorder {
+		r := chi.NewRouter()
+		r.Get("/api/seller/auctions/{id}/analytics", func(w http.ResponseWriter, r *http.Request) {
+			auctionHandler.GetAuctionAnalytics(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+		})
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/seller/auctions/%d/analytics%s", auctionID, query), nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(sellerID, "?bucket=day")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp handler.AuctionAnalyticsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, auctionID, resp.AuctionID)
+	assert.Equal(t, 2, resp.WatcherCount)
+	assert.Equal(t, 2, resp.UniqueBidders)
+	assert.Equal(t, 3, resp.TotalBids)
+	assert.Equal(t, "day", resp.Bucket)
+	require.NotEmpty(t, resp.BidsOverTime)
+	total := 0
+	for _, b := range resp.BidsOverTime {
+		total += b.Bids
+	}
+	assert.Equal(t, 3, total)
+	assert.Equal(t, "250.00", resp.BidsOverTime[len(resp.BidsOverTime)-1].HighBid)
+
+	// Another user, even one who bid, can't see the seller's analytics
+	rec = get(bob, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = get(sellerID, "?bucket=week")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -2,11 +2,14 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
@@ -24,15 +27,14 @@ func TestClerkSync_NewUser(t *testing.T) {
 	authHandler := handler.NewAuthHandler(db, logger)
 
 	body := map[string]string{
-		"clerk_user_id": "clerk_test_123",
-		"email":         "newuser@example.com",
-		"first_name":    "New",
-		"last_name":     "User",
+		"first_name": "New",
+		"last_name":  "User",
 	}
 	bodyBytes, _ := json.Marshal(body)
 
 	req := httptest.NewRequest("POST", "/api/auth/clerk-sync", bytes.NewReader(bodyBytes))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(middleware.WithClerkClaims(req.Context(), "clerk_test_123", "newuser@example.com"))
 	rec := httptest.NewRecorder()
 
 	authHandler.ClerkSync(rec, req)
@@ -55,22 +57,23 @@ func TestClerkSync_ExistingUser(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
-	// Create existing user
+	// A local account that predates Clerk is linked by email
 	existingEmail := "existing@example.com"
-	fixtures.CreateUser(t, db, existingEmail, "Existing", "User")
+	existingID := fixtures.CreateUser(t, db, existingEmail, "Existing", "User")
+	_, err := db.Exec(context.Background(), `UPDATE users SET clerk_user_id = NULL WHERE id = $1`, existingID)
+	require.NoError(t, err)
 
 	authHandler := handler.NewAuthHandler(db, logger)
 
 	body := map[string]string{
-		"clerk_user_id": "clerk_existing_123",
-		"email":         existingEmail,
-		"first_name":    "Updated",
-		"last_name":     "Name",
+		"first_name": "Updated",
+		"last_name":  "Name",
 	}
 	bodyBytes, _ := json.Marshal(body)
 
 	req := httptest.NewRequest("POST", "/api/auth/clerk-sync", bytes.NewReader(bodyBytes))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(middleware.WithClerkClaims(req.Context(), "clerk_existing_123", existingEmail))
 	rec := httptest.NewRecorder()
 
 	authHandler.ClerkSync(rec, req)
@@ -78,27 +81,127 @@ func TestClerkSync_ExistingUser(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 
 	var resp map[string]interface{}
-	err := json.Unmarshal(rec.Body.Bytes(), &resp)
+	err = json.Unmarshal(rec.Body.Bytes(), &resp)
 	require.NoError(t, err)
 
 	user := resp["user"].(map[string]interface{})
 	assert.Equal(t, existingEmail, user["email"])
+	assert.Equal(t, float64(existingID), user["id"])
+
+	var clerkID string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT clerk_user_id FROM users WHERE id = $1`, existingID).Scan(&clerkID))
+	assert.Equal(t, "clerk_existing_123", clerkID)
 }
 
-func TestClerkSync_MissingFields(t *testing.T) {
+// clerkSync posts a sync as the given verified Clerk session and returns the
+// status and decoded body
+func clerkSync(t *testing.T, authHandler *handler.AuthHandler, clerkUserID, email string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/auth/clerk-sync", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(middleware.WithClerkClaims(req.Context(), clerkUserID, email))
+	rec := httptest.NewRecorder()
+	authHandler.ClerkSync(rec, req)
+
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestClerkSync_EmailChange(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	authHandler := handler.NewAuthHandler(db, logger)
+
+	code, resp := clerkSync(t, authHandler, "clerk_mover", "old@example.com")
+	require.Equal(t, http.StatusOK, code)
+	userID := resp["user"].(map[string]interface{})["id"]
 
+	// Same Clerk account, new email: same user, email follows
+	code, resp = clerkSync(t, authHandler, "clerk_mover", "new@example.com")
+	require.Equal(t, http.StatusOK, code)
+	user := resp["user"].(map[string]interface{})
+	assert.Equal(t, userID, user["id"])
+	assert.Equal(t, "new@example.com", user["email"])
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM users WHERE email IN ('old@example.com', 'new@example.com')
+	`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestClerkSync_DuplicateEmail(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	authHandler := handler.NewAuthHandler(db, logger)
 
-	// Missing clerk_user_id
-	body := map[string]string{
-		"email": "test@example.com",
+	code, _ := clerkSync(t, authHandler, "clerk_first", "shared@example.com")
+	require.Equal(t, http.StatusOK, code)
+
+	// A second Clerk account with the same email doesn't take over the first
+	code, resp := clerkSync(t, authHandler, "clerk_second", "shared@example.com")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "email_in_use", resp["code"])
+
+	// Nor can an existing account change its email onto someone else's
+	code, _ = clerkSync(t, authHandler, "clerk_third", "third@example.com")
+	require.Equal(t, http.StatusOK, code)
+	code, resp = clerkSync(t, authHandler, "clerk_third", "shared@example.com")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "email_in_use", resp["code"])
+
+	var clerkID string
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT clerk_user_id FROM users WHERE email = 'shared@example.com'
+	`).Scan(&clerkID))
+	assert.Equal(t, "clerk_first", clerkID)
+}
+
+func TestClerkSync_ConcurrentNewUser(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	authHandler := handler.NewAuthHandler(db, logger)
+
+	const syncs = 10
+	var wg sync.WaitGroup
+	codes := make([]int, syncs)
+	ids := make([]interface{}, syncs)
+	for i := 0; i < syncs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code, resp := clerkSync(t, authHandler, "clerk_racer", "racer@example.com")
+			codes[i] = code
+			if user, ok := resp["user"].(map[string]interface{}); ok {
+				ids[i] = user["id"]
+			}
+		}(i)
 	}
-	bodyBytes, _ := json.Marshal(body)
+	wg.Wait()
 
-	req := httptest.NewRequest("POST", "/api/auth/clerk-sync", bytes.NewReader(bodyBytes))
+	for i := 0; i < syncs; i++ {
+		assert.Equal(t, http.StatusOK, codes[i])
+		assert.Equal(t, ids[0], ids[i])
+	}
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM users WHERE clerk_user_id = 'clerk_racer'
+	`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestClerkSync_MissingFields(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	authHandler := handler.NewAuthHandler(db, logger)
+
+	// A session token without an email claim
+	req := httptest.NewRequest("POST", "/api/auth/clerk-sync", strings.NewReader(`{}`))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(middleware.WithClerkClaims(req.Context(), "clerk_no_email", ""))
 	rec := httptest.NewRecorder()
 
 	authHandler.ClerkSync(rec, req)
@@ -106,6 +209,35 @@ func TestClerkSync_MissingFields(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
+func TestClerkSync_IgnoresBodyIdentity(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	victimID := fixtures.CreateUser(t, db, "victim@example.com", "Victim", "User")
+	var victimClerkID string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT clerk_user_id FROM users WHERE id = $1`, victimID).Scan(&victimClerkID))
+
+	authHandler := handler.NewAuthHandler(db, logger)
+	body := `{"clerk_user_id": "` + victimClerkID + `", "email": "attacker@example.com"}`
+
+	// No session at all
+	req := httptest.NewRequest("POST", "/api/auth/clerk-sync", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	authHandler.ClerkSync(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The attacker's own session: the body's identity is ignored
+	req = httptest.NewRequest("POST", "/api/auth/clerk-sync", strings.NewReader(body))
+	req = req.WithContext(middleware.WithClerkClaims(req.Context(), "clerk_attacker", "attacker@example.com"))
+	rec = httptest.NewRecorder()
+	authHandler.ClerkSync(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var email string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT email FROM users WHERE id = $1`, victimID).Scan(&email))
+	assert.Equal(t, "victim@example.com", email)
+}
+
 func TestMe_Authenticated(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
@@ -180,10 +312,60 @@ func TestUpdateProfile(t *testing.T) {
 
 	// Verify update
 	var firstName, lastName, phone string
-	db.QueryRow(t.Context(), "SELECT first_name, last_name, phone FROM users WHERE id = $1", userID).
+	db.QueryRow(context.Background(), "SELECT first_name, last_name, phone FROM users WHERE id = $1", userID).
 		Scan(&firstName, &lastName, &phone)
 	assert.Equal(t, "New", firstName)
 	assert.Equal(t, "Updated", lastName)
 	assert.Equal(t, "555-1234", phone)
 }
 
+func TestBidEligibility(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	authHandler := handler.NewAuthHandler(db, logger)
+
+	profile, empty := "profile_ok", ""
+	tests := []struct {
+		name        string
+		verified    bool
+		paymentID   *string
+		wantCanBid  bool
+		wantReasons []string
+	}{
+		{"verified with payment", true, &profile, true, []string{}},
+		{"not verified", false, &profile, false, []string{"id_not_verified"}},
+		{"no payment method", true, nil, false, []string{"no_payment_method"}},
+		{"empty payment profile", true, &empty, false, []string{"no_payment_method"}},
+		{"neither", false, nil, false, []string{"id_not_verified", "no_payment_method"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := fixtures.BuyerUser(t, db)
+			_, err := db.Exec(context.Background(), `
+				UPDATE users SET
+					id_verified_at = CASE WHEN $2 THEN NOW() END,
+					authorize_payment_profile_id = $3
+				WHERE id = $1
+			`, userID, tt.verified, tt.paymentID)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("GET", "/api/auth/bid-eligibility", nil)
+			req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+			rec := httptest.NewRecorder()
+			authHandler.BidEligibility(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var resp struct {
+				CanBid  bool     `json:"can_bid"`
+				Reasons []string `json:"reasons"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantCanBid, resp.CanBid)
+			assert.Equal(t, tt.wantReasons, resp.Reasons)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	authHandler.BidEligibility(rec, httptest.NewRequest("GET", "/api/auth/bid-eligibility", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -9,22 +9,27 @@ import (
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/ayubfarah/vehicle-auc/internal/realtime"
 	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func setupBidTestServer(t *testing.T, db *pgxpool.Pool, engine *bidengine.Engine, logger *slog.Logger) *chi.Mux {
-	bidHandler := handler.NewBidHandler(engine, logger)
+func setupBidTestServer(t *testing.T, db *pgxpool.Pool, engine *bidengine.Engine, logger *slog.Logger, opts ...handler.BidHandlerOption) *chi.Mux {
+	bidHandler := handler.NewBidHandler(engine, logger, opts...)
 
 	r := chi.NewRouter()
 	r.Post("/api/auctions/{id}/bids", func(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +37,10 @@ func setupBidTestServer(t *testing.T, db *pgxpool.Pool, engine *bidengine.Engine
 		ctx := middleware.WithUserID(r.Context(), userID)
 		bidHandler.PlaceBid(w, r.WithContext(ctx))
 	})
+	r.Post("/api/auctions/{id}/proxy", func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("test_user_id").(int64)
+		bidHandler.PlaceProxyBid(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+	})
 	r.Get("/api/bids/{ticketId}/status", bidHandler.GetBidStatus)
 	return r
 }
@@ -79,12 +88,12 @@ func TestPlaceBid_Success(t *testing.T) {
 	assert.NotEmpty(t, resp["ticket_id"])
 	assert.Equal(t, "queued", resp["status"])
 
-	// Wait for processing
-	time.Sleep(100 * time.Millisecond)
+	// Wait for broadcasts to go out
+	broker.Flush()
 
 	// Verify auction updated
 	var currentBid float64
-	db.QueryRow(t.Context(), "SELECT current_bid FROM auctions WHERE id = $1", auctionID).Scan(&currentBid)
+	db.QueryRow(context.Background(), "SELECT current_bid FROM auctions WHERE id = $1", auctionID).Scan(&currentBid)
 	assert.Equal(t, 150.00, currentBid)
 }
 
@@ -152,8 +161,8 @@ func TestPlaceBid_TooLow(t *testing.T) {
 
 	assert.Equal(t, http.StatusAccepted, rec.Code) // Still accepted (async)
 
-	// Wait for processing
-	time.Sleep(100 * time.Millisecond)
+	// Wait for broadcasts to go out
+	broker.Flush()
 
 	// Verify auction NOT updated (bid was too low)
 	var currentBid float64
@@ -191,7 +200,7 @@ func TestPlaceBid_VerifyBidRecorded(t *testing.T) {
 	r.ServeHTTP(rec, req)
 	assert.Equal(t, http.StatusAccepted, rec.Code)
 
-	time.Sleep(100 * time.Millisecond)
+	broker.Flush()
 
 	// Verify bid was recorded in bids table
 	var bidCount int
@@ -250,10 +259,959 @@ func TestPlaceBid_OCC_VersionIncremented(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	r.ServeHTTP(rec, req)
-	time.Sleep(100 * time.Millisecond)
+	broker.Flush()
 
 	// Verify version was incremented (OCC)
 	var newVersion int
 	db.QueryRow(context.Background(), "SELECT version FROM auctions WHERE id = $1", auctionID).Scan(&newVersion)
 	assert.Equal(t, initialVersion+1, newVersion)
 }
+
+func TestGetBidStatus_EnrichedWithAuctionState(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	broker := realtime.NewBroker(logger)
+	broker.Start()
+	defer broker.Stop()
+
+	engine := bidengine.NewEngine(db, logger, broker, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	r := setupBidTestServer(t, db, engine, logger)
+
+	body := map[string]string{"amount": "250.00"}
+	bodyBytes, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/api/auctions/"+strconv.FormatInt(auctionID, 10)+"/bids", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "test_user_id", buyerID))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	var submitResp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &submitResp))
+	ticketID := submitResp["ticket_id"].(string)
+
+	req = httptest.NewRequest("GET", "/api/bids/"+ticketID+"/status", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status struct {
+		Status    string `json:"status"`
+		IsWinning bool   `json:"is_winning"`
+		Auction   *struct {
+			Status     string `json:"status"`
+			CurrentBid string `json:"current_bid"`
+			BidCount   int    `json:"bid_count"`
+			EndsAt     string `json:"ends_at"`
+		} `json:"auction"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+
+	assert.Equal(t, "accepted", status.Status)
+	assert.True(t, status.IsWinning)
+	require.NotNil(t, status.Auction)
+	assert.Equal(t, "active", status.Auction.Status)
+	assert.True(t, decimal.RequireFromString(status.Auction.CurrentBid).Equal(decimal.NewFromInt(250)))
+	assert.Equal(t, 1, status.Auction.BidCount)
+	assert.NotEmpty(t, status.Auction.EndsAt)
+}
+
+func TestPlaceBid_ReserveMetExtensionFiresOnce(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuctionEndingSoon(t, db, vehicleID)
+
+	ctx := context.Background()
+	_, err := db.Exec(ctx, `UPDATE vehicles SET reserve_price = 200 WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+	// No snipe budget, so only the reserve rule can extend
+	_, err = db.Exec(ctx, `UPDATE auctions SET extend_on_reserve_met = true, max_extensions = 0 WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+
+	var originalEndsAt time.Time
+	require.NoError(t, db.QueryRow(ctx, `SELECT ends_at FROM auctions WHERE id = $1`, auctionID).Scan(&originalEndsAt))
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	bid := func(amount int64) domain.BidResult {
+		ticketID := uuid.New().String()
+		require.NoError(t, engine.Submit(domain.BidRequest{
+			TicketID:  ticketID,
+			AuctionID: auctionID,
+			UserID:    buyerID,
+			Amount:    decimal.NewFromInt(amount),
+			CreatedAt: time.Now(),
+		}))
+		result, err := engine.GetResult(ticketID, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, "accepted", result.Status)
+		return result
+	}
+
+	var endsAt time.Time
+	var applied bool
+
+	// Below reserve: no extension
+	bid(150)
+	require.NoError(t, db.QueryRow(ctx, `SELECT ends_at, reserve_extension_applied FROM auctions WHERE id = $1`, auctionID).Scan(&endsAt, &applied))
+	assert.True(t, endsAt.Equal(originalEndsAt))
+	assert.False(t, applied)
+
+	// Meets reserve: one-time extension
+	bid(250)
+	require.NoError(t, db.QueryRow(ctx, `SELECT ends_at, reserve_extension_applied FROM auctions WHERE id = $1`, auctionID).Scan(&endsAt, &applied))
+	assert.True(t, endsAt.After(originalEndsAt))
+	assert.True(t, applied)
+	extendedEndsAt := endsAt
+
+	// Later bids don't extend again
+	bid(300)
+	require.NoError(t, db.QueryRow(ctx, `SELECT ends_at FROM auctions WHERE id = $1`, auctionID).Scan(&endsAt))
+	assert.True(t, endsAt.Equal(extendedEndsAt))
+}
+
+func TestPlaceBid_BelowStartingPrice(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	bid := func(amount int64) domain.BidResult {
+		ticketID := uuid.New().String()
+		require.NoError(t, engine.Submit(domain.BidRequest{
+			TicketID:  ticketID,
+			AuctionID: auctionID,
+			UserID:    buyerID,
+			Amount:    decimal.NewFromInt(amount),
+			CreatedAt: time.Now(),
+		}))
+		result, err := engine.GetResult(ticketID, time.Second)
+		require.NoError(t, err)
+		return result
+	}
+
+	// Opening bid must clear the starting price
+	result := bid(1)
+	assert.Equal(t, "rejected", result.Status)
+	assert.Equal(t, "below_starting_price", result.Reason)
+
+	var bidCount int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT bid_count FROM auctions WHERE id = $1`, auctionID).Scan(&bidCount))
+	assert.Equal(t, 0, bidCount)
+
+	// Exactly the starting price opens the auction
+	result = bid(20000)
+	assert.Equal(t, "accepted", result.Status)
+}
+
+func TestPlaceBid_AntiSnipeExtensionEndToEnd(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuctionEndingSoon(t, db, vehicleID) // 2 min threshold, 2 min extension
+
+	ctx := context.Background()
+	var originalEndsAt time.Time
+	require.NoError(t, db.QueryRow(ctx, `SELECT ends_at FROM auctions WHERE id = $1`, auctionID).Scan(&originalEndsAt))
+
+	// Pin the clock 30s before close so the bid lands inside the snipe window
+	now := originalEndsAt.Add(-30 * time.Second)
+	clock := func() time.Time { return now }
+
+	broker := realtime.NewBroker(logger)
+	broker.Start()
+	defer broker.Stop()
+
+	sub := &realtime.Subscriber{
+		ID:       uuid.New().String(),
+		Messages: make(chan []byte, 10),
+		Done:     make(chan struct{}),
+	}
+	broker.Subscribe(auctionID, sub)
+	defer broker.Unsubscribe(auctionID, sub)
+
+	engine := bidengine.NewEngine(db, logger, broker,
+		bidengine.WithSyncMode(true),
+		bidengine.WithClock(clock),
+	)
+	engine.Start()
+	defer engine.Stop()
+
+	ticketID := uuid.New().String()
+	require.NoError(t, engine.Submit(domain.BidRequest{
+		TicketID:  ticketID,
+		AuctionID: auctionID,
+		UserID:    buyerID,
+		Amount:    decimal.NewFromInt(500),
+		CreatedAt: now,
+	}))
+	result, err := engine.GetResult(ticketID, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "accepted", result.Status)
+
+	expectedEndsAt := originalEndsAt.Add(2 * time.Minute)
+
+	// Extension is visible through the API
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
+
+	req := httptest.NewRequest("GET", "/api/auctions/"+strconv.FormatInt(auctionID, 10), nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var auction map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auction))
+	assert.Equal(t, float64(1), auction["extension_count"])
+
+	endsAt, err := time.Parse(time.RFC3339, auction["ends_at"].(string))
+	require.NoError(t, err)
+	assert.True(t, endsAt.Equal(expectedEndsAt.Truncate(time.Second)), "ends_at %s, want %s", endsAt, expectedEndsAt)
+
+	// And pushed to SSE subscribers
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case msg := <-sub.Messages:
+			if !bytes.HasPrefix(msg, []byte("event: auction_extended\n")) {
+				continue
+			}
+			payload := bytes.TrimSpace(bytes.TrimPrefix(msg, []byte("event: auction_extended\ndata: ")))
+			var event domain.BidEvent
+			require.NoError(t, json.Unmarshal(payload, &event))
+			assert.Equal(t, auctionID, event.AuctionID)
+			assert.True(t, event.EndsAt.Equal(expectedEndsAt))
+			return
+		case <-deadline:
+			t.Fatal("did not receive auction_extended event")
+		}
+	}
+}
+
+func TestDurableQueue_ReplaysAfterRestart(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	// First engine accepts the bid but goes down before any worker runs
+	first := bidengine.NewEngine(db, logger, nil, bidengine.WithDurableQueue(true))
+	ticketID := uuid.New().String()
+	require.NoError(t, first.Submit(domain.BidRequest{
+		TicketID:  ticketID,
+		AuctionID: auctionID,
+		UserID:    buyerID,
+		Amount:    decimal.NewFromInt(500),
+		CreatedAt: time.Now(),
+	}))
+	first.Stop()
+
+	var staged int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM bid_queue WHERE ticket_id = $1`, ticketID).Scan(&staged))
+	assert.Equal(t, 1, staged)
+
+	// A fresh engine on the same database picks it up on start
+	second := bidengine.NewEngine(db, logger, nil, bidengine.WithDurableQueue(true))
+	second.Start()
+	defer second.Stop()
+
+	result, err := second.GetResult(ticketID, 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", result.Status)
+
+	var currentBid decimal.Decimal
+	var bidCount int
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT current_bid, bid_count FROM auctions WHERE id = $1
+	`, auctionID).Scan(&currentBid, &bidCount))
+	assert.True(t, currentBid.Equal(decimal.NewFromInt(500)))
+	assert.Equal(t, 1, bidCount)
+
+	// Processed bids are removed from the staging table
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM bid_queue WHERE ticket_id = $1`, ticketID).Scan(&staged))
+	assert.Equal(t, 0, staged)
+}
+
+func TestDurableQueue_ReplaysBidInFlightAtStop(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	// Hold the auction row so the worker's update blocks mid-bid
+	lock, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer lock.Rollback(ctx)
+	_, err = lock.Exec(ctx, `SELECT 1 FROM auctions WHERE id = $1 FOR UPDATE`, auctionID)
+	require.NoError(t, err)
+
+	first := bidengine.NewEngine(db, logger, nil, bidengine.WithDurableQueue(true))
+	first.Start()
+	ticketID := uuid.New().String()
+	require.NoError(t, first.Submit(domain.BidRequest{
+		TicketID:  ticketID,
+		AuctionID: auctionID,
+		UserID:    buyerID,
+		Amount:    decimal.NewFromInt(500),
+		CreatedAt: time.Now(),
+	}))
+	require.Eventually(t, func() bool {
+		var waiting int
+		err := db.QueryRow(ctx, `
+			SELECT COUNT(*) FROM pg_stat_activity
+			WHERE wait_event_type = 'Lock' AND query LIKE '%UPDATE auctions%'
+		`).Scan(&waiting)
+		return err == nil && waiting > 0
+	}, 5*time.Second, 10*time.Millisecond, "bid never reached the auction update")
+
+	// Shutting down aborts the bid, but it stays staged for the next process
+	first.Stop()
+	require.NoError(t, lock.Rollback(ctx))
+
+	var staged int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM bid_queue WHERE ticket_id = $1`, ticketID).Scan(&staged))
+	assert.Equal(t, 1, staged)
+
+	second := bidengine.NewEngine(db, logger, nil, bidengine.WithDurableQueue(true))
+	second.Start()
+	defer second.Stop()
+
+	result, err := second.GetResult(ticketID, 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", result.Status)
+
+	var bidCount int
+	require.NoError(t, db.QueryRow(ctx, `SELECT bid_count FROM auctions WHERE id = $1`, auctionID).Scan(&bidCount))
+	assert.Equal(t, 1, bidCount)
+}
+
+func TestDurableQueue_AcceptedBidUnstagesInItsTransaction(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	// A staged proxy-only bid is priced at replay, so a second run would be
+	// accepted again rather than rejected as too low
+	ticketID := uuid.New().String()
+	req := domain.BidRequest{
+		TicketID:  ticketID,
+		AuctionID: auctionID,
+		UserID:    buyerID,
+		MaxBid:    decimal.NewFromInt(5000),
+		ProxyOnly: true,
+		CreatedAt: time.Now(),
+	}
+	_, err := db.Exec(ctx, `
+		INSERT INTO bid_queue (ticket_id, auction_id, user_id, amount, max_bid, created_at, proxy_only)
+		VALUES ($1, $2, $3, 0, $4, $5, true)
+	`, ticketID, auctionID, buyerID, req.MaxBid, req.CreatedAt)
+	require.NoError(t, err)
+
+	// Sync mode never acknowledges through the engine, like a process that
+	// dies right after the bid commits
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true), bidengine.WithDurableQueue(true))
+	require.NoError(t, engine.Submit(req))
+	result, err := engine.GetResult(ticketID, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "accepted", result.Status)
+
+	var staged int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM bid_queue WHERE ticket_id = $1`, ticketID).Scan(&staged))
+	assert.Equal(t, 0, staged, "a restart must not replay an accepted bid")
+}
+
+func TestPlaceBid_NonNumericAmount(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	r := setupBidTestServer(t, db, engine, logger)
+
+	cases := map[string]string{
+		"boolean": `{"amount": true}`,
+		"object":  `{"amount": {"value": 500}}`,
+		"array":   `{"amount": [500]}`,
+		"null":    `{"amount": null}`,
+		// Too small or too long to do arithmetic on cheaply
+		"tiny exponent": `{"amount": "1e-10000000"}`,
+		"over-long":     `{"amount": "` + strings.Repeat("1", 100) + `"}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auctions/"+strconv.FormatInt(auctionID, 10)+"/bids", bytes.NewReader([]byte(body)))
+			req.Header.Set("Content-Type", "application/json")
+			req = req.WithContext(context.WithValue(req.Context(), "test_user_id", buyerID))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp map[string]string
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "invalid bid amount", resp["error"])
+		})
+	}
+}
+
+func TestPlaceBid_SyncResponseReturnsTerminalResult(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	broker := realtime.NewBroker(logger)
+	broker.Start()
+	defer broker.Stop()
+
+	engine := bidengine.NewEngine(db, logger, broker, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	r := setupBidTestServer(t, db, engine, logger, handler.WithSyncResponse(true))
+
+	placeBid := func(amount string) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(map[string]string{"amount": amount})
+		req := httptest.NewRequest("POST", "/api/auctions/"+strconv.FormatInt(auctionID, 10)+"/bids", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "test_user_id", buyerID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Accepted bid returns 200 with the final result, no ticket polling
+	rec := placeBid("150.00")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result domain.BidResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "accepted", result.Status)
+	assert.NotZero(t, result.BidID)
+	assert.True(t, result.IsWinning)
+
+	// Bid below the current high is rejected with a reason
+	rec = placeBid("120.00")
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	result = domain.BidResult{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "rejected", result.Status)
+	assert.NotEmpty(t, result.Reason)
+}
+
+func TestPlaceBid_MaxBidCap(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID) // starting price 100.00
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	broker := realtime.NewBroker(logger)
+	broker.Start()
+	defer broker.Stop()
+
+	engine := bidengine.NewEngine(db, logger, broker,
+		bidengine.WithSyncMode(true),
+		bidengine.WithMaxBidMultiple(10),
+	)
+	engine.Start()
+	defer engine.Stop()
+
+	r := setupBidTestServer(t, db, engine, logger, handler.WithSyncResponse(true))
+
+	placeBid := func(amount, maxBid string) domain.BidResult {
+		bodyBytes, _ := json.Marshal(map[string]any{"amount": amount, "max_bid": json.Number(maxBid)})
+		req := httptest.NewRequest("POST", "/api/auctions/"+strconv.FormatInt(auctionID, 10)+"/bids", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "test_user_id", buyerID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		var result domain.BidResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		return result
+	}
+
+	// Above 10x the starting price is rejected before any bid lands
+	result := placeBid("150.00", "1000.01")
+	assert.Equal(t, "rejected", result.Status)
+	assert.Equal(t, "max_bid_too_high", result.Reason)
+
+	var bidCount int
+	require.NoError(t, db.QueryRow(context.Background(), "SELECT bid_count FROM auctions WHERE id = $1", auctionID).Scan(&bidCount))
+	assert.Equal(t, 0, bidCount)
+
+	// Exactly at the cap is allowed
+	result = placeBid("150.00", "1000.00")
+	assert.Equal(t, "accepted", result.Status)
+
+	// Once bidding starts the cap follows the current bid: 150 x 10, then 200 x 10
+	result = placeBid("200.00", "1500.00")
+	assert.Equal(t, "accepted", result.Status)
+	result = placeBid("300.00", "2000.01")
+	assert.Equal(t, "max_bid_too_high", result.Reason)
+}
+
+func TestPlaceBid_WaitReturnsFinalResult(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	broker := realtime.NewBroker(logger)
+	broker.Start()
+	defer broker.Stop()
+
+	// Async engine: results arrive from a worker, not inside Submit
+	engine := bidengine.NewEngine(db, logger, broker)
+	engine.Start()
+	defer engine.Stop()
+
+	placeBid := func(r http.Handler, amount string) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(map[string]string{"amount": amount})
+		req := httptest.NewRequest("POST", "/api/auctions/"+strconv.FormatInt(auctionID, 10)+"/bids?wait=true", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "test_user_id", buyerID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	r := setupBidTestServer(t, db, engine, logger, handler.WithWaitTimeout(5*time.Second))
+
+	t.Run("accepted", func(t *testing.T) {
+		rec := placeBid(r, "150.00")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var result domain.BidResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, "accepted", result.Status)
+		assert.True(t, result.IsWinning)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		rec := placeBid(r, "120.00")
+		require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+		var result domain.BidResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, "rejected", result.Status)
+		assert.Equal(t, "bid_too_low", result.Reason)
+	})
+
+	t.Run("timeout falls back to ticket", func(t *testing.T) {
+		impatient := setupBidTestServer(t, db, engine, logger, handler.WithWaitTimeout(time.Nanosecond))
+		rec := placeBid(impatient, "200.00")
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		var resp handler.PlaceBidResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "queued", resp.Status)
+		require.NotEmpty(t, resp.TicketID)
+
+		// The ticket still resolves through the normal status poll
+		req := httptest.NewRequest("GET", "/api/bids/"+resp.TicketID+"/status", nil)
+		rec = httptest.NewRecorder()
+		impatient.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var result domain.BidResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, "accepted", result.Status)
+	})
+}
+
+func TestPlaceProxyBid_LeadsOneIncrementAbove(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	rivalID := fixtures.BuyerUser(t, db)
+	proxyID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuctionWithBid(t, db, vehicleID, 150, rivalID)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	r := setupBidTestServer(t, db, engine, logger, handler.WithSyncResponse(true))
+	post := func(path string, userID int64, body map[string]string) (int, domain.BidResult) {
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/api/auctions/"+strconv.FormatInt(auctionID, 10)+path, bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "test_user_id", userID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		var result domain.BidResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result), rec.Body.String())
+		return rec.Code, result
+	}
+	leader := func() (decimal.Decimal, int64) {
+		var currentBid decimal.Decimal
+		var leaderID int64
+		require.NoError(t, db.QueryRow(ctx, `
+			SELECT current_bid, current_bid_user_id FROM auctions WHERE id = $1
+		`, auctionID).Scan(&currentBid, &leaderID))
+		return currentBid, leaderID
+	}
+
+	// A max below the minimum next bid can't enter
+	code, result := post("/proxy", proxyID, map[string]string{"max_bid": "150.00"})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "max_bid_too_low", result.Reason)
+
+	// Proxy-only enters one increment above the current bid, not at the max
+	code, result = post("/proxy", proxyID, map[string]string{"max_bid": "500.00"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "accepted", result.Status)
+	assert.True(t, result.IsWinning)
+	assert.Equal(t, "150.01", result.Amount.StringFixed(2))
+
+	currentBid, leaderID := leader()
+	assert.Equal(t, "150.01", currentBid.StringFixed(2))
+	assert.Equal(t, proxyID, leaderID)
+
+	var maxBid decimal.Decimal
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT max_bid FROM bids WHERE auction_id = $1 AND user_id = $2
+	`, auctionID, proxyID).Scan(&maxBid))
+	assert.Equal(t, "500.00", maxBid.StringFixed(2))
+
+	// A challenge under the max is answered with an auto-bid one increment higher
+	code, result = post("/bids", rivalID, map[string]string{"amount": "200.00"})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "outbid_by_proxy", result.Reason)
+	assert.False(t, result.IsWinning)
+
+	currentBid, leaderID = leader()
+	assert.Equal(t, "200.01", currentBid.StringFixed(2))
+	assert.Equal(t, proxyID, leaderID)
+
+	var autoBids int
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM bids WHERE auction_id = $1 AND user_id = $2 AND is_auto_bid
+	`, auctionID, proxyID).Scan(&autoBids))
+	assert.Equal(t, 1, autoBids)
+
+	// Beating the max takes the lead one increment over it
+	code, result = post("/bids", rivalID, map[string]string{"amount": "600.00"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "500.01", result.Amount.StringFixed(2))
+
+	currentBid, leaderID = leader()
+	assert.Equal(t, "500.01", currentBid.StringFixed(2))
+	assert.Equal(t, rivalID, leaderID)
+}
+
+func TestPlaceBid_AmountOutOfRange(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	r := setupBidTestServer(t, db, engine, logger,
+		handler.WithSyncResponse(true),
+		handler.WithMaxAmount(decimal.NewFromInt(10_000_000)),
+	)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/auctions/"+strconv.FormatInt(auctionID, 10)+"/bids", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "test_user_id", buyerID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	cases := map[string]string{
+		"huge number":             `{"amount": 1e308}`,
+		"huge string":             `{"amount": "1e308"}`,
+		"just over the cap":       `{"amount": 10000000.01}`,
+		"oversized max_bid":       `{"amount": 150, "max_bid": 1e12}`,
+		"scientific over the cap": `{"amount": "2.5E7"}`,
+		// Rejected on the exponent alone, before any decimal arithmetic
+		"huge exponent":         `{"amount": "1e10000000"}`,
+		"huge exponent max_bid": `{"amount": 150, "max_bid": 1e10000000}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			rec := post(body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp map[string]string
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "amount_out_of_range", resp["reason"])
+			assert.Equal(t, "10000000.00", resp["max_amount"])
+		})
+	}
+
+	var bidCount int
+	require.NoError(t, db.QueryRow(context.Background(), "SELECT bid_count FROM auctions WHERE id = $1", auctionID).Scan(&bidCount))
+	assert.Zero(t, bidCount, "nothing out of range reaches the engine")
+
+	// Scientific notation within range is just another way to write the amount
+	rec := post(`{"amount": 1.5e2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var result domain.BidResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "accepted", result.Status)
+	assert.Equal(t, "150.00", result.Amount.StringFixed(2))
+}
+
+func TestPlaceBid_ExtendOnLeaderChangeOnly(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	alice := fixtures.BuyerUser(t, db)
+	bob := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuctionEndingSoon(t, db, fixtures.TestVehicle(t, db, sellerID))
+	_, err := db.Exec(ctx, `UPDATE auctions SET extend_on_leader_change_only = true WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	endsAt := func() (time.Time, int) {
+		var endsAt time.Time
+		var extensions int
+		require.NoError(t, db.QueryRow(ctx, `SELECT ends_at, extension_count FROM auctions WHERE id = $1`, auctionID).Scan(&endsAt, &extensions))
+		return endsAt, extensions
+	}
+	original, _ := endsAt()
+
+	// Alice takes the lead from nobody: a new leader, so it extends
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, alice, "150").Status)
+	afterFirst, extensions := endsAt()
+	assert.True(t, afterFirst.After(original))
+	assert.Equal(t, 1, extensions)
+
+	// Alice raising her own bid in the window doesn't move the end
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, alice, "200").Status)
+	afterRaise, extensions := endsAt()
+	assert.True(t, afterRaise.Equal(afterFirst))
+	assert.Equal(t, 1, extensions)
+
+	// Bob taking the lead does
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, bob, "250").Status)
+	afterChallenge, extensions := endsAt()
+	assert.True(t, afterChallenge.After(afterRaise))
+	assert.Equal(t, 2, extensions)
+}
+
+func TestPlaceBid_AuctionMinIncrement(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	alice := fixtures.BuyerUser(t, db)
+	bob := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	_, err := db.Exec(ctx, `UPDATE auctions SET min_increment = 1000 WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, alice, "150").Status)
+
+	// Above the current bid but short of the auction's increment
+	result := submitSyncBid(t, engine, auctionID, bob, "500")
+	assert.Equal(t, "rejected", result.Status)
+	assert.Equal(t, "below_min_increment", result.Reason)
+	// The rejection says what to bid instead
+	assert.Equal(t, "150.00", result.PreviousHighBid.StringFixed(2))
+	assert.Equal(t, "1150.00", result.NextMinBid.StringFixed(2))
+
+	// The default one-cent step no longer applies
+	result = submitSyncBid(t, engine, auctionID, bob, "150.01")
+	assert.Equal(t, "rejected", result.Status)
+	assert.Equal(t, "below_min_increment", result.Reason)
+
+	// Not above the current bid at all is still bid_too_low
+	result = submitSyncBid(t, engine, auctionID, bob, "150")
+	assert.Equal(t, "bid_too_low", result.Reason)
+	assert.Equal(t, "150.00", result.PreviousHighBid.StringFixed(2))
+	assert.Equal(t, "1150.00", result.NextMinBid.StringFixed(2))
+
+	assert.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, bob, "1150").Status)
+}
+
+func TestPlaceBid_ExtensionTotalTimeCap(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	alice := fixtures.BuyerUser(t, db)
+	bob := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuctionEndingSoon(t, db, fixtures.TestVehicle(t, db, sellerID))
+	// Every bid lands in the snipe window, and the count cap is far off
+	_, err := db.Exec(ctx, `
+		UPDATE auctions SET snipe_threshold_minutes = 60, extension_minutes = 2, max_extensions = 10
+		WHERE id = $1
+	`, auctionID)
+	require.NoError(t, err)
+
+	engine := bidengine.NewEngine(db, logger, nil,
+		bidengine.WithSyncMode(true),
+		bidengine.WithMaxExtensionTotal(3*time.Minute),
+	)
+	engine.Start()
+	defer engine.Stop()
+
+	state := func() (time.Time, int, int) {
+		var endsAt time.Time
+		var count, seconds int
+		require.NoError(t, db.QueryRow(ctx, `
+			SELECT ends_at, extension_count, extended_seconds FROM auctions WHERE id = $1
+		`, auctionID).Scan(&endsAt, &count, &seconds))
+		return endsAt, count, seconds
+	}
+	original, _, _ := state()
+
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, alice, "150").Status)
+	endsAt, count, seconds := state()
+	assert.Equal(t, 2*time.Minute, endsAt.Sub(original))
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 120, seconds)
+
+	// Only a minute of the cap is left, so that's all this one adds
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, bob, "200").Status)
+	endsAt, count, seconds = state()
+	assert.Equal(t, 3*time.Minute, endsAt.Sub(original))
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 180, seconds)
+
+	// Cap reached: no more extensions with eight left on the count
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, alice, "250").Status)
+	endsAt, count, seconds = state()
+	assert.Equal(t, 3*time.Minute, endsAt.Sub(original))
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 180, seconds)
+}
+
+func TestPlaceBid_Deadline(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	// The status still says scheduled though the auction has started, and
+	// extensions are off so ends_at stays put
+	endsAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	_, err := db.Exec(ctx, `
+		UPDATE auctions SET status = 'scheduled', ends_at = $2, max_extensions = 0 WHERE id = $1
+	`, auctionID, endsAt)
+	require.NoError(t, err)
+
+	var now time.Time
+	engine := bidengine.NewEngine(db, logger, nil,
+		bidengine.WithSyncMode(true),
+		bidengine.WithClock(func() time.Time { return now }),
+		bidengine.WithClockSkew(500*time.Millisecond),
+	)
+	engine.Start()
+	defer engine.Stop()
+
+	bidAt := func(at time.Time, amount int64) domain.BidResult {
+		now = at
+		ticketID := uuid.New().String()
+		require.NoError(t, engine.Submit(domain.BidRequest{
+			TicketID:  ticketID,
+			AuctionID: auctionID,
+			UserID:    buyerID,
+			Amount:    decimal.NewFromInt(amount),
+			CreatedAt: at,
+		}))
+		result, err := engine.GetResult(ticketID, time.Second)
+		require.NoError(t, err)
+		return result
+	}
+
+	// Just before ends_at: taken despite the stale status, which it promotes
+	result := bidAt(endsAt.Add(-time.Millisecond), 100)
+	require.Equal(t, "accepted", result.Status, result.Reason)
+	var status string
+	require.NoError(t, db.QueryRow(ctx, `SELECT status::text FROM auctions WHERE id = $1`, auctionID).Scan(&status))
+	assert.Equal(t, "active", status)
+
+	// Just after, but inside the clock-skew allowance
+	result = bidAt(endsAt.Add(300*time.Millisecond), 200)
+	assert.Equal(t, "accepted", result.Status, result.Reason)
+
+	// Past the allowance
+	result = bidAt(endsAt.Add(600*time.Millisecond), 300)
+	assert.Equal(t, "rejected", result.Status)
+	assert.Equal(t, "auction_ended", result.Reason)
+
+	var bidCount int
+	require.NoError(t, db.QueryRow(ctx, `SELECT bid_count FROM auctions WHERE id = $1`, auctionID).Scan(&bidCount))
+	assert.Equal(t, 2, bidCount)
+}
@@ -0,0 +1,160 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/payments"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuyNow_ConcurrentRequestsExactlyOneWins(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET buy_now_price = 5000 WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/buy-now", auctionHandler.BuyNow)
+
+	const buyers = 10
+	buyerIDs := make([]int64, buyers)
+	for i := range buyerIDs {
+		buyerIDs[i] = fixtures.VerifiedUser(t, db)
+	}
+
+	codes := make([]int, buyers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, buyerID := range buyerIDs {
+		wg.Add(1)
+		go func(i int, buyerID int64) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", fmt.Sprintf("/api/auctions/%d/buy-now", auctionID), nil)
+			req = req.WithContext(middleware.WithUserID(req.Context(), buyerID))
+			rec := httptest.NewRecorder()
+			<-start
+			r.ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}(i, buyerID)
+	}
+	close(start)
+	wg.Wait()
+
+	var won, conflicted int
+	var winnerID int64
+	for i, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			won++
+			winnerID = buyerIDs[i]
+		case http.StatusConflict:
+			conflicted++
+		}
+	}
+	assert.Equal(t, 1, won)
+	assert.Equal(t, buyers-1, conflicted)
+
+	var orders int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM orders WHERE auction_id = $1`, auctionID).Scan(&orders))
+	assert.Equal(t, 1, orders)
+
+	// Ended exactly once: a single version bump from the fixture's 0
+	var status string
+	var version int
+	var winner *int64
+	var winningBid float64
+	err = db.QueryRow(context.Background(), `
+		SELECT status::text, version, winner_id, winning_bid::float8 FROM auctions WHERE id = $1
+	`, auctionID).Scan(&status, &version, &winner, &winningBid)
+	require.NoError(t, err)
+	assert.Equal(t, "ended", status)
+	assert.Equal(t, 1, version)
+	require.NotNil(t, winner)
+	assert.Equal(t, winnerID, *winner)
+	assert.Equal(t, 5000.0, winningBid)
+}
+
+func TestBuyNow_Rejections(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.VerifiedUser(t, db)
+	noBuyNow := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET buy_now_price = 5000 WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+	outbid := fixtures.TestAuction(t, db, vehicleID)
+	_, err = db.Exec(context.Background(), `UPDATE auctions SET current_bid = 5000, bid_count = 1 WHERE id = $1`, outbid)
+	require.NoError(t, err)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	buyNow := func(userID, auctionID int64) int {
+		r := chi.NewRouter()
+		r.Post("/api/auctions/{id}/buy-now", auctionHandler.BuyNow)
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/auctions/%d/buy-now", auctionID), nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, buyNow(buyerID, noBuyNow))
+	assert.Equal(t, http.StatusForbidden, buyNow(sellerID, outbid))
+	assert.Equal(t, http.StatusConflict, buyNow(buyerID, outbid))
+	assert.Equal(t, http.StatusNotFound, buyNow(buyerID, 999999))
+}
+
+func TestBuyNow_ChargesBuyer(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	vehicleID := fixtures.TestVehicle(t, db, fixtures.SellerUser(t, db))
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET buy_now_price = 5000 WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+	buyerID := fixtures.VerifiedUser(t, db)
+	var profileID string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT authorize_payment_profile_id FROM users WHERE id = $1`, buyerID).Scan(&profileID))
+
+	gateway := &mockGateway{}
+	capturer := payments.NewCapturer(db, logger, gateway, payments.WithInterval(time.Hour))
+	capturer.Start()
+	defer capturer.Stop()
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil, handler.WithPaymentQueue(capturer))
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/auctions/%d/buy-now", auctionID), nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), buyerID))
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/buy-now", auctionHandler.BuyNow)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	require.Eventually(t, func() bool {
+		var status string
+		require.NoError(t, db.QueryRow(context.Background(), `SELECT status::text FROM orders WHERE auction_id = $1`, auctionID).Scan(&status))
+		return status == "paid"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{profileID + " 5000.00"}, gateway.captured())
+}
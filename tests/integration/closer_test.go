@@ -0,0 +1,602 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/closer"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/ayubfarah/vehicle-auc/internal/payments"
+	"github.com/ayubfarah/vehicle-auc/internal/realtime"
+	"github.com/ayubfarah/vehicle-auc/internal/webhook"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloser_NotifiesWinnerAndLosers(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	winnerID := fixtures.BuyerUser(t, db)
+	loserID := fixtures.BuyerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	bid := func(userID, amount int64) {
+		ticketID := uuid.New().String()
+		require.NoError(t, engine.Submit(domain.BidRequest{
+			TicketID:  ticketID,
+			AuctionID: auctionID,
+			UserID:    userID,
+			Amount:    decimal.NewFromInt(amount),
+			CreatedAt: time.Now(),
+		}))
+		result, err := engine.GetResult(ticketID, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, "accepted", result.Status)
+	}
+	bid(loserID, 500)
+	bid(winnerID, 600)
+	bid(loserID, 700)
+	bid(winnerID, 800)
+
+	// Loser has the notification stream open
+	broker := realtime.NewBroker(logger)
+	stream := &realtime.Subscriber{ID: uuid.New().String(), UserID: loserID, Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	broker.SubscribeUser(stream)
+	defer broker.UnsubscribeUser(stream)
+
+	// Run the closer from a clock past the auction's end
+	c := closer.New(db, logger,
+		closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }),
+		closer.WithPublisher(broker),
+	)
+	closed, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, closed)
+
+	var status string
+	var auctionWinner *int64
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT status::text, winner_id FROM auctions WHERE id = $1
+	`, auctionID).Scan(&status, &auctionWinner))
+	assert.Equal(t, "ended", status)
+	require.NotNil(t, auctionWinner)
+	assert.Equal(t, winnerID, *auctionWinner)
+
+	var orderBuyer int64
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT buyer_id FROM orders WHERE auction_id = $1`, auctionID).Scan(&orderBuyer))
+	assert.Equal(t, winnerID, orderBuyer)
+
+	notification := func(userID int64) (string, map[string]interface{}) {
+		var notifType string
+		var raw []byte
+		require.NoError(t, db.QueryRow(context.Background(), `
+			SELECT type, data FROM notifications WHERE user_id = $1
+		`, userID).Scan(&notifType, &raw))
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal(raw, &data))
+		return notifType, data
+	}
+
+	// One notification per distinct bidder, with the final price
+	notifType, data := notification(winnerID)
+	assert.Equal(t, "auction_won", notifType)
+	assert.Equal(t, "800.00", data["final_price"])
+
+	notifType, data = notification(loserID)
+	assert.Equal(t, "auction_lost", notifType)
+	assert.Equal(t, "800.00", data["final_price"])
+
+	select {
+	case msg := <-stream.Messages:
+		assert.Contains(t, string(msg), `"type":"auction_lost"`)
+	case <-time.After(time.Second):
+		t.Fatal("loser's notification stream got nothing")
+	}
+
+	// A second sweep finds nothing left to close
+	closed, err = c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, closed)
+}
+
+func TestCloser_AutoRelistsWhenReserveNotMet(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	vehicleID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2021, "Mazda", "MX-5", 20000)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET reserve_price = 30000 WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+	_, err = db.Exec(context.Background(), `UPDATE auctions SET auto_relist_price_drops = '{10,20}' WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	ticketID := uuid.New().String()
+	require.NoError(t, engine.Submit(domain.BidRequest{
+		TicketID:  ticketID,
+		AuctionID: auctionID,
+		UserID:    buyerID,
+		Amount:    decimal.NewFromInt(25000),
+		CreatedAt: time.Now(),
+	}))
+	result, err := engine.GetResult(ticketID, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "accepted", result.Status)
+
+	c := closer.New(db, logger,
+		closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }),
+	)
+	closed, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, closed)
+
+	// The original ends unsold
+	var winnerID *int64
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT winner_id FROM auctions WHERE id = $1`, auctionID).Scan(&winnerID))
+	assert.Nil(t, winnerID)
+
+	// Exactly one relist, first round of the schedule, same vehicle
+	var relistID int64
+	var status string
+	var round int
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT id, status::text, auto_relist_round FROM auctions WHERE relisted_from = $1
+	`, auctionID).Scan(&relistID, &status, &round))
+	assert.Equal(t, "active", status)
+	assert.Equal(t, 1, round)
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM auctions WHERE vehicle_id = $1`, vehicleID).Scan(&count))
+	assert.Equal(t, 2, count)
+
+	// Prices cut by 10%
+	var startingPrice, reservePrice decimal.Decimal
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT starting_price, reserve_price FROM vehicles WHERE id = $1
+	`, vehicleID).Scan(&startingPrice, &reservePrice))
+	assert.True(t, startingPrice.Equal(decimal.NewFromInt(18000)), startingPrice.String())
+	assert.True(t, reservePrice.Equal(decimal.NewFromInt(27000)), reservePrice.String())
+
+	var notifType string
+	var raw []byte
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT type, data FROM notifications WHERE user_id = $1
+	`, sellerID).Scan(&notifType, &raw))
+	assert.Equal(t, "auction_relisted", notifType)
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &data))
+	assert.Equal(t, float64(relistID), data["new_auction_id"])
+	assert.Equal(t, "18000.00", data["starting_price"])
+
+	// The relist isn't due yet, so a second sweep changes nothing
+	closed, err = c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, closed)
+}
+
+func TestCloser_ClosesManyAuctionsConcurrently(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+
+	const total = 40
+	auctionIDs := make([]int64, total)
+	for i := range auctionIDs {
+		auctionIDs[i] = fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 150, buyerID)
+	}
+
+	// A bid is mid-update on one of them; the sweep must skip it, not wait
+	busy, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer busy.Rollback(ctx)
+	_, err = busy.Exec(ctx, `SELECT id FROM auctions WHERE id = $1 FOR UPDATE`, auctionIDs[0])
+	require.NoError(t, err)
+
+	broadcaster := &recordingBroadcaster{}
+	c := closer.New(db, logger,
+		closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }),
+		closer.WithConcurrency(8),
+		closer.WithBroadcaster(broadcaster),
+	)
+	closed, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, total-1, closed)
+
+	var ended, orders int
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM auctions WHERE id = ANY($1) AND status = 'ended' AND winner_id = $2
+	`, auctionIDs, buyerID).Scan(&ended))
+	assert.Equal(t, total-1, ended)
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT auction_id) FROM orders WHERE auction_id = ANY($1) AND buyer_id = $2
+	`, auctionIDs, buyerID).Scan(&orders))
+	assert.Equal(t, total-1, orders)
+
+	// Exactly one auction_ended per closed auction
+	seen := make(map[int64]int)
+	for _, event := range broadcaster.Events() {
+		require.Equal(t, "auction_ended", event.Type)
+		seen[event.AuctionID]++
+	}
+	assert.Len(t, seen, total-1)
+	for auctionID, n := range seen {
+		assert.Equal(t, 1, n, "auction %d", auctionID)
+	}
+	assert.NotContains(t, seen, auctionIDs[0])
+
+	// Once the bid lets go, the next sweep picks up the straggler
+	require.NoError(t, busy.Rollback(ctx))
+	closed, err = c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, closed)
+}
+
+func TestCloser_SendsLifecycleWebhooks(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuctionWithBid(t, db, vehicleID, 5000, buyerID)
+
+	received := make(chan webhook.Payload, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !webhook.Verify("ops", r.Header.Get(webhook.HeaderTimestamp), body, r.Header.Get(webhook.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload webhook.Payload
+		json.Unmarshal(body, &payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	dispatcher := webhook.NewDispatcher(db, logger, webhook.WithEndpoints(webhook.Endpoint{URL: server.URL, Secret: "ops"}))
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	c := closer.New(db, logger,
+		closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }),
+		closer.WithNotifier(dispatcher),
+	)
+	closed, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, closed)
+
+	var orderID int64
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT id FROM orders WHERE auction_id = $1`, auctionID).Scan(&orderID))
+
+	events := map[string]map[string]any{}
+	for len(events) < 2 {
+		select {
+		case p := <-received:
+			events[p.Event] = p.Data.(map[string]any)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("lifecycle webhooks not delivered, got %v", events)
+		}
+	}
+
+	ended := events[webhook.EventAuctionEnded]
+	assert.Equal(t, float64(auctionID), ended["auction_id"])
+	assert.Equal(t, "expired", ended["reason"])
+	assert.Equal(t, true, ended["sold"])
+	assert.Equal(t, float64(buyerID), ended["winner_id"])
+	assert.Equal(t, "5000.00", ended["final_price"])
+
+	order := events[webhook.EventOrderCreated]
+	assert.Equal(t, float64(orderID), order["order_id"])
+	assert.Equal(t, float64(buyerID), order["buyer_id"])
+	assert.Equal(t, float64(sellerID), order["seller_id"])
+	assert.Equal(t, "5000.00", order["sale_price"])
+}
+
+func TestCloser_CloseIsIdempotent(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 150, buyerID)
+
+	c := closer.New(db, logger, closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }))
+
+	// Two closers race for the same auction; only one ends it
+	results := make(chan bool, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ok, err := c.Close(ctx, auctionID)
+			assert.NoError(t, err)
+			results <- ok
+		}()
+	}
+	assert.NotEqual(t, <-results, <-results)
+
+	var orderID int64
+	require.NoError(t, db.QueryRow(ctx, `SELECT id FROM orders WHERE auction_id = $1`, auctionID).Scan(&orderID))
+
+	// A close that gets past the status check anyway finds the order already
+	// there and still succeeds
+	_, err := db.Exec(ctx, `UPDATE auctions SET status = 'active' WHERE id = $1`, auctionID)
+	require.NoError(t, err)
+	ok, err := c.Close(ctx, auctionID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	var orders int
+	var firstID int64
+	var status string
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*), MIN(id) FROM orders WHERE auction_id = $1`, auctionID).Scan(&orders, &firstID))
+	assert.Equal(t, 1, orders)
+	assert.Equal(t, orderID, firstID)
+	require.NoError(t, db.QueryRow(ctx, `SELECT status::text FROM auctions WHERE id = $1`, auctionID).Scan(&status))
+	assert.Equal(t, "ended", status)
+}
+
+func TestCloser_CapturesWinnerPayment(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	closeWith := func(gateway *mockGateway) (orderID int64, profileID string) {
+		buyerID := fixtures.BuyerUser(t, db)
+		require.NoError(t, db.QueryRow(ctx, `SELECT authorize_payment_profile_id FROM users WHERE id = $1`, buyerID).Scan(&profileID))
+		auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 150, buyerID)
+
+		capturer := payments.NewCapturer(db, logger, gateway, payments.WithInterval(time.Hour))
+		capturer.Start()
+		defer capturer.Stop()
+		c := closer.New(db, logger,
+			closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }),
+			closer.WithPayments(capturer),
+		)
+		ok, err := c.Close(ctx, auctionID)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.NoError(t, db.QueryRow(ctx, `SELECT id FROM orders WHERE auction_id = $1`, auctionID).Scan(&orderID))
+
+		// Charged off the close path, long before the hourly sweep
+		require.Eventually(t, func() bool {
+			var status string
+			require.NoError(t, db.QueryRow(ctx, `SELECT status::text FROM orders WHERE id = $1`, orderID).Scan(&status))
+			return status != "pending_payment"
+		}, 5*time.Second, 10*time.Millisecond)
+		return orderID, profileID
+	}
+	order := func(orderID int64) (status string, transactionID, paymentError *string, paidAt *time.Time) {
+		require.NoError(t, db.QueryRow(ctx, `
+			SELECT status::text, payment_intent_id, payment_error, paid_at FROM orders WHERE id = $1
+		`, orderID).Scan(&status, &transactionID, &paymentError, &paidAt))
+		return
+	}
+	captures := func(result string) float64 {
+		return testutil.ToFloat64(metrics.ExternalAPICallsTotal.WithLabelValues("authorize_net", "capture", result))
+	}
+
+	t.Run("success marks the order paid", func(t *testing.T) {
+		gateway := &mockGateway{}
+		before := captures("success")
+
+		orderID, profileID := closeWith(gateway)
+		assert.Equal(t, []string{profileID + " 150.00"}, gateway.captured())
+
+		status, transactionID, paymentError, paidAt := order(orderID)
+		assert.Equal(t, "paid", status)
+		require.NotNil(t, transactionID)
+		assert.Equal(t, fmt.Sprintf("txn-%d", orderID), *transactionID)
+		assert.Nil(t, paymentError)
+		assert.NotNil(t, paidAt)
+		assert.Equal(t, before+1, captures("success"))
+	})
+
+	t.Run("decline marks the order payment_failed", func(t *testing.T) {
+		gateway := &mockGateway{captureErrs: []error{fmt.Errorf("%w: card declined", payments.ErrDeclined)}}
+		before := captures("error")
+
+		orderID, _ := closeWith(gateway)
+		assert.Len(t, gateway.captured(), 1)
+
+		status, transactionID, paymentError, paidAt := order(orderID)
+		assert.Equal(t, "payment_failed", status)
+		assert.Nil(t, transactionID)
+		require.NotNil(t, paymentError)
+		assert.Equal(t, "payment declined: card declined", *paymentError)
+		assert.Nil(t, paidAt)
+		assert.Equal(t, before+1, captures("error"))
+	})
+
+	t.Run("held for review keeps the transaction for the review", func(t *testing.T) {
+		gateway := &mockGateway{captureErrs: []error{fmt.Errorf("%w: pending review", payments.ErrHeldForReview)}}
+
+		orderID, _ := closeWith(gateway)
+		assert.Len(t, gateway.captured(), 1)
+
+		status, transactionID, paymentError, paidAt := order(orderID)
+		assert.Equal(t, "payment_review", status)
+		require.NotNil(t, transactionID)
+		assert.Equal(t, fmt.Sprintf("txn-%d", orderID), *transactionID)
+		require.NotNil(t, paymentError)
+		assert.Equal(t, "payment held for review: pending review", *paymentError)
+		assert.Nil(t, paidAt)
+	})
+}
+
+func TestCapturer_RetryFindsChargeWhoseReplyWasLost(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, fixtures.SellerUser(t, db)), 150, buyerID)
+	c := closer.New(db, logger, closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }))
+	_, err := c.Close(ctx, auctionID)
+	require.NoError(t, err)
+
+	now := time.Now()
+	gateway := &mockGateway{lostReplies: 1}
+	capturer := payments.NewCapturer(db, logger, gateway,
+		payments.WithInterval(time.Minute),
+		payments.WithClock(func() time.Time { return now }),
+	)
+
+	// The charge goes through but its reply is lost, so it looks transient
+	_, err = capturer.RunOnce(ctx)
+	require.NoError(t, err)
+	var status string
+	require.NoError(t, db.QueryRow(ctx, `SELECT status::text FROM orders WHERE auction_id = $1`, auctionID).Scan(&status))
+	assert.Equal(t, "pending_payment", status)
+
+	// The retry finds that charge instead of making another
+	now = now.Add(time.Minute)
+	_, err = capturer.RunOnce(ctx)
+	require.NoError(t, err)
+	var orderID int64
+	var transactionID string
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT id, status::text, payment_intent_id FROM orders WHERE auction_id = $1
+	`, auctionID).Scan(&orderID, &status, &transactionID))
+	assert.Equal(t, "paid", status)
+	assert.Equal(t, fmt.Sprintf("txn-%d", orderID), transactionID)
+	assert.Len(t, gateway.captured(), 1, "the buyer is charged once")
+}
+
+func TestCapturer_RetriesTransientFailures(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, fixtures.SellerUser(t, db)), 150, buyerID)
+	c := closer.New(db, logger, closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }))
+	ok, err := c.Close(ctx, auctionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	var orderID int64
+	require.NoError(t, db.QueryRow(ctx, `SELECT id FROM orders WHERE auction_id = $1`, auctionID).Scan(&orderID))
+
+	now := time.Now()
+	gateway := &mockGateway{captureErrs: []error{errors.New("connection reset"), errors.New("status 503")}}
+	capturer := payments.NewCapturer(db, logger, gateway,
+		payments.WithInterval(time.Minute),
+		payments.WithMaxAttempts(3),
+		payments.WithClock(func() time.Time { return now }),
+	)
+	status := func() (string, int, *string) {
+		var status string
+		var attempts int
+		var paymentError *string
+		require.NoError(t, db.QueryRow(ctx, `
+			SELECT status::text, payment_attempts, payment_error FROM orders WHERE id = $1
+		`, orderID).Scan(&status, &attempts, &paymentError))
+		return status, attempts, paymentError
+	}
+
+	// An order nobody enqueued is found by the sweep; a network error keeps it
+	// pending with the reason
+	n, err := capturer.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	got, attempts, paymentError := status()
+	assert.Equal(t, "pending_payment", got)
+	assert.Equal(t, 1, attempts)
+	require.NotNil(t, paymentError)
+	assert.Equal(t, "connection reset", *paymentError)
+
+	// Not due again until the backoff passes
+	n, err = capturer.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	now = now.Add(time.Minute)
+	_, err = capturer.RunOnce(ctx)
+	require.NoError(t, err)
+	got, attempts, _ = status()
+	assert.Equal(t, "pending_payment", got)
+	assert.Equal(t, 2, attempts)
+
+	// Backoff grows with the attempt number
+	now = now.Add(time.Minute)
+	n, err = capturer.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	now = now.Add(time.Minute)
+	_, err = capturer.RunOnce(ctx)
+	require.NoError(t, err)
+	got, attempts, paymentError = status()
+	assert.Equal(t, "paid", got)
+	assert.Equal(t, 3, attempts)
+	assert.Nil(t, paymentError)
+	assert.Len(t, gateway.captured(), 3)
+
+	// Paid orders are never charged again
+	now = now.Add(time.Hour)
+	n, err = capturer.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestCapturer_GivesUpAfterMaxAttempts(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, fixtures.SellerUser(t, db)), 150, buyerID)
+	c := closer.New(db, logger, closer.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) }))
+	_, err := c.Close(ctx, auctionID)
+	require.NoError(t, err)
+
+	now := time.Now()
+	gateway := &mockGateway{captureErrs: []error{errors.New("timeout"), errors.New("timeout")}}
+	capturer := payments.NewCapturer(db, logger, gateway,
+		payments.WithMaxAttempts(2),
+		payments.WithClock(func() time.Time { return now }),
+	)
+	for i := 0; i < 2; i++ {
+		_, err := capturer.RunOnce(ctx)
+		require.NoError(t, err)
+		now = now.Add(time.Hour)
+	}
+
+	var status, paymentError string
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT status::text, payment_error FROM orders WHERE auction_id = $1
+	`, auctionID).Scan(&status, &paymentError))
+	assert.Equal(t, "payment_failed", status)
+	assert.Equal(t, "timeout", paymentError)
+	assert.Len(t, gateway.captured(), 2)
+}
@@ -0,0 +1,68 @@
+package integration
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/dbpool"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolExhaustion_Returns503(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	// A one-connection pool that gives up on a busy pool quickly
+	poolConfig, err := pgxpool.ParseConfig(db.Config().ConnString())
+	require.NoError(t, err)
+	poolConfig.MaxConns = 1
+	poolConfig.ConnConfig.Tracer = dbpool.NewTracer(50 * time.Millisecond)
+	tiny, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	require.NoError(t, err)
+	defer tiny.Close()
+
+	vehicleHandler := handler.NewVehicleHandler(tiny, logger, &config.Config{DBQueryTimeout: 5 * time.Second})
+	list := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/vehicles", nil)
+		rec := httptest.NewRecorder()
+		vehicleHandler.ListVehicles(rec, req)
+		return rec
+	}
+
+	// Saturate the pool, then pile requests on
+	held, err := tiny.Acquire(ctx)
+	require.NoError(t, err)
+
+	const requests = 8
+	recs := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range recs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs[i] = list()
+		}()
+	}
+	wg.Wait()
+
+	for _, rec := range recs {
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code, rec.Body.String())
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	}
+
+	// Serves again once a connection is free
+	held.Release()
+	assert.Equal(t, http.StatusOK, list().Code)
+}
@@ -0,0 +1,185 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/realtime"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// forceOCCConflictOnce makes the next UPDATE on auctions affect no rows, which
+// the processor sees exactly like a lost version race
+func forceOCCConflictOnce(t *testing.T, db *pgxpool.Pool) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := db.Exec(ctx, `
+		CREATE SEQUENCE IF NOT EXISTS test_occ_conflict_seq;
+		CREATE OR REPLACE FUNCTION test_occ_conflict() RETURNS TRIGGER AS $$
+		BEGIN
+			IF nextval('test_occ_conflict_seq') = 1 THEN
+				RETURN NULL;
+			END IF;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+		CREATE TRIGGER test_occ_conflict BEFORE UPDATE ON auctions
+			FOR EACH ROW EXECUTE FUNCTION test_occ_conflict();
+	`)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		db.Exec(ctx, `
+			DROP TRIGGER IF EXISTS test_occ_conflict ON auctions;
+			DROP FUNCTION IF EXISTS test_occ_conflict();
+			DROP SEQUENCE IF EXISTS test_occ_conflict_seq;
+		`)
+	})
+}
+
+func TestDebugSlowBids_ReflectsRetry(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	buyerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+
+	forceOCCConflictOnce(t, db)
+
+	broker := realtime.NewBroker(logger)
+	broker.Start()
+	defer broker.Stop()
+
+	// Async mode so the bid goes through a per-auction worker
+	engine := bidengine.NewEngine(db, logger, broker,
+		bidengine.WithMaxRetries(3),
+		bidengine.WithRetryBackoff(time.Millisecond),
+	)
+	engine.Start()
+	defer engine.Stop()
+
+	ticketID := uuid.New().String()
+	require.NoError(t, engine.Submit(domain.BidRequest{
+		TicketID:  ticketID,
+		AuctionID: auctionID,
+		UserID:    buyerID,
+		Amount:    decimal.NewFromInt(150),
+		CreatedAt: time.Now(),
+	}))
+
+	result, err := engine.GetResult(ticketID, 5*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "accepted", result.Status)
+	require.Equal(t, 1, result.Retries)
+
+	debugHandler := handler.NewDebugHandler(engine, broker, db, logger)
+
+	req := httptest.NewRequest("GET", "/debug/slowbids", nil)
+	rec := httptest.NewRecorder()
+	debugHandler.SlowBids(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Auctions []bidengine.WorkerStats `json:"auctions"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Auctions, 1)
+
+	stats := resp.Auctions[0]
+	assert.Equal(t, auctionID, stats.AuctionID)
+	assert.Equal(t, int64(1), stats.Processed)
+	assert.Equal(t, int64(1), stats.Retries)
+	assert.Greater(t, stats.MaxDurationMs, float64(0))
+}
+
+func TestDebugSeed_Rerunnable(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "test")
+	db := fixtures.SetupEmptyTestDB(t)
+	fixtures.MigrateTestDB(t, db)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	debugHandler := handler.NewDebugHandler(nil, nil, db, logger)
+	seed := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		debugHandler.Seed(rec, httptest.NewRequest("POST", "/debug/seed"+query, nil))
+		return rec
+	}
+	counts := func() map[string]int {
+		got := map[string]int{}
+		for _, table := range []string{"users", "vehicles", "vehicle_images", "auctions", "bids", "watchlist", "notifications"} {
+			var n int
+			require.NoError(t, db.QueryRow(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n))
+			got[table] = n
+		}
+		return got
+	}
+	want := map[string]int{
+		"users": 5, "vehicles": 10, "vehicle_images": 10, "auctions": 6,
+		"bids": 8, "watchlist": 5, "notifications": 3,
+	}
+
+	require.Equal(t, http.StatusOK, seed("").Code)
+	assert.Equal(t, want, counts())
+
+	// Drift from use is put back, and nothing is duplicated
+	_, err := db.Exec(ctx, `UPDATE auctions SET status = 'ended', current_bid = 1 WHERE id = 1`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, seed("").Code)
+	assert.Equal(t, want, counts())
+
+	var status string
+	var currentBid float64
+	require.NoError(t, db.QueryRow(ctx, `SELECT status::text, current_bid FROM auctions WHERE id = 1`).Scan(&status, &currentBid))
+	assert.Equal(t, "active", status)
+	assert.Equal(t, 24500.0, currentBid)
+
+	// New rows after the seed still get fresh IDs
+	fixtures.CreateUser(t, db, "after-seed@example.com", "After", "Seed")
+}
+
+func TestDebugSeed_ResetClearsConflicts(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "test")
+	db := fixtures.SetupEmptyTestDB(t)
+	fixtures.MigrateTestDB(t, db)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// Left over from elsewhere: a different user holding a seed email
+	_, err := db.Exec(context.Background(), `
+		INSERT INTO users (id, email, role) VALUES (100, 'buyer3@test.com', 'buyer')
+	`)
+	require.NoError(t, err)
+
+	debugHandler := handler.NewDebugHandler(nil, nil, db, logger)
+
+	rec := httptest.NewRecorder()
+	debugHandler.Seed(rec, httptest.NewRequest("POST", "/debug/seed", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "seed users")
+	assert.Contains(t, rec.Body.String(), "reset=true")
+
+	rec = httptest.NewRecorder()
+	debugHandler.Seed(rec, httptest.NewRequest("POST", "/debug/seed?reset=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var users int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM users`).Scan(&users))
+	assert.Equal(t, 5, users)
+}
@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/drafts"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDraftSweeper_WarnsThenArchivesStaleDraft(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	staleID := fixtures.TestVehicle(t, db, sellerID)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET status = 'draft' WHERE id = $1`, staleID)
+	require.NoError(t, err)
+
+	// Sweeps run with a clock 31 days out, so every current draft is stale
+	now := time.Now().Add(31 * 24 * time.Hour)
+	clock := func() time.Time { return now }
+	sweeper := drafts.New(db, logger,
+		drafts.WithStaleAfter(30*24*time.Hour),
+		drafts.WithArchiveAfter(7*24*time.Hour),
+		drafts.WithClock(clock),
+	)
+
+	warned, archived, err := sweeper.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, warned)
+	assert.Equal(t, 0, archived)
+
+	var notifications int
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND type = 'draft_stale'
+	`, sellerID).Scan(&notifications))
+	assert.Equal(t, 1, notifications)
+
+	// A second sweep before the grace period runs out does nothing
+	now = now.Add(24 * time.Hour)
+	warned, archived, err = sweeper.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, warned)
+	assert.Equal(t, 0, archived)
+
+	// Still untouched a week after the warning: archived
+	now = now.Add(7 * 24 * time.Hour)
+	_, archived, err = sweeper.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, archived)
+
+	var status string
+	var archivedAt *time.Time
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT status::text, archived_at FROM vehicles WHERE id = $1
+	`, staleID).Scan(&status, &archivedAt))
+	assert.Equal(t, "archived", status)
+	assert.NotNil(t, archivedAt)
+
+	// The seller can undo the archive
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+	r := chi.NewRouter()
+	r.Post("/api/vehicles/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		vehicleHandler.RestoreVehicle(w, r.WithContext(middleware.WithUserID(r.Context(), sellerID)))
+	})
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/vehicles/%d/restore", staleID), nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var notifiedAt *time.Time
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT status::text, archived_at, stale_draft_notified_at FROM vehicles WHERE id = $1
+	`, staleID).Scan(&status, &archivedAt, &notifiedAt))
+	assert.Equal(t, "draft", status)
+	assert.Nil(t, archivedAt)
+	assert.Nil(t, notifiedAt)
+}
+
+func TestDraftSweeper_EditClearsWarning(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET status = 'draft' WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+
+	now := time.Now().Add(31 * 24 * time.Hour)
+	sweeper := drafts.New(db, logger,
+		drafts.WithStaleAfter(30*24*time.Hour),
+		drafts.WithArchiveAfter(7*24*time.Hour),
+		drafts.WithClock(func() time.Time { return now }),
+	)
+	warned, _, err := sweeper.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, warned)
+
+	// The seller edits the draft after the warning
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+	r := chi.NewRouter()
+	r.Patch("/api/vehicles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		vehicleHandler.UpdateVehicle(w, r.WithContext(middleware.WithUserID(r.Context(), sellerID)))
+	})
+	req := httptest.NewRequest("PATCH", fmt.Sprintf("/api/vehicles/%d", vehicleID), strings.NewReader(`{"mileage": 36000}`))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	now = now.Add(8 * 24 * time.Hour)
+	_, archived, err := sweeper.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, archived)
+}
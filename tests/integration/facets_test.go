@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// facetVehicle creates an active vehicle with the given make and body type ("" leaves it unset)
+func facetVehicle(t *testing.T, db *pgxpool.Pool, sellerID int64, vehicleMake, bodyType string) int64 {
+	t.Helper()
+	vehicleID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2020, vehicleMake, "Model", 10000)
+	if bodyType != "" {
+		_, err := db.Exec(context.Background(), `UPDATE vehicles SET body_type = $1 WHERE id = $2`, bodyType, vehicleID)
+		require.NoError(t, err)
+	}
+	return vehicleID
+}
+
+func TestListVehicles_Facets(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	facetVehicle(t, db, sellerID, "Toyota", "Sedan")
+	facetVehicle(t, db, sellerID, "Toyota", "SUV")
+	facetVehicle(t, db, sellerID, "Honda", "Sedan")
+	facetVehicle(t, db, sellerID, "Ford", "Truck")
+	facetVehicle(t, db, sellerID, "Ford", "")
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	list := func(query string) map[string]json.RawMessage {
+		req := httptest.NewRequest("GET", "/api/vehicles"+query, nil)
+		rec := httptest.NewRecorder()
+		vehicleHandler.ListVehicles(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// Facets are opt-in
+	assert.NotContains(t, list(""), "facets")
+
+	var facets handler.Facets
+	require.NoError(t, json.Unmarshal(list("?facets=true")["facets"], &facets))
+	assert.Equal(t, map[string]int64{"Sedan": 2, "SUV": 1, "Truck": 1, "unspecified": 1}, facets.BodyType)
+	assert.Equal(t, map[string]int64{"Toyota": 2, "Honda": 1, "Ford": 2}, facets.Make)
+
+	// Counts follow the active filters
+	facets = handler.Facets{}
+	require.NoError(t, json.Unmarshal(list("?facets=true&make=toyota")["facets"], &facets))
+	assert.Equal(t, map[string]int64{"Sedan": 1, "SUV": 1}, facets.BodyType)
+	assert.Equal(t, map[string]int64{"Toyota": 2}, facets.Make)
+}
+
+func TestListAuctions_Facets(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	fixtures.TestAuction(t, db, facetVehicle(t, db, sellerID, "Toyota", "Sedan"))
+	fixtures.TestAuction(t, db, facetVehicle(t, db, sellerID, "Toyota", "Coupe"))
+	fixtures.TestAuction(t, db, facetVehicle(t, db, sellerID, "Ford", "Truck"))
+	hidden := fixtures.TestAuction(t, db, facetVehicle(t, db, sellerID, "Ford", "Truck"))
+	_, err := db.Exec(context.Background(), `UPDATE auctions SET hidden = true WHERE id = $1`, hidden)
+	require.NoError(t, err)
+	facetVehicle(t, db, sellerID, "Honda", "Sedan") // not in an auction
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/auctions?facets=true", nil)
+	rec := httptest.NewRecorder()
+	auctionHandler.ListAuctions(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Facets handler.Facets `json:"facets"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]int64{"Sedan": 1, "Coupe": 1, "Truck": 1}, resp.Facets.BodyType)
+	assert.Equal(t, map[string]int64{"Toyota": 2, "Ford": 1}, resp.Facets.Make)
+}
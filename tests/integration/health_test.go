@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/version"
 	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,6 +35,26 @@ func TestHealthEndpoint(t *testing.T) {
 	assert.Contains(t, resp, "timestamp")
 	assert.Contains(t, resp, "uptime")
 	assert.Contains(t, resp, "checks")
+
+	// Build info is always present, if empty in unstamped test builds
+	assert.Contains(t, resp, "version")
+	assert.Contains(t, resp, "commit")
+	assert.Contains(t, resp, "build_time")
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	commit, buildTime := version.Commit, version.BuildTime
+	t.Cleanup(func() { version.Commit, version.BuildTime = commit, buildTime })
+	version.Commit, version.BuildTime = "abc1234", "2025-06-01T12:00:00Z"
+
+	// Doesn't need the database
+	healthHandler := handler.NewHealthHandler(nil)
+
+	rec := httptest.NewRecorder()
+	healthHandler.Version(rec, httptest.NewRequest("GET", "/version", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"version":"dev","commit":"abc1234","build_time":"2025-06-01T12:00:00Z"}`, rec.Body.String())
 }
 
 func TestReadyEndpoint(t *testing.T) {
@@ -2,6 +2,7 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/ayubfarah/vehicle-auc/internal/config"
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
@@ -90,7 +92,7 @@ func TestGetUploadURL_NotOwner(t *testing.T) {
 
 	r.ServeHTTP(rec, req)
 
-	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
 
 func TestAddImage(t *testing.T) {
@@ -131,7 +133,7 @@ func TestAddImage(t *testing.T) {
 
 	// Verify in database
 	var count int
-	db.QueryRow(t.Context(), "SELECT COUNT(*) FROM vehicle_images WHERE vehicle_id = $1", vehicleID).Scan(&count)
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM vehicle_images WHERE vehicle_id = $1", vehicleID).Scan(&count)
 	assert.Equal(t, 1, count)
 }
 
@@ -176,7 +178,7 @@ func TestDeleteImage(t *testing.T) {
 
 	// Create image
 	var imageID int64
-	db.QueryRow(t.Context(), `
+	db.QueryRow(context.Background(), `
 		INSERT INTO vehicle_images (vehicle_id, s3_key, url, is_primary, display_order)
 		VALUES ($1, 'test.jpg', 'https://example.com/test.jpg', true, 1)
 		RETURNING id
@@ -199,7 +201,7 @@ func TestDeleteImage(t *testing.T) {
 
 	// Verify deleted
 	var count int
-	db.QueryRow(t.Context(), "SELECT COUNT(*) FROM vehicle_images WHERE id = $1", imageID).Scan(&count)
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM vehicle_images WHERE id = $1", imageID).Scan(&count)
 	assert.Equal(t, 0, count)
 }
 
@@ -214,7 +216,7 @@ func TestDeleteImage_NotOwner(t *testing.T) {
 
 	// Create image
 	var imageID int64
-	db.QueryRow(t.Context(), `
+	db.QueryRow(context.Background(), `
 		INSERT INTO vehicle_images (vehicle_id, s3_key, url, is_primary, display_order)
 		VALUES ($1, 'test.jpg', 'https://example.com/test.jpg', true, 1)
 		RETURNING id
@@ -233,7 +235,7 @@ func TestDeleteImage_NotOwner(t *testing.T) {
 
 	r.ServeHTTP(rec, req)
 
-	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
 
 func TestGetVehicleImages(t *testing.T) {
@@ -244,13 +246,13 @@ func TestGetVehicleImages(t *testing.T) {
 	vehicleID := fixtures.TestVehicle(t, db, sellerID)
 
 	// Create images
-	db.Exec(t.Context(), `
+	db.Exec(context.Background(), `
 		INSERT INTO vehicle_images (vehicle_id, s3_key, url, is_primary, display_order)
 		VALUES ($1, 'img1.jpg', 'https://example.com/img1.jpg', true, 1),
 		       ($1, 'img2.jpg', 'https://example.com/img2.jpg', false, 2)
 	`, vehicleID)
 
-	vehicleHandler := handler.NewVehicleHandler(db, logger)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
 
 	r := chi.NewRouter()
 	r.Get("/api/vehicles/{id}/images", vehicleHandler.GetVehicleImages)
@@ -269,3 +271,133 @@ func TestGetVehicleImages(t *testing.T) {
 	assert.Len(t, images, 2)
 }
 
+
+// mockStorage reports which objects exist, standing in for S3
+type mockStorage struct {
+	objects map[string]bool
+}
+
+func (m *mockStorage) GenerateUploadURL(ctx context.Context, bucket, key, contentType string, expires time.Duration) (string, error) {
+	return "https://" + bucket + ".example.com/" + key + "?signed=true", nil
+}
+
+func (m *mockStorage) DeleteObject(ctx context.Context, bucket, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *mockStorage) ObjectExists(ctx context.Context, bucket, key string) (bool, error) {
+	return m.objects[key], nil
+}
+
+func TestAddImage_VerifyUpload(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	cfg := &config.Config{
+		AWSS3Bucket:        "test-bucket",
+		ImageVerifyUploads: true,
+	}
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	storage := &mockStorage{objects: map[string]bool{"vehicles/1/present.jpg": true}}
+	imageHandler := handler.NewImageHandler(db, logger, cfg, storage)
+
+	r := chi.NewRouter()
+	r.Post("/api/vehicles/{id}/images", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		imageHandler.AddImage(w, r.WithContext(ctx))
+	})
+
+	tests := []struct {
+		name   string
+		s3Key  string
+		status int
+	}{
+		{"object present", "vehicles/1/present.jpg", http.StatusCreated},
+		{"object absent", "vehicles/1/phantom.jpg", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]interface{}{
+				"s3_key": tt.s3Key,
+				"url":    "https://test-bucket.s3.amazonaws.com/" + tt.s3Key,
+			}
+			bodyBytes, _ := json.Marshal(body)
+
+			req := httptest.NewRequest("POST", "/api/vehicles/"+strconv.FormatInt(vehicleID, 10)+"/images", bytes.NewReader(bodyBytes))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+
+	// Only the verified upload should be recorded
+	var count int
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM vehicle_images WHERE vehicle_id = $1", vehicleID).Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestAddImage_AutoPrimaryBatch(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	cfg := &config.Config{ImageAutoPrimary: true}
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	imageHandler := handler.NewImageHandler(db, logger, cfg, nil)
+
+	r := chi.NewRouter()
+	r.Post("/api/vehicles/{id}/images", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		imageHandler.AddImage(w, r.WithContext(ctx))
+	})
+
+	addImage := func(key string, isPrimary bool) map[string]interface{} {
+		body := map[string]interface{}{
+			"s3_key":     key,
+			"url":        "https://example.com/" + key,
+			"is_primary": isPrimary,
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest("POST", "/api/vehicles/"+strconv.FormatInt(vehicleID, 10)+"/images", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	countPrimary := func() int {
+		var count int
+		db.QueryRow(context.Background(), "SELECT COUNT(*) FROM vehicle_images WHERE vehicle_id = $1 AND is_primary", vehicleID).Scan(&count)
+		return count
+	}
+
+	// Batch without hints: the first upload becomes primary
+	first := addImage("batch-1.jpg", false)
+	assert.Equal(t, true, first["is_primary"])
+	second := addImage("batch-2.jpg", false)
+	assert.Equal(t, false, second["is_primary"])
+	addImage("batch-3.jpg", false)
+	assert.Equal(t, 1, countPrimary())
+
+	// A hint moves the primary without leaving two behind
+	hinted := addImage("batch-4.jpg", true)
+	assert.Equal(t, true, hinted["is_primary"])
+	assert.Equal(t, 1, countPrimary())
+
+	var primaryKey string
+	db.QueryRow(context.Background(), "SELECT s3_key FROM vehicle_images WHERE vehicle_id = $1 AND is_primary", vehicleID).Scan(&primaryKey)
+	assert.Equal(t, "batch-4.jpg", primaryKey)
+}
@@ -0,0 +1,74 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/migrate"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations_BootstrapEmptyDatabase(t *testing.T) {
+	db := fixtures.SetupEmptyTestDB(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	fixtures.MigrateTestDB(t, db)
+
+	files, err := migrate.Files()
+	require.NoError(t, err)
+	var recorded int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&recorded))
+	assert.Equal(t, len(files), recorded)
+
+	// A second run finds nothing left to apply
+	applied, err := migrate.Up(ctx, db)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	// Create a listing and read it back through the handlers
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+	r := chi.NewRouter()
+	r.Post("/api/vehicles", func(w http.ResponseWriter, r *http.Request) {
+		vehicleHandler.CreateVehicle(w, r.WithContext(middleware.WithUserID(r.Context(), sellerID)))
+	})
+	r.Get("/api/vehicles/{id}", vehicleHandler.GetVehicle)
+
+	body := `{"vin": "1HGCM82633A004352", "year": 2021, "make": "Honda", "model": "Accord", "starting_price": 15000}`
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/vehicles", bytes.NewReader([]byte(body))))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var created struct {
+		VehicleID int64 `json:"vehicle_id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/vehicles/%d", created.VehicleID), nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Vehicle struct {
+			VIN  string `json:"vin"`
+			Make string `json:"make"`
+		} `json:"vehicle"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "1HGCM82633A004352", resp.Vehicle.VIN)
+	assert.Equal(t, "Honda", resp.Vehicle.Make)
+}
@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupModerationRouter(db *pgxpool.Pool, logger *slog.Logger, userID int64) *chi.Mux {
+	moderationHandler := handler.NewModerationHandler(db, logger)
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+		})
+	})
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(middleware.RequireRole(db, logger, "admin"))
+		r.Get("/moderation", moderationHandler.ListQueue)
+		r.Post("/moderation/vehicles/{id}", moderationHandler.ReviewVehicle)
+	})
+	return r
+}
+
+func reviewVehicle(t *testing.T, r http.Handler, vehicleID int64, status string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := fmt.Sprintf(`{"status": %q, "reason": "title mismatch"}`, status)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/admin/moderation/vehicles/%d", vehicleID), strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestListAuctions_ExcludesHidden(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	adminID := fixtures.AdminUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	flaggedVehicleID := fixtures.TestVehicle(t, db, sellerID)
+	fixtures.TestAuction(t, db, flaggedVehicleID)
+	visibleAuctionID := fixtures.TestAuction(t, db, fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000))
+
+	rec := reviewVehicle(t, setupModerationRouter(db, logger, adminID), flaggedVehicleID, "rejected")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	req := httptest.NewRequest("GET", "/api/auctions", nil)
+	rec = httptest.NewRecorder()
+	auctionHandler.ListAuctions(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	auctions := resp["items"].([]interface{})
+	require.Len(t, auctions, 1)
+	assert.Equal(t, float64(visibleAuctionID), auctions[0].(map[string]interface{})["id"])
+	assert.Equal(t, float64(1), resp["total"])
+
+	// The auction row is preserved, just hidden
+	var hidden bool
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT hidden FROM auctions WHERE vehicle_id = $1`, flaggedVehicleID).Scan(&hidden))
+	assert.True(t, hidden)
+}
+
+func TestGetAuction_HiddenVisibleToAdminsAndBidders(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	adminID := fixtures.AdminUser(t, db)
+	bidderID := fixtures.BuyerUser(t, db)
+	strangerID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	auctionID := fixtures.TestAuction(t, db, vehicleID)
+	fixtures.TestBid(t, db, auctionID, bidderID, decimal.NewFromInt(100), "accepted")
+
+	rec := reviewVehicle(t, setupModerationRouter(db, logger, adminID), vehicleID, "flagged")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	get := func(userID int64) int {
+		r := chi.NewRouter()
+		r.Get("/api/auctions/{id}", func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if userID != 0 {
+				ctx = middleware.WithUserID(ctx, userID)
+			}
+			auctionHandler.GetAuction(w, r.WithContext(ctx))
+		})
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d", auctionID), nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, get(0))
+	assert.Equal(t, http.StatusNotFound, get(strangerID))
+	assert.Equal(t, http.StatusOK, get(bidderID))
+	assert.Equal(t, http.StatusOK, get(adminID))
+
+	// Approving brings it back for everyone
+	rec = reviewVehicle(t, setupModerationRouter(db, logger, adminID), vehicleID, "approved")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusOK, get(0))
+}
+
+func TestModeration_RequiresAdmin(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	rec := reviewVehicle(t, setupModerationRouter(db, logger, sellerID), vehicleID, "approved")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
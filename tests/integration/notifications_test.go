@@ -1,12 +1,15 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
@@ -20,7 +23,7 @@ import (
 
 func createTestNotification(t *testing.T, db *pgxpool.Pool, userID int64, title, notifType string) int64 {
 	var id int64
-	err := db.QueryRow(t.Context(),
+	err := db.QueryRow(context.Background(),
 		`INSERT INTO notifications (user_id, type, title, message) VALUES ($1, $2, $3, $4) RETURNING id`,
 		userID, notifType, title, "Test message",
 	).Scan(&id)
@@ -52,7 +55,7 @@ func TestGetNotifications_Empty(t *testing.T) {
 	err := json.Unmarshal(rec.Body.Bytes(), &resp)
 	require.NoError(t, err)
 
-	notifications := resp["notifications"].([]interface{})
+	notifications := resp["items"].([]interface{})
 	assert.Len(t, notifications, 0)
 	assert.Equal(t, float64(0), resp["total"])
 	assert.Equal(t, float64(0), resp["unread"])
@@ -86,10 +89,13 @@ func TestGetNotifications_WithData(t *testing.T) {
 	var resp map[string]interface{}
 	json.Unmarshal(rec.Body.Bytes(), &resp)
 
-	notifications := resp["notifications"].([]interface{})
+	notifications := resp["items"].([]interface{})
 	assert.Len(t, notifications, 2)
 	assert.Equal(t, float64(2), resp["total"])
 	assert.Equal(t, float64(2), resp["unread"])
+	assert.Equal(t, float64(20), resp["limit"])
+	assert.Equal(t, float64(0), resp["offset"])
+	assert.Equal(t, false, resp["has_more"])
 }
 
 func TestGetUnreadCount(t *testing.T) {
@@ -104,7 +110,7 @@ func TestGetUnreadCount(t *testing.T) {
 	notif3 := createTestNotification(t, db, userID, "Read", "bid_accepted")
 
 	// Mark one as read
-	db.Exec(t.Context(), "UPDATE notifications SET read_at = NOW() WHERE id = $1", notif3)
+	db.Exec(context.Background(), "UPDATE notifications SET read_at = NOW() WHERE id = $1", notif3)
 
 	notifHandler := handler.NewNotificationHandler(db, logger)
 
@@ -150,7 +156,7 @@ func TestMarkRead(t *testing.T) {
 
 	// Verify marked as read
 	var readAt interface{}
-	db.QueryRow(t.Context(), "SELECT read_at FROM notifications WHERE id = $1", notifID).Scan(&readAt)
+	db.QueryRow(context.Background(), "SELECT read_at FROM notifications WHERE id = $1", notifID).Scan(&readAt)
 	assert.NotNil(t, readAt)
 }
 
@@ -162,7 +168,7 @@ func TestMarkRead_AlreadyRead(t *testing.T) {
 	notifID := createTestNotification(t, db, userID, "Already Read", "bid_outbid")
 
 	// Mark as read
-	db.Exec(t.Context(), "UPDATE notifications SET read_at = NOW() WHERE id = $1", notifID)
+	db.Exec(context.Background(), "UPDATE notifications SET read_at = NOW() WHERE id = $1", notifID)
 
 	notifHandler := handler.NewNotificationHandler(db, logger)
 
@@ -208,10 +214,63 @@ func TestMarkAllRead(t *testing.T) {
 
 	// Verify all marked as read
 	var unreadCount int
-	db.QueryRow(t.Context(), "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&unreadCount)
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&unreadCount)
 	assert.Equal(t, 0, unreadCount)
 }
 
+func TestMarkReadBulk(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	userID := fixtures.BuyerUser(t, db)
+	otherID := fixtures.BuyerUser(t, db)
+
+	first := createTestNotification(t, db, userID, "Unread 1", "bid_outbid")
+	second := createTestNotification(t, db, userID, "Unread 2", "auction_won")
+	third := createTestNotification(t, db, userID, "Unread 3", "bid_accepted")
+	others := createTestNotification(t, db, otherID, "Someone else's", "bid_outbid")
+
+	notifHandler := handler.NewNotificationHandler(db, logger)
+
+	r := chi.NewRouter()
+	r.Post("/api/notifications/read", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), userID)
+		notifHandler.MarkReadBulk(w, r.WithContext(ctx))
+	})
+	markRead := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/notifications/read", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Another user's notification in the list is ignored
+	body := fmt.Sprintf(`{"ids": [%d, %d, %d]}`, first, third, others)
+	rec := markRead(body)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp map[string]int64
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, int64(2), resp["updated"])
+
+	isRead := func(id int64) bool {
+		var read bool
+		require.NoError(t, db.QueryRow(context.Background(), "SELECT read_at IS NOT NULL FROM notifications WHERE id = $1", id).Scan(&read))
+		return read
+	}
+	assert.True(t, isRead(first))
+	assert.False(t, isRead(second))
+	assert.True(t, isRead(third))
+	assert.False(t, isRead(others))
+
+	// Already-read IDs aren't counted again
+	rec = markRead(body)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, int64(0), resp["updated"])
+
+	assert.Equal(t, http.StatusBadRequest, markRead(`{"ids": []}`).Code)
+	assert.Equal(t, http.StatusBadRequest, markRead(`{"ids": "all"}`).Code)
+}
+
 func TestDeleteNotification(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
@@ -236,7 +295,7 @@ func TestDeleteNotification(t *testing.T) {
 
 	// Verify deleted
 	var count int
-	db.QueryRow(t.Context(), "SELECT COUNT(*) FROM notifications WHERE id = $1", notifID).Scan(&count)
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM notifications WHERE id = $1", notifID).Scan(&count)
 	assert.Equal(t, 0, count)
 }
 
@@ -275,7 +334,7 @@ func TestGetNotifications_UnreadOnly(t *testing.T) {
 	readNotif := createTestNotification(t, db, userID, "Read", "auction_won")
 
 	// Mark one as read
-	db.Exec(t.Context(), "UPDATE notifications SET read_at = NOW() WHERE id = $1", readNotif)
+	db.Exec(context.Background(), "UPDATE notifications SET read_at = NOW() WHERE id = $1", readNotif)
 
 	notifHandler := handler.NewNotificationHandler(db, logger)
 
@@ -295,7 +354,60 @@ func TestGetNotifications_UnreadOnly(t *testing.T) {
 	var resp map[string]interface{}
 	json.Unmarshal(rec.Body.Bytes(), &resp)
 
-	notifications := resp["notifications"].([]interface{})
+	notifications := resp["items"].([]interface{})
 	assert.Len(t, notifications, 1)
 }
 
+func TestGetNotifications_FilterByType(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	userID := fixtures.BuyerUser(t, db)
+	createTestNotification(t, db, userID, "Outbid 1", "outbid")
+	readOutbid := createTestNotification(t, db, userID, "Outbid 2", "outbid")
+	createTestNotification(t, db, userID, "Won", "auction_won")
+	createTestNotification(t, db, userID, "Lost", "auction_lost")
+	_, err := db.Exec(context.Background(), `UPDATE notifications SET read_at = NOW() WHERE id = $1`, readOutbid)
+	require.NoError(t, err)
+
+	notifHandler := handler.NewNotificationHandler(db, logger)
+	r := chi.NewRouter()
+	r.Get("/api/notifications", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), userID)
+		notifHandler.GetNotifications(w, r.WithContext(ctx))
+	})
+	list := func(query string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/notifications"+query, nil))
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+	typesOf := func(resp map[string]interface{}) []string {
+		types := []string{}
+		for _, n := range resp["items"].([]interface{}) {
+			types = append(types, n.(map[string]interface{})["type"].(string))
+		}
+		return types
+	}
+
+	code, resp := list("?type=outbid")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"outbid", "outbid"}, typesOf(resp))
+	assert.Equal(t, float64(2), resp["total"])
+	assert.Equal(t, float64(1), resp["unread"])
+
+	// Combined with the unread filter
+	_, resp = list("?type=outbid&unread=true")
+	assert.Equal(t, []string{"outbid"}, typesOf(resp))
+
+	// Repeated and comma-separated forms
+	_, resp = list("?type=auction_won&type=auction_lost")
+	assert.ElementsMatch(t, []string{"auction_won", "auction_lost"}, typesOf(resp))
+	_, resp = list("?type=auction_won,outbid")
+	assert.Len(t, typesOf(resp), 3)
+
+	code, resp = list("?type=bogus")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, resp["error"], "bogus")
+}
@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/payments"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockGateway returns canned payment methods per profile and records
+// captures, failing them with captureErrs in order, then succeeding. The
+// next lostReplies charges that go through still report an error, as if the
+// connection dropped before the reply; FindCapture finds them.
+type mockGateway struct {
+	methods map[string][]payments.PaymentMethod
+
+	mu          sync.Mutex
+	captures    []string
+	captureErrs []error
+	lostReplies int
+	charged     map[int64]string
+}
+
+func (m *mockGateway) ListPaymentMethods(ctx context.Context, profileID string) ([]payments.PaymentMethod, error) {
+	return m.methods[profileID], nil
+}
+
+func (m *mockGateway) Capture(ctx context.Context, profileID string, amount decimal.Decimal, orderID int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.captures = append(m.captures, profileID+" "+amount.StringFixed(2))
+	transactionID := fmt.Sprintf("txn-%d", orderID)
+	if len(m.captureErrs) > 0 {
+		err := m.captureErrs[0]
+		m.captureErrs = m.captureErrs[1:]
+		if errors.Is(err, payments.ErrHeldForReview) {
+			return transactionID, err
+		}
+		return "", err
+	}
+	if m.charged == nil {
+		m.charged = make(map[int64]string)
+	}
+	m.charged[orderID] = transactionID
+	if m.lostReplies > 0 {
+		m.lostReplies--
+		return "", errors.New("connection reset")
+	}
+	return transactionID, nil
+}
+
+func (m *mockGateway) FindCapture(ctx context.Context, profileID string, orderID int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.charged[orderID], nil
+}
+
+// captured lists the captures so far as "profile amount"
+func (m *mockGateway) captured() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.captures...)
+}
+
+func TestGetPaymentStatus_MaskedMethods(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	userID := fixtures.VerifiedUser(t, db)
+	var profileID string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT authorize_payment_profile_id FROM users WHERE id = $1`, userID).Scan(&profileID))
+
+	gateway := &mockGateway{methods: map[string][]payments.PaymentMethod{
+		profileID: {
+			{ID: "pm_1", Brand: "visa", Last4: "4242", ExpMonth: 12, ExpYear: 2030, IsDefault: true},
+			// A misbehaving gateway leaking more than four digits gets truncated
+			{ID: "pm_2", Brand: "mastercard", Last4: "5555555555554444", ExpMonth: 1, ExpYear: 2029},
+		},
+	}}
+	paymentHandler := handler.NewPaymentHandler(db, logger, gateway)
+
+	req := httptest.NewRequest("GET", "/api/me/payment", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	paymentHandler.GetPaymentStatus(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		HasPaymentMethod bool                     `json:"has_payment_method"`
+		PaymentMethods   []payments.PaymentMethod `json:"payment_methods"`
+		Verification     struct {
+			IsIDVerified bool `json:"is_id_verified"`
+		} `json:"verification"`
+		CanBid bool `json:"can_bid"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	assert.True(t, resp.HasPaymentMethod)
+	assert.True(t, resp.Verification.IsIDVerified)
+	assert.True(t, resp.CanBid)
+	require.Len(t, resp.PaymentMethods, 2)
+	assert.Equal(t, "visa", resp.PaymentMethods[0].Brand)
+	assert.Equal(t, "4242", resp.PaymentMethods[0].Last4)
+	assert.Equal(t, "4444", resp.PaymentMethods[1].Last4)
+	assert.NotContains(t, rec.Body.String(), "5555555555554444")
+}
+
+func TestGetPaymentStatus_RequiresAuth(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	paymentHandler := handler.NewPaymentHandler(db, logger, &mockGateway{})
+
+	req := httptest.NewRequest("GET", "/api/me/payment", nil)
+	rec := httptest.NewRecorder()
+	paymentHandler.GetPaymentStatus(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestGetPaymentStatus_NoProfile(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	userID := fixtures.TestUser(t, db)
+	paymentHandler := handler.NewPaymentHandler(db, logger, &mockGateway{})
+
+	req := httptest.NewRequest("GET", "/api/me/payment", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	paymentHandler.GetPaymentStatus(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, false, resp["has_payment_method"])
+	assert.Empty(t, resp["payment_methods"])
+	assert.Equal(t, false, resp["can_bid"])
+}
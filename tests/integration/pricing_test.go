@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedSale records an ended auction for a comparable vehicle; a zero price means it didn't sell
+func seedSale(t *testing.T, db *pgxpool.Pool, sellerID, buyerID int64, year int, make, model string, price float64) {
+	t.Helper()
+	vehicleID := fixtures.TestVehicleWithDetails(t, db, sellerID, year, make, model, 1000)
+
+	var winnerID *int64
+	var winningBid *float64
+	if price > 0 {
+		winnerID = &buyerID
+		winningBid = &price
+	}
+	_, err := db.Exec(context.Background(), `
+		INSERT INTO auctions (vehicle_id, status, starts_at, ends_at, winner_id, winning_bid)
+		VALUES ($1, 'ended', $2, $3, $4, $5)
+	`, vehicleID, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour), winnerID, winningBid)
+	require.NoError(t, err)
+}
+
+func getPricingInsights(t *testing.T, h *handler.VehicleHandler, vehicleID, userID int64) (*httptest.ResponseRecorder, handler.PricingInsightsResponse) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/vehicles/"+strconv.FormatInt(vehicleID, 10)+"/pricing-insights", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", strconv.FormatInt(vehicleID, 10))
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	req = req.WithContext(middleware.WithUserID(ctx, userID))
+	rec := httptest.NewRecorder()
+
+	h.GetPricingInsights(rec, req)
+
+	var resp handler.PricingInsightsResponse
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	}
+	return rec, resp
+}
+
+func TestGetPricingInsights_FromHistoricalSales(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+
+	// Four comparable sales, one no-sale, and an unrelated model that must be ignored
+	seedSale(t, db, sellerID, buyerID, 2021, "Honda", "Civic", 18000)
+	seedSale(t, db, sellerID, buyerID, 2022, "Honda", "Civic", 20000)
+	seedSale(t, db, sellerID, buyerID, 2022, "Honda", "Civic", 22000)
+	seedSale(t, db, sellerID, buyerID, 2023, "Honda", "Civic", 24000)
+	seedSale(t, db, sellerID, buyerID, 2022, "Honda", "Civic", 0)
+	seedSale(t, db, sellerID, buyerID, 2022, "Honda", "Accord", 90000)
+
+	vehicleID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Honda", "Civic", 15000)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET reserve_price = 21000 WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+
+	h := handler.NewVehicleHandler(db, logger, &config.Config{})
+	rec, resp := getPricingInsights(t, h, vehicleID, sellerID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	assert.Equal(t, "make_model", resp.Comparables)
+	assert.Equal(t, 4, resp.SampleSize)
+	assert.Equal(t, "low", resp.Confidence)
+	require.NotNil(t, resp.MedianSalePrice)
+	assert.Equal(t, "21000.00", *resp.MedianSalePrice)
+	require.NotNil(t, resp.SuggestedReservePrice)
+	assert.Equal(t, "19500.00", resp.SuggestedReservePrice.Low)
+	assert.Equal(t, "21000.00", resp.SuggestedReservePrice.High)
+	require.NotNil(t, resp.SuggestedStartingPrice)
+	assert.Equal(t, "11700.00", resp.SuggestedStartingPrice.Low)
+	assert.Equal(t, "15600.00", resp.SuggestedStartingPrice.High)
+
+	// 4 of 5 sold, and 2 of those 4 cleared the 21000 reserve
+	require.NotNil(t, resp.SellThroughRate)
+	assert.InDelta(t, 0.8, *resp.SellThroughRate, 0.001)
+	assert.InDelta(t, 0.4, resp.EstimatedSellThrough, 0.001)
+}
+
+func TestGetPricingInsights_SparseData(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	seedSale(t, db, sellerID, buyerID, 2022, "Lotus", "Emira", 95000)
+
+	vehicleID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Lotus", "Emira", 80000)
+
+	h := handler.NewVehicleHandler(db, logger, &config.Config{})
+	rec, resp := getPricingInsights(t, h, vehicleID, sellerID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	assert.Equal(t, "none", resp.Comparables)
+	assert.Equal(t, "insufficient_data", resp.Confidence)
+	assert.Nil(t, resp.SuggestedStartingPrice)
+	assert.Nil(t, resp.SuggestedReservePrice)
+	assert.Nil(t, resp.SellThroughRate)
+	assert.Equal(t, 0.5, resp.EstimatedSellThrough)
+	assert.NotEmpty(t, resp.Message)
+
+	// Other sellers can't see insights for the listing, or tell that it exists
+	otherID := fixtures.BuyerUser(t, db)
+	rec, _ = getPricingInsights(t, h, vehicleID, otherID)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
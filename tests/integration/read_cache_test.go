@@ -0,0 +1,146 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/realtime"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAuction_ReadCache(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	// Bids reach the cache the way they do in the server: through the broker
+	auctionCache := handler.NewAuctionCache(time.Minute)
+	broker := realtime.NewBroker(logger, realtime.WithEventHook(auctionCache.Observe))
+	broker.Start()
+	defer broker.Stop()
+
+	engine := bidengine.NewEngine(db, logger, broker, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, broker, nil,
+		handler.WithAuctionCache(auctionCache))
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}", auctionHandler.GetAuction)
+
+	get := func() map[string]interface{} {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d", auctionID), nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp struct {
+			Auction map[string]interface{} `json:"auction"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.Auction
+	}
+	hits := func() float64 {
+		return testutil.ToFloat64(metrics.ReadCacheRequests.WithLabelValues("auction", "hit"))
+	}
+
+	assert.Equal(t, "Honda", get()["make"])
+
+	// Changed behind the cache's back, with no event: the second read is a
+	// hit and never sees it
+	_, err := db.Exec(ctx, `UPDATE vehicles SET make = 'Acura' WHERE id = (SELECT vehicle_id FROM auctions WHERE id = $1)`, auctionID)
+	require.NoError(t, err)
+	before := hits()
+	assert.Equal(t, "Honda", get()["make"])
+	assert.Equal(t, before+1, hits())
+
+	// Viewer counts aren't changes
+	auctionCache.Observe(domain.BidEvent{Type: "viewer_count", AuctionID: auctionID, Viewers: 3})
+	assert.Equal(t, "Honda", get()["make"])
+
+	// An accepted bid bumps the version and expires the entry
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, buyerID, "150.00").Status)
+	broker.Flush()
+
+	auction := get()
+	assert.Equal(t, "Acura", auction["make"])
+	assert.Equal(t, true, auction["has_bids"])
+	assert.Equal(t, "150.00", auction["current_bid"])
+
+	// A replayed event for the version already cached keeps the entry
+	var version int
+	require.NoError(t, db.QueryRow(ctx, `SELECT version FROM auctions WHERE id = $1`, auctionID).Scan(&version))
+	_, err = db.Exec(ctx, `UPDATE vehicles SET make = 'Honda' WHERE id = (SELECT vehicle_id FROM auctions WHERE id = $1)`, auctionID)
+	require.NoError(t, err)
+	auctionCache.Observe(domain.BidEvent{Type: "bid_accepted", AuctionID: auctionID, Version: version})
+	assert.Equal(t, "Acura", get()["make"])
+
+	auctionCache.Observe(domain.BidEvent{Type: "bid_accepted", AuctionID: auctionID, Version: version + 1})
+	assert.Equal(t, "Honda", get()["make"])
+}
+
+func TestGetVehicle_ReadCache(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{ReadCacheTTL: time.Minute})
+	r := chi.NewRouter()
+	r.Get("/api/vehicles/{id}", vehicleHandler.GetVehicle)
+	r.Patch("/api/vehicles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		vehicleHandler.UpdateVehicle(w, r.WithContext(middleware.WithUserID(r.Context(), sellerID)))
+	})
+
+	get := func() map[string]interface{} {
+		req := httptest.NewRequest("GET", "/api/vehicles/"+itoa(vehicleID), nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp struct {
+			Vehicle map[string]interface{} `json:"vehicle"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.Vehicle
+	}
+
+	assert.Equal(t, "Honda", get()["make"])
+
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET mileage = 1234 WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+	assert.Equal(t, 35000.0, get()["mileage"], "second read should come from the cache")
+
+	// Saving through the API bumps the version and expires the entry
+	req := httptest.NewRequest("PATCH", "/api/vehicles/"+itoa(vehicleID), strings.NewReader(`{"make": "Acura"}`))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	vehicle := get()
+	assert.Equal(t, "Acura", vehicle["make"])
+	assert.Equal(t, 1234.0, vehicle["mileage"])
+}
@@ -0,0 +1,282 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// retractRouter serves the retract endpoint with a 30s window
+func retractRouter(db *pgxpool.Pool) http.Handler {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{BidRetractWindow: 30 * time.Second}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/bids/{bidId}/retract", auctionHandler.RetractBid)
+	return r
+}
+
+func retract(t *testing.T, r http.Handler, auctionID, bidID, userID int64) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/auctions/%d/bids/%d/retract", auctionID, bidID), nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func latestBidID(t *testing.T, db *pgxpool.Pool, auctionID, userID int64) int64 {
+	t.Helper()
+	var id int64
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT id FROM bids WHERE auction_id = $1 AND user_id = $2 ORDER BY id DESC LIMIT 1
+	`, auctionID, userID).Scan(&id))
+	return id
+}
+
+func TestRetractBid_RestoresPreviousLead(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	firstID := fixtures.BuyerUser(t, db)
+	secondID := fixtures.VerifiedUser(t, db)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, firstID, "150.00").Status)
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, secondID, "200.00").Status)
+	firstBid := latestBidID(t, db, auctionID, firstID)
+	secondBid := latestBidID(t, db, auctionID, secondID)
+
+	r := retractRouter(db)
+
+	// Someone else's bid doesn't exist as far as the caller is concerned
+	rec := retract(t, r, auctionID, secondBid, firstID)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	rec = retract(t, r, auctionID, secondBid, secondID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Status           string `json:"status"`
+		CurrentBid       string `json:"current_bid"`
+		CurrentBidUserID *int64 `json:"current_bid_user_id"`
+		BidCount         int    `json:"bid_count"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "retracted", resp.Status)
+	assert.Equal(t, "150.00", resp.CurrentBid)
+	require.NotNil(t, resp.CurrentBidUserID)
+	assert.Equal(t, firstID, *resp.CurrentBidUserID)
+	assert.Equal(t, 1, resp.BidCount)
+
+	var currentBid decimal.Decimal
+	var leaderID int64
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT current_bid, current_bid_user_id FROM auctions WHERE id = $1
+	`, auctionID).Scan(&currentBid, &leaderID))
+	assert.Equal(t, "150.00", currentBid.StringFixed(2))
+	assert.Equal(t, firstID, leaderID)
+
+	var firstStatus, secondStatus string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT status::text FROM bids WHERE id = $1`, firstBid).Scan(&firstStatus))
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT status::text FROM bids WHERE id = $1`, secondBid).Scan(&secondStatus))
+	assert.Equal(t, "accepted", firstStatus)
+	assert.Equal(t, "retracted", secondStatus)
+	assert.Contains(t, auctionEventTypes(t, db, auctionID), bidengine.EventRetracted)
+
+	// The retracted bid no longer counts as bidding on the auction
+	view := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	reads := chi.NewRouter()
+	reads.Get("/api/auctions/{id}", view.GetAuction)
+	reads.Get("/api/auctions/{id}/bids", view.GetBidHistory)
+
+	rec = httptest.NewRecorder()
+	reads.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d/bids", auctionID), nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var history struct {
+		Bids  []map[string]interface{} `json:"bids"`
+		Total int                      `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	assert.Equal(t, 1, history.Total)
+	require.Len(t, history.Bids, 1)
+	assert.Equal(t, "150.00", history.Bids[0]["amount"])
+
+	rec = httptest.NewRecorder()
+	reads.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/auctions/%d", auctionID), nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var detail struct {
+		Auction struct {
+			UniqueBidders int `json:"unique_bidders"`
+		} `json:"auction"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &detail))
+	assert.Equal(t, 1, detail.Auction.UniqueBidders)
+
+	// Retracted once is retracted for good
+	rec = retract(t, r, auctionID, secondBid, secondID)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "bid_not_highest")
+
+	// The restored lead is a real lead: the next bid has to beat it
+	assert.Equal(t, "rejected", submitSyncBid(t, engine, auctionID, secondID, "140.00").Status)
+	assert.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, secondID, "160.00").Status)
+}
+
+func TestRetractBid_ForbiddenAfterWindowOrOutbid(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	firstID := fixtures.BuyerUser(t, db)
+	secondID := fixtures.VerifiedUser(t, db)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, firstID, "150.00").Status)
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, secondID, "200.00").Status)
+	firstBid := latestBidID(t, db, auctionID, firstID)
+	secondBid := latestBidID(t, db, auctionID, secondID)
+
+	r := retractRouter(db)
+
+	// Still in the window, but outbid
+	rec := retract(t, r, auctionID, firstBid, firstID)
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "bid_not_highest")
+
+	// The high bid, but placed a minute ago
+	_, err := db.Exec(context.Background(), `UPDATE bids SET created_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, secondBid)
+	require.NoError(t, err)
+	rec = retract(t, r, auctionID, secondBid, secondID)
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "retract_window_closed")
+
+	var currentBid decimal.Decimal
+	var leaderID int64
+	var bidCount int
+	require.NoError(t, db.QueryRow(context.Background(), `
+		SELECT current_bid, current_bid_user_id, bid_count FROM auctions WHERE id = $1
+	`, auctionID).Scan(&currentBid, &leaderID, &bidCount))
+	assert.Equal(t, "200.00", currentBid.StringFixed(2))
+	assert.Equal(t, secondID, leaderID)
+	assert.Equal(t, 2, bidCount)
+	assert.NotContains(t, auctionEventTypes(t, db, auctionID), bidengine.EventRetracted)
+}
+
+func TestRetractBid_MasksPrivateLeader(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	firstID := fixtures.BuyerUser(t, db)
+	secondID := fixtures.VerifiedUser(t, db)
+	_, err := db.Exec(context.Background(), `UPDATE users SET hide_bidder_identity = true WHERE id = $1`, firstID)
+	require.NoError(t, err)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, firstID, "150.00").Status)
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, secondID, "200.00").Status)
+
+	broadcaster := &recordingBroadcaster{}
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{BidRetractWindow: 30 * time.Second}, broadcaster, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/bids/{bidId}/retract", auctionHandler.RetractBid)
+
+	rec := retract(t, r, auctionID, latestBidID(t, db, auctionID, secondID), secondID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Nil(t, resp["current_bid_user_id"])
+	assert.Equal(t, "Bidder 1", resp["high_bidder_alias"])
+
+	events := broadcaster.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "bid_retracted", events[0].Type)
+	assert.Equal(t, int64(0), events[0].BidderID)
+	assert.Equal(t, "150.00", events[0].Amount.StringFixed(2))
+}
+
+func TestRetractBid_CappedAndListedForAdmins(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+	firstID := fixtures.BuyerUser(t, db)
+	shillID := fixtures.VerifiedUser(t, db)
+
+	engine := bidengine.NewEngine(db, logger, nil, bidengine.WithSyncMode(true))
+	engine.Start()
+	defer engine.Stop()
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{
+		BidRetractWindow:        30 * time.Second,
+		BidRetractMaxPerAuction: 1,
+	}, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/api/auctions/{id}/bids/{bidId}/retract", auctionHandler.RetractBid)
+	r.Get("/api/admin/retractions", auctionHandler.ListRetractions)
+
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, firstID, "150.00").Status)
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, shillID, "200.00").Status)
+	rec := retract(t, r, auctionID, latestBidID(t, db, auctionID, shillID), shillID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// The second probe on the same auction is refused and changes nothing
+	require.Equal(t, "accepted", submitSyncBid(t, engine, auctionID, shillID, "210.00").Status)
+	rec = retract(t, r, auctionID, latestBidID(t, db, auctionID, shillID), shillID)
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "retract_limit_reached")
+
+	var currentBid decimal.Decimal
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT current_bid FROM auctions WHERE id = $1`, auctionID).Scan(&currentBid))
+	assert.Equal(t, "210.00", currentBid.StringFixed(2))
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/admin/retractions?user_id=%d", shillID), nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var list struct {
+		Items []struct {
+			AuctionID       int64  `json:"auction_id"`
+			Amount          string `json:"amount"`
+			UserRetractions int    `json:"user_retractions"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, auctionID, list.Items[0].AuctionID)
+	assert.Equal(t, "200.00", list.Items[0].Amount)
+	assert.Equal(t, 1, list.Items[0].UserRetractions)
+}
@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupReviewRouter(db *pgxpool.Pool, logger *slog.Logger) *chi.Mux {
+	reviewHandler := handler.NewReviewHandler(db, logger)
+
+	r := chi.NewRouter()
+	r.Post("/api/orders/{id}/review", func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := strconv.ParseInt(r.Header.Get("X-Test-User"), 10, 64)
+		reviewHandler.CreateReview(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+	})
+	r.Get("/api/sellers/{id}/reviews", reviewHandler.GetSellerReviews)
+	return r
+}
+
+func postReview(r http.Handler, orderID, userID int64, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/orders/"+strconv.FormatInt(orderID, 10)+"/review", strings.NewReader(body))
+	req.Header.Set("X-Test-User", strconv.FormatInt(userID, 10))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateReview_OnlyBuyerOfCompletedOrder(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	r := setupReviewRouter(db, logger)
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	otherID := fixtures.BuyerUser(t, db)
+
+	delivered := fixtures.TestOrder(t, db, fixtures.TestVehicle(t, db, sellerID), buyerID, "delivered")
+	inTransit := fixtures.TestOrder(t, db, fixtures.TestVehicle(t, db, sellerID), buyerID, "in_transit")
+
+	// Someone other than the buyer
+	rec := postReview(r, delivered, otherID, `{"rating": 1}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The seller can't review themselves either
+	rec = postReview(r, delivered, sellerID, `{"rating": 5}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Order not completed yet
+	rec = postReview(r, inTransit, buyerID, `{"rating": 4}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Rating out of range
+	rec = postReview(r, delivered, buyerID, `{"rating": 6}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = postReview(r, delivered, buyerID, `{"rating": 5, "comment": "Exactly as described"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// One review per order
+	rec = postReview(r, delivered, buyerID, `{"rating": 3}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM reviews WHERE seller_id = $1`, sellerID).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestGetSellerReviews_AggregateUpdates(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	r := setupReviewRouter(db, logger)
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+
+	getSummary := func() map[string]interface{} {
+		req := httptest.NewRequest("GET", "/api/sellers/"+strconv.FormatInt(sellerID, 10)+"/reviews", nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// No reviews yet
+	resp := getSummary()
+	assert.Nil(t, resp["average_rating"])
+	assert.Equal(t, float64(0), resp["total"])
+
+	first := fixtures.TestOrder(t, db, fixtures.TestVehicle(t, db, sellerID), buyerID, "delivered")
+	require.Equal(t, http.StatusCreated, postReview(r, first, buyerID, `{"rating": 5}`).Code)
+
+	resp = getSummary()
+	assert.Equal(t, "5.00", resp["average_rating"])
+	assert.Equal(t, float64(1), resp["total"])
+
+	second := fixtures.TestOrder(t, db, fixtures.TestVehicle(t, db, sellerID), buyerID, "delivered")
+	require.Equal(t, http.StatusCreated, postReview(r, second, buyerID, `{"rating": 2, "comment": "Slow pickup"}`).Code)
+
+	resp = getSummary()
+	assert.Equal(t, "3.50", resp["average_rating"])
+	assert.Equal(t, float64(2), resp["total"])
+	counts := resp["rating_counts"].(map[string]interface{})
+	assert.Equal(t, float64(1), counts["2"])
+	assert.Equal(t, float64(1), counts["5"])
+
+	// Newest first
+	reviews := resp["reviews"].([]interface{})
+	require.Len(t, reviews, 2)
+	assert.Equal(t, "Slow pickup", reviews[0].(map[string]interface{})["comment"])
+}
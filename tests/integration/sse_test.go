@@ -0,0 +1,332 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/config"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/realtime"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type auctionTick struct {
+	AuctionID        int64  `json:"auction_id"`
+	Status           string `json:"status"`
+	CurrentBid       string `json:"current_bid"`
+	BidCount         int    `json:"bid_count"`
+	SecondsRemaining int64  `json:"seconds_remaining"`
+}
+
+func TestStreamAuction_TickCarriesCountdown(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 150, buyerID)
+
+	broker := realtime.NewBroker(logger)
+	broker.Start()
+	defer broker.Stop()
+
+	cfg := &config.Config{SSEKeepaliveInterval: 1100 * time.Millisecond, SSETickEvent: "tick"}
+	sseHandler := handler.NewSSEHandler(db, broker, logger, cfg)
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}/stream", sseHandler.StreamAuction)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/auctions/%d/stream", server.URL, auctionID), nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Collect the first two ticks, skipping the connected event
+	ticks := make([]auctionTick, 0, 2)
+	event := ""
+	scanner := bufio.NewScanner(resp.Body)
+	for len(ticks) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "tick":
+			var tick auctionTick
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &tick))
+			ticks = append(ticks, tick)
+		case strings.HasPrefix(line, ": keepalive"):
+			t.Fatal("got a bare keepalive comment instead of a tick")
+		}
+	}
+	require.Len(t, ticks, 2, scanner.Err())
+
+	assert.Equal(t, auctionID, ticks[0].AuctionID)
+	assert.Equal(t, "active", ticks[0].Status)
+	assert.Equal(t, "150.00", ticks[0].CurrentBid)
+	assert.Equal(t, 1, ticks[0].BidCount)
+	assert.Positive(t, ticks[1].SecondsRemaining)
+	assert.Less(t, ticks[1].SecondsRemaining, ticks[0].SecondsRemaining)
+}
+
+func TestStreamAuction_TickIsCachedUntilAnAuctionEvent(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuctionWithBid(t, db, fixtures.TestVehicle(t, db, sellerID), 150, buyerID)
+
+	ticks := handler.NewTickCache(time.Minute)
+	broker := realtime.NewBroker(logger, realtime.WithEventHook(ticks.Observe))
+	broker.Start()
+	defer broker.Stop()
+
+	cfg := &config.Config{SSEKeepaliveInterval: 300 * time.Millisecond, SSETickEvent: "tick"}
+	sseHandler := handler.NewSSEHandler(db, broker, logger, cfg, handler.WithTickCache(ticks))
+	r := chi.NewRouter()
+	r.Get("/api/auctions/{id}/stream", sseHandler.StreamAuction)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, "GET", fmt.Sprintf("%s/api/auctions/%d/stream", server.URL, auctionID), nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	nextTick := func() auctionTick {
+		event := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: ") && event == "tick":
+				var tick auctionTick
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &tick))
+				return tick
+			}
+		}
+		t.Fatalf("stream ended before a tick: %v", scanner.Err())
+		return auctionTick{}
+	}
+
+	assert.Equal(t, "150.00", nextTick().CurrentBid)
+
+	// A change that sends no event isn't read again on every tick
+	var version int
+	require.NoError(t, db.QueryRow(ctx, `
+		UPDATE auctions SET current_bid = 200, bid_count = 2, version = version + 1
+		WHERE id = $1 RETURNING version
+	`, auctionID).Scan(&version))
+	assert.Equal(t, "150.00", nextTick().CurrentBid)
+
+	// An auction event expires it
+	broker.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: auctionID, Amount: decimal.NewFromInt(200), Version: version})
+	require.Eventually(t, func() bool { return nextTick().CurrentBid == "200.00" }, 5*time.Second, time.Millisecond)
+}
+
+func TestStream_MaxConnsPerUser(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	broker := realtime.NewBroker(logger, realtime.WithMaxConnsPerUser(3))
+	broker.Start()
+	defer broker.Stop()
+
+	sseHandler := handler.NewSSEHandler(nil, broker, logger, &config.Config{SSEKeepaliveInterval: time.Minute})
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := strconv.ParseInt(r.Header.Get("X-Test-User"), 10, 64)
+			next.ServeHTTP(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+		})
+	})
+	r.Get("/api/auctions/{id}/stream", sseHandler.StreamAuction)
+	r.Get("/api/notifications/stream", sseHandler.StreamNotifications)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	open := func(userID int64, path string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Test-User", strconv.FormatInt(userID, 10))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// User 1 fills the cap across auction and notification streams
+	for _, path := range []string{"/api/auctions/1/stream", "/api/auctions/2/stream", "/api/notifications/stream"} {
+		resp := open(1, path)
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, open(1, "/api/auctions/3/stream").StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, open(1, "/api/notifications/stream").StatusCode)
+
+	// Another user still gets in
+	assert.Equal(t, http.StatusOK, open(2, "/api/auctions/1/stream").StatusCode)
+	assert.Equal(t, http.StatusOK, open(2, "/api/notifications/stream").StatusCode)
+}
+
+func TestStream_MaxConnsPerIP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	broker := realtime.NewBroker(logger, realtime.WithMaxConnsPerIP(4))
+	broker.Start()
+	defer broker.Stop()
+
+	// Trust the loopback test client as a proxy so X-Forwarded-For picks the IP
+	trusted, err := middleware.ParseTrustedProxies([]string{"127.0.0.1", "::1"})
+	require.NoError(t, err)
+	sseHandler := handler.NewSSEHandler(nil, broker, logger, &config.Config{SSEKeepaliveInterval: time.Minute})
+	r := chi.NewRouter()
+	r.Use(middleware.RealIP(trusted))
+	r.Get("/api/auctions/{id}/stream", sseHandler.StreamAuction)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	open := func(ip, path string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Forwarded-For", ip)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// Anonymous viewers from one address fill its cap across auctions
+	for i := 1; i <= 4; i++ {
+		resp := open("203.0.113.7", fmt.Sprintf("/api/auctions/%d/stream", i))
+		require.Equal(t, http.StatusOK, resp.StatusCode, i)
+	}
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusTooManyRequests, open("203.0.113.7", "/api/auctions/1/stream").StatusCode)
+	}
+
+	// Another address still gets in
+	assert.Equal(t, http.StatusOK, open("198.51.100.1", "/api/auctions/1/stream").StatusCode)
+}
+
+func TestStreamGlobal_BidOnAnyAuction(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	broker := realtime.NewBroker(logger, realtime.WithGlobalInterval(50*time.Millisecond))
+	broker.Start()
+	defer broker.Stop()
+
+	sseHandler := handler.NewSSEHandler(nil, broker, logger, &config.Config{SSEKeepaliveInterval: time.Minute})
+	r := chi.NewRouter()
+	r.Get("/api/auctions/stream", sseHandler.StreamGlobal)
+	r.Get("/api/auctions/{id}/stream", sseHandler.StreamAuction)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/auctions/stream", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Wait for the connected event so the subscription is in place
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && scanner.Text() != "event: connected" {
+	}
+
+	// Bids on auctions nobody is watching individually still reach the stream
+	for _, auctionID := range []int64{11, 42} {
+		broker.Broadcast(domain.BidEvent{
+			Type:      "bid_accepted",
+			AuctionID: auctionID,
+			Amount:    decimal.NewFromInt(auctionID * 100),
+			BidCount:  1,
+			Timestamp: time.Now(),
+		})
+	}
+
+	type update struct {
+		AuctionID  int64  `json:"auction_id"`
+		Event      string `json:"event"`
+		CurrentBid string `json:"current_bid"`
+		BidCount   int    `json:"bid_count"`
+	}
+	seen := make(map[int64]update)
+	event := ""
+	for len(seen) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "auction_update":
+			var u update
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &u))
+			seen[u.AuctionID] = u
+		}
+	}
+	require.Len(t, seen, 2, scanner.Err())
+	assert.Equal(t, update{AuctionID: 11, Event: "bid", CurrentBid: "1100", BidCount: 1}, seen[11])
+	assert.Equal(t, update{AuctionID: 42, Event: "bid", CurrentBid: "4200", BidCount: 1}, seen[42])
+}
+
+func TestPGRelay_CrossInstanceDelivery(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// Two brokers standing in for two server replicas on one database
+	channel := "sse_test_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	instanceA := realtime.NewBroker(logger, realtime.WithRelay(realtime.NewPGRelay(db, channel)))
+	instanceB := realtime.NewBroker(logger, realtime.WithRelay(realtime.NewPGRelay(db, channel)))
+	for _, b := range []*realtime.Broker{instanceA, instanceB} {
+		b.Start()
+		defer b.Stop()
+		select {
+		case <-b.RelayReady():
+		case <-time.After(5 * time.Second):
+			t.Fatal("relay never started listening")
+		}
+	}
+
+	sub := &realtime.Subscriber{ID: "viewer-on-b", Messages: make(chan []byte, 10), Done: make(chan struct{})}
+	require.NoError(t, instanceB.Subscribe(42, sub))
+
+	instanceA.Broadcast(domain.BidEvent{Type: "bid_accepted", AuctionID: 42, Amount: decimal.NewFromInt(150), Timestamp: time.Now()})
+	select {
+	case msg := <-sub.Messages:
+		assert.Contains(t, string(msg), "event: bid_accepted")
+		assert.Contains(t, string(msg), `"auction_id":42`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("bid on instance A never reached instance B")
+	}
+}
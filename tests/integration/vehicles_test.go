@@ -1,17 +1,26 @@
 package integration
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ayubfarah/vehicle-auc/internal/config"
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/metrics"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
 	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,7 +29,7 @@ func TestListVehiclesEmpty(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
-	vehicleHandler := handler.NewVehicleHandler(db, logger)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
 
 	req := httptest.NewRequest("GET", "/api/vehicles", nil)
 	rec := httptest.NewRecorder()
@@ -48,7 +57,7 @@ func TestListVehiclesWithData(t *testing.T) {
 	fixtures.TestVehicle(t, db, sellerID)
 	fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000)
 
-	vehicleHandler := handler.NewVehicleHandler(db, logger)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
 
 	req := httptest.NewRequest("GET", "/api/vehicles", nil)
 	rec := httptest.NewRecorder()
@@ -61,7 +70,7 @@ func TestListVehiclesWithData(t *testing.T) {
 	err := json.Unmarshal(rec.Body.Bytes(), &resp)
 	require.NoError(t, err)
 
-	vehicles := resp["vehicles"].([]interface{})
+	vehicles := resp["items"].([]interface{})
 	assert.Len(t, vehicles, 2)
 }
 
@@ -74,7 +83,7 @@ func TestListVehiclesFilterByMake(t *testing.T) {
 	fixtures.TestVehicle(t, db, sellerID)                                        // Honda
 	fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000) // Toyota
 
-	vehicleHandler := handler.NewVehicleHandler(db, logger)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
 
 	req := httptest.NewRequest("GET", "/api/vehicles?make=Honda", nil)
 	rec := httptest.NewRecorder()
@@ -87,13 +96,56 @@ func TestListVehiclesFilterByMake(t *testing.T) {
 	err := json.Unmarshal(rec.Body.Bytes(), &resp)
 	require.NoError(t, err)
 
-	vehicles := resp["vehicles"].([]interface{})
+	vehicles := resp["items"].([]interface{})
 	assert.Len(t, vehicles, 1)
 
 	vehicle := vehicles[0].(map[string]interface{})
 	assert.Equal(t, "Honda", vehicle["make"])
 }
 
+func TestListVehiclesFilter_PartialAndLiteral(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	fixtures.TestVehicle(t, db, sellerID) // Honda Civic
+	fixtures.TestVehicleWithDetails(t, db, sellerID, 2023, "Tesla", "Model_3", 40000)
+	fixtures.TestVehicleWithDetails(t, db, sellerID, 2023, "Tesla", "Model33", 40000)
+	fixtures.TestVehicleWithDetails(t, db, sellerID, 2020, "A+ Motors", "100% Electric", 15000)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	models := func(query string) []string {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/vehicles?"+query, nil)
+		rec := httptest.NewRecorder()
+		vehicleHandler.ListVehicles(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp struct {
+			Items []struct {
+				Model string `json:"model"`
+			} `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		out := make([]string, 0, len(resp.Items))
+		for _, v := range resp.Items {
+			out = append(out, v.Model)
+		}
+		return out
+	}
+
+	// Partial, case-insensitive matches
+	assert.Equal(t, []string{"Civic"}, models("make=hon"))
+	assert.ElementsMatch(t, []string{"Model_3", "Model33"}, models("model=odel"))
+
+	// Metacharacters match themselves only
+	assert.Equal(t, []string{"Model_3"}, models("model=l_3"))
+	assert.Equal(t, []string{"100% Electric"}, models("model=100%25"))
+	assert.Equal(t, []string{"Model_3"}, models("model=_"))
+	assert.Equal(t, []string{"100% Electric"}, models("make=A%2B"))
+}
+
 func TestGetVehicle(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
@@ -102,7 +154,7 @@ func TestGetVehicle(t *testing.T) {
 	sellerID := fixtures.SellerUser(t, db)
 	vehicleID := fixtures.TestVehicle(t, db, sellerID)
 
-	vehicleHandler := handler.NewVehicleHandler(db, logger)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
 
 	// Setup router to extract URL params
 	r := chi.NewRouter()
@@ -128,7 +180,7 @@ func TestGetVehicleNotFound(t *testing.T) {
 	db := fixtures.SetupTestDBWithMigrations(t)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
-	vehicleHandler := handler.NewVehicleHandler(db, logger)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
 
 	r := chi.NewRouter()
 	r.Get("/api/vehicles/{id}", vehicleHandler.GetVehicle)
@@ -151,7 +203,7 @@ func TestListVehiclesPagination(t *testing.T) {
 		fixtures.TestVehicleWithDetails(t, db, sellerID, 2020+i, "Test", "Model", float64(10000+i*1000))
 	}
 
-	vehicleHandler := handler.NewVehicleHandler(db, logger)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
 
 	// Test limit
 	req := httptest.NewRequest("GET", "/api/vehicles?limit=2", nil)
@@ -161,7 +213,7 @@ func TestListVehiclesPagination(t *testing.T) {
 	var resp map[string]interface{}
 	json.Unmarshal(rec.Body.Bytes(), &resp)
 
-	vehicles := resp["vehicles"].([]interface{})
+	vehicles := resp["items"].([]interface{})
 	assert.Len(t, vehicles, 2)
 	assert.True(t, resp["has_more"].(bool))
 
@@ -171,11 +223,745 @@ func TestListVehiclesPagination(t *testing.T) {
 	vehicleHandler.ListVehicles(rec, req)
 
 	json.Unmarshal(rec.Body.Bytes(), &resp)
-	vehicles = resp["vehicles"].([]interface{})
+	vehicles = resp["items"].([]interface{})
 	assert.Len(t, vehicles, 2)
 }
 
+func TestUpdateVehicle_StaleVersionRejected(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	r := chi.NewRouter()
+	r.Get("/api/vehicles/{id}", vehicleHandler.GetVehicle)
+	r.Put("/api/vehicles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		vehicleHandler.UpdateVehicle(w, r.WithContext(ctx))
+	})
+
+	// Both editors load the same version
+	req := httptest.NewRequest("GET", "/api/vehicles/"+itoa(vehicleID), nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.Equal(t, `"1"`, etag)
+
+	update := func(ifMatch string, body map[string]interface{}) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest("PUT", "/api/vehicles/"+itoa(vehicleID), bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// First editor wins
+	rec = update(etag, map[string]interface{}{"mileage": 50000})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	// Second editor still holds version 1
+	rec = update(etag, map[string]interface{}{"mileage": 99999})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// Same precondition via the body field
+	rec = update("", map[string]interface{}{"mileage": 99999, "version": 1})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var mileage, version int
+	err := db.QueryRow(context.Background(), `SELECT mileage, version FROM vehicles WHERE id = $1`, vehicleID).Scan(&mileage, &version)
+	require.NoError(t, err)
+	assert.Equal(t, 50000, mileage)
+	assert.Equal(t, 2, version)
+
+	// A precondition on a vehicle that's gone is a 404, not a conflict
+	_, err = db.Exec(context.Background(), `DELETE FROM vehicles WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+	rec = update(`"2"`, map[string]interface{}{"mileage": 1})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUpdateVehicle_InvalidIfMatch(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	r := chi.NewRouter()
+	r.Put("/api/vehicles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		vehicleHandler.UpdateVehicle(w, r.WithContext(ctx))
+	})
+
+	req := httptest.NewRequest("PUT", "/api/vehicles/"+itoa(vehicleID), bytes.NewReader([]byte(`{"mileage": 1}`)))
+	req.Header.Set("If-Match", `"abc"`)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
 func itoa(i int64) string {
 	return strconv.FormatInt(i, 10)
 }
 
+
+func TestSubmitVehicle_ListingLimit(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	fixtures.TestVehicle(t, db, sellerID) // already active
+	draftID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET status = 'draft' WHERE id = $1`, draftID)
+	require.NoError(t, err)
+
+	submit := func(limit int) *httptest.ResponseRecorder {
+		vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{MaxActiveListingsPerSeller: limit})
+		r := chi.NewRouter()
+		r.Post("/api/vehicles/{id}/submit", func(w http.ResponseWriter, r *http.Request) {
+			ctx := middleware.WithUserID(r.Context(), sellerID)
+			vehicleHandler.SubmitVehicle(w, r.WithContext(ctx))
+		})
+
+		req := httptest.NewRequest("POST", "/api/vehicles/"+itoa(draftID)+"/submit", nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := submit(1)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "active listing limit reached")
+
+	var status string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT status FROM vehicles WHERE id = $1`, draftID).Scan(&status))
+	assert.Equal(t, "draft", status)
+
+	rec = submit(2)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCreateVehicle_FieldValidationErrors(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	tests := []struct {
+		name   string
+		body   string
+		fields map[string]string
+	}{
+		{
+			name:   "missing vin",
+			body:   `{"year": 2021, "make": "Honda", "model": "Accord", "starting_price": 15000}`,
+			fields: map[string]string{"vin": "is required"},
+		},
+		{
+			name:   "year out of range",
+			body:   `{"vin": "1HGBH41JXMN109186", "year": 1800, "make": "Honda", "model": "Accord", "starting_price": 15000}`,
+			fields: map[string]string{"year": "must be at least 1900"},
+		},
+		{
+			name: "short vin and missing model",
+			body: `{"vin": "123", "year": 2021, "make": "Honda", "starting_price": 15000}`,
+			fields: map[string]string{
+				"vin":   "must be 17 characters",
+				"model": "is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/vehicles", bytes.NewReader([]byte(tt.body)))
+			req = req.WithContext(middleware.WithUserID(req.Context(), sellerID))
+			rec := httptest.NewRecorder()
+			vehicleHandler.CreateVehicle(rec, req)
+
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var resp struct {
+				Error  string            `json:"error"`
+				Fields map[string]string `json:"fields"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation failed", resp.Error)
+			assert.Equal(t, tt.fields, resp.Fields)
+		})
+	}
+}
+
+func TestVehicleDescription_Policy(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{DescriptionMaxLength: 40},
+		handler.WithBlocklist(handler.NewWordBlocklist([]string{"scam", "wire me"})))
+
+	vins := []string{"1HGBH41JXMN109181", "1HGBH41JXMN109182", "1HGBH41JXMN109183", "1HGBH41JXMN109184"}
+	create := func(description string) *httptest.ResponseRecorder {
+		vin := vins[0]
+		vins = vins[1:]
+		body, err := json.Marshal(map[string]interface{}{
+			"vin": vin, "year": 2021, "make": "Honda", "model": "Accord",
+			"starting_price": 15000, "description": description,
+		})
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/api/vehicles", bytes.NewReader(body))
+		req = req.WithContext(middleware.WithUserID(req.Context(), sellerID))
+		rec := httptest.NewRecorder()
+		vehicleHandler.CreateVehicle(rec, req)
+		return rec
+	}
+	fieldError := func(rec *httptest.ResponseRecorder) string {
+		t.Helper()
+		require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		var resp struct {
+			Fields map[string]string `json:"fields"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.Fields["description"]
+	}
+
+	t.Run("clean description is stored without control characters", func(t *testing.T) {
+		rec := create("  One owner.\x00\x1b[31m\r\nClassic  ")
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var created struct {
+			ID int64 `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+		var stored string
+		require.NoError(t, db.QueryRow(context.Background(), `SELECT description FROM vehicles WHERE id = $1`, created.ID).Scan(&stored))
+		assert.Equal(t, "One owner.[31m\nClassic", stored)
+	})
+
+	t.Run("over length", func(t *testing.T) {
+		assert.Equal(t, "must be at most 40 characters", fieldError(create(strings.Repeat("a", 41))))
+	})
+
+	t.Run("blocked term", func(t *testing.T) {
+		assert.Equal(t, `contains a disallowed term: "scam"`, fieldError(create("Definitely not a SCAM.")))
+		assert.Equal(t, `contains a disallowed term: "wire me"`, fieldError(create("Wire me the deposit")))
+	})
+}
+
+func TestCreateVehicle_DuplicateVIN(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	create := func() *httptest.ResponseRecorder {
+		body := `{"vin": "1HGCM82633A004352", "year": 2021, "make": "Honda", "model": "Accord", "starting_price": 15000}`
+		req := httptest.NewRequest("POST", "/api/vehicles", bytes.NewReader([]byte(body)))
+		req = req.WithContext(middleware.WithUserID(req.Context(), sellerID))
+		rec := httptest.NewRecorder()
+		vehicleHandler.CreateVehicle(rec, req)
+		return rec
+	}
+
+	rec := create()
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+	rec = create()
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var resp map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "vin_already_listed", resp["code"])
+
+	// Once the first listing is archived the VIN can be listed again
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET status = 'archived' WHERE id = $1`, int64(created["vehicle_id"].(float64)))
+	require.NoError(t, err)
+
+	rec = create()
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestSubmitVehicle_RequiresVerifiedSeller(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	verifiedID := fixtures.SellerUser(t, db)
+	unverifiedID := fixtures.TestUser(t, db)
+	adminID := fixtures.AdminUser(t, db)
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	submit := func(sellerID int64) (int64, *httptest.ResponseRecorder) {
+		draftID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000)
+		_, err := db.Exec(context.Background(), `UPDATE vehicles SET status = 'draft' WHERE id = $1`, draftID)
+		require.NoError(t, err)
+
+		r := chi.NewRouter()
+		r.Post("/api/vehicles/{id}/submit", func(w http.ResponseWriter, r *http.Request) {
+			ctx := middleware.WithUserID(r.Context(), sellerID)
+			vehicleHandler.SubmitVehicle(w, r.WithContext(ctx))
+		})
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/vehicles/"+itoa(draftID)+"/submit", nil))
+		return draftID, rec
+	}
+	statusOf := func(vehicleID int64) string {
+		var status string
+		require.NoError(t, db.QueryRow(context.Background(), `SELECT status FROM vehicles WHERE id = $1`, vehicleID).Scan(&status))
+		return status
+	}
+
+	draftID, rec := submit(unverifiedID)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "seller_not_verified", body["code"])
+	assert.Equal(t, "draft", statusOf(draftID))
+
+	draftID, rec = submit(verifiedID)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "active", statusOf(draftID))
+
+	// Admins list on the platform's behalf without ID verification
+	draftID, rec = submit(adminID)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "active", statusOf(draftID))
+}
+
+func TestUpdateVehicle_EnumValidation(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	r := chi.NewRouter()
+	r.Put("/api/vehicles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		vehicleHandler.UpdateVehicle(w, r.WithContext(ctx))
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+	}{
+		{"valid values", `{"drivetrain": "AWD", "fuel_type": "Hybrid", "title_status": "rebuilt"}`, http.StatusOK, ""},
+		{"unknown drivetrain", `{"drivetrain": "6WD"}`, http.StatusBadRequest, "drivetrain"},
+		{"unknown fuel type", `{"fuel_type": "Steam"}`, http.StatusBadRequest, "fuel_type"},
+		{"wrong case title status", `{"title_status": "CLEAN"}`, http.StatusBadRequest, "title_status"},
+		{"unknown condition grade", `{"condition_grade": "Z"}`, http.StatusBadRequest, "condition_grade"},
+		{"unknown transmission", `{"transmission": "Telepathic"}`, http.StatusBadRequest, "transmission"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/vehicles/"+itoa(vehicleID), bytes.NewReader([]byte(tt.body)))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.field == "" {
+				return
+			}
+
+			var resp struct {
+				Fields map[string]string `json:"fields"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Contains(t, resp.Fields, tt.field)
+		})
+	}
+
+	var drivetrain, fuelType string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT drivetrain, fuel_type FROM vehicles WHERE id = $1`, vehicleID).Scan(&drivetrain, &fuelType))
+	assert.Equal(t, "AWD", drivetrain)
+	assert.Equal(t, "Hybrid", fuelType)
+}
+
+func TestGetVehicleOptions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	vehicleHandler := handler.NewVehicleHandler(nil, logger, &config.Config{})
+
+	req := httptest.NewRequest("GET", "/api/vehicles/options", nil)
+	rec := httptest.NewRecorder()
+	vehicleHandler.GetVehicleOptions(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp map[string][]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	for _, field := range []string{"title_status", "condition_grade", "fuel_type", "drivetrain", "transmission"} {
+		assert.NotEmpty(t, resp[field], field)
+	}
+}
+
+func TestSubmitVehicle_MinImages(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	draftID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2022, "Toyota", "Camry", 20000)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET status = 'draft' WHERE id = $1`, draftID)
+	require.NoError(t, err)
+	fixtures.TestImages(t, db, draftID, 1)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{MinImagesToSubmit: 3})
+	r := chi.NewRouter()
+	r.Post("/api/vehicles/{id}/submit", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		vehicleHandler.SubmitVehicle(w, r.WithContext(ctx))
+	})
+	submit := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/vehicles/"+itoa(draftID)+"/submit", nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := submit()
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "at least 3 images are required: 1 uploaded, 2 more needed")
+
+	var status string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT status FROM vehicles WHERE id = $1`, draftID).Scan(&status))
+	assert.Equal(t, "draft", status)
+
+	fixtures.TestImages(t, db, draftID, 2)
+
+	rec = submit()
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestListVehicles_QueryTimeout(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	fixtures.TestVehicle(t, db, sellerID)
+
+	t.Run("request deadline already passed", func(t *testing.T) {
+		vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{DBQueryTimeout: 5 * time.Second})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+
+		req := httptest.NewRequest("GET", "/api/vehicles", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		vehicleHandler.ListVehicles(rec, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		var resp map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "database timeout", resp["error"])
+	})
+
+	t.Run("configured query timeout", func(t *testing.T) {
+		vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{DBQueryTimeout: time.Nanosecond})
+
+		req := httptest.NewRequest("GET", "/api/vehicles", nil)
+		rec := httptest.NewRecorder()
+		vehicleHandler.ListVehicles(rec, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	})
+}
+
+func TestUpdateVehicle_PatchKeepsOmittedFieldsPutClearsThem(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+	_, err := db.Exec(context.Background(), `UPDATE vehicles SET reserve_price = 5000 WHERE id = $1`, vehicleID)
+	require.NoError(t, err)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	r := chi.NewRouter()
+	r.Patch("/api/vehicles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		vehicleHandler.UpdateVehicle(w, r.WithContext(ctx))
+	})
+	r.Put("/api/vehicles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := middleware.WithUserID(r.Context(), sellerID)
+		vehicleHandler.ReplaceVehicle(w, r.WithContext(ctx))
+	})
+
+	send := func(method string, body map[string]interface{}) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/api/vehicles/"+itoa(vehicleID), bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	type snapshot struct {
+		Mileage      *int
+		Trim         *string
+		ReservePrice *float64
+		City         *string
+	}
+	load := func() snapshot {
+		var s snapshot
+		err := db.QueryRow(context.Background(), `
+			SELECT mileage, trim, reserve_price::float8, location_city FROM vehicles WHERE id = $1
+		`, vehicleID).Scan(&s.Mileage, &s.Trim, &s.ReservePrice, &s.City)
+		require.NoError(t, err)
+		return s
+	}
+
+	// PATCH only touches what was sent
+	rec := send("PATCH", map[string]interface{}{"mileage": 40000})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	got := load()
+	require.NotNil(t, got.Mileage)
+	assert.Equal(t, 40000, *got.Mileage)
+	require.NotNil(t, got.Trim)
+	assert.Equal(t, "Sport", *got.Trim)
+	require.NotNil(t, got.ReservePrice)
+	assert.Equal(t, 5000.0, *got.ReservePrice)
+	require.NotNil(t, got.City)
+
+	// PUT without the full representation is rejected
+	rec = send("PUT", map[string]interface{}{"mileage": 45000})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// PUT resets omitted optional fields, which is how reserve_price gets cleared
+	rec = send("PUT", map[string]interface{}{
+		"year":           2021,
+		"make":           "Honda",
+		"model":          "Accord",
+		"starting_price": 100,
+		"mileage":        45000,
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	got = load()
+	require.NotNil(t, got.Mileage)
+	assert.Equal(t, 45000, *got.Mileage)
+	assert.Nil(t, got.Trim)
+	assert.Nil(t, got.ReservePrice)
+	assert.Nil(t, got.City)
+}
+
+func TestVehicleOwnership_NonOwnerGetsNotFound(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	otherID := fixtures.BuyerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(middleware.WithUserID(r.Context(), otherID)))
+		})
+	})
+	r.Patch("/api/vehicles/{id}", vehicleHandler.UpdateVehicle)
+	r.Put("/api/vehicles/{id}", vehicleHandler.ReplaceVehicle)
+	r.Delete("/api/vehicles/{id}", vehicleHandler.DeleteVehicle)
+	r.Post("/api/vehicles/{id}/submit", vehicleHandler.SubmitVehicle)
+	r.Post("/api/vehicles/{id}/restore", vehicleHandler.RestoreVehicle)
+
+	routes := []struct{ method, path string }{
+		{"PATCH", "/api/vehicles/%s"},
+		{"PUT", "/api/vehicles/%s"},
+		{"DELETE", "/api/vehicles/%s"},
+		{"POST", "/api/vehicles/%s/submit"},
+		{"POST", "/api/vehicles/%s/restore"},
+	}
+	for _, route := range routes {
+		send := func(id string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(route.method, fmt.Sprintf(route.path, id), bytes.NewReader([]byte(`{"mileage": 1}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			return rec
+		}
+
+		// Someone else's vehicle looks exactly like one that doesn't exist
+		notOwned := send(itoa(vehicleID))
+		missing := send("999999")
+		assert.Equal(t, http.StatusNotFound, notOwned.Code, "%s %s", route.method, route.path)
+		assert.Equal(t, missing.Code, notOwned.Code, "%s %s", route.method, route.path)
+		assert.Equal(t, missing.Body.String(), notOwned.Body.String(), "%s %s", route.method, route.path)
+	}
+
+	var mileage int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT mileage FROM vehicles WHERE id = $1`, vehicleID).Scan(&mileage))
+	assert.NotEqual(t, 1, mileage)
+}
+
+func TestVehicleOwnership_NonOwnerEditIsAudited(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	otherID := fixtures.BuyerUser(t, db)
+	vehicleID := fixtures.TestVehicle(t, db, sellerID)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(middleware.WithUserID(r.Context(), otherID)))
+		})
+	})
+	r.Patch("/api/vehicles/{id}", vehicleHandler.UpdateVehicle)
+
+	edit := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/vehicles/"+id, bytes.NewReader([]byte(`{"mileage": 1}`)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", "audit-req-1")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	denied := testutil.ToFloat64(metrics.AuthzDeniedTotal.WithLabelValues(middleware.DeniedNotOwner))
+	rec := edit(itoa(vehicleID))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, denied+1, testutil.ToFloat64(metrics.AuthzDeniedTotal.WithLabelValues(middleware.DeniedNotOwner)))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry), logs.String())
+	assert.Equal(t, "authz_denied", entry["msg"])
+	assert.Equal(t, float64(otherID), entry["user_id"])
+	assert.Equal(t, "vehicle", entry["resource"])
+	assert.Equal(t, "PATCH /api/vehicles/{id}", entry["action"])
+	assert.Equal(t, middleware.DeniedNotOwner, entry["reason"])
+	assert.Equal(t, "audit-req-1", entry["request_id"])
+
+	// A vehicle that doesn't exist isn't an authorization failure
+	logs.Reset()
+	assert.Equal(t, http.StatusNotFound, edit("999999").Code)
+	assert.Equal(t, denied+1, testutil.ToFloat64(metrics.AuthzDeniedTotal.WithLabelValues(middleware.DeniedNotOwner)))
+	assert.Empty(t, logs.String())
+}
+
+func TestTransferVehicle(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+
+	sellerID := fixtures.SellerUser(t, db)
+	staffID := fixtures.SellerUser(t, db)
+	buyerID := fixtures.BuyerUser(t, db)
+	adminID := fixtures.AdminUser(t, db)
+
+	vehicleHandler := handler.NewVehicleHandler(db, logger, &config.Config{})
+	transfer := func(userID, vehicleID, toUserID int64) *httptest.ResponseRecorder {
+		r := chi.NewRouter()
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+			})
+		})
+		r.Post("/api/vehicles/{id}/transfer", vehicleHandler.TransferVehicle)
+
+		body := fmt.Sprintf(`{"to_user_id": %d}`, toUserID)
+		req := httptest.NewRequest("POST", "/api/vehicles/"+itoa(vehicleID)+"/transfer", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	ownerOf := func(vehicleID int64) int64 {
+		var owner int64
+		require.NoError(t, db.QueryRow(ctx, `SELECT seller_id FROM vehicles WHERE id = $1`, vehicleID).Scan(&owner))
+		return owner
+	}
+
+	// A vehicle whose auction already ended moves along with its history
+	soldID := fixtures.TestVehicle(t, db, sellerID)
+	endedAuctionID := fixtures.TestAuction(t, db, soldID)
+	_, err := db.Exec(ctx, `UPDATE auctions SET status = 'ended' WHERE id = $1`, endedAuctionID)
+	require.NoError(t, err)
+
+	rec := transfer(buyerID, soldID, staffID)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "strangers can't transfer")
+	rec = transfer(sellerID, soldID, buyerID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "target must be a seller")
+	assert.Equal(t, sellerID, ownerOf(soldID))
+
+	rec = transfer(sellerID, soldID, staffID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, staffID, ownerOf(soldID))
+
+	var from, to, by int64
+	require.NoError(t, db.QueryRow(ctx, `
+		SELECT from_user_id, to_user_id, transferred_by FROM vehicle_transfers WHERE vehicle_id = $1
+	`, soldID).Scan(&from, &to, &by))
+	assert.Equal(t, []int64{sellerID, staffID, sellerID}, []int64{from, to, by})
+
+	// A live auction blocks the transfer, even for an admin
+	liveID := fixtures.TestVehicle(t, db, sellerID)
+	liveAuctionID := fixtures.TestAuction(t, db, liveID)
+	rec = transfer(adminID, liveID, staffID)
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	assert.Equal(t, sellerID, ownerOf(liveID))
+
+	var transfers int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM vehicle_transfers WHERE vehicle_id = $1`, liveID).Scan(&transfers))
+	assert.Zero(t, transfers)
+
+	// Once it ends, an admin can reassign it
+	_, err = db.Exec(ctx, `UPDATE auctions SET status = 'ended' WHERE id = $1`, liveAuctionID)
+	require.NoError(t, err)
+	rec = transfer(adminID, liveID, staffID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, staffID, ownerOf(liveID))
+
+	// A listed vehicle only goes to a verified seller
+	unverifiedID := fixtures.SellerUser(t, db)
+	_, err = db.Exec(ctx, `UPDATE users SET id_verified_at = NULL WHERE id = $1`, unverifiedID)
+	require.NoError(t, err)
+	listedID := fixtures.TestVehicle(t, db, sellerID)
+	rec = transfer(sellerID, listedID, unverifiedID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "seller_not_verified")
+	assert.Equal(t, sellerID, ownerOf(listedID))
+
+	// A draft can move, but its new owner can't put it up for auction
+	draftID := fixtures.TestVehicle(t, db, sellerID)
+	_, err = db.Exec(ctx, `UPDATE vehicles SET status = 'draft' WHERE id = $1`, draftID)
+	require.NoError(t, err)
+	rec = transfer(sellerID, draftID, unverifiedID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	auctionHandler := handler.NewAuctionHandler(db, logger, &config.Config{}, nil, nil)
+	body := fmt.Sprintf(`{"vehicle_id": %d, "starts_at": %q, "ends_at": %q}`, draftID,
+		time.Now().Format(time.RFC3339), time.Now().Add(24*time.Hour).Format(time.RFC3339))
+	req := httptest.NewRequest("POST", "/api/auctions", strings.NewReader(body))
+	rec = httptest.NewRecorder()
+	auctionHandler.CreateAuction(rec, req.WithContext(middleware.WithUserID(req.Context(), unverifiedID)))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "seller_not_verified")
+}
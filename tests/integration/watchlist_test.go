@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/ayubfarah/vehicle-auc/internal/config"
 	"github.com/ayubfarah/vehicle-auc/internal/handler"
 	"github.com/ayubfarah/vehicle-auc/internal/middleware"
 	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
@@ -22,7 +24,7 @@ func TestGetWatchlist_Empty(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
 	userID := fixtures.BuyerUser(t, db)
-	watchlistHandler := handler.NewWatchlistHandler(db, logger)
+	watchlistHandler := handler.NewWatchlistHandler(db, logger, &config.Config{})
 
 	r := chi.NewRouter()
 	r.Get("/api/watchlist", func(w http.ResponseWriter, r *http.Request) {
@@ -41,7 +43,7 @@ func TestGetWatchlist_Empty(t *testing.T) {
 	err := json.Unmarshal(rec.Body.Bytes(), &resp)
 	require.NoError(t, err)
 
-	watchlist := resp["watchlist"].([]interface{})
+	watchlist := resp["items"].([]interface{})
 	assert.Len(t, watchlist, 0)
 	assert.Equal(t, float64(0), resp["total"])
 }
@@ -55,7 +57,7 @@ func TestAddToWatchlist(t *testing.T) {
 	vehicleID := fixtures.TestVehicle(t, db, sellerID)
 	auctionID := fixtures.TestAuction(t, db, vehicleID)
 
-	watchlistHandler := handler.NewWatchlistHandler(db, logger)
+	watchlistHandler := handler.NewWatchlistHandler(db, logger, &config.Config{})
 
 	r := chi.NewRouter()
 	r.Post("/api/auctions/{id}/watch", func(w http.ResponseWriter, r *http.Request) {
@@ -72,7 +74,7 @@ func TestAddToWatchlist(t *testing.T) {
 
 	// Verify in database
 	var count int
-	db.QueryRow(t.Context(), "SELECT COUNT(*) FROM watchlist WHERE user_id = $1 AND auction_id = $2", userID, auctionID).Scan(&count)
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM watchlist WHERE user_id = $1 AND auction_id = $2", userID, auctionID).Scan(&count)
 	assert.Equal(t, 1, count)
 }
 
@@ -86,9 +88,9 @@ func TestAddToWatchlist_Duplicate(t *testing.T) {
 	auctionID := fixtures.TestAuction(t, db, vehicleID)
 
 	// Add to watchlist first
-	db.Exec(t.Context(), "INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)", userID, auctionID)
+	db.Exec(context.Background(), "INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)", userID, auctionID)
 
-	watchlistHandler := handler.NewWatchlistHandler(db, logger)
+	watchlistHandler := handler.NewWatchlistHandler(db, logger, &config.Config{})
 
 	r := chi.NewRouter()
 	r.Post("/api/auctions/{id}/watch", func(w http.ResponseWriter, r *http.Request) {
@@ -106,7 +108,7 @@ func TestAddToWatchlist_Duplicate(t *testing.T) {
 
 	// Still only one entry
 	var count int
-	db.QueryRow(t.Context(), "SELECT COUNT(*) FROM watchlist WHERE user_id = $1 AND auction_id = $2", userID, auctionID).Scan(&count)
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM watchlist WHERE user_id = $1 AND auction_id = $2", userID, auctionID).Scan(&count)
 	assert.Equal(t, 1, count)
 }
 
@@ -120,9 +122,9 @@ func TestRemoveFromWatchlist(t *testing.T) {
 	auctionID := fixtures.TestAuction(t, db, vehicleID)
 
 	// Add to watchlist first
-	db.Exec(t.Context(), "INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)", userID, auctionID)
+	db.Exec(context.Background(), "INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)", userID, auctionID)
 
-	watchlistHandler := handler.NewWatchlistHandler(db, logger)
+	watchlistHandler := handler.NewWatchlistHandler(db, logger, &config.Config{})
 
 	r := chi.NewRouter()
 	r.Delete("/api/auctions/{id}/watch", func(w http.ResponseWriter, r *http.Request) {
@@ -139,7 +141,7 @@ func TestRemoveFromWatchlist(t *testing.T) {
 
 	// Verify removed
 	var count int
-	db.QueryRow(t.Context(), "SELECT COUNT(*) FROM watchlist WHERE user_id = $1 AND auction_id = $2", userID, auctionID).Scan(&count)
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM watchlist WHERE user_id = $1 AND auction_id = $2", userID, auctionID).Scan(&count)
 	assert.Equal(t, 0, count)
 }
 
@@ -152,7 +154,7 @@ func TestIsWatching(t *testing.T) {
 	vehicleID := fixtures.TestVehicle(t, db, sellerID)
 	auctionID := fixtures.TestAuction(t, db, vehicleID)
 
-	watchlistHandler := handler.NewWatchlistHandler(db, logger)
+	watchlistHandler := handler.NewWatchlistHandler(db, logger, &config.Config{})
 
 	r := chi.NewRouter()
 	r.Get("/api/auctions/{id}/watching", func(w http.ResponseWriter, r *http.Request) {
@@ -171,7 +173,7 @@ func TestIsWatching(t *testing.T) {
 	assert.False(t, resp["watching"])
 
 	// Add to watchlist
-	db.Exec(t.Context(), "INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)", userID, auctionID)
+	db.Exec(context.Background(), "INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)", userID, auctionID)
 
 	// Now watching
 	req = httptest.NewRequest("GET", "/api/auctions/"+strconv.FormatInt(auctionID, 10)+"/watching", nil)
@@ -192,9 +194,9 @@ func TestGetWatchlist_WithData(t *testing.T) {
 	auctionID := fixtures.TestAuction(t, db, vehicleID)
 
 	// Add to watchlist
-	db.Exec(t.Context(), "INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)", userID, auctionID)
+	db.Exec(context.Background(), "INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)", userID, auctionID)
 
-	watchlistHandler := handler.NewWatchlistHandler(db, logger)
+	watchlistHandler := handler.NewWatchlistHandler(db, logger, &config.Config{})
 
 	r := chi.NewRouter()
 	r.Get("/api/watchlist", func(w http.ResponseWriter, r *http.Request) {
@@ -212,11 +214,65 @@ func TestGetWatchlist_WithData(t *testing.T) {
 	var resp map[string]interface{}
 	json.Unmarshal(rec.Body.Bytes(), &resp)
 
-	watchlist := resp["watchlist"].([]interface{})
+	watchlist := resp["items"].([]interface{})
 	assert.Len(t, watchlist, 1)
 	assert.Equal(t, float64(1), resp["total"])
+	assert.Equal(t, false, resp["has_more"])
 
 	item := watchlist[0].(map[string]interface{})
 	assert.Equal(t, float64(auctionID), item["auction_id"])
+	assert.Contains(t, item, "vehicle")
+	assert.Contains(t, item, "added_at")
 }
 
+func TestGetRecommendations_PrefersWatchedMakeAndBody(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	userID := fixtures.BuyerUser(t, db)
+	sellerID := fixtures.SellerUser(t, db)
+	listing := func(make, model, bodyType string, price float64) int64 {
+		vehicleID := fixtures.TestVehicleWithDetails(t, db, sellerID, 2021, make, model, price)
+		_, err := db.Exec(context.Background(), `UPDATE vehicles SET body_type = $2 WHERE id = $1`, vehicleID, bodyType)
+		require.NoError(t, err)
+		return fixtures.TestAuction(t, db, vehicleID)
+	}
+
+	watchedCivic := listing("Honda", "Civic", "Sedan", 20000)
+	watchedAccord := listing("Honda", "Accord", "Sedan", 22000)
+	hondaSedan := listing("Honda", "Insight", "Sedan", 21000)
+	hondaSUV := listing("Honda", "CR-V", "SUV", 24000)
+	toyotaSedan := listing("Toyota", "Camry", "Sedan", 19000)
+	unrelated := listing("Ford", "F-150", "Truck", 80000)
+
+	for _, auctionID := range []int64{watchedCivic, watchedAccord} {
+		_, err := db.Exec(context.Background(), `INSERT INTO watchlist (user_id, auction_id) VALUES ($1, $2)`, userID, auctionID)
+		require.NoError(t, err)
+	}
+
+	watchlistHandler := handler.NewWatchlistHandler(db, logger, &config.Config{})
+	r := chi.NewRouter()
+	r.Get("/api/recommendations", func(w http.ResponseWriter, r *http.Request) {
+		watchlistHandler.GetRecommendations(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+	})
+
+	req := httptest.NewRequest("GET", "/api/recommendations", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Recommendations []handler.RecommendedAuction `json:"recommendations"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	ids := make([]int64, 0, len(resp.Recommendations))
+	for _, a := range resp.Recommendations {
+		ids = append(ids, a.ID)
+	}
+	// Honda sedans first, then Hondas, then other sedans; nothing watched or unrelated
+	assert.Equal(t, []int64{hondaSedan, hondaSUV, toyotaSedan}, ids)
+	assert.NotContains(t, ids, unrelated)
+	assert.Greater(t, resp.Recommendations[0].Score, resp.Recommendations[1].Score)
+	assert.Greater(t, resp.Recommendations[1].Score, resp.Recommendations[2].Score)
+}
@@ -0,0 +1,197 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubfarah/vehicle-auc/internal/bidengine"
+	"github.com/ayubfarah/vehicle-auc/internal/domain"
+	"github.com/ayubfarah/vehicle-auc/internal/handler"
+	"github.com/ayubfarah/vehicle-auc/internal/middleware"
+	"github.com/ayubfarah/vehicle-auc/internal/webhook"
+	"github.com/ayubfarah/vehicle-auc/tests/fixtures"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedWebhook struct {
+	headers http.Header
+	body    []byte
+}
+
+// webhookReceiver is a mock integration endpoint recording deliveries
+func webhookReceiver(t *testing.T) (*httptest.Server, chan receivedWebhook) {
+	received := make(chan receivedWebhook, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{headers: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+// registerWebhook registers url for userID; the receivers are local
+// httptest servers, so the public-address check is lifted
+func registerWebhook(t *testing.T, db *pgxpool.Pool, logger *slog.Logger, userID int64, url string) handler.WebhookResponse {
+	t.Helper()
+	webhookHandler := handler.NewWebhookHandler(db, logger, handler.WithInsecureWebhookURLs(true))
+
+	req := httptest.NewRequest("POST", "/api/me/webhooks", strings.NewReader(`{"url": "`+url+`"}`))
+	req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	webhookHandler.CreateWebhook(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var resp handler.WebhookResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.Secret)
+	return resp
+}
+
+func TestBidWebhooks_SignedAndScopedToUser(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sellerID := fixtures.SellerUser(t, db)
+	firstBidderID := fixtures.BuyerUser(t, db)
+	secondBidderID := fixtures.BuyerUser(t, db)
+	auctionID := fixtures.TestAuction(t, db, fixtures.TestVehicle(t, db, sellerID))
+
+	firstServer, firstReceived := webhookReceiver(t)
+	secondServer, secondReceived := webhookReceiver(t)
+	firstHook := registerWebhook(t, db, logger, firstBidderID, firstServer.URL)
+	secondHook := registerWebhook(t, db, logger, secondBidderID, secondServer.URL)
+
+	dispatcher := webhook.NewDispatcher(db, logger, webhook.WithInsecureURLs(true))
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	engine := bidengine.NewEngine(db, logger, nil,
+		bidengine.WithSyncMode(true),
+		bidengine.WithNotifier(dispatcher),
+	)
+	engine.Start()
+	defer engine.Stop()
+
+	bid := func(userID, amount int64) {
+		ticketID := uuid.New().String()
+		require.NoError(t, engine.Submit(domain.BidRequest{
+			TicketID:  ticketID,
+			AuctionID: auctionID,
+			UserID:    userID,
+			Amount:    decimal.NewFromInt(amount),
+			CreatedAt: time.Now(),
+		}))
+		result, err := engine.GetResult(ticketID, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, "accepted", result.Status)
+	}
+
+	expect := func(received chan receivedWebhook, secret, event string) webhook.Payload {
+		t.Helper()
+		select {
+		case got := <-received:
+			assert.Equal(t, event, got.headers.Get(webhook.HeaderEvent))
+			assert.True(t, webhook.Verify(secret, got.headers.Get(webhook.HeaderTimestamp), got.body, got.headers.Get(webhook.HeaderSignature)),
+				"signature must verify with the registering user's secret")
+
+			var payload webhook.Payload
+			require.NoError(t, json.Unmarshal(got.body, &payload))
+			assert.Equal(t, event, payload.Event)
+			return payload
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %s webhook delivered", event)
+			return webhook.Payload{}
+		}
+	}
+
+	// First bidder only hears about their own bid
+	bid(firstBidderID, 500)
+	expect(firstReceived, firstHook.Secret, webhook.EventBidAccepted)
+
+	// Second bidder takes the lead: they get accepted, first bidder gets outbid
+	bid(secondBidderID, 600)
+	expect(secondReceived, secondHook.Secret, webhook.EventBidAccepted)
+	outbid := expect(firstReceived, firstHook.Secret, webhook.EventBidOutbid)
+
+	// The outbid payload doesn't reveal who outbid them
+	data := outbid.Data.(map[string]interface{})
+	assert.NotContains(t, data, "bidder_id")
+	assert.NotContains(t, data, "user_id")
+
+	// No cross-user leakage
+	select {
+	case got := <-secondReceived:
+		t.Fatalf("unexpected delivery to second bidder: %s", got.body)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestDeleteWebhook_OnlyOwn(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	ownerID := fixtures.BuyerUser(t, db)
+	otherID := fixtures.BuyerUser(t, db)
+	hook := registerWebhook(t, db, logger, ownerID, "https://example.com/hook")
+
+	webhookHandler := handler.NewWebhookHandler(db, logger)
+	r := setupWebhookRouter(webhookHandler, otherID)
+
+	req := httptest.NewRequest("DELETE", "/api/me/webhooks/"+itoa(hook.ID), nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM user_webhooks WHERE id = $1`, hook.ID).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestCreateWebhook_RejectsInternalURLs(t *testing.T) {
+	db := fixtures.SetupTestDBWithMigrations(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	userID := fixtures.BuyerUser(t, db)
+	webhookHandler := handler.NewWebhookHandler(db, logger)
+
+	for _, url := range []string{
+		"http://93.184.216.34/hook",
+		"https://127.0.0.1/hook",
+		"https://localhost/hook",
+		"https://10.0.0.5/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+	} {
+		req := httptest.NewRequest("POST", "/api/me/webhooks", strings.NewReader(`{"url": "`+url+`"}`))
+		req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		webhookHandler.CreateWebhook(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, url)
+	}
+
+	var count int
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM user_webhooks WHERE user_id = $1`, userID).Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func setupWebhookRouter(h *handler.WebhookHandler, userID int64) http.Handler {
+	r := chi.NewRouter()
+	r.Delete("/api/me/webhooks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		h.DeleteWebhook(w, r.WithContext(middleware.WithUserID(r.Context(), userID)))
+	})
+	return r
+}